@@ -37,9 +37,12 @@ var helpText = func(colors logger.Colors) string {
   --bundle              Bundle all dependencies into the output files
   --define:K=V          Substitute K with V while parsing
   --external:M          Exclude module M from the bundle (can use * wildcards)
-  --format=...          Output format (iife | cjs | esm, no default when not
-                        bundling, otherwise default is iife when platform
-                        is browser and cjs when platform is node)
+                        or use M=G to read package M from the global G in
+                        the iife, cjs, and umd formats
+  --format=...          Output format (iife | cjs | esm | umd | system, no
+                        default when not bundling, otherwise default is iife
+                        when platform is browser and cjs when platform is
+                        node)
   --loader:X=L          Use loader L to load file extension X, where L is
                         one of: js | jsx | ts | tsx | json | text | base64 |
                         file | dataurl | binary
@@ -50,7 +53,7 @@ var helpText = func(colors logger.Colors) string {
                         default browser)
   --serve=...           Start a local HTTP server on this host:port for outputs
   --sourcemap           Emit a source map
-  --splitting           Enable code splitting (currently only for esm)
+  --splitting           Enable code splitting (for esm, cjs, and iife)
   --target=...          Environment target (e.g. es2017, chrome58, firefox57,
                         safari11, edge16, node10, default esnext)
   --watch               Watch mode: rebuild on file system changes
@@ -67,18 +70,35 @@ var helpText = func(colors logger.Colors) string {
   --chunk-names=...         Path template to use for code splitting chunks
                             (default "[name]-[hash]")
   --color=...               Force use of color terminal escapes (true | false)
+  --dedupe-packages         Bundle one copy of files that are identical across
+                            nested "node_modules" copies of a package version
   --entry-names=...         Path template to use for entry point output paths
                             (default "[dir]/[name]", can also use "[hash]")
+  --federation-name=...     The name of this build's module federation
+                            container (requires the iife format)
+  --federation-expose:N=P   Expose the module at path P to other builds as N
+                            in the "remoteEntry.js" container entry
+  --federation-remote:N=U   Read imports of "N/..." from the container named
+                            N, which is loaded from the URL U for import()
+  --federation-shared:P     Share package P with other builds, or use P=R to
+                            accept other copies in the version range R
   --footer:T=...            Text to be appended to each output file of type T
                             where T is one of: css | js
-  --global-name=...         The name of the global for the IIFE format
+  --global-name=...         The name of the global for the IIFE and UMD formats
+  --hmr                     Allow modules to be replaced at run-time using
+                            "import.meta.hot" (rebuilds generate ".hmr.js"
+                            update files)
   --ignore-annotations      Enable this to work with packages that have
                             incorrect tree-shaking annotations
   --inject:F                Import the file F into all input files and
                             automatically replace matching globals with imports
+  --jsx-dev                 Use React's automatic runtime in development mode
   --jsx-factory=...         What to use for JSX instead of React.createElement
   --jsx-fragment=...        What to use for JSX instead of React.Fragment
-  --jsx=...                 Set to "preserve" to disable transforming JSX to JS
+  --jsx-import-source=...   Override the package name for the automatic runtime
+                            (default "react")
+  --jsx=...                 Set to "automatic" to use React's automatic runtime
+                            or to "preserve" to disable transforming JSX to JS
   --keep-names              Preserve "name" on functions and classes
   --legal-comments=...      Where to place license comments (none | inline |
                             eof | linked | external, default eof when bundling
@@ -89,10 +109,19 @@ var helpText = func(colors logger.Colors) string {
   --main-fields=...         Override the main file order in package.json
                             (default "browser,module,main" when platform is
                             browser and "main,module" when platform is node)
+  --manual-chunk:P=N        Put modules matching the package name or path
+                            pattern P in the code splitting chunk named N
+  --max-chunk-size=...      Split shared code splitting chunks larger than this
+                            many bytes along module boundaries
   --metafile=...            Write metadata about the build to a JSON file
   --minify-whitespace       Remove whitespace in output files
   --minify-identifiers      Shorten identifiers in output files
   --minify-syntax           Use equivalent but shorter syntax in output files
+  --min-chunk-size=...      Merge shared code splitting chunks smaller than this
+                            many bytes into other chunks
+  --node-builtin:M=...      How to handle imports of the builtin node module M
+                            (external | error | empty | polyfill | polyfill:P,
+                            M can be * for all other builtin modules)
   --out-extension:.js=.mjs  Use a custom output extension instead of ".js"
   --outbase=...             The base path used to determine entry point output
                             paths (for multiple entry points)
@@ -24,6 +24,14 @@ const (
 	// A call to "require.resolve()"
 	ImportRequireResolve
 
+	// A "new URL(path, import.meta.url)" expression with a string argument
+	ImportNewURL
+
+	// A "new URL(path, import.meta.url)" expression passed to "new Worker()" or
+	// "new SharedWorker()", with or without the "{ type: 'module' }" option
+	ImportWorker
+	ImportModuleWorker
+
 	// A CSS "@import" rule
 	ImportAt
 
@@ -44,6 +52,10 @@ func (kind ImportKind) StringForMetafile() string {
 		return "dynamic-import"
 	case ImportRequireResolve:
 		return "require-resolve"
+	case ImportNewURL:
+		return "new-url"
+	case ImportWorker, ImportModuleWorker:
+		return "worker"
 	case ImportAt, ImportAtConditional:
 		return "import-rule"
 	case ImportURL:
@@ -59,6 +71,10 @@ func (kind ImportKind) IsFromCSS() bool {
 	return kind == ImportAt || kind == ImportURL
 }
 
+func (kind ImportKind) IsWorker() bool {
+	return kind == ImportWorker || kind == ImportModuleWorker
+}
+
 type ImportRecord struct {
 	Range      logger.Range
 	Path       logger.Path
@@ -107,6 +123,11 @@ type ImportRecord struct {
 	// If true, this was originally written as a bare "import 'file'" statement
 	WasOriginallyBareImport bool
 
+	// If true, this is an "import()" of a chunk generated by code splitting for
+	// an output format without native ES module support. The printer loads the
+	// chunk with "require()" or with the runtime "__loadChunk" helper instead.
+	IsChunkLoad bool
+
 	Kind ImportKind
 }
 
@@ -66,11 +66,40 @@ type Bundle struct {
 	files       []scannerFile
 	entryPoints []graph.EntryPoint
 
+	// Files passed to "new Worker()" are extra entry points that are linked
+	// separately from the other entry points
+	workerEntryPoints []workerEntryPoint
+
+	// Each of these files was replaced by an identical copy of the same package
+	// in another "node_modules" directory. This is only used for the metafile.
+	dedupedFiles []dedupedFile
+
 	// The unique key prefix is a random string that is unique to every bundling
 	// operation. It is used as a prefix for the unique keys assigned to every
 	// chunk during linking. These unique keys are used to identify each chunk
 	// before the final output paths have been computed.
 	uniqueKeyPrefix string
+
+	// This is shared between incremental builds so that hot module replacement
+	// can tell which modules changed since the previous build
+	hmrCache *cache.HMRCache
+}
+
+type workerEntryPoint struct {
+	graph.EntryPoint
+
+	// Module workers use the "esm" format and other workers use "iife"
+	isModule bool
+}
+
+// The same file can be used as both a module worker and a classic worker, in
+// which case it's linked twice. This key tells those two workers apart.
+func workerKey(sourceIndex uint32, isModule bool) uint32 {
+	key := sourceIndex * 2
+	if isModule {
+		key++
+	}
+	return key
 }
 
 type parseArgs struct {
@@ -385,6 +414,20 @@ func parseFile(args parseArgs) {
 					continue
 				}
 
+				// Modules exposed by other builds are read from their containers at
+				// run-time, so they are always external
+				if federation := args.options.Federation; federation != nil {
+					if _, ok := federation.RemoteForPath(record.Path.Text); ok {
+						resolveResult := &resolver.ResolveResult{
+							PathPair:   resolver.PathPair{Primary: logger.Path{Text: record.Path.Text}},
+							IsExternal: true,
+						}
+						cache[record.Path.Text] = resolveResult
+						result.resolveResults[importRecordIndex] = resolveResult
+						continue
+					}
+				}
+
 				// Run the resolver and log an error if the path couldn't be resolved
 				resolveResult, didLogError, debug := runOnResolvePlugins(
 					args.options.Plugins,
@@ -432,7 +475,7 @@ func parseFile(args parseArgs) {
 								}
 							}
 						}
-						if args.options.Platform != config.PlatformNode {
+						if _, ok := args.options.NodeBuiltins[record.Path.Text]; !ok && args.options.Platform != config.PlatformNode {
 							if _, ok := resolver.BuiltInNodeModules[record.Path.Text]; ok {
 								switch logger.API {
 								case logger.CLIAPI:
@@ -950,6 +993,25 @@ type scanner struct {
 	visited       map[logger.Path]uint32
 	resultChannel chan parseResult
 	remaining     int
+
+	// This is only filled out when deduplicating packages. It's also only ever
+	// modified by a single thread.
+	packageIdentities map[uint32]resolver.PackageIdentity
+	dedupedFiles      []dedupedFile
+
+	// This is only filled out when using module federation. It maps the entry
+	// point of each shared package to information about that package.
+	federationShares map[uint32]graph.FederationShare
+
+	// Entry points for workers are discovered while scanning
+	workerEntryPoints    []workerEntryPoint
+	workerEntryPointKeys map[uint32]bool
+}
+
+type dedupedFile struct {
+	sourceIndex          uint32
+	canonicalSourceIndex uint32
+	identity             resolver.PackageIdentity
 }
 
 type EntryPoint struct {
@@ -1031,10 +1093,18 @@ func ScanBundle(
 		}
 	}()
 
+	if options.DedupePackages {
+		s.packageIdentities = make(map[uint32]resolver.PackageIdentity)
+	}
+	if options.Federation != nil {
+		s.federationShares = make(map[uint32]graph.FederationShare)
+	}
+
 	s.preprocessInjectedFiles()
 	entryPointMeta := s.addEntryPoints(entryPoints)
 	s.scanAllDependencies()
 	files := s.processScannedFiles()
+	s.reportDisallowedBuiltins(entryPointMeta)
 
 	onStartWaitGroup.Wait()
 	return Bundle{
@@ -1042,7 +1112,11 @@ func ScanBundle(
 		res:             res,
 		files:           files,
 		entryPoints:     entryPointMeta,
+		dedupedFiles:    s.dedupedFiles,
 		uniqueKeyPrefix: uniqueKeyPrefix,
+		hmrCache:        &caches.HMRCache,
+
+		workerEntryPoints: s.workerEntryPoints,
 	}
 }
 
@@ -1052,6 +1126,7 @@ const (
 	inputKindNormal inputKind = iota
 	inputKindEntryPoint
 	inputKindStdin
+	inputKindContainer
 )
 
 // This returns the source index of the resulting file
@@ -1079,21 +1154,44 @@ func (s *scanner) maybeParseFile(
 	sourceIndex = s.allocateSourceIndex(visitedKey, cache.SourceIndexNormal)
 	s.visited[visitedKey] = sourceIndex
 	s.remaining++
+
+	// Entry points are never deduplicated since they each need their own output
+	if s.packageIdentities != nil && kind == inputKindNormal && resolveResult.PackageIdentity != nil {
+		s.packageIdentities[sourceIndex] = *resolveResult.PackageIdentity
+	}
 	optionsClone := s.options
-	if kind != inputKindStdin {
+	if kind == inputKindContainer {
+		optionsClone.Stdin = &config.StdinInfo{
+			Loader:        config.LoaderJS,
+			Contents:      s.generateContainerEntry(),
+			AbsResolveDir: s.fs.Cwd(),
+		}
+	} else if kind != inputKindStdin {
 		optionsClone.Stdin = nil
 	}
 
 	// Allow certain properties to be overridden
+	if resolveResult.JSX != config.TSJSXNone {
+		optionsClone.JSX.SetOptionsFromTSJSX(resolveResult.JSX)
+	}
 	if len(resolveResult.JSXFactory) > 0 {
 		optionsClone.JSX.Factory = config.JSXExpr{Parts: resolveResult.JSXFactory}
 	}
 	if len(resolveResult.JSXFragment) > 0 {
 		optionsClone.JSX.Fragment = config.JSXExpr{Parts: resolveResult.JSXFragment}
 	}
+	if resolveResult.JSXImportSource != "" {
+		optionsClone.JSX.ImportSource = resolveResult.JSXImportSource
+	}
 	if resolveResult.UseDefineForClassFieldsTS != config.Unspecified {
 		optionsClone.UseDefineForClassFields = resolveResult.UseDefineForClassFieldsTS
 	}
+	if resolveResult.ExperimentalDecoratorsTS != config.Unspecified {
+		optionsClone.ExperimentalDecorators = resolveResult.ExperimentalDecoratorsTS
+	}
+	if resolveResult.EmitDecoratorMetadataTS {
+		optionsClone.EmitDecoratorMetadata = true
+	}
 	if resolveResult.PreserveUnusedImportsTS {
 		optionsClone.PreserveUnusedImportsTS = true
 	}
@@ -1295,6 +1393,17 @@ func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 		})
 	}
 
+	// Module federation adds a container entry that exposes modules to other builds
+	if federation := s.options.Federation; federation != nil && len(federation.Exposes) > 0 {
+		containerPath := logger.Path{Text: "<container>"}
+		resolveResult := resolver.ResolveResult{PathPair: resolver.PathPair{Primary: containerPath}}
+		sourceIndex := s.maybeParseFile(resolveResult, containerPath.Text, nil, logger.Range{}, nil, inputKindContainer, nil)
+		entryMetas = append(entryMetas, graph.EntryPoint{
+			OutputPath:  "remoteEntry",
+			SourceIndex: sourceIndex,
+		})
+	}
+
 	// Check each entry point ahead of time to see if it's a real file
 	entryPointAbsResolveDir := s.fs.Cwd()
 	for i := range entryPoints {
@@ -1548,9 +1657,25 @@ func (s *scanner) scanAllDependencies() {
 				path := resolveResult.PathPair.Primary
 				if !resolveResult.IsExternal {
 					// Handle a path within the bundle
+					kind := inputKindNormal
+					if record.Kind.IsWorker() {
+						kind = inputKindEntryPoint
+					}
 					sourceIndex := s.maybeParseFile(*resolveResult, s.res.PrettyPath(path),
-						&result.file.inputFile.Source, record.Range, resolveResult.PluginData, inputKindNormal, nil)
+						&result.file.inputFile.Source, record.Range, resolveResult.PluginData, kind, nil)
 					record.SourceIndex = ast.MakeIndex32(sourceIndex)
+
+					// Each worker is bundled as a separate entry point
+					if record.Kind.IsWorker() {
+						s.addWorkerEntryPoint(sourceIndex, path, record.Kind == ast.ImportModuleWorker, &result.file.inputFile.Source, record.Range)
+					}
+
+					// Remember which files are the entry points of shared packages
+					if federation := s.options.Federation; federation != nil {
+						if versionRange, ok := federation.Shared[record.Path.Text]; ok {
+							s.addFederationShare(sourceIndex, record.Path.Text, versionRange, resolveResult, &result.file.inputFile.Source, record.Range)
+						}
+					}
 				} else {
 					// If the path to the external module is relative to the source
 					// file, rewrite the path to be relative to the working directory
@@ -1580,6 +1705,10 @@ func (s *scanner) processScannedFiles() []scannerFile {
 	s.timer.Begin("Process scanned files")
 	defer s.timer.End("Process scanned files")
 
+	// Collapse identical copies of the same package before import records are
+	// finalized so that everything below only ever sees the canonical copy
+	canonicalSourceIndices := s.dedupePackages()
+
 	// Now that all files have been scanned, process the final file import records
 	for i, result := range s.results {
 		if !result.ok {
@@ -1628,6 +1757,11 @@ func (s *scanner) processScannedFiles() []scannerFile {
 					}
 				}
 
+				// Redirect imports of a duplicate package file to the canonical copy
+				if canonical, ok := canonicalSourceIndices[record.SourceIndex.GetIndex()]; ok {
+					record.SourceIndex = ast.MakeIndex32(canonical)
+				}
+
 				// Generate metadata about each import
 				if s.options.NeedsMetafile {
 					if isFirstImport {
@@ -1653,8 +1787,17 @@ func (s *scanner) processScannedFiles() []scannerFile {
 							"Bundling with conditional \"@import\" rules is not currently supported")
 					}
 
-				case ast.ImportURL:
-					// Using a JavaScript or CSS file with CSS "url()" is not allowed
+				case ast.ImportWorker, ast.ImportModuleWorker:
+					// Using a CSS file as a worker is not allowed
+					otherFile := &s.results[record.SourceIndex.GetIndex()].file
+					if _, ok := otherFile.inputFile.Repr.(*graph.CSSRepr); ok {
+						s.log.AddRangeError(&tracker, record.Range,
+							fmt.Sprintf("Cannot use %q as a worker", otherFile.inputFile.Source.PrettyPath))
+					}
+
+				case ast.ImportURL, ast.ImportNewURL:
+					// Using a JavaScript or CSS file with CSS "url()" or with JavaScript
+					// "new URL(path, import.meta.url)" is not allowed
 					otherFile := &s.results[record.SourceIndex.GetIndex()].file
 					switch otherRepr := otherFile.inputFile.Repr.(type) {
 					case *graph.CSSRepr:
@@ -1826,9 +1969,264 @@ func (s *scanner) processScannedFiles() []scannerFile {
 			files[sourceIndex] = result.file
 		}
 	}
+
+	// Mark the entry points of shared packages, which may have been replaced
+	// by the canonical copy of a duplicate package
+	for sourceIndex, share := range s.federationShares {
+		if share.Name == "" {
+			continue
+		}
+		if canonical, ok := canonicalSourceIndices[sourceIndex]; ok {
+			sourceIndex = canonical
+		}
+		share := share
+		files[sourceIndex].inputFile.FederationShare = &share
+	}
 	return files
 }
 
+func (s *scanner) addWorkerEntryPoint(
+	sourceIndex uint32,
+	path logger.Path,
+	isModule bool,
+	importSource *logger.Source,
+	importPathRange logger.Range,
+) {
+	key := workerKey(sourceIndex, isModule)
+	if s.workerEntryPointKeys[key] {
+		return
+	}
+	if s.options.WriteToStdout {
+		tracker := logger.MakeLineColumnTracker(importSource)
+		s.log.AddRangeError(&tracker, importPathRange,
+			fmt.Sprintf("Cannot bundle the worker %q without an output path configured", s.res.PrettyPath(path)))
+		return
+	}
+	if s.workerEntryPointKeys == nil {
+		s.workerEntryPointKeys = make(map[uint32]bool)
+	}
+
+	// Workers are named like chunks since they aren't user-specified entry points
+	_, base, _ := logger.PlatformIndependentPathDirBaseExt(path.Text)
+	s.workerEntryPointKeys[key] = true
+	s.workerEntryPoints = append(s.workerEntryPoints, workerEntryPoint{
+		EntryPoint: graph.EntryPoint{
+			OutputPath:  sanitizeFilePathForVirtualModulePath(base),
+			SourceIndex: sourceIndex,
+		},
+		isModule: isModule,
+	})
+}
+
+func (s *scanner) addFederationShare(
+	sourceIndex uint32,
+	name string,
+	versionRange string,
+	resolveResult *resolver.ResolveResult,
+	importSource *logger.Source,
+	importPathRange logger.Range,
+) {
+	if _, ok := s.federationShares[sourceIndex]; ok {
+		return
+	}
+
+	// The version of the bundled copy comes from its "package.json" file
+	identity := resolveResult.PackageIdentity
+	if identity == nil {
+		tracker := logger.MakeLineColumnTracker(importSource)
+		s.log.AddRangeWarning(&tracker, importPathRange,
+			fmt.Sprintf("Cannot share %q with other builds because its version is unknown", name))
+		s.federationShares[sourceIndex] = graph.FederationShare{}
+		return
+	}
+	if versionRange == "" {
+		versionRange = "^" + identity.Version
+	}
+	s.federationShares[sourceIndex] = graph.FederationShare{
+		Name:    name,
+		Version: identity.Version,
+		Range:   versionRange,
+	}
+}
+
+// The container entry registers a container for this build that other builds
+// can read exposed modules from. Exposed modules are only evaluated when they
+// are first read.
+func (s *scanner) generateContainerEntry() string {
+	federation := s.options.Federation
+	names := make([]string, 0, len(federation.Exposes))
+	for name := range federation.Exposes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sb := strings.Builder{}
+	sb.WriteString("var modules = {\n")
+	for _, name := range names {
+		path := federation.Exposes[name]
+		if !s.fs.IsAbs(path) {
+			path = s.fs.Join(s.fs.Cwd(), path)
+		}
+		sb.WriteString(fmt.Sprintf("  %s: () => require(%s),\n",
+			js_printer.QuoteForJSON(name, false), js_printer.QuoteForJSON(path, false)))
+	}
+	sb.WriteString("};\n")
+	sb.WriteString("var federation = globalThis.esbuildFederation ||= { containers: {}, shared: {} };\n")
+	sb.WriteString(fmt.Sprintf("federation.containers[%s] = {\n", js_printer.QuoteForJSON(federation.Name, false)))
+	sb.WriteString("  get(module) {\n")
+	sb.WriteString("    if (!Object.prototype.hasOwnProperty.call(modules, module))\n")
+	sb.WriteString(fmt.Sprintf("      throw new Error('Module \"' + module + '\" is not exposed by container \"' + %s + '\"');\n",
+		js_printer.QuoteForJSON(federation.Name, false)))
+	sb.WriteString("    return modules[module]();\n")
+	sb.WriteString("  }\n")
+	sb.WriteString("};\n")
+	return sb.String()
+}
+
+// Builtin node modules that are configured to be an error are reported here
+// instead of in the resolver because the error is much more useful when it
+// says how the builtin module ended up in the bundle. Files are visited in
+// breadth-first order from the entry points so that each chain is the
+// shortest one, and so that the output is deterministic.
+func (s *scanner) reportDisallowedBuiltins(entryPoints []graph.EntryPoint) {
+	if s.options.Mode != config.ModeBundle || len(s.options.NodeBuiltins) == 0 {
+		return
+	}
+
+	type importer struct {
+		sourceIndex       uint32
+		importRecordIndex uint32
+	}
+	importers := make(map[uint32]importer)
+	visited := make(map[uint32]bool)
+	var queue []uint32
+	visit := func(sourceIndex uint32) {
+		if !visited[sourceIndex] {
+			visited[sourceIndex] = true
+			queue = append(queue, sourceIndex)
+		}
+	}
+	for _, entryPoint := range entryPoints {
+		visit(entryPoint.SourceIndex)
+	}
+	for _, injectedFile := range s.options.InjectedFiles {
+		visit(injectedFile.Source.Index)
+	}
+
+	for len(queue) > 0 {
+		sourceIndex := queue[0]
+		queue = queue[1:]
+		result := &s.results[sourceIndex]
+		if !result.ok {
+			continue
+		}
+		records := *result.file.inputFile.Repr.ImportRecords()
+
+		for importRecordIndex := range records {
+			record := &records[importRecordIndex]
+			if record.SourceIndex.IsValid() {
+				if otherSourceIndex := record.SourceIndex.GetIndex(); !visited[otherSourceIndex] {
+					importers[otherSourceIndex] = importer{sourceIndex: sourceIndex, importRecordIndex: uint32(importRecordIndex)}
+					visit(otherSourceIndex)
+				}
+				continue
+			}
+
+			if resolveResult := result.resolveResults[importRecordIndex]; resolveResult == nil || !resolveResult.IsDisallowedBuiltin {
+				continue
+			}
+
+			// Walk back up to the entry point, then put the chain in import order
+			var notes []logger.MsgData
+			for child := sourceIndex; ; {
+				parent, ok := importers[child]
+				if !ok {
+					break
+				}
+				parentResult := &s.results[parent.sourceIndex]
+				parentRecords := *parentResult.file.inputFile.Repr.ImportRecords()
+				tracker := logger.MakeLineColumnTracker(&parentResult.file.inputFile.Source)
+				notes = append(notes, logger.RangeData(&tracker, parentRecords[parent.importRecordIndex].Range,
+					fmt.Sprintf("The file %q imports the file %q here",
+						parentResult.file.inputFile.Source.PrettyPath, s.results[child].file.inputFile.Source.PrettyPath)))
+				child = parent.sourceIndex
+			}
+			for i, j := 0, len(notes)-1; i < j; i, j = i+1, j-1 {
+				notes[i], notes[j] = notes[j], notes[i]
+			}
+
+			tracker := logger.MakeLineColumnTracker(&result.file.inputFile.Source)
+			s.log.AddRangeErrorWithNotes(&tracker, record.Range,
+				fmt.Sprintf("Importing the builtin node module %q is not allowed in this build", strings.TrimPrefix(record.Path.Text, "node:")),
+				notes)
+		}
+	}
+}
+
+// Multiple copies of the same version of a package can end up in different
+// "node_modules" directories (e.g. "node_modules/a/node_modules/b" and
+// "node_modules/c/node_modules/b"). Files with the same package name, version,
+// and path within the package are assumed to be identical. This picks one
+// copy of each file and returns a map from every other copy to that one.
+//
+// The canonical copy is chosen after scanning instead of during scanning
+// because the order that files are discovered in is non-deterministic. The
+// shortest path is used since it's usually the most hoisted copy.
+func (s *scanner) dedupePackages() map[uint32]uint32 {
+	if len(s.packageIdentities) == 0 {
+		return nil
+	}
+
+	groups := make(map[resolver.PackageIdentity][]uint32)
+	for sourceIndex, identity := range s.packageIdentities {
+		if s.results[sourceIndex].ok {
+			groups[identity] = append(groups[identity], sourceIndex)
+		}
+	}
+
+	canonicalSourceIndices := make(map[uint32]uint32)
+	for identity, sourceIndices := range groups {
+		if len(sourceIndices) < 2 {
+			continue
+		}
+
+		// Sort for determinism
+		sort.Slice(sourceIndices, func(i int, j int) bool {
+			a := s.results[sourceIndices[i]].file.inputFile.Source.KeyPath.Text
+			b := s.results[sourceIndices[j]].file.inputFile.Source.KeyPath.Text
+			return len(a) < len(b) || (len(a) == len(b) && a < b)
+		})
+
+		canonical := sourceIndices[0]
+		for _, sourceIndex := range sourceIndices[1:] {
+			canonicalSourceIndices[sourceIndex] = canonical
+			s.dedupedFiles = append(s.dedupedFiles, dedupedFile{
+				sourceIndex:          sourceIndex,
+				canonicalSourceIndex: canonical,
+				identity:             identity,
+			})
+		}
+	}
+
+	// Sort for determinism
+	sort.Slice(s.dedupedFiles, func(i int, j int) bool {
+		a := s.results[s.dedupedFiles[i].sourceIndex].file.inputFile.Source.KeyPath.Text
+		b := s.results[s.dedupedFiles[j].sourceIndex].file.inputFile.Source.KeyPath.Text
+		return a < b
+	})
+
+	if s.log.Level <= logger.LevelDebug {
+		for _, deduped := range s.dedupedFiles {
+			s.log.AddDebug(nil, logger.Loc{}, fmt.Sprintf("Replaced %q with the identical file %q from %s@%s",
+				s.results[deduped.sourceIndex].file.inputFile.Source.PrettyPath,
+				s.results[deduped.canonicalSourceIndex].file.inputFile.Source.PrettyPath,
+				deduped.identity.Name, deduped.identity.Version))
+		}
+	}
+
+	return canonicalSourceIndices
+}
+
 func (s *scanner) validateTLA(sourceIndex uint32) tlaCheck {
 	result := &s.results[sourceIndex]
 
@@ -1988,11 +2386,16 @@ func (b *Bundle) Compile(log logger.Log, options config.Options, timer *helpers.
 	dataForSourceMaps := b.computeDataForSourceMapsInParallel(&options, allReachableFiles)
 	timer.End("Spawn source map tasks")
 
+	// Workers must be linked before the code that creates them since their
+	// final output paths are substituted into that code
+	workerPaths, workerOutputFiles := b.linkWorkers(log, options, timer, files, dataForSourceMaps)
+
 	var resultGroups [][]graph.OutputFile
 	if options.CodeSplitting || len(b.entryPoints) == 1 {
 		// If code splitting is enabled or if there's only one entry point, link all entry points together
-		resultGroups = [][]graph.OutputFile{link(
-			&options, timer, log, b.fs, b.res, files, b.entryPoints, b.uniqueKeyPrefix, allReachableFiles, dataForSourceMaps)}
+		outputFiles, _ := link(
+			&options, timer, log, b.fs, b.res, files, b.entryPoints, b.uniqueKeyPrefix, allReachableFiles, dataForSourceMaps, b.hmrCache, workerPaths)
+		resultGroups = [][]graph.OutputFile{outputFiles}
 	} else {
 		// Otherwise, link each entry point with the runtime file separately
 		waitGroup := sync.WaitGroup{}
@@ -2003,8 +2406,8 @@ func (b *Bundle) Compile(log logger.Log, options config.Options, timer *helpers.
 				entryPoints := []graph.EntryPoint{entryPoint}
 				forked := timer.Fork()
 				reachableFiles := findReachableFiles(files, entryPoints)
-				resultGroups[i] = link(
-					&options, forked, log, b.fs, b.res, files, entryPoints, b.uniqueKeyPrefix, reachableFiles, dataForSourceMaps)
+				resultGroups[i], _ = link(
+					&options, forked, log, b.fs, b.res, files, entryPoints, b.uniqueKeyPrefix, reachableFiles, dataForSourceMaps, b.hmrCache, workerPaths)
 				timer.Join(forked)
 				waitGroup.Done()
 			}(i, entryPoint)
@@ -2012,11 +2415,13 @@ func (b *Bundle) Compile(log logger.Log, options config.Options, timer *helpers.
 		waitGroup.Wait()
 	}
 
-	// Join the results in entry point order for determinism
+	// Join the results in entry point order for determinism. Workers go last
+	// since they aren't entry points that were passed in by the user.
 	var outputFiles []graph.OutputFile
 	for _, group := range resultGroups {
 		outputFiles = append(outputFiles, group...)
 	}
+	outputFiles = append(outputFiles, workerOutputFiles...)
 
 	// Also generate the metadata file if necessary
 	var metafileJSON string
@@ -2094,6 +2499,133 @@ func (b *Bundle) Compile(log logger.Log, options config.Options, timer *helpers.
 	return outputFiles, metafileJSON
 }
 
+// Each worker is linked by itself with the runtime file, and is linked after
+// any workers that it creates itself. Workers that create each other are not
+// supported since the final path of each one would depend on the other.
+func (b *Bundle) linkWorkers(
+	log logger.Log,
+	options config.Options,
+	timer *helpers.Timer,
+	files []graph.InputFile,
+	dataForSourceMaps func() []dataForSourceMap,
+) (map[uint32]string, []graph.OutputFile) {
+	if len(b.workerEntryPoints) == 0 {
+		return nil, nil
+	}
+
+	// Each worker gets a separate format that doesn't share code with anything
+	workerOptions := options
+	workerOptions.CodeSplitting = false
+	workerOptions.AbsOutputFile = ""
+	workerOptions.GlobalName = nil
+	workerOptions.EntryPathTemplate = options.ChunkPathTemplate
+	workerOptions.HotModuleReplacement = false
+	workerOptions.ManualChunks = nil
+	workerOptions.Federation = nil
+
+	const (
+		workerNotVisited = iota
+		workerInProgress
+		workerDone
+	)
+	workerIndices := make(map[uint32]int, len(b.workerEntryPoints))
+	for i, worker := range b.workerEntryPoints {
+		workerIndices[workerKey(worker.SourceIndex, worker.isModule)] = i
+	}
+	workerPaths := make(map[uint32]string)
+	status := make([]int, len(b.workerEntryPoints))
+	var outputFiles []graph.OutputFile
+	var visit func(int)
+
+	visit = func(i int) {
+		worker := b.workerEntryPoints[i]
+		switch status[i] {
+		case workerInProgress:
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Cannot bundle the worker %q because it creates itself",
+				b.files[worker.SourceIndex].inputFile.Source.PrettyPath))
+			return
+		case workerDone:
+			return
+		}
+		status[i] = workerInProgress
+
+		// Link the workers created by this worker first
+		entryPoints := []graph.EntryPoint{worker.EntryPoint}
+		reachableFiles := findReachableFiles(files, entryPoints)
+		for _, sourceIndex := range reachableFiles {
+			for _, record := range *files[sourceIndex].Repr.ImportRecords() {
+				if record.Kind.IsWorker() && record.SourceIndex.IsValid() {
+					visit(workerIndices[workerKey(record.SourceIndex.GetIndex(), record.Kind == ast.ImportModuleWorker)])
+				}
+			}
+		}
+
+		// Classic workers use the "iife" format, which can't contain "import.meta"
+		if !worker.isModule {
+			checkForImportMetaInClassicWorker(log, files, worker)
+		}
+
+		if !log.HasErrors() {
+			options := workerOptions
+			if worker.isModule {
+				options.OutputFormat = config.FormatESModule
+			} else {
+				options.OutputFormat = config.FormatIIFE
+			}
+			results, entryPointPaths := link(
+				&options, timer, log, b.fs, b.res, files, entryPoints, b.uniqueKeyPrefix, reachableFiles, dataForSourceMaps, nil, workerPaths)
+			workerPaths[workerKey(worker.SourceIndex, worker.isModule)] = entryPointPaths[worker.SourceIndex]
+			outputFiles = append(outputFiles, results...)
+		}
+		status[i] = workerDone
+	}
+
+	for i := range b.workerEntryPoints {
+		visit(i)
+	}
+	return workerPaths, outputFiles
+}
+
+// Files are parsed once using the options for the output format of the main
+// build, so they may contain "import.meta" even though it's not available in
+// the "iife" format used for classic workers. Substituting it with an empty
+// object like for other formats isn't helpful either, since the most common
+// use is "new URL(path, import.meta.url)", which would then throw.
+func checkForImportMetaInClassicWorker(log logger.Log, files []graph.InputFile, worker workerEntryPoint) {
+	visited := make(map[uint32]bool)
+	var visit func(uint32) bool
+	visit = func(sourceIndex uint32) bool {
+		if visited[sourceIndex] {
+			return false
+		}
+		visited[sourceIndex] = true
+		file := &files[sourceIndex]
+		repr, ok := file.Repr.(*graph.JSRepr)
+		if !ok {
+			return false
+		}
+		if r := repr.AST.ImportMetaKeyword; r.Len > 0 {
+			tracker := logger.MakeLineColumnTracker(&file.Source)
+			log.AddRangeErrorWithNotes(&tracker, r,
+				fmt.Sprintf("Cannot use \"import.meta\" in the classic worker %q", files[worker.SourceIndex].Source.PrettyPath),
+				[]logger.MsgData{{Text: "Classic workers are bundled in the \"iife\" format, which doesn't support \"import.meta\". " +
+					"You can pass \"{ type: 'module' }\" to \"new Worker()\" to create a module worker instead."}})
+			return true
+		}
+
+		// Other workers and assets aren't bundled into this worker
+		for _, record := range repr.AST.ImportRecords {
+			if record.SourceIndex.IsValid() && !record.Kind.IsWorker() && record.Kind != ast.ImportNewURL {
+				if visit(record.SourceIndex.GetIndex()) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	visit(worker.SourceIndex)
+}
+
 // Find all files reachable from all entry points. This order should be
 // deterministic given that the entry point order is deterministic, since the
 // returned order is the postorder of the graph traversal and import record
@@ -2249,7 +2781,29 @@ func (b *Bundle) generateMetadataJSON(results []graph.OutputFile, allReachableFi
 		}
 	}
 
-	sb.WriteString("\n  }\n}\n")
+	sb.WriteString("\n  }")
+
+	// Write information about deduplicated files
+	if len(b.dedupedFiles) > 0 {
+		sb.WriteString(",\n  \"deduplicated\": [")
+		totalInputBytes := 0
+		for i, deduped := range b.dedupedFiles {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			inputBytes := len(b.files[deduped.sourceIndex].inputFile.Source.Contents)
+			totalInputBytes += inputBytes
+			sb.WriteString(fmt.Sprintf("\n    {\n      \"path\": %s,\n      \"canonical\": %s,\n      \"package\": %s,\n      \"version\": %s,\n      \"inputBytes\": %d\n    }",
+				js_printer.QuoteForJSON(b.files[deduped.sourceIndex].inputFile.Source.PrettyPath, asciiOnly),
+				js_printer.QuoteForJSON(b.files[deduped.canonicalSourceIndex].inputFile.Source.PrettyPath, asciiOnly),
+				js_printer.QuoteForJSON(deduped.identity.Name, asciiOnly),
+				js_printer.QuoteForJSON(deduped.identity.Version, asciiOnly),
+				inputBytes))
+		}
+		sb.WriteString(fmt.Sprintf("\n  ],\n  \"deduplicatedInputBytes\": %d", totalInputBytes))
+	}
+
+	sb.WriteString("\n}\n")
 	return sb.String()
 }
 
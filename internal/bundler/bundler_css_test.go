@@ -623,3 +623,76 @@ func TestCSSAndJavaScriptCodeSplittingIssue1064(t *testing.T) {
 		},
 	})
 }
+
+func TestCSSCodeSplittingPerChunk(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import "./a.css"
+				import {shared} from "./shared.js"
+				console.log(shared)
+				import("./lazy.js").then(ns => console.log(ns.lazy))
+			`,
+			"/b.js": `
+				import {shared} from "./shared.js"
+				console.log(shared)
+			`,
+			"/shared.js": `
+				import "./shared.css"
+				export let shared = 1
+			`,
+			"/lazy.js": `
+				import "./lazy.css"
+				import {shared} from "./shared.js"
+				export let lazy = shared + 1
+			`,
+			"/a.css":      `.a { color: red }`,
+			"/shared.css": `.shared { color: green }`,
+			"/lazy.css":   `.lazy { color: blue }`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestCSSCodeSplittingPerChunkIIFE(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import "./a.css"
+				import {shared} from "./shared.js"
+				console.log(shared)
+				import("./lazy.js").then(ns => console.log(ns.lazy))
+			`,
+			"/b.js": `
+				import {shared} from "./shared.js"
+				console.log(shared)
+			`,
+			"/shared.js": `
+				import "./shared.css"
+				export let shared = 1
+			`,
+			"/lazy.js": `
+				import "./lazy.css"
+				import {shared} from "./shared.js"
+				export let lazy = shared + 1
+			`,
+			"/a.css":      `.a { color: red }`,
+			"/shared.css": `.shared { color: green }`,
+			"/lazy.css":   `.lazy { color: blue }`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			PublicPath:    "/static",
+		},
+	})
+}
@@ -365,6 +365,334 @@ func TestExportFormsCommonJS(t *testing.T) {
 	})
 }
 
+func TestExportFormsUMD(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export default 123
+				export var v = 234
+				export let l = 234
+				export const c = 234
+				export {Class as C}
+				export function Fn() {}
+				export class Class {}
+				export * from './a'
+				export * as b from './b'
+			`,
+			"/a.js": "export const abc = undefined",
+			"/b.js": "export const xyz = null",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			GlobalName:    []string{"globalName"},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestExportFormsSystem(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export default 123
+				export var v = 234
+				export let l = 234
+				export const c = 234
+				export {Class as C}
+				export function Fn() {}
+				export class Class {}
+				export * from './a'
+				export * as b from './b'
+			`,
+			"/a.js": "export const abc = undefined",
+			"/b.js": "export const xyz = null",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestExternalsUMD(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import React from "react"
+				import {render} from "react-dom"
+				export let app = () => render(React.createElement("div"))
+				import("@scope/lazy-thing").then(console.log)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			GlobalName:    []string{"my", "lib"},
+			AbsOutputFile: "/out.js",
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"react":             true,
+					"react-dom":         true,
+					"@scope/lazy-thing": true,
+				},
+			},
+		},
+	})
+}
+
+func TestCommonJSEntryPointUMD(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `module.exports = {foo: require("./foo")}`,
+			"/foo.js":   `exports.bar = 123`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			GlobalName:    []string{"globalName"},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestExternalGlobalsIIFE(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import React, {createElement} from "react"
+				import * as dom from "react-dom"
+				const {render} = require("react-dom")
+				render(createElement("div", React.version, dom.version))
+				import("react").then(ns => console.log(ns.default))
+				import("other").then(console.log)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputFile: "/out.js",
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"react":     true,
+					"react-dom": true,
+					"other":     true,
+				},
+				Globals: map[string][]string{
+					"react":     {"window", "React"},
+					"react-dom": {"window", "react-dom"},
+				},
+			},
+		},
+	})
+}
+
+func TestExternalsSystem(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import React from "react"
+				import {render} from "react-dom"
+				export let app = () => render(React.createElement("div"))
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"react":     true,
+					"react-dom": true,
+				},
+			},
+		},
+	})
+}
+
+func TestHotModuleReplacement(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {count, increment} from './counter'
+				import * as ns from './cjs'
+				import data from './data.json'
+				increment()
+				console.log(count, ns, data)
+				if (import.meta.hot) import.meta.hot.accept()
+			`,
+			"/counter.js": `
+				export let count = import.meta.hot.data.count || 0
+				export function increment() { count++ }
+				import.meta.hot.dispose(data => { data.count = count })
+			`,
+			"/cjs.js": `
+				exports.foo = 123
+			`,
+			"/data.json": `{"x": 1}`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			OutputFormat:         config.FormatESModule,
+			AbsOutputFile:        "/out.js",
+			HotModuleReplacement: true,
+		},
+	})
+}
+
+func TestHotModuleReplacementUpdate(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {count, increment} from './counter'
+				import * as ns from './cjs'
+				increment()
+				console.log(count, ns)
+				if (import.meta.hot) import.meta.hot.accept()
+			`,
+			"/counter.js": `
+				export let count = 0
+				export function increment() { count++ }
+			`,
+			"/cjs.js": `
+				exports.foo = 123
+			`,
+		},
+		rebuildFiles: map[string]string{
+			"/entry.js": `
+				import {count, increment} from './counter'
+				import * as ns from './cjs'
+				increment()
+				console.log('changed', count, ns)
+				if (import.meta.hot) import.meta.hot.accept()
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			OutputFormat:         config.FormatESModule,
+			AbsOutputFile:        "/out.js",
+			HotModuleReplacement: true,
+		},
+	})
+}
+
+func TestHotModuleReplacementTopLevelAwait(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				await import('./other')
+			`,
+			"/other.js": `
+				export default 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			OutputFormat:         config.FormatESModule,
+			AbsOutputFile:        "/out.js",
+			HotModuleReplacement: true,
+		},
+		expectedScanLog: `entry.js: error: Top-level await is currently not supported with hot module replacement
+`,
+	})
+}
+
+func TestFederationContainer(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/button.js": `
+				import { version } from 'lib'
+				export const Button = () => 'Button using lib ' + version
+			`,
+			"/src/header.js": `
+				import { Button } from './button'
+				export default () => Button()
+			`,
+			"/node_modules/lib/package.json": `{ "name": "lib", "version": "1.2.0" }`,
+			"/node_modules/lib/index.js": `
+				export const version = '1.2.0'
+			`,
+		},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatIIFE,
+			AbsOutputDir: "/out",
+			Federation: &config.Federation{
+				Name: "app",
+				Exposes: map[string]string{
+					"./Button": "/src/button.js",
+					"./Header": "/src/header.js",
+				},
+				Shared: map[string]string{"lib": ""},
+			},
+		},
+	})
+}
+
+func TestFederationRemote(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { Button } from 'remote/Button'
+				import Header from 'remote'
+				import { version } from 'lib'
+				console.log(Button(), Header(), version, require('remote/cjs'))
+				import('remote/Lazy').then(ns => ns.default())
+			`,
+			"/node_modules/lib/package.json": `{ "name": "lib", "version": "1.3.0" }`,
+			"/node_modules/lib/index.js": `
+				exports.version = '1.3.0'
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputFile: "/out.js",
+			Federation: &config.Federation{
+				Remotes: map[string]string{"remote": "https://example.com/remoteEntry.js"},
+				Shared:  map[string]string{"lib": "^1.0.0"},
+			},
+		},
+	})
+}
+
+func TestFederationSharedWithoutVersion(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { version } from 'lib'
+				console.log(version)
+			`,
+			"/node_modules/lib/package.json": `{ "name": "lib" }`,
+			"/node_modules/lib/index.js": `
+				export const version = '1.0.0'
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputFile: "/out.js",
+			Federation: &config.Federation{
+				Shared: map[string]string{"lib": ""},
+			},
+		},
+		expectedScanLog: `entry.js: warning: Cannot share "lib" with other builds because its version is unknown
+`,
+	})
+}
+
 func TestExportChain(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -481,6 +809,89 @@ func TestJSXImportsES6(t *testing.T) {
 	})
 }
 
+func TestJSXAutomaticImportsAlreadyInScope(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.jsx": `
+				import { jsx } from './custom-react'
+				import { Fragment } from './custom-react'
+				console.log(<div jsx={jsx}/>, <><Fragment/></>)
+			`,
+			"/custom-react.js": `
+				export function jsx() {}
+				export function Fragment() {}
+			`,
+		},
+		entryPaths: []string{"/entry.jsx"},
+		options: config.Options{
+			Mode: config.ModeBundle,
+			JSX: config.JSXOptions{
+				AutomaticRuntime: true,
+			},
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"react/jsx-runtime": true,
+				},
+			},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestJSXAutomaticImportsES6(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.jsx": `
+				console.log(<div key="a"/>, <><b/><c/></>, <div {...props} key="d"/>)
+			`,
+			"/node_modules/react/jsx-runtime.js": `
+				export function jsx() {}
+				export function jsxs() {}
+				export function Fragment() {}
+			`,
+			"/node_modules/react/index.js": `
+				export function createElement() {}
+			`,
+		},
+		entryPaths: []string{"/entry.jsx"},
+		options: config.Options{
+			Mode: config.ModeBundle,
+			JSX: config.JSXOptions{
+				AutomaticRuntime: true,
+			},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestJSXAutomaticDevelopment(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.jsx": `
+				console.log(<div/>)
+				export default function f() {
+					return <><b/><c/></>
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.jsx"},
+		options: config.Options{
+			Mode: config.ModeBundle,
+			JSX: config.JSXOptions{
+				AutomaticRuntime: true,
+				Development:      true,
+				ImportSource:     "preact",
+			},
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"preact/jsx-dev-runtime": true,
+				},
+			},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
 func TestJSXSyntaxInJS(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -3134,9 +3545,6 @@ func TestTopLevelAwaitIIFE(t *testing.T) {
 			OutputFormat:  config.FormatIIFE,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: error: Top-level await is currently not supported with the "iife" output format
-entry.js: error: Top-level await is currently not supported with the "iife" output format
-`,
 	})
 }
 
@@ -3154,8 +3562,52 @@ func TestTopLevelAwaitCJS(t *testing.T) {
 			OutputFormat:  config.FormatCommonJS,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: error: Top-level await is currently not supported with the "cjs" output format
-entry.js: error: Top-level await is currently not supported with the "cjs" output format
+	})
+}
+
+func TestTopLevelAwaitImportChainCJS(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {a} from './a'
+				import {sync} from './sync'
+				export let b = a + 1
+				console.log(b, sync)
+			`,
+			"/a.js": `
+				import './c'
+				export let a = await foo()
+			`,
+			"/c.js": `
+				await 0
+			`,
+			"/sync.js": `
+				export let sync = 1
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatCommonJS,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTopLevelAwaitUMD(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				await foo;
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			AbsOutputFile: "/out.js",
+		},
+		expectedScanLog: `entry.js: error: Top-level await is currently not supported with the "umd" output format
 `,
 	})
 }
@@ -3223,9 +3675,6 @@ func TestTopLevelAwaitNoBundleCommonJS(t *testing.T) {
 			Mode:          config.ModeConvertFormat,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: error: Top-level await is currently not supported with the "cjs" output format
-entry.js: error: Top-level await is currently not supported with the "cjs" output format
-`,
 	})
 }
 
@@ -3243,9 +3692,6 @@ func TestTopLevelAwaitNoBundleIIFE(t *testing.T) {
 			Mode:          config.ModeConvertFormat,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: error: Top-level await is currently not supported with the "iife" output format
-entry.js: error: Top-level await is currently not supported with the "iife" output format
-`,
 	})
 }
 
@@ -4707,3 +5153,84 @@ func TestStrictModeNestedFnDeclKeepNamesVariableInliningIssue1552(t *testing.T)
 		},
 	})
 }
+
+func TestWorkerEntryPoints(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { shared } from './shared'
+				console.log(shared,
+					new Worker(new URL('./worker.js', import.meta.url)),
+					new SharedWorker(new URL('./module-worker.js', import.meta.url), { type: 'module' }),
+				)
+			`,
+			"/worker.js": `
+				import { shared } from './shared'
+				onmessage = e => postMessage(shared + e.data)
+			`,
+			"/module-worker.js": `
+				export let count = 0
+				let nested = new Worker(new URL('./worker.js', import.meta.url))
+				onmessage = e => nested.postMessage(++count)
+			`,
+			"/shared.js": `
+				export let shared = 'shared'
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestWorkerClassicImportMeta(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./worker.js', import.meta.url))
+			`,
+			"/worker.js": `
+				import './nested'
+			`,
+			"/nested.js": `
+				let nested = new Worker(new URL('./module-worker.js', import.meta.url), { type: 'module' })
+				onmessage = e => nested.postMessage(e.data)
+			`,
+			"/module-worker.js": `
+				onmessage = e => postMessage(import.meta.url)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+		expectedCompileLog: `nested.js: error: Cannot use "import.meta" in the classic worker "worker.js"
+note: Classic workers are bundled in the "iife" format, which doesn't support "import.meta". You can pass "{ type: 'module' }" to "new Worker()" to create a module worker instead.
+`,
+	})
+}
+
+func TestWorkerModuleAndClassic(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./worker.js', import.meta.url))
+				new Worker(new URL('./worker.js', import.meta.url), { type: 'module' })
+			`,
+			"/worker.js": `
+				onmessage = e => postMessage(e.data)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+	})
+}
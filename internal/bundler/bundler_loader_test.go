@@ -469,6 +469,119 @@ func TestLoaderFileRelativePathAssetNamesJS(t *testing.T) {
 	})
 }
 
+func TestLoaderFileNewURLImportMeta(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entries/entry.js": `
+				console.log(
+					new URL('../images/image.png', import.meta.url),
+					new URL('../images/image.png', import.meta.url).href,
+					new URL('./data.txt', import.meta.url),
+
+					// These should be left alone
+					new URL('https://example.com/image.png', import.meta.url),
+					new URL('../images/image.png', location.href),
+					new URL('../images/image.png'),
+				)
+				export function shadowed(URL) {
+					return new URL('../images/missing.png', import.meta.url)
+				}
+			`,
+			"/src/entries/data.txt": "text",
+			"/src/images/image.png": "x",
+		},
+		entryPaths: []string{"/src/entries/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputBase: "/src",
+			AbsOutputDir:  "/out",
+			AssetPathTemplate: []config.PathTemplate{
+				{Data: "", Placeholder: config.DirPlaceholder},
+				{Data: "/", Placeholder: config.NamePlaceholder},
+				{Data: "-", Placeholder: config.HashPlaceholder},
+			},
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".txt": config.LoaderDataURL,
+				".png": config.LoaderFile,
+			},
+		},
+	})
+}
+
+func TestLoaderFileNewURLImportMetaIIFE(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(
+					new URL('./image.png', import.meta.url),
+					new URL('./data.txt', import.meta.url),
+				)
+			`,
+			"/data.txt":  "text",
+			"/image.png": "x",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatIIFE,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".txt": config.LoaderDataURL,
+				".png": config.LoaderFile,
+			},
+		},
+		expectedCompileLog: `entry.js: warning: The URL for "./image.png" will be invalid at run-time because "import.meta.url" is not available in the "iife" output format
+note: You can set the public path to an absolute URL such as "https://example.com/assets/" so that the URL doesn't depend on "import.meta.url".
+`,
+	})
+}
+
+func TestLoaderFileNewURLImportMetaCommonJSPublicPath(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(new URL('./image.png', import.meta.url))
+			`,
+			"/image.png": "x",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatCommonJS,
+			AbsOutputDir: "/out",
+			PublicPath:   "https://example.com/assets/",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".png": config.LoaderFile,
+			},
+		},
+	})
+}
+
+func TestLoaderFileNewURLImportMetaJS(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(new URL('./other.js', import.meta.url))
+			`,
+			"/other.js": `
+				console.log('other')
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+		expectedScanLog: `entry.js: error: Cannot use "other.js" as a URL
+`,
+	})
+}
+
 func TestLoaderFileRelativePathAssetNamesCSS(t *testing.T) {
 	loader_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -908,15 +908,91 @@ func TestLowerAsyncES5(t *testing.T) {
 			UnsupportedJSFeatures: es(5),
 			AbsOutputFile:         "/out.js",
 		},
-		expectedScanLog: `arrow-1.js: error: Transforming async functions to the configured target environment is not supported yet
-arrow-2.js: error: Transforming async functions to the configured target environment is not supported yet
-export-def-1.js: error: Transforming async functions to the configured target environment is not supported yet
-export-def-2.js: error: Transforming async functions to the configured target environment is not supported yet
-fn-expr.js: error: Transforming async functions to the configured target environment is not supported yet
-fn-stmt.js: error: Transforming async functions to the configured target environment is not supported yet
-obj-method.js: error: Transforming async functions to the configured target environment is not supported yet
-obj-method.js: error: Transforming object literal extensions to the configured target environment is not supported yet
-`,
+	})
+}
+
+func TestLowerGeneratorES5(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { foo as a } from './gen-stmt'
+				import { foo as b } from './gen-loop'
+				import { foo as c } from './gen-try'
+				import { foo as d } from './async-gen'
+				import { foo as e } from './for-await'
+				console.log(a, b, c, d, e)
+			`,
+			"/gen-stmt.js": `
+				export function* foo(x) {
+					var y = yield x
+					yield* bar(y)
+					return y + arguments.length
+				}
+			`,
+			"/gen-loop.js": `
+				export function* foo(items) {
+					outer: for (var i = 0; i < items.length; i++) {
+						for (var key in items[i]) {
+							if (!key) continue outer
+							yield key
+						}
+					}
+				}
+			`,
+			"/gen-try.js": `
+				export function* foo() {
+					try {
+						yield 1
+					} catch (e) {
+						yield e
+					} finally {
+						cleanup()
+					}
+				}
+			`,
+			"/async-gen.js": `
+				export async function* foo(x) {
+					yield await x
+					yield* bar()
+				}
+			`,
+			"/for-await.js": `
+				export async function foo(x) {
+					for await (var y of x) bar(y)
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			UnsupportedJSFeatures: es(5),
+			AbsOutputFile:         "/out.js",
+		},
+	})
+}
+
+func TestLowerAsyncGeneratorES2017(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export async function* foo(x) {
+					yield await x
+					yield* bar()
+				}
+				export async function baz(x) {
+					label: for await (let y of x) {
+						if (y) continue label
+						await y
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			UnsupportedJSFeatures: es(2017),
+			AbsOutputFile:         "/out.js",
+		},
 	})
 }
 
@@ -1615,3 +1691,233 @@ func TestLowerNullishCoalescingAssignmentIssue1493(t *testing.T) {
 		},
 	})
 }
+
+func TestLowerDestructuringES5(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				var {a, b: [c, d = 1], ...e} = f
+				var [g, , ...h] = i
+				;[j, k.l] = [k.l, j]
+				console.log({m: {n}} = o)
+				function p({q, r = 2}, [s]) { return q + r + s }
+				var t = ({u}) => u
+				for (var [v, w] of x) console.log(v, w)
+				for ({y} in z) console.log(y)
+				try { throw 0 } catch ({message}) { console.log(message) }
+				export {a, c, d, e, g, h, p, t}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			UnsupportedJSFeatures: compat.Destructuring | compat.ObjectRestSpread,
+			AbsOutputFile:         "/out.js",
+		},
+	})
+}
+
+func TestLowerClassES5(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {Base} from './base'
+				export class Derived extends Base {
+					#secret = 1
+					static count = 0
+					constructor(x) {
+						super(x)
+						Derived.count++
+					}
+					get secret() { return this.#secret }
+					set secret(value) { this.#secret = value }
+					toString() { return 'Derived(' + super.toString() + ')' }
+					static create() { return new this(1) }
+				}
+				export let Anon = class extends Derived {}
+			`,
+			"/base.js": `
+				export class Base {
+					constructor(x) { this.x = x }
+					toString() { return String(this.x) }
+				}
+				class Unused {}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			UnsupportedJSFeatures: compat.Class | compat.ClassField | compat.ClassStaticField | compat.ClassPrivateField | compat.ClassPrivateAccessor,
+			AbsOutputFile:         "/out.js",
+		},
+	})
+}
+
+func TestLowerLetConstES5(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {fns} from './loop'
+				let x = 1
+				{
+					let x = 2
+					console.log(x)
+				}
+				{
+					const x = 3
+					console.log(x)
+				}
+				function foo() {
+					let y = x
+					if (y) {
+						let y = 4
+						return y
+					}
+					return y
+				}
+				console.log(x, foo(), fns)
+			`,
+			"/loop.js": `
+				export let fns = []
+				for (let i = 0; i < 3; i++) {
+					for (let i = 0; i < 2; i++) {
+						fns.push(() => i)
+					}
+					fns.push(() => i)
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			UnsupportedJSFeatures: compat.Let | compat.Const,
+			AbsOutputFile:         "/out.js",
+		},
+	})
+}
+
+func TestLowerES2015SyntaxES5(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {sum, keys} from './args'
+				let obj = {
+					[keys[0]]: 1,
+					total(...args) {
+						return sum(0, ...args, this[keys[0]])
+					},
+				}
+				for (let x of [1, 2]) console.log(x)
+				for (let key of keys) console.log(key, obj.total(key.length))
+				console.log(new Date(...[2020, 0, 1]), [...keys, 'c'])
+			`,
+			"/args.js": `
+				export let keys = ['a', 'b']
+				export function sum(a = 0, ...[b, ...rest]) {
+					return rest.length ? sum(a + b, ...rest) : a + (b || 0)
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			UnsupportedJSFeatures: es(5),
+			AbsOutputFile:         "/out.js",
+		},
+	})
+}
+
+func TestLowerDecorators(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {logged, bound} from './decorators'
+				@logged
+				export class Foo {
+					@logged static count = 0
+					@logged accessor name = 'foo'
+					@logged #secret = 1
+					@bound greet() { return 'Hello ' + this.name + this.#secret }
+					@logged static accessor #instances = []
+					static add(foo) { Foo.#instances.push(foo) }
+				}
+				export default @logged class extends Foo {}
+			`,
+			"/decorators.js": `
+				export function logged(value, context) {
+					console.log(context.kind, context.name)
+				}
+				export function bound(value, context) {
+					context.addInitializer(function() { this[context.name] = this[context.name].bind(this) })
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTSLowerDecoratorsTsconfigJson(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import legacy from './legacy'
+				import standard from './standard'
+				console.log(legacy, standard)
+			`,
+			"/legacy/index.ts": `
+				export default class {
+					@dec foo(@dec x: number) {}
+				}
+			`,
+			"/standard/index.ts": `
+				export default class {
+					@dec foo(x: number) {}
+					@dec accessor bar: number = 1
+				}
+			`,
+			"/standard/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"experimentalDecorators": false
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTSLowerDecoratorsParameterError(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				export default class {
+					foo(@dec x: number) {}
+				}
+			`,
+			"/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"experimentalDecorators": false
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+		expectedScanLog: `entry.ts: error: Parameter decorators only work when experimental decorators are enabled
+`,
+	})
+}
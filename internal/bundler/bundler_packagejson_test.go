@@ -1929,3 +1929,313 @@ Users/user/project/src/entry.js: note: Import from "pkg/extra/other/file.js" to
 `,
 	})
 }
+
+func TestPackageJsonDedupePackages(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from 'a'
+				import c from 'c'
+				console.log(a, c)
+			`,
+			"/Users/user/project/node_modules/a/package.json": `{ "name": "a", "version": "1.0.0" }`,
+			"/Users/user/project/node_modules/a/index.js":     `import b from 'b'; export default 'a' + b`,
+			"/Users/user/project/node_modules/c/package.json": `{ "name": "c", "version": "1.0.0" }`,
+			"/Users/user/project/node_modules/c/index.js":     `import b from 'b'; export default 'c' + b`,
+
+			// These are identical copies of the same version
+			"/Users/user/project/node_modules/a/node_modules/b/package.json": `{ "name": "b", "version": "2.0.0" }`,
+			"/Users/user/project/node_modules/a/node_modules/b/index.js":     `import {x} from './x'; export default x`,
+			"/Users/user/project/node_modules/a/node_modules/b/x.js":         `export let x = 'b'`,
+			"/Users/user/project/node_modules/c/node_modules/b/package.json": `{ "name": "b", "version": "2.0.0" }`,
+			"/Users/user/project/node_modules/c/node_modules/b/index.js":     `import {x} from './x'; export default x`,
+			"/Users/user/project/node_modules/c/node_modules/b/x.js":         `export let x = 'b'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/Users/user/project/out.js",
+			DedupePackages: true,
+		},
+	})
+}
+
+func TestPackageJsonDedupePackagesMetafile(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from 'a'
+				import c from 'c'
+				console.log(a, c)
+			`,
+			"/Users/user/project/node_modules/a/package.json": `{ "name": "a", "version": "1.0.0" }`,
+			"/Users/user/project/node_modules/a/index.js":     `import b from 'b'; export default 'a' + b`,
+			"/Users/user/project/node_modules/c/package.json": `{ "name": "c", "version": "1.0.0" }`,
+			"/Users/user/project/node_modules/c/index.js":     `import b from 'b'; export default 'c' + b`,
+
+			// These are identical copies of the same version
+			"/Users/user/project/node_modules/a/node_modules/b/package.json": `{ "name": "b", "version": "2.0.0" }`,
+			"/Users/user/project/node_modules/a/node_modules/b/index.js":     `import {x} from './x'; export default x`,
+			"/Users/user/project/node_modules/a/node_modules/b/x.js":         `export let x = 'b'`,
+			"/Users/user/project/node_modules/c/node_modules/b/package.json": `{ "name": "b", "version": "2.0.0" }`,
+			"/Users/user/project/node_modules/c/node_modules/b/index.js":     `import {x} from './x'; export default x`,
+			"/Users/user/project/node_modules/c/node_modules/b/x.js":         `export let x = 'b'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/Users/user/project/out.js",
+			DedupePackages: true,
+			NeedsMetafile:  true,
+		},
+	})
+}
+
+func TestPackageJsonDedupePackagesDifferentVersions(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from 'a'
+				import c from 'c'
+				console.log(a, c)
+			`,
+			"/Users/user/project/node_modules/a/package.json": `{ "name": "a", "version": "1.0.0" }`,
+			"/Users/user/project/node_modules/a/index.js":     `import b from 'b'; export default 'a' + b`,
+			"/Users/user/project/node_modules/c/package.json": `{ "name": "c", "version": "1.0.0" }`,
+			"/Users/user/project/node_modules/c/index.js":     `import b from 'b'; export default 'c' + b`,
+
+			// These are different versions and must not be merged
+			"/Users/user/project/node_modules/a/node_modules/b/package.json": `{ "name": "b", "version": "2.0.0" }`,
+			"/Users/user/project/node_modules/a/node_modules/b/index.js":     `export default 'b2'`,
+			"/Users/user/project/node_modules/c/node_modules/b/package.json": `{ "name": "b", "version": "3.0.0" }`,
+			"/Users/user/project/node_modules/c/node_modules/b/index.js":     `export default 'b3'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/Users/user/project/out.js",
+			DedupePackages: true,
+		},
+	})
+}
+
+func TestPackageJsonCustomPlatform(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from 'pkg-main'
+				import b from 'pkg-exports'
+				import c from 'pkg-browser'
+				import fs from 'fs'
+				import path from 'node:path'
+				console.log(a, b, c, fs, path)
+			`,
+			"/Users/user/project/node_modules/pkg-main/package.json": `
+				{
+					"main": "./main.js",
+					"module": "./module.js",
+					"react-native": "./native.js"
+				}
+			`,
+			"/Users/user/project/node_modules/pkg-main/main.js":   `export default 'main'`,
+			"/Users/user/project/node_modules/pkg-main/module.js": `export default 'module'`,
+			"/Users/user/project/node_modules/pkg-main/native.js": `export default 'native'`,
+			"/Users/user/project/node_modules/pkg-exports/package.json": `
+				{
+					"exports": {
+						"node": "./node.js",
+						"browser": "./browser.js",
+						"react-native": "./native.js",
+						"default": "./default.js"
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/pkg-exports/node.js":    `export default 'node'`,
+			"/Users/user/project/node_modules/pkg-exports/browser.js": `export default 'browser'`,
+			"/Users/user/project/node_modules/pkg-exports/native.js":  `export default 'native'`,
+			"/Users/user/project/node_modules/pkg-exports/default.js": `export default 'default'`,
+			"/Users/user/project/node_modules/pkg-browser/package.json": `
+				{
+					"main": "./main.js",
+					"browser": {
+						"./main.js": "./main-browser.js"
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/pkg-browser/main.js":         `export default 'main'`,
+			"/Users/user/project/node_modules/pkg-browser/main-browser.js": `export default 'browser'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/Users/user/project/out.js",
+			Platform:      config.PlatformNeutral,
+			CustomPlatform: &config.CustomPlatform{
+				Name:           "react-native",
+				Conditions:     []string{"react-native"},
+				MainFields:     []string{"react-native", "module", "main"},
+				BuiltinModules: map[string]bool{"fs": true, "path": true},
+				BrowserField:   true,
+			},
+		},
+	})
+}
+
+func TestPackageJsonCustomPlatformNoBrowserField(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from 'pkg-browser'
+				import b from 'pkg-exports'
+				console.log(a, b)
+			`,
+			"/Users/user/project/node_modules/pkg-browser/package.json": `
+				{
+					"main": "./main.js",
+					"browser": "./browser.js"
+				}
+			`,
+			"/Users/user/project/node_modules/pkg-browser/main.js":    `export default 'main'`,
+			"/Users/user/project/node_modules/pkg-browser/browser.js": `export default 'browser'`,
+			"/Users/user/project/node_modules/pkg-exports/package.json": `
+				{
+					"exports": {
+						"browser": "./browser.js",
+						"worker": "./worker.js",
+						"default": "./default.js"
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/pkg-exports/browser.js": `export default 'browser'`,
+			"/Users/user/project/node_modules/pkg-exports/worker.js":  `export default 'worker'`,
+			"/Users/user/project/node_modules/pkg-exports/default.js": `export default 'default'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/Users/user/project/out.js",
+			Platform:      config.PlatformBrowser,
+			CustomPlatform: &config.CustomPlatform{
+				Name:       "worker",
+				Conditions: []string{"worker"},
+				MainFields: []string{"main"},
+			},
+		},
+	})
+}
+
+func TestPackageJsonNodeBuiltinPolicies(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import fs from 'fs'
+				import os from 'node:os'
+				import path from 'path'
+				import events from 'events'
+				import crypto from 'crypto'
+				console.log(fs, os, path, events, crypto)
+			`,
+			"/Users/user/project/node_modules/path-browserify/package.json": `{ "main": "./index.js" }`,
+			"/Users/user/project/node_modules/path-browserify/index.js":     `export default 'path-browserify'`,
+			"/Users/user/project/node_modules/events/package.json":          `{ "main": "./events.js" }`,
+			"/Users/user/project/node_modules/events/events.js":             `export default 'events'`,
+			"/Users/user/project/node_modules/my-crypto/index.js":           `export default 'my-crypto'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/Users/user/project/out.js",
+			Platform:      config.PlatformBrowser,
+			NodeBuiltins: map[string]config.NodeBuiltinPolicy{
+				"fs":     {Mode: config.NodeBuiltinEmpty},
+				"os":     {Mode: config.NodeBuiltinExternal},
+				"path":   {Mode: config.NodeBuiltinPolyfill, Polyfill: "path-browserify"},
+				"events": {Mode: config.NodeBuiltinPolyfill, Polyfill: "events"},
+				"crypto": {Mode: config.NodeBuiltinPolyfill, Polyfill: "my-crypto"},
+			},
+		},
+	})
+}
+
+func TestPackageJsonNodeBuiltinPolicyOverridesPlatformNode(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import fs from 'fs'
+				import path from 'node:path'
+				console.log(fs, path)
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/Users/user/project/out.js",
+			Platform:      config.PlatformNode,
+			NodeBuiltins: map[string]config.NodeBuiltinPolicy{
+				"fs": {Mode: config.NodeBuiltinEmpty},
+			},
+		},
+	})
+}
+
+func TestPackageJsonNodeBuiltinPolicyError(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import './a'
+				require('node:child_process')
+			`,
+			"/Users/user/project/src/a.js": `
+				import 'pkg'
+			`,
+			"/Users/user/project/node_modules/pkg/index.js": `
+				import { spawn } from 'child_process'
+				import 'fs'
+				spawn()
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/Users/user/project/out.js",
+			Platform:      config.PlatformNode,
+			NodeBuiltins: map[string]config.NodeBuiltinPolicy{
+				"child_process": {Mode: config.NodeBuiltinError},
+			},
+		},
+		expectedScanLog: `Users/user/project/node_modules/pkg/index.js: error: Importing the builtin node module "child_process" is not allowed in this build
+Users/user/project/src/entry.js: note: The file "Users/user/project/src/entry.js" imports the file "Users/user/project/src/a.js" here
+Users/user/project/src/a.js: note: The file "Users/user/project/src/a.js" imports the file "Users/user/project/node_modules/pkg/index.js" here
+Users/user/project/src/entry.js: error: Importing the builtin node module "child_process" is not allowed in this build
+`,
+	})
+}
+
+func TestPackageJsonNodeBuiltinPolicyMissingPolyfill(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import { Buffer } from 'buffer'
+				console.log(Buffer)
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/Users/user/project/out.js",
+			Platform:      config.PlatformBrowser,
+			NodeBuiltins: map[string]config.NodeBuiltinPolicy{
+				"buffer": {Mode: config.NodeBuiltinPolyfill, Polyfill: "buffer"},
+			},
+		},
+		expectedScanLog: `Users/user/project/src/entry.js: error: Could not resolve "buffer" (mark it as external to exclude it from the bundle)
+note: The builtin node module "buffer" is configured to be substituted with the package "buffer", which could not be found
+`,
+	})
+}
@@ -525,3 +525,410 @@ func TestSplittingPublicPathEntryName(t *testing.T) {
 		},
 	})
 }
+
+func TestSplittingManualChunkPackages(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {render} from "react-dom"
+				import {shared} from "./shared.js"
+				render(shared)
+			`,
+			"/b.js": `
+				import {createElement} from "react"
+				import {notReact} from "@scope/react"
+				import {shared} from "./shared.js"
+				console.log(createElement(shared), notReact)
+			`,
+			"/shared.js":                          `export let shared = 123`,
+			"/node_modules/react/index.js":        `export function createElement(x) { return [x] }`,
+			"/node_modules/react-dom/index.js":    `import {createElement} from "react"; export function render(x) { createElement(x) }`,
+			"/node_modules/@scope/react/index.js": `export let notReact = 1`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunkPattern{
+				{WildcardPattern: config.WildcardPattern{Prefix: "react"}, ChunkName: "vendor", IsPackage: true},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunkPath(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {x} from "./lib/x.js"
+				import {y} from "./lib/y.js"
+				console.log(x, y)
+			`,
+			"/b.js": `
+				import {y} from "./lib/y.js"
+				import("./c.js")
+				console.log(y)
+			`,
+			"/c.js":     `export let c = 3`,
+			"/lib/x.js": `export let x = 1`,
+			"/lib/y.js": `export let y = 2`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunkPattern{
+				{WildcardPattern: config.WildcardPattern{Prefix: "/lib/"}, ChunkName: "lib"},
+				{WildcardPattern: config.WildcardPattern{Prefix: "/c.js"}, ChunkName: "ignored"},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunkDependencies(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {u} from "./lib/u.js"
+				console.log(u)
+			`,
+			"/b.js": `
+				import {shared} from "./shared.js"
+				console.log(shared)
+			`,
+			"/c.js": `
+				import {common} from "./common.js"
+				console.log(common)
+			`,
+			"/lib/u.js": `
+				import {shared} from "../shared.js"
+				import {common} from "../common.js"
+				export let u = shared + common
+			`,
+			"/lib/u2.js": `export let u2 = 2`,
+			"/shared.js": `
+				import {u2} from "./lib/u2.js"
+				export let shared = u2
+			`,
+			"/common.js": `export let common = 3`,
+		},
+		entryPaths: []string{"/a.js", "/b.js", "/c.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			NeedsMetafile: true,
+			ManualChunks: []config.ManualChunkPattern{
+				{WildcardPattern: config.WildcardPattern{Prefix: "/lib/"}, ChunkName: "lib"},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunkCycleWithEntryPoint(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {u} from "./lib/u.js"
+				export let a = 1
+				console.log(u)
+			`,
+			"/lib/u.js": `
+				import {a} from "../a.js"
+				export let u = () => a
+			`,
+		},
+		entryPaths: []string{"/a.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunkPattern{
+				{WildcardPattern: config.WildcardPattern{Prefix: "/lib/"}, ChunkName: "lib"},
+			},
+		},
+		expectedCompileLog: `error: Cannot generate manual chunk "lib" because it would be part of a circular import between chunks
+note: The file "lib/u.js" in manual chunk "lib" imports the file "a.js" in the chunk for entry point "a.js"
+note: The file "a.js" in the chunk for entry point "a.js" imports the file "lib/u.js" in manual chunk "lib"
+`,
+	})
+}
+
+func TestSplittingManualChunkCycleBetweenManualChunks(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {u} from "./lib/u.js"
+				console.log(u)
+			`,
+			"/lib/u.js": `
+				import {v} from "../vendor/v.js"
+				export let u = v
+			`,
+			"/vendor/v.js": `
+				import {w} from "../lib/w.js"
+				export let v = w
+			`,
+			"/lib/w.js": `export let w = 2`,
+		},
+		entryPaths: []string{"/a.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunkPattern{
+				{WildcardPattern: config.WildcardPattern{Prefix: "/lib/"}, ChunkName: "lib"},
+				{WildcardPattern: config.WildcardPattern{Prefix: "/vendor/"}, ChunkName: "vendor"},
+			},
+		},
+		expectedCompileLog: `error: Cannot generate manual chunk "lib" because it would be part of a circular import between chunks
+note: The file "lib/u.js" in manual chunk "lib" imports the file "vendor/v.js" in manual chunk "vendor"
+note: The file "vendor/v.js" in manual chunk "vendor" imports the file "lib/w.js" in manual chunk "lib"
+`,
+	})
+}
+
+func TestSplittingSharedAndDynamicIntoCommonJS(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {foo, setFoo} from "./shared.js"
+				setFoo(123)
+				console.log(foo)
+				import("./b.js").then(({bar}) => console.log(bar))
+			`,
+			"/b.js": `
+				import {foo} from "./shared.js"
+				export let bar = foo + 1
+			`,
+			"/shared.js": `
+				export let foo
+				export function setFoo(value) {
+					foo = value
+				}
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatCommonJS,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingSharedAndDynamicIntoIIFE(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {foo, setFoo} from "./shared.js"
+				setFoo(123)
+				console.log(foo)
+				import("./b.js").then(({bar}) => console.log(bar))
+			`,
+			"/b.js": `
+				import {foo} from "./shared.js"
+				export let bar = foo + 1
+			`,
+			"/shared.js": `
+				export let foo
+				export function setFoo(value) {
+					foo = value
+				}
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingCallsIntoOtherChunksWithoutThis(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {fn, Foo} from "./shared.js"
+				fn()
+				fn` + "`tag`" + `
+				new Foo()
+				console.log(fn, typeof fn)
+			`,
+			"/b.js": `
+				import {fn} from "./shared.js"
+				fn()
+			`,
+			"/shared.js": `
+				export function fn() { return this }
+				export class Foo {}
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatCommonJS,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingDynamicIntoIIFEPublicPath(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import("./foo.js").then(({bar}) => console.log(bar))
+			`,
+			"/foo.js": `
+				export let bar = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			CodeSplitting:     true,
+			OutputFormat:      config.FormatIIFE,
+			AbsOutputDir:      "/out",
+			PublicPath:        "https://example.com/assets",
+			MinifyIdentifiers: true,
+		},
+	})
+}
+
+func TestSplittingMinChunkSize(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {ab} from "./ab.js"
+				import {abc} from "./abc.js"
+				console.log(ab, abc)
+			`,
+			"/b.js": `
+				import {ab} from "./ab.js"
+				import {bc} from "./bc.js"
+				import {abc} from "./abc.js"
+				console.log(ab, bc, abc)
+			`,
+			"/c.js": `
+				import {bc} from "./bc.js"
+				import {abc} from "./abc.js"
+				console.log(bc, abc)
+			`,
+			"/ab.js":  `export let ab = 1`,
+			"/abc.js": `export let abc = 2`,
+			"/bc.js":  `console.log("side effect"); export let bc = 3`,
+		},
+		entryPaths: []string{"/a.js", "/b.js", "/c.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			MinChunkSize:  100,
+		},
+	})
+}
+
+func TestSplittingMinChunkSizeDuplicateIntoImporters(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {util} from "./util.js"
+				console.log(util())
+			`,
+			"/b.js": `
+				import {util} from "./util.js"
+				console.log(util())
+			`,
+			"/c.js": `
+				import {big} from "./lib/big.js"
+				console.log(big)
+			`,
+			"/util.js": `
+				import {big} from "./lib/big.js"
+				export function util() { return big.length }
+			`,
+			"/lib/big.js": `
+				console.log("manual chunks are never merged or duplicated")
+				export let big = "this is a shared file"
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js", "/c.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			MinChunkSize:  100,
+			ManualChunks: []config.ManualChunkPattern{
+				{WildcardPattern: config.WildcardPattern{Prefix: "/lib/"}, ChunkName: "lib"},
+			},
+		},
+	})
+}
+
+func TestSplittingMinChunkSizeNoDuplicateForDynamicImport(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {util} from "./util.js"
+				console.log(util())
+				import("./b.js")
+			`,
+			"/b.js": `
+				import {util} from "./util.js"
+				console.log(util())
+			`,
+			"/util.js": `export function util() { return 1 }`,
+		},
+		entryPaths: []string{"/a.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			MinChunkSize:  100,
+		},
+	})
+}
+
+func TestSplittingMaxChunkSize(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {x} from "./x.js"
+				import {y} from "./y.js"
+				import {z} from "./z.js"
+				console.log(x, y, z)
+			`,
+			"/b.js": `
+				import {x} from "./x.js"
+				import {y} from "./y.js"
+				import {z} from "./z.js"
+				console.log(x + y + z)
+			`,
+			"/x.js": `export let x = "this is the first shared file"`,
+			"/y.js": `import {x} from "./x.js"; export let y = x + "!"`,
+			"/z.js": `export let z = "this is the third shared file"`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			MaxChunkSize:  60,
+		},
+	})
+}
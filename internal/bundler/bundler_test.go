@@ -20,6 +20,7 @@ import (
 	"github.com/trustelem/esbuild/internal/compat"
 	"github.com/trustelem/esbuild/internal/config"
 	"github.com/trustelem/esbuild/internal/fs"
+	"github.com/trustelem/esbuild/internal/graph"
 	"github.com/trustelem/esbuild/internal/logger"
 	"github.com/trustelem/esbuild/internal/resolver"
 	"github.com/trustelem/esbuild/internal/test"
@@ -55,6 +56,11 @@ type bundled struct {
 	expectedScanLog    string
 	expectedCompileLog string
 	options            config.Options
+
+	// If present, these files are changed after the first build and then the
+	// bundle is built again using the same caches, like an incremental build.
+	// Only the results of the second build are compared against the snapshot.
+	rebuildFiles map[string]string
 }
 
 type suite struct {
@@ -70,7 +76,6 @@ func (s *suite) expectBundled(t *testing.T, args bundled) {
 	testName := t.Name()
 	t.Run("", func(t *testing.T) {
 		t.Helper()
-		fs := fs.MockFS(args.files)
 		if args.options.ExtensionOrder == nil {
 			args.options.ExtensionOrder = []string{".tsx", ".ts", ".jsx", ".js", ".css", ".json"}
 		}
@@ -81,33 +86,53 @@ func (s *suite) expectBundled(t *testing.T, args bundled) {
 			// Apply this default to all tests since it was not configurable when the tests were written
 			args.options.TreeShaking = true
 		}
-		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug)
 		caches := cache.MakeCacheSet()
-		resolver := resolver.NewResolver(fs, log, caches, args.options)
 		entryPoints := make([]EntryPoint, 0, len(args.entryPaths))
 		for _, path := range args.entryPaths {
 			entryPoints = append(entryPoints, EntryPoint{InputPath: path})
 		}
-		bundle := ScanBundle(log, fs, resolver, caches, entryPoints, args.options, nil)
-		msgs := log.Done()
-		assertLog(t, msgs, args.expectedScanLog)
+		build := func(files map[string]string) ([]graph.OutputFile, string, bool) {
+			fs := fs.MockFS(files)
+			log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug)
+			resolver := resolver.NewResolver(fs, log, caches, args.options, nil)
+			bundle := ScanBundle(log, fs, resolver, caches, entryPoints, args.options, nil)
+			msgs := log.Done()
+			assertLog(t, msgs, args.expectedScanLog)
 
-		// Stop now if there were any errors during the scan
-		if hasErrors(msgs) {
-			return
-		}
+			// Stop now if there were any errors during the scan
+			if hasErrors(msgs) {
+				return nil, "", false
+			}
 
-		log = logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug)
-		args.options.OmitRuntimeForTests = true
-		results, _ := bundle.Compile(log, args.options, nil)
-		msgs = log.Done()
-		assertLog(t, msgs, args.expectedCompileLog)
+			log = logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug)
+			options := args.options
+			options.OmitRuntimeForTests = true
+			results, metafileJSON := bundle.Compile(log, options, nil)
+			msgs = log.Done()
+			assertLog(t, msgs, args.expectedCompileLog)
 
-		// Stop now if there were any errors during the compile
-		if hasErrors(msgs) {
+			// Stop now if there were any errors during the compile
+			return results, metafileJSON, !hasErrors(msgs)
+		}
+		results, metafileJSON, ok := build(args.files)
+		if !ok {
 			return
 		}
 
+		// Build again with the changed files if requested
+		if args.rebuildFiles != nil {
+			files := make(map[string]string, len(args.files))
+			for path, contents := range args.files {
+				files[path] = contents
+			}
+			for path, contents := range args.rebuildFiles {
+				files[path] = contents
+			}
+			if results, metafileJSON, ok = build(files); !ok {
+				return
+			}
+		}
+
 		// Don't include source maps in results since they are just noise. Source
 		// map validity is tested separately in a test that uses Mozilla's source
 		// map parsing library.
@@ -120,6 +145,12 @@ func (s *suite) expectBundled(t *testing.T, args bundled) {
 				generated += fmt.Sprintf("---------- %s ----------\n%s", result.AbsPath, string(result.Contents))
 			}
 		}
+		if args.options.NeedsMetafile {
+			if generated != "" {
+				generated += "\n"
+			}
+			generated += fmt.Sprintf("---------- metafile.json ----------\n%s", metafileJSON)
+		}
 		s.compareSnapshot(t, testName, generated)
 	})
 }
@@ -728,6 +728,95 @@ func TestTypeScriptDecoratorsKeepNames(t *testing.T) {
 	})
 }
 
+func TestTypeScriptDecoratorMetadata(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				import { Service, IService } from './service'
+				interface Local {}
+				@dec
+				export class Foo {
+					constructor(service: Service, other: IService, @inject('x') local: Local, name?: string) {}
+					@dec str: string
+					@dec num: number | null
+					@dec mixed: string | number
+					@dec list: Service[]
+					@dec fn: () => void
+					@dec literal: 'a' | 'b'
+					@dec qualified: ns.Type
+					@dec none
+					@dec method(x: boolean, ...rest: string[]): void {}
+					@dec async asyncMethod(x) {}
+					params(@dec x: Date): Promise<number> { return null }
+					@dec get accessor(): number { return 1 }
+					set accessor(value: string) {}
+				}
+			`,
+			"/service.ts": `
+				export class Service {}
+				export interface IService {}
+			`,
+			"/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"experimentalDecorators": true,
+						"emitDecoratorMetadata": true
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTypeScriptDecoratorMetadataEnums(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				import { ImportedNum, ImportedStr, ImportedMixed, ImportedClass } from './enums'
+				enum Num { A, B = 2 }
+				enum Str { A = 'a', B = ` + "`b`" + ` }
+				enum Mixed { A = 1, B = 'b' }
+				enum Merged { A }
+				enum Merged { B = 1 }
+				@dec
+				export class Foo {
+					constructor(a: Num, b: Str, c: Mixed, d: Merged, e: Later) {}
+					@dec imported(a: ImportedNum, b: ImportedStr, c: ImportedMixed, d: ImportedClass) {}
+					@dec method(x: Num): Str { return Str.A }
+				}
+				enum Later { A = 'a' + 'b' }
+			`,
+			"/enums.ts": `
+				export enum ImportedNum { A }
+				export enum ImportedStr { A = 'a' }
+				export enum ImportedStr { B = 'b' }
+				export enum ImportedMixed { A, B = 'b' }
+				export class ImportedClass {}
+			`,
+			"/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"experimentalDecorators": true,
+						"emitDecoratorMetadata": true
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
 func TestTSExportDefaultTypeIssue316(t *testing.T) {
 	ts_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -1244,3 +1333,92 @@ func TestTSAbstractClassFieldUseDefine(t *testing.T) {
 		},
 	})
 }
+
+func TestTSEnumCrossModuleInliningAccess(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				import { a, b, c, d, e } from './enums'
+				import * as ns from './enums'
+				console.log([
+					a.x,
+					b['y'],
+					c.z,
+					d.w,
+					ns.e.v,
+					b.missing,
+				])
+			`,
+			"/enums.ts": `
+				export enum a { x = 1 }
+				export enum b { y = 'y' }
+				export const enum c { z = 2 * 3 }
+				export enum d { w = Math.random() }
+				export enum e { u, v }
+			`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTSEnumCrossModuleInliningAssign(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				import { a } from './enums'
+				a.x = 5
+				a['x'] += 1
+				a.x++
+				--a.x
+				delete a.x;
+				[a.x, ...a['y']] = [1];
+				({ z: a.x, ...a.y } = {})
+				for (a.x in {}) ;
+				for (a.y of []) ;
+				console.log(a.x, a.x.toFixed())
+			`,
+			"/enums.ts": `
+				export enum a { x, y, z }
+			`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTSEnumCrossModuleTreeShaking(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				import { a_DROP, b_DROP, c_KEEP, d_KEEP, e_KEEP } from './reexport'
+				console.log([a_DROP.x, b_DROP.y, c_KEEP.z])
+				console.log(d_KEEP)
+				console.log(e_KEEP[Math.random() < 0.5 ? 'x' : 'y'])
+			`,
+			"/reexport.ts": `
+				export * from './enums'
+			`,
+			"/enums.ts": `
+				export enum a_DROP { x = 1 }
+				export enum b_DROP { y = 'y' }
+				export enum b_DROP { z = 2 }
+				export enum c_KEEP { z = foo() }
+				export enum d_KEEP { x }
+				export enum e_KEEP { x }
+				declare let foo: () => number
+			`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
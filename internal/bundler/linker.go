@@ -12,6 +12,7 @@ import (
 	"sync"
 
 	"github.com/trustelem/esbuild/internal/ast"
+	"github.com/trustelem/esbuild/internal/cache"
 	"github.com/trustelem/esbuild/internal/compat"
 	"github.com/trustelem/esbuild/internal/config"
 	"github.com/trustelem/esbuild/internal/css_ast"
@@ -44,6 +45,32 @@ type linkerContext struct {
 	// We may need to refer to the CommonJS "module" symbol for exports
 	unboundModuleRef js_ast.Ref
 
+	// Code splitting for the "iife" format uses a global registry of chunks
+	unboundGlobalThisRef js_ast.Ref
+	chunkRegistryRef     js_ast.Ref
+	loadChunkRef         js_ast.Ref
+
+	// Code splitting with the "esm" and "iife" formats loads the stylesheets of
+	// a chunk before the chunk itself when it's imported using "import()"
+	loadCSSRef js_ast.Ref
+
+	// This maps the key of each chunk loaded with "import()" in the "iife"
+	// format to the keys of the chunks to load in order (the chunk itself last)
+	chunkLoadOrder map[string][]string
+
+	// This maps the key of each chunk loaded with "import()" to the keys of the
+	// stylesheets to load before it in order
+	cssLoadOrder map[string][]string
+
+	// External packages that are read from a global variable instead of being
+	// loaded with "require()". This is only used by formats that would
+	// otherwise use "require()" for external packages.
+	externalGlobals map[string][]string
+
+	// Module federation reads the modules of other builds from their containers
+	federationRequireRef js_ast.Ref
+	federationImportRef  js_ast.Ref
+
 	// We may need to refer to the "__esm" and/or "__commonJS" runtime symbols
 	cjsRuntimeRef js_ast.Ref
 	esmRuntimeRef js_ast.Ref
@@ -56,6 +83,20 @@ type linkerContext struct {
 	// This is passed to us from the bundling phase
 	uniqueKeyPrefix      string
 	uniqueKeyPrefixBytes []byte // This is just "uniqueKeyPrefix" in byte form
+
+	// Hot module replacement compares the code generated for each module with
+	// the code from the previous build to generate updates
+	hmrCache *cache.HMRCache
+
+	// Workers are linked before the code that creates them. This maps the key
+	// of each worker entry point (see "workerKey") to its final output path
+	// relative to the output directory.
+	workerPaths map[uint32]string
+
+	// This maps the source index of each entry point to the final output path of
+	// its chunk relative to the output directory. It's used to fill out the
+	// "workerPaths" map of the code that creates a worker.
+	entryPointPaths map[uint32]string
 }
 
 type partRange struct {
@@ -69,6 +110,11 @@ type chunkInfo struct {
 	// chunk before the final output path has been computed.
 	uniqueKey string
 
+	// This is like "uniqueKey" but always represents the final path of this
+	// chunk relative to the output directory. It's used as the name of this
+	// chunk in the chunk registry of the "iife" format.
+	uniqueKeyForID string
+
 	filesWithPartsInChunk map[uint32]bool
 	entryBits             helpers.BitSet
 
@@ -77,6 +123,11 @@ type chunkInfo struct {
 	sourceIndex   uint32 // An index into "c.sources"
 	entryPointBit uint   // An index into "c.graph.EntryPoints"
 
+	// If non-empty, this chunk was requested by the "ManualChunks" option. Its
+	// files were reached from different sets of entry points, so "entryBits" is
+	// the union of all of them.
+	manualChunkName string
+
 	// For code splitting
 	crossChunkImports []chunkImport
 
@@ -94,6 +145,10 @@ type chunkInfo struct {
 	// If non-empty, this chunk needs to generate an external legal comments file.
 	externalLegalComments []byte
 
+	// If non-empty, this chunk needs to generate a hot module replacement update
+	// file with the modules that changed since the previous build.
+	hmrUpdate []byte
+
 	// When this chunk is initially generated in isolation, the output pieces
 	// will contain slices of the output with the unique keys of other chunks
 	// omitted.
@@ -123,6 +178,8 @@ const (
 	outputPieceNone outputPieceIndexKind = iota
 	outputPieceAssetIndex
 	outputPieceChunkIndex
+	outputPieceChunkIDIndex
+	outputPieceWorkerIndex
 )
 
 // This is a chunk of source code followed by a reference to another chunk. For
@@ -166,6 +223,16 @@ type chunkReprJS struct {
 	crossChunkSuffixStmts  []js_ast.Stmt
 	exportsToOtherChunks   map[js_ast.Ref]string
 	importsFromOtherChunks map[uint32]crossChunkImportItemArray
+
+	// For code splitting in output formats without "import" statements. Symbols
+	// imported from this chunk become properties of the namespace symbol.
+	namespaceRef        js_ast.Ref
+	usesChunkRegistry   bool
+	isLoadedDynamically bool
+
+	// With code splitting, this is the chunk containing the CSS imported by the
+	// files in this chunk, if there is any
+	cssChunkIndex ast.Index32
 }
 
 type chunkReprCSS struct {
@@ -215,7 +282,9 @@ func link(
 	uniqueKeyPrefix string,
 	reachableFiles []uint32,
 	dataForSourceMaps func() []dataForSourceMap,
-) []graph.OutputFile {
+	hmrCache *cache.HMRCache,
+	workerPaths map[uint32]string,
+) ([]graph.OutputFile, map[uint32]string) {
 	timer.Begin("Link")
 	defer timer.End("Link")
 
@@ -231,6 +300,9 @@ func link(
 		dataForSourceMaps:    dataForSourceMaps,
 		uniqueKeyPrefix:      uniqueKeyPrefix,
 		uniqueKeyPrefixBytes: []byte(uniqueKeyPrefix),
+		hmrCache:             hmrCache,
+		workerPaths:          workerPaths,
+		entryPointPaths:      make(map[uint32]string),
 		graph: graph.CloneLinkerGraph(
 			inputFiles,
 			reachableFiles,
@@ -240,9 +312,14 @@ func link(
 	}
 	timer.End("Clone linker graph")
 
-	// Use a smaller version of these functions if we don't need profiler names
+	// Use a smaller version of these functions if we don't need profiler names.
+	// Hot module replacement registers CommonJS closures by name instead so
+	// that they can be replaced later.
 	runtimeRepr := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr)
-	if c.options.ProfilerNames {
+	if c.options.HotModuleReplacement {
+		c.cjsRuntimeRef = runtimeRepr.AST.NamedExports["__hmrModule"].Ref
+		c.esmRuntimeRef = runtimeRepr.AST.NamedExports["__esm"].Ref
+	} else if c.options.ProfilerNames {
 		c.cjsRuntimeRef = runtimeRepr.AST.NamedExports["__commonJS"].Ref
 		c.esmRuntimeRef = runtimeRepr.AST.NamedExports["__esm"].Ref
 	} else {
@@ -250,7 +327,7 @@ func link(
 		c.esmRuntimeRef = runtimeRepr.AST.NamedExports["__esmMin"].Ref
 	}
 
-	for _, entryPoint := range entryPoints {
+	for _, entryPoint := range c.graph.EntryPoints() {
 		if repr, ok := c.graph.Files[entryPoint.SourceIndex].InputFile.Repr.(*graph.JSRepr); ok {
 			// Loaders default to CommonJS when they are the entry point and the output
 			// format is not ESM-compatible since that avoids generating the ESM-to-CJS
@@ -262,10 +339,12 @@ func link(
 
 			// Entry points with ES6 exports must generate an exports object when
 			// targeting non-ES6 formats. Note that the IIFE format only needs this
-			// when the global name is present, since that's the only way the exports
-			// can actually be observed externally.
+			// when the global name is present or when code splitting is active,
+			// since those are the only ways the exports can actually be observed
+			// externally.
 			if repr.AST.ExportKeyword.Len > 0 && (options.OutputFormat == config.FormatCommonJS ||
-				(options.OutputFormat == config.FormatIIFE && len(options.GlobalName) > 0)) {
+				options.OutputFormat == config.FormatUMD || options.OutputFormat == config.FormatSystem ||
+				(options.OutputFormat == config.FormatIIFE && (len(options.GlobalName) > 0 || options.CodeSplitting))) {
 				repr.AST.UsesExportsRef = true
 				repr.Meta.ForceIncludeExportsForEntryPoint = true
 			}
@@ -279,11 +358,49 @@ func link(
 		c.unboundModuleRef = js_ast.InvalidRef
 	}
 
+	// Code splitting with the "iife" format refers to the global chunk registry
+	if c.options.OutputFormat == config.FormatIIFE && c.options.CodeSplitting {
+		c.unboundGlobalThisRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, js_ast.SymbolUnbound, "globalThis")
+		c.chunkRegistryRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, js_ast.SymbolOther, "esbuildChunks")
+		c.loadChunkRef = runtimeRepr.AST.ModuleScope.Members["__loadChunk"].Ref
+	} else {
+		c.unboundGlobalThisRef = js_ast.InvalidRef
+		c.chunkRegistryRef = js_ast.InvalidRef
+		c.loadChunkRef = js_ast.InvalidRef
+	}
+
+	// External packages can be mapped to global variables in formats that would
+	// otherwise load them with "require()"
+	if c.options.OutputFormat == config.FormatIIFE || c.options.OutputFormat == config.FormatCommonJS {
+		c.externalGlobals = c.options.ExternalModules.Globals
+	}
+
+	// Module federation reads the modules of other builds with the runtime
+	if c.options.Federation != nil {
+		c.federationRequireRef = runtimeRepr.AST.NamedExports["__federationRequire"].Ref
+		c.federationImportRef = runtimeRepr.AST.NamedExports["__federationImport"].Ref
+	} else {
+		c.federationRequireRef = js_ast.InvalidRef
+		c.federationImportRef = js_ast.InvalidRef
+	}
+
+	// Code splitting also splits up the CSS imported from JavaScript, which
+	// must be loaded alongside each chunk
+	c.loadCSSRef = js_ast.InvalidRef
+	if c.options.CodeSplitting && (c.options.OutputFormat == config.FormatESModule || c.options.OutputFormat == config.FormatIIFE) {
+		for _, sourceIndex := range c.graph.ReachableFiles {
+			if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr); ok && repr.CSSSourceIndex.IsValid() {
+				c.loadCSSRef = runtimeRepr.AST.ModuleScope.Members["__loadCSS"].Ref
+				break
+			}
+		}
+	}
+
 	c.scanImportsAndExports()
 
 	// Stop now if there were errors
 	if c.log.HasErrors() {
-		return []graph.OutputFile{}
+		return []graph.OutputFile{}, nil
 	}
 
 	c.treeShakingAndCodeSplitting()
@@ -295,26 +412,31 @@ func link(
 	}
 
 	chunks := c.computeChunks()
+
+	// Stop now if there were errors
+	if c.log.HasErrors() {
+		return []graph.OutputFile{}, nil
+	}
+
 	c.computeCrossChunkDependencies(chunks)
 
 	// Make sure calls to "js_ast.FollowSymbols()" in parallel goroutines after this
 	// won't hit concurrent map mutation hazards
 	js_ast.FollowAllSymbols(c.graph.Symbols)
 
-	return c.generateChunksInParallel(chunks)
+	return c.generateChunksInParallel(chunks), c.entryPointPaths
 }
 
 // Currently the automatic chunk generation algorithm should by construction
 // never generate chunks that import each other since files are allocated to
-// chunks based on which entry points they are reachable from.
+// chunks based on which entry points they are reachable from. Manual chunks
+// are assigned so that they don't either, and "computeChunks" reports an
+// error for manual chunk configurations that would require a cycle.
 //
-// This will change in the future when we allow manual chunk labels. But before
-// we allow manual chunk labels, we'll need to rework module initialization to
-// allow code splitting chunks to be lazily-initialized.
-//
-// Since that work hasn't been finished yet, cycles in the chunk import graph
-// can cause initialization bugs. So let's forbid these cycles for now to guard
-// against code splitting bugs that could cause us to generate buggy chunks.
+// Module initialization hasn't been reworked to allow code splitting chunks to
+// be lazily-initialized, so cycles in the chunk import graph can cause
+// initialization bugs. So let's forbid these cycles for now to guard against
+// code splitting bugs that could cause us to generate buggy chunks.
 func (c *linkerContext) enforceNoCyclicChunkImports(chunks []chunkInfo) {
 	var validate func(int, []int)
 	validate = func(chunkIndex int, path []int) {
@@ -382,6 +504,11 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo) []graph.Out
 		chunk.finalRelPath = config.TemplateToString(config.SubstituteTemplate(chunk.finalTemplate, config.PathPlaceholders{
 			Hash: hashSubstitution,
 		}))
+
+		// Remember where each entry point ended up in case it's a worker
+		if _, ok := chunk.chunkRepr.(*chunkReprJS); ok && chunk.isEntryPoint {
+			c.entryPointPaths[chunk.sourceIndex] = chunk.finalRelPath
+		}
 	}
 
 	// Generate the final output files by joining file pieces together
@@ -442,6 +569,19 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo) []graph.Out
 				})
 			}
 
+			// Generate the optional hot module replacement update for this chunk
+			if chunk.hmrUpdate != nil {
+				ext := path.Ext(chunk.finalRelPath)
+				finalRelPathForHMR := chunk.finalRelPath[:len(chunk.finalRelPath)-len(ext)] + ".hmr" + ext
+				outputFiles = append(outputFiles, graph.OutputFile{
+					AbsPath:  c.fs.Join(c.options.AbsOutputDir, finalRelPathForHMR),
+					Contents: chunk.hmrUpdate,
+					JSONMetadataChunk: fmt.Sprintf(
+						"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(chunk.hmrUpdate)),
+					IsHMRUpdate: true,
+				})
+			}
+
 			// Generate the optional source map for this chunk
 			if c.options.SourceMap != config.SourceMapNone && chunk.outputSourceMap.HasContent() {
 				outputSourceMap := chunk.outputSourceMap.Finalize(outputSourceMapShifts)
@@ -570,6 +710,24 @@ func (c *linkerContext) substituteFinalPaths(
 			shift.Before.AdvanceString(chunk.uniqueKey)
 			shift.After.AdvanceString(importPath)
 			shifts = append(shifts, shift)
+
+		case outputPieceChunkIDIndex:
+			chunk := chunks[piece.index]
+
+			// Make sure to always use forward slashes, even on Windows
+			id := path.Clean(strings.ReplaceAll(chunk.finalRelPath, "\\", "/"))
+
+			j.AddString(id)
+			shift.Before.AdvanceString(chunk.uniqueKeyForID)
+			shift.After.AdvanceString(id)
+			shifts = append(shifts, shift)
+
+		case outputPieceWorkerIndex:
+			importPath := modifyPath(c.workerPaths[piece.index])
+			j.AddString(importPath)
+			shift.Before.AdvanceString(fmt.Sprintf("%sW%08d", c.uniqueKeyPrefix, piece.index))
+			shift.After.AdvanceString(importPath)
+			shifts = append(shifts, shift)
 		}
 	}
 
@@ -714,10 +872,28 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 		imports        map[js_ast.Ref]bool
 		exports        map[js_ast.Ref]bool
 		dynamicImports map[int]bool
+		chunkLoads     map[int]bool
 	}
 
 	chunkMetas := make([]chunkMeta, len(chunks))
 
+	// Small shared chunks may have been copied into several chunks (see
+	// "mergeSmallChunks"). Every chunk with a copy of such a file uses its own
+	// copy, so symbols declared in these files are never imported from another
+	// chunk.
+	isFileInChunk := make(map[uint32]bool)
+	isFileDuplicated := make(map[uint32]bool)
+	for _, chunk := range chunks {
+		if _, ok := chunk.chunkRepr.(*chunkReprJS); ok {
+			for sourceIndex := range chunk.filesWithPartsInChunk {
+				if isFileInChunk[sourceIndex] {
+					isFileDuplicated[sourceIndex] = true
+				}
+				isFileInChunk[sourceIndex] = true
+			}
+		}
+	}
+
 	// For each chunk, see what symbols it uses from other chunks. Do this in
 	// parallel because it's the most expensive part of this function.
 	waitGroup := sync.WaitGroup{}
@@ -748,6 +924,15 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 								record.Path.Text = chunks[otherChunkIndex].uniqueKey
 								record.SourceIndex = ast.Index32{}
 
+								// The chunk loader in the "iife" format refers to chunks by name
+								if record.IsChunkLoad && c.options.OutputFormat == config.FormatIIFE {
+									record.Path.Text = chunks[otherChunkIndex].uniqueKeyForID
+									if chunkMeta.chunkLoads == nil {
+										chunkMeta.chunkLoads = make(map[int]bool)
+									}
+									chunkMeta.chunkLoads[int(otherChunkIndex)] = true
+								}
+
 								// Track this cross-chunk dynamic import so we make sure to
 								// include its hash when we're calculating the hashes of all
 								// dependencies of this chunk.
@@ -766,7 +951,7 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 						// chunk. In that case this will overwrite the same value below which
 						// is fine.
 						for _, declared := range part.DeclaredSymbols {
-							if declared.IsTopLevel {
+							if declared.IsTopLevel && !isFileDuplicated[sourceIndex] {
 								c.graph.Symbols.Get(declared.Ref).ChunkIndex = ast.MakeIndex32(uint32(chunkIndex))
 							}
 						}
@@ -893,6 +1078,11 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 			}
 		}
 
+		// Chunks loaded by the chunk loader must add themselves to the registry
+		for otherChunkIndex := range chunkMeta.chunkLoads {
+			chunks[otherChunkIndex].chunkRepr.(*chunkReprJS).isLoadedDynamically = true
+		}
+
 		// Make sure we also track dynamic cross-chunk imports. These need to be
 		// tracked so we count them as dependencies of this chunk for the purpose
 		// of hash calculation.
@@ -942,6 +1132,58 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 				}}}
 			}
 
+		case config.FormatCommonJS, config.FormatIIFE:
+			r := renamer.ExportRenamer{}
+			var properties []js_ast.Property
+			for _, export := range c.sortedCrossChunkExportItems(chunkMetas[chunkIndex].exports) {
+				var alias string
+				if c.options.MinifyIdentifiers {
+					alias = r.NextMinifiedName()
+				} else {
+					alias = r.NextRenamedName(c.graph.Symbols.Get(export.Ref).OriginalName)
+				}
+
+				// "get a() { return a; }"
+				properties = append(properties, js_ast.Property{
+					Kind: js_ast.PropertyGet,
+					Key:  js_ast.Expr{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(alias)}},
+					ValueOrNil: js_ast.Expr{Data: &js_ast.EFunction{Fn: js_ast.Fn{Body: js_ast.FnBody{Stmts: []js_ast.Stmt{
+						{Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: export.Ref}}}},
+					}}}}},
+				})
+				chunkRepr.exportsToOtherChunks[export.Ref] = alias
+			}
+
+			// Other chunks access these exports as properties of a namespace object
+			if len(properties) > 0 {
+				chunkRepr.namespaceRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, js_ast.SymbolOther, "chunk")
+			}
+
+			// Entry points that are loaded dynamically register themselves in
+			// "generateEntryPointTailJS" instead
+			if len(properties) == 0 && (chunk.isEntryPoint || !chunkRepr.isLoadedDynamically) {
+				break
+			}
+			exportsObject := js_ast.Expr{Data: &js_ast.EObject{Properties: properties}}
+
+			if c.options.OutputFormat == config.FormatCommonJS {
+				// "module.exports = { get a() { return a; } };"
+				chunkRepr.crossChunkSuffixStmts = []js_ast.Stmt{js_ast.AssignStmt(
+					js_ast.Expr{Data: &js_ast.EDot{
+						Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.unboundModuleRef}},
+						Name:   "exports",
+					}},
+					exportsObject,
+				)}
+			} else {
+				// "esbuildChunks['chunk.js'] = { get a() { return a; } };"
+				chunkRepr.usesChunkRegistry = true
+				chunkRepr.crossChunkSuffixStmts = []js_ast.Stmt{js_ast.AssignStmt(
+					c.chunkRegistryEntry(chunk.uniqueKeyForID),
+					exportsObject,
+				)}
+			}
+
 		default:
 			panic("Internal error")
 		}
@@ -984,13 +1226,155 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 					}})
 				}
 
+			case config.FormatCommonJS:
+				importRecordIndex := uint32(len(chunk.crossChunkImports))
+				chunk.crossChunkImports = append(chunk.crossChunkImports, chunkImport{
+					importKind: ast.ImportRequire,
+					chunkIndex: crossChunkImport.chunkIndex,
+				})
+				require := js_ast.Expr{Data: &js_ast.ERequireString{ImportRecordIndex: importRecordIndex}}
+				if len(crossChunkImport.sortedImportItems) > 0 {
+					// "var chunk = require('./chunk.js');"
+					otherChunkRepr := chunks[crossChunkImport.chunkIndex].chunkRepr.(*chunkReprJS)
+					crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SLocal{
+						Decls: []js_ast.Decl{{
+							Binding:    js_ast.Binding{Data: &js_ast.BIdentifier{Ref: otherChunkRepr.namespaceRef}},
+							ValueOrNil: require,
+						}},
+					}})
+				} else {
+					// "require('./chunk.js');"
+					crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: require}})
+				}
+
+			case config.FormatIIFE:
+				// Chunks in the "iife" format are loaded in order by the user (or by
+				// the chunk loader), so importing a chunk is just a registry lookup
+				chunk.crossChunkImports = append(chunk.crossChunkImports, chunkImport{
+					importKind: ast.ImportStmt,
+					chunkIndex: crossChunkImport.chunkIndex,
+				})
+				if len(crossChunkImport.sortedImportItems) > 0 {
+					// "var chunk = esbuildChunks['chunk.js'];"
+					otherChunk := &chunks[crossChunkImport.chunkIndex]
+					chunkRepr.usesChunkRegistry = true
+					crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SLocal{
+						Decls: []js_ast.Decl{{
+							Binding:    js_ast.Binding{Data: &js_ast.BIdentifier{Ref: otherChunk.chunkRepr.(*chunkReprJS).namespaceRef}},
+							ValueOrNil: c.chunkRegistryEntry(otherChunk.uniqueKeyForID),
+						}},
+					}})
+				}
+
 			default:
 				panic("Internal error")
 			}
 		}
 
+		// "var esbuildChunks = globalThis.esbuildChunks || (globalThis.esbuildChunks = {});"
+		if chunkRepr.usesChunkRegistry || chunkRepr.isLoadedDynamically {
+			chunkRepr.usesChunkRegistry = true
+			globalRegistry := js_ast.Expr{Data: &js_ast.EDot{
+				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.unboundGlobalThisRef}},
+				Name:   "esbuildChunks",
+			}}
+			crossChunkPrefixStmts = append([]js_ast.Stmt{{Data: &js_ast.SLocal{
+				Decls: []js_ast.Decl{{
+					Binding: js_ast.Binding{Data: &js_ast.BIdentifier{Ref: c.chunkRegistryRef}},
+					ValueOrNil: js_ast.Expr{Data: &js_ast.EBinary{
+						Op:    js_ast.BinOpLogicalOr,
+						Left:  globalRegistry,
+						Right: js_ast.Assign(globalRegistry, js_ast.Expr{Data: &js_ast.EObject{}}),
+					}},
+				}},
+			}}}, crossChunkPrefixStmts...)
+		}
+
 		chunkRepr.crossChunkPrefixStmts = crossChunkPrefixStmts
 	}
+
+	// Chunks loaded dynamically in the "iife" format must be loaded after all of
+	// the chunks they import. Determine the order of chunks to load for each one.
+	if c.options.OutputFormat == config.FormatIIFE {
+		c.chunkLoadOrder = make(map[string][]string)
+		for chunkIndex, chunk := range chunks {
+			if chunkRepr, ok := chunk.chunkRepr.(*chunkReprJS); ok && chunkRepr.isLoadedDynamically {
+				var order []string
+				visited := make(map[uint32]bool)
+				var visit func(uint32)
+				visit = func(chunkIndex uint32) {
+					if visited[chunkIndex] {
+						return
+					}
+					visited[chunkIndex] = true
+					for _, chunkImport := range chunks[chunkIndex].crossChunkImports {
+						if chunkImport.importKind != ast.ImportDynamic {
+							visit(chunkImport.chunkIndex)
+						}
+					}
+					order = append(order, chunks[chunkIndex].uniqueKeyForID)
+				}
+				visit(uint32(chunkIndex))
+				c.chunkLoadOrder[chunk.uniqueKeyForID] = order
+			}
+		}
+	}
+
+	// Chunks loaded with "import()" load their stylesheets first. The stylesheet
+	// of a user-specified entry point already contains the stylesheets of the
+	// chunks it imports. Otherwise the stylesheets of the imported chunks must
+	// be loaded before the stylesheet of the chunk itself.
+	if c.loadCSSRef != js_ast.InvalidRef {
+		c.cssLoadOrder = make(map[string][]string)
+		for chunkIndex, chunk := range chunks {
+			if _, ok := chunk.chunkRepr.(*chunkReprJS); !ok || !chunk.isEntryPoint {
+				continue
+			}
+			var order []string
+			visited := make(map[uint32]bool)
+			var visit func(uint32)
+			visit = func(chunkIndex uint32) {
+				if visited[chunkIndex] {
+					return
+				}
+				visited[chunkIndex] = true
+				chunk := &chunks[chunkIndex]
+				if !chunk.isEntryPoint || !c.graph.Files[chunk.sourceIndex].IsUserSpecifiedEntryPoint() {
+					for _, chunkImport := range chunk.crossChunkImports {
+						if chunkImport.importKind != ast.ImportDynamic {
+							visit(chunkImport.chunkIndex)
+						}
+					}
+				}
+				if cssChunkIndex := chunk.chunkRepr.(*chunkReprJS).cssChunkIndex; cssChunkIndex.IsValid() {
+					cssChunk := &chunks[cssChunkIndex.GetIndex()]
+					if c.options.OutputFormat == config.FormatIIFE {
+						order = append(order, cssChunk.uniqueKeyForID)
+					} else {
+						order = append(order, cssChunk.uniqueKey)
+					}
+				}
+			}
+			visit(uint32(chunkIndex))
+			if len(order) > 0 {
+				if c.options.OutputFormat == config.FormatIIFE {
+					c.cssLoadOrder[chunk.uniqueKeyForID] = order
+				} else {
+					c.cssLoadOrder[chunk.uniqueKey] = order
+				}
+			}
+		}
+	}
+}
+
+// This returns "esbuildChunks['chunk.js']" for the chunk registry of the
+// "iife" format, where "chunk.js" is the path of the chunk relative to the
+// output directory.
+func (c *linkerContext) chunkRegistryEntry(uniqueKeyForID string) js_ast.Expr {
+	return js_ast.Expr{Data: &js_ast.EIndex{
+		Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.chunkRegistryRef}},
+		Index:  js_ast.Expr{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(uniqueKeyForID)}},
+	}}
 }
 
 type crossChunkImport struct {
@@ -1109,6 +1493,37 @@ func (c *linkerContext) scanImportsAndExports() {
 			file.InputFile.AdditionalFiles = additionalFiles
 
 		case *graph.JSRepr:
+			// Inline the URLs of assets referenced with "new URL(path, import.meta.url)"
+			// into the JavaScript file the same way as for CSS "url()" tokens
+			var additionalFiles []graph.OutputFile
+			for importRecordIndex := range repr.AST.ImportRecords {
+				record := &repr.AST.ImportRecords[importRecordIndex]
+				if record.Kind.IsWorker() && record.SourceIndex.IsValid() {
+					c.warnAboutMissingImportMetaURL(file, record, "")
+
+					// Workers have already been linked separately. Refer to the output
+					// file for the worker using a unique key that will be substituted
+					// with the final path of the worker later on.
+					record.Path.Text = fmt.Sprintf("%sW%08d", c.uniqueKeyPrefix, workerKey(record.SourceIndex.GetIndex(), record.Kind == ast.ImportModuleWorker))
+					record.Path.Namespace = ""
+					record.SourceIndex = ast.Index32{}
+				} else if record.Kind == ast.ImportNewURL && record.SourceIndex.IsValid() {
+					otherFile := &c.graph.Files[record.SourceIndex.GetIndex()]
+					if otherRepr, ok := otherFile.InputFile.Repr.(*graph.JSRepr); ok {
+						c.warnAboutMissingImportMetaURL(file, record, otherRepr.AST.URLForCSS)
+						record.Path.Text = otherRepr.AST.URLForCSS
+						record.Path.Namespace = ""
+						record.SourceIndex = ast.Index32{}
+
+						// Copy the additional files to the output directory
+						additionalFiles = append(additionalFiles, otherFile.InputFile.AdditionalFiles...)
+					}
+				}
+			}
+			if additionalFiles != nil {
+				file.InputFile.AdditionalFiles = append(additionalFiles, file.InputFile.AdditionalFiles...)
+			}
+
 			for importRecordIndex := range repr.AST.ImportRecords {
 				record := &repr.AST.ImportRecords[importRecordIndex]
 				if !record.SourceIndex.IsValid() {
@@ -1168,12 +1583,36 @@ func (c *linkerContext) scanImportsAndExports() {
 				}
 			}
 
+			// Hot module replacement needs every module to be a separate closure
+			// that can be evaluated again, and other modules must access its exports
+			// through its exports object so they see the exports of the new version
+			if c.options.HotModuleReplacement && sourceIndex != runtime.SourceIndex {
+				repr.Meta.Wrap = graph.WrapCJS
+				repr.AST.ExportsKind = js_ast.ExportsCommonJS
+			}
+
+			// CommonJS and IIFE output can't use top-level await, so modules that
+			// use it (or import a module that does) are moved into async closures
+			// that are awaited by the modules that import them
+			if repr.Meta.IsAsyncOrHasAsyncDependency && repr.Meta.Wrap == graph.WrapNone &&
+				(c.options.OutputFormat == config.FormatCommonJS || c.options.OutputFormat == config.FormatIIFE) {
+				repr.Meta.Wrap = graph.WrapESM
+			}
+
+			// The entry point of a shared package must be a closure that is only
+			// evaluated if this build's copy of the package is the one that's used
+			if file.InputFile.FederationShare != nil {
+				repr.Meta.Wrap = graph.WrapCJS
+				repr.AST.ExportsKind = js_ast.ExportsCommonJS
+			}
+
 			// If the output format doesn't have an implicit CommonJS wrapper, any file
 			// that uses CommonJS features will need to be wrapped, even though the
 			// resulting wrapper won't be invoked by other files. An exception is made
 			// for entry point files in CommonJS format (or when in pass-through mode).
 			if repr.AST.ExportsKind == js_ast.ExportsCommonJS && (!file.IsEntryPoint() ||
-				c.options.OutputFormat == config.FormatIIFE || c.options.OutputFormat == config.FormatESModule) {
+				c.options.OutputFormat == config.FormatIIFE || c.options.OutputFormat == config.FormatESModule ||
+				c.options.OutputFormat == config.FormatUMD || c.options.OutputFormat == config.FormatSystem) {
 				repr.Meta.Wrap = graph.WrapCJS
 			}
 		}
@@ -1346,6 +1785,7 @@ func (c *linkerContext) scanImportsAndExports() {
 	// parts that declare the export to all parts that use the import. Also
 	// generate wrapper parts for wrapped files.
 	c.timer.Begin("Step 6")
+	importsCSS := make(map[uint32]bool)
 	for _, sourceIndex := range c.graph.ReachableFiles {
 		file := &c.graph.Files[sourceIndex]
 		repr, ok := file.InputFile.Repr.(*graph.JSRepr)
@@ -1399,10 +1839,19 @@ func (c *linkerContext) scanImportsAndExports() {
 		for importRef, importData := range repr.Meta.ImportsToBind {
 			resolvedRepr := c.graph.Files[importData.SourceIndex].InputFile.Repr.(*graph.JSRepr)
 			partsDeclaringSymbol := resolvedRepr.TopLevelSymbolToParts(importData.Ref)
+			enum := c.graph.TSEnums[importData.Ref]
 
 			for _, partIndex := range repr.AST.NamedImports[importRef].LocalPartsWithUses {
 				part := &repr.AST.Parts[partIndex]
 
+				// Don't depend on an imported enum if all uses of it in this part are
+				// property accesses that will be inlined by the printer. This lets the
+				// enum be removed by tree shaking if it's no longer used anywhere.
+				if enum != nil && allUsesAreInlinedEnumValues(part, importRef, enum) {
+					delete(part.SymbolUses, importRef)
+					continue
+				}
+
 				// Depend on the file containing the imported symbol
 				for _, resolvedPartIndex := range partsDeclaringSymbol {
 					part.Dependencies = append(part.Dependencies, js_ast.Dependency{
@@ -1475,19 +1924,66 @@ func (c *linkerContext) scanImportsAndExports() {
 		for partIndex, part := range repr.AST.Parts {
 			toModuleUses := uint32(0)
 			runtimeRequireUses := uint32(0)
+			loadChunkUses := uint32(0)
+			loadCSSUses := uint32(0)
+			federationRequireUses := uint32(0)
+			federationImportUses := uint32(0)
 
 			// Imports of wrapped files must depend on the wrapper
 			for _, importRecordIndex := range part.ImportRecordIndices {
 				record := &repr.AST.ImportRecords[importRecordIndex]
 
+				// Asset and worker URLs have already been inlined and aren't imported at run-time
+				if record.Kind == ast.ImportNewURL || record.Kind.IsWorker() {
+					continue
+				}
+
 				// Don't follow external imports (this includes import() expressions)
 				if !record.SourceIndex.IsValid() || c.isExternalDynamicImport(record, sourceIndex) {
+					// An "import()" of another chunk loads the stylesheets for that
+					// chunk first if there are any
+					if record.SourceIndex.IsValid() && c.loadCSSRef != js_ast.InvalidRef &&
+						c.staticallyImportsCSS(record.SourceIndex.GetIndex(), importsCSS) {
+						loadCSSUses++
+					}
+
+					// This is an "import()" of another chunk. Output formats without
+					// native ES module support load chunks with "require()" or with the
+					// runtime chunk loader instead. Either way the result is converted to
+					// a module namespace object with the "__toModule" wrapper.
+					if record.SourceIndex.IsValid() && c.options.CodeSplitting && !c.options.OutputFormat.KeepES6ImportExportSyntax() {
+						record.IsChunkLoad = true
+						record.WrapWithToModule = true
+						toModuleUses++
+						if c.options.OutputFormat == config.FormatIIFE {
+							loadChunkUses++
+						}
+						continue
+					}
+
+					// Modules exposed by other builds are read from their containers
+					if c.options.Federation != nil && !record.SourceIndex.IsValid() {
+						if _, ok := c.options.Federation.RemoteForPath(record.Path.Text); ok {
+							if record.Kind == ast.ImportDynamic {
+								federationImportUses++
+							} else {
+								federationRequireUses++
+								if record.Kind != ast.ImportRequire {
+									record.WrapWithToModule = true
+									toModuleUses++
+								}
+							}
+							continue
+						}
+					}
+
 					// This is an external import. Check if it will be a "require()" call.
 					if record.Kind == ast.ImportRequire || !c.options.OutputFormat.KeepES6ImportExportSyntax() ||
 						(record.Kind == ast.ImportDynamic && c.options.UnsupportedJSFeatures.Has(compat.DynamicImport)) {
 						// We should use "__require" instead of "require" if we're not
-						// generating a CommonJS output file, since it won't exist otherwise
-						if config.ShouldCallRuntimeRequire(c.options.Mode, c.options.OutputFormat) {
+						// generating a CommonJS output file, since it won't exist otherwise.
+						// This isn't needed for packages read from a global variable.
+						if config.ShouldCallRuntimeRequire(c.options.Mode, c.options.OutputFormat) && c.externalGlobals[record.Path.Text] == nil {
 							record.CallRuntimeRequire = true
 							runtimeRequireUses++
 						}
@@ -1543,6 +2039,16 @@ func (c *linkerContext) scanImportsAndExports() {
 			// code for node, then substitute a "__require" wrapper for "require".
 			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, uint32(partIndex), "__require", runtimeRequireUses)
 
+			// Chunks loaded with "import()" in the "iife" format need "__loadChunk"
+			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, uint32(partIndex), "__loadChunk", loadChunkUses)
+
+			// Chunks loaded with "import()" that have stylesheets need "__loadCSS"
+			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, uint32(partIndex), "__loadCSS", loadCSSUses)
+
+			// Modules of other builds are read with the module federation runtime
+			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, uint32(partIndex), "__federationRequire", federationRequireUses)
+			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, uint32(partIndex), "__federationImport", federationImportUses)
+
 			// If there's an ES6 export star statement of a non-ES6 module, then we're
 			// going to need the "__reExport" symbol from the runtime
 			reExportUses := uint32(0)
@@ -1579,6 +2085,55 @@ func (c *linkerContext) scanImportsAndExports() {
 	c.timer.End("Step 6")
 }
 
+// Returns true if every use of the imported symbol in this part is a property
+// access on an enum member whose value will be inlined by the printer
+func allUsesAreInlinedEnumValues(part *js_ast.Part, ref js_ast.Ref, enum map[string]js_ast.TSEnumValue) bool {
+	properties, ok := part.ImportSymbolPropertyUses[ref]
+	if !ok {
+		return false
+	}
+	count := uint32(0)
+	for name, use := range properties {
+		if _, ok := enum[name]; !ok {
+			return false
+		}
+		count += use.CountEstimate
+	}
+	return count == part.SymbolUses[ref].CountEstimate
+}
+
+// Returns true if this file or any file it imports without using "import()"
+// imports a CSS file. The results are memoized in "cache".
+func (c *linkerContext) staticallyImportsCSS(sourceIndex uint32, cache map[uint32]bool) bool {
+	if result, ok := cache[sourceIndex]; ok {
+		return result
+	}
+	visited := make(map[uint32]bool)
+	var visit func(uint32) bool
+	visit = func(sourceIndex uint32) bool {
+		if visited[sourceIndex] {
+			return false
+		}
+		visited[sourceIndex] = true
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			return false
+		}
+		if repr.CSSSourceIndex.IsValid() {
+			return true
+		}
+		for _, record := range repr.AST.ImportRecords {
+			if record.SourceIndex.IsValid() && !c.isExternalDynamicImport(&record, sourceIndex) && visit(record.SourceIndex.GetIndex()) {
+				return true
+			}
+		}
+		return false
+	}
+	result := visit(sourceIndex)
+	cache[sourceIndex] = result
+	return result
+}
+
 func (c *linkerContext) generateCodeForLazyExport(sourceIndex uint32) {
 	file := &c.graph.Files[sourceIndex]
 	repr := file.InputFile.Repr.(*graph.JSRepr)
@@ -1847,7 +2402,8 @@ func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
 }
 
 func (c *linkerContext) createWrapperForFile(sourceIndex uint32) {
-	repr := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+	file := &c.graph.Files[sourceIndex]
+	repr := file.InputFile.Repr.(*graph.JSRepr)
 
 	switch repr.Meta.Wrap {
 	// If this is a CommonJS file, we're going to need to generate a wrapper
@@ -1876,6 +2432,18 @@ func (c *linkerContext) createWrapperForFile(sourceIndex uint32) {
 				PartIndex:   partIndex,
 			}
 		}
+
+		// With hot module replacement and for shared packages, ESM files are also
+		// wrapped this way and other files access their exports dynamically
+		// through the exports object. So the wrapper must always populate the
+		// exports object.
+		if c.options.HotModuleReplacement || file.InputFile.FederationShare != nil {
+			dependencies = append(dependencies, js_ast.Dependency{
+				SourceIndex: sourceIndex,
+				PartIndex:   js_ast.NSExportPartIndex,
+			})
+		}
+
 		partIndex := c.graph.AddPartToFile(sourceIndex, js_ast.Part{
 			SymbolUses: map[js_ast.Ref]js_ast.SymbolUse{
 				repr.AST.WrapperRef: {CountEstimate: 1},
@@ -1889,6 +2457,9 @@ func (c *linkerContext) createWrapperForFile(sourceIndex uint32) {
 		})
 		repr.Meta.WrapperPartIndex = ast.MakeIndex32(partIndex)
 		c.graph.GenerateSymbolImportAndUse(sourceIndex, partIndex, c.cjsRuntimeRef, 1, runtime.SourceIndex)
+		if file.InputFile.FederationShare != nil {
+			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, partIndex, "__federationShare", 1)
+		}
 
 	// If this is a lazily-initialized ESM file, we're going to need to
 	// generate a wrapper for the ESM closure. That will end up looking
@@ -2155,6 +2726,10 @@ loop:
 				symbol.ImportItemStatus = js_ast.ImportItemMissing
 				c.log.AddRangeWarning(trackerFile.LineColumnTracker(), r, fmt.Sprintf(
 					"Import %q will always be undefined because there is no matching export", namedImport.Alias))
+			} else if namedImport.IsOnlyUsedForTSMetadata {
+				// This was probably a TypeScript type, so the metadata for it should
+				// just become "Object" at run-time instead of failing to bundle
+				symbol.ImportItemStatus = js_ast.ImportItemMissing
 			} else {
 				c.log.AddRangeError(trackerFile.LineColumnTracker(), r, fmt.Sprintf("No matching export in %q for import %q",
 					c.graph.Files[nextTracker.sourceIndex].InputFile.Source.PrettyPath, namedImport.Alias))
@@ -2724,8 +3299,8 @@ func (c *linkerContext) findImportedCSSFilesInJSOrder(entryPoint uint32) (order
 			// Ignore dead code that has been removed from the bundle. Any code
 			// that's reachable from the entry point, even through lazy dynamic
 			// imports, could end up being activated by the bundle and needs its
-			// CSS to be included. This may change if/when code splitting is
-			// supported for CSS.
+			// CSS to be included. The exception is when code splitting is active,
+			// since then each dynamic import loads its own CSS.
 			if !part.IsLive {
 				continue
 			}
@@ -2737,7 +3312,7 @@ func (c *linkerContext) findImportedCSSFilesInJSOrder(entryPoint uint32) (order
 			// concept. But we don't want to manipulate <style> tags at run-time so
 			// this is the only way to do it.
 			for _, importRecordIndex := range part.ImportRecordIndices {
-				if record := &repr.AST.ImportRecords[importRecordIndex]; record.SourceIndex.IsValid() {
+				if record := &repr.AST.ImportRecords[importRecordIndex]; record.SourceIndex.IsValid() && !c.isExternalDynamicImport(record, sourceIndex) {
 					visit(record.SourceIndex.GetIndex(), ast.MakeIndex32(sourceIndex))
 				}
 			}
@@ -2883,6 +3458,12 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 			// discovered in JS source order, where JS source order is arbitrary but
 			// consistent for dynamic imports. Then we run the CSS import order
 			// algorithm to determine the final CSS file order for the chunk.
+			//
+			// With code splitting, this is done later instead once the files in
+			// each chunk are known.
+			if c.options.CodeSplitting {
+				break
+			}
 			if cssSourceIndices := c.findImportedCSSFilesInJSOrder(entryPoint.SourceIndex); len(cssSourceIndices) > 0 {
 				externalOrder, internalOrder := c.findImportedFilesInCSSOrder(cssSourceIndices)
 				cssFilesWithPartsInChunk := make(map[uint32]bool)
@@ -2916,9 +3497,28 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 	}
 
 	// Figure out which JS files are in which chunk
+	manualChunkForFile := c.assignFilesToManualChunks()
+	manualChunks := make(map[string]chunkInfo)
 	for _, sourceIndex := range c.graph.ReachableFiles {
 		if file := &c.graph.Files[sourceIndex]; file.IsLive {
 			if _, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
+				// Files that are assigned to a manual chunk go in that chunk regardless
+				// of which entry points they are reachable from
+				if name, ok := manualChunkForFile[sourceIndex]; ok {
+					chunk, ok := manualChunks[name]
+					if !ok {
+						chunk.entryBits = file.EntryBits.Clone()
+						chunk.manualChunkName = name
+						chunk.filesWithPartsInChunk = make(map[uint32]bool)
+						chunk.chunkRepr = &chunkReprJS{}
+						manualChunks[name] = chunk
+					} else {
+						chunk.entryBits.Union(file.EntryBits)
+					}
+					chunk.filesWithPartsInChunk[uint32(sourceIndex)] = true
+					continue
+				}
+
 				key := file.EntryBits.String()
 				chunk, ok := jsChunks[key]
 				if !ok {
@@ -2932,10 +3532,22 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 		}
 	}
 
+	// Manual chunks can still end up importing each other in a cycle. That's
+	// the user's configuration, so report it instead of generating bad code.
+	if len(manualChunks) > 0 && c.reportCyclicManualChunks(jsChunks, manualChunks) {
+		return nil
+	}
+
+	// Optionally merge shared chunks that are too small and split shared chunks
+	// that are too big
+	if c.options.MinChunkSize > 0 || c.options.MaxChunkSize > 0 {
+		c.applyChunkSizeLimits(jsChunks, manualChunks)
+	}
+
 	// Sort the chunks for determinism. This matters because we use chunk indices
 	// as sorting keys in a few places.
-	sortedChunks := make([]chunkInfo, 0, len(jsChunks)+len(cssChunks))
-	sortedKeys := make([]string, 0, len(jsChunks)+len(cssChunks))
+	sortedChunks := make([]chunkInfo, 0, len(jsChunks)+len(manualChunks)+len(cssChunks))
+	sortedKeys := make([]string, 0, len(jsChunks)+len(manualChunks)+len(cssChunks))
 	for key := range jsChunks {
 		sortedKeys = append(sortedKeys, key)
 	}
@@ -2944,6 +3556,14 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 		sortedChunks = append(sortedChunks, jsChunks[key])
 	}
 	sortedKeys = sortedKeys[:0]
+	for name := range manualChunks {
+		sortedKeys = append(sortedKeys, name)
+	}
+	sort.Strings(sortedKeys)
+	for _, name := range sortedKeys {
+		sortedChunks = append(sortedChunks, manualChunks[name])
+	}
+	sortedKeys = sortedKeys[:0]
 	for key := range cssChunks {
 		sortedKeys = append(sortedKeys, key)
 	}
@@ -2979,19 +3599,67 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 		}
 	}
 
-	// Assign general information to each chunk
-	for chunkIndex := range sortedChunks {
-		chunk := &sortedChunks[chunkIndex]
-
-		// Assign a unique key to each chunk. This key encodes the index directly so
-		// we can easily recover it later without needing to look it up in a map. The
-		// last 8 numbers of the key are the chunk index.
-		chunk.uniqueKey = fmt.Sprintf("%sC%08d", c.uniqueKeyPrefix, chunkIndex)
-
-		// Determine the standard file extension
-		var stdExt string
-		switch chunk.chunkRepr.(type) {
-		case *chunkReprJS:
+	// With code splitting, the CSS imported by the files in each JS chunk goes
+	// in a CSS chunk of its own that is loaded along with the JS chunk. The CSS
+	// chunk for a user-specified entry point is the exception. It contains all
+	// CSS that the entry point imports without "import()" so that it can be
+	// loaded with a single "<link>" tag.
+	if c.options.CodeSplitting {
+		jsChunkCount := len(sortedChunks)
+		for chunkIndex := 0; chunkIndex < jsChunkCount; chunkIndex++ {
+			chunk := sortedChunks[chunkIndex]
+			chunkRepr, ok := chunk.chunkRepr.(*chunkReprJS)
+			if !ok {
+				continue
+			}
+			var cssSourceIndices []uint32
+			if chunk.isEntryPoint && c.graph.Files[chunk.sourceIndex].IsUserSpecifiedEntryPoint() {
+				cssSourceIndices = c.findImportedCSSFilesInJSOrder(chunk.sourceIndex)
+			} else {
+				for _, sourceIndex := range chunkRepr.filesInChunkInOrder {
+					if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr); ok && repr.CSSSourceIndex.IsValid() {
+						cssSourceIndices = append(cssSourceIndices, repr.CSSSourceIndex.GetIndex())
+					}
+				}
+			}
+			if len(cssSourceIndices) == 0 {
+				continue
+			}
+			externalOrder, internalOrder := c.findImportedFilesInCSSOrder(cssSourceIndices)
+			cssFilesWithPartsInChunk := make(map[uint32]bool)
+			for _, sourceIndex := range internalOrder {
+				cssFilesWithPartsInChunk[uint32(sourceIndex)] = true
+			}
+			chunkRepr.cssChunkIndex = ast.MakeIndex32(uint32(len(sortedChunks)))
+			sortedChunks = append(sortedChunks, chunkInfo{
+				entryBits:             chunk.entryBits,
+				isEntryPoint:          chunk.isEntryPoint,
+				sourceIndex:           chunk.sourceIndex,
+				entryPointBit:         chunk.entryPointBit,
+				manualChunkName:       chunk.manualChunkName,
+				filesWithPartsInChunk: cssFilesWithPartsInChunk,
+				chunkRepr: &chunkReprCSS{
+					externalImportsInOrder: externalOrder,
+					filesInChunkInOrder:    internalOrder,
+				},
+			})
+		}
+	}
+
+	// Assign general information to each chunk
+	for chunkIndex := range sortedChunks {
+		chunk := &sortedChunks[chunkIndex]
+
+		// Assign a unique key to each chunk. This key encodes the index directly so
+		// we can easily recover it later without needing to look it up in a map. The
+		// last 8 numbers of the key are the chunk index.
+		chunk.uniqueKey = fmt.Sprintf("%sC%08d", c.uniqueKeyPrefix, chunkIndex)
+		chunk.uniqueKeyForID = fmt.Sprintf("%sI%08d", c.uniqueKeyPrefix, chunkIndex)
+
+		// Determine the standard file extension
+		var stdExt string
+		switch chunk.chunkRepr.(type) {
+		case *chunkReprJS:
 			stdExt = c.options.OutputExtensionJS
 		case *chunkReprCSS:
 			stdExt = c.options.OutputExtensionCSS
@@ -3038,6 +3706,9 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 		} else {
 			dir = "/"
 			base = "chunk"
+			if chunk.manualChunkName != "" {
+				base = chunk.manualChunkName
+			}
 			ext = stdExt
 			template = c.options.ChunkPathTemplate
 		}
@@ -3053,6 +3724,571 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 	return sortedChunks
 }
 
+// Files matching a manual chunk pattern go in that chunk. But putting only
+// those files in the chunk can create a cycle in the chunk import graph: if a
+// file in the manual chunk imports a file in an automatic chunk, that chunk
+// may import the manual chunk back (e.g. "a.js" imports "lib/u.js" which
+// imports "helper.js", and "helper.js" ends up in the chunk for "a.js").
+//
+// So the statically-imported dependencies of files in a manual chunk are
+// pulled into that chunk too, as long as every entry point that can reach the
+// dependency can also reach the manual chunk. Moving those files doesn't
+// cause any entry point to load more code than before. The remaining automatic
+// chunks imported by a manual chunk are reachable from some entry point that
+// can't reach the manual chunk, so they can't import the manual chunk back.
+func (c *linkerContext) assignFilesToManualChunks() map[uint32]string {
+	if len(c.options.ManualChunks) == 0 {
+		return nil
+	}
+
+	isJSFileInChunk := func(sourceIndex uint32) bool {
+		file := &c.graph.Files[sourceIndex]
+		_, ok := file.InputFile.Repr.(*graph.JSRepr)
+		return ok && file.IsLive
+	}
+
+	// Assign the files that match a pattern first
+	manualChunkForFile := make(map[uint32]string)
+	entryBitsForChunk := make(map[string]helpers.BitSet)
+	var names []string
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		if !isJSFileInChunk(sourceIndex) {
+			continue
+		}
+		if name, ok := c.manualChunkNameForFile(sourceIndex); ok {
+			manualChunkForFile[sourceIndex] = name
+			entryBits := c.graph.Files[sourceIndex].EntryBits
+			if chunkBits, ok := entryBitsForChunk[name]; ok {
+				chunkBits.Union(entryBits)
+			} else {
+				entryBitsForChunk[name] = entryBits.Clone()
+				names = append(names, name)
+			}
+		}
+	}
+
+	// Then pull in their dependencies. Manual chunks are visited in name order
+	// for determinism when a dependency is shared by more than one of them.
+	sort.Strings(names)
+	for _, name := range names {
+		chunkBits := entryBitsForChunk[name]
+		var queue []uint32
+		for _, sourceIndex := range c.graph.ReachableFiles {
+			if manualChunkForFile[sourceIndex] == name {
+				queue = append(queue, sourceIndex)
+			}
+		}
+		for len(queue) > 0 {
+			sourceIndex := queue[0]
+			queue = queue[1:]
+			for _, otherSourceIndex := range c.staticallyImportedFiles(sourceIndex) {
+				if _, ok := manualChunkForFile[otherSourceIndex]; ok || otherSourceIndex == runtime.SourceIndex ||
+					!isJSFileInChunk(otherSourceIndex) || c.graph.Files[otherSourceIndex].IsEntryPoint() ||
+					c.graph.Files[otherSourceIndex].EntryBits.CountNotIn(chunkBits) > 0 {
+					continue
+				}
+				manualChunkForFile[otherSourceIndex] = name
+				queue = append(queue, otherSourceIndex)
+			}
+		}
+	}
+
+	return manualChunkForFile
+}
+
+// Pulling dependencies into manual chunks can't prevent all cycles. For
+// example, two manual chunks can contain files that import each other, or a
+// file in a manual chunk can import an entry point that imports it back. This
+// reports the first cycle found as an error and returns true if there was one.
+func (c *linkerContext) reportCyclicManualChunks(jsChunks map[string]chunkInfo, manualChunks map[string]chunkInfo) bool {
+	// Automatic and manual chunks share this key space. Manual chunks use a
+	// prefix that can't collide with the string form of a bit set.
+	allChunks := make(map[string]chunkInfo, len(jsChunks)+len(manualChunks))
+	for key, chunk := range jsChunks {
+		allChunks[key] = chunk
+	}
+	for name, chunk := range manualChunks {
+		allChunks["manual:"+name] = chunk
+	}
+	chunkForFile := make(map[uint32]string)
+	for key, chunk := range allChunks {
+		for sourceIndex := range chunk.filesWithPartsInChunk {
+			chunkForFile[sourceIndex] = key
+		}
+	}
+
+	// Remember the first import between each pair of chunks so the error can
+	// say which files are responsible
+	type chunkImport struct {
+		importer uint32
+		imported uint32
+	}
+	chunkImports := make(map[string]map[string]chunkImport)
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		key, ok := chunkForFile[sourceIndex]
+		if !ok {
+			continue
+		}
+		for _, otherSourceIndex := range c.staticallyImportedFiles(sourceIndex) {
+			if otherKey, ok := chunkForFile[otherSourceIndex]; ok && otherKey != key {
+				imports := chunkImports[key]
+				if imports == nil {
+					imports = make(map[string]chunkImport)
+					chunkImports[key] = imports
+				}
+				if _, ok := imports[otherKey]; !ok {
+					imports[otherKey] = chunkImport{importer: sourceIndex, imported: otherSourceIndex}
+				}
+			}
+		}
+	}
+
+	// Search for a cycle. Automatic chunks can't form a cycle on their own, so
+	// every cycle involves a manual chunk and it's enough to start from those.
+	names := make([]string, 0, len(manualChunks))
+	for name := range manualChunks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var cycle []string
+	visited := make(map[string]bool)
+	var path []string
+	var visit func(string) bool
+	visit = func(key string) bool {
+		for i, other := range path {
+			if other == key {
+				cycle = append(cycle, path[i:]...)
+				return true
+			}
+		}
+		if visited[key] {
+			return false
+		}
+		visited[key] = true
+		path = append(path, key)
+		otherKeys := make([]string, 0, len(chunkImports[key]))
+		for otherKey := range chunkImports[key] {
+			otherKeys = append(otherKeys, otherKey)
+		}
+		sort.Strings(otherKeys)
+		for _, otherKey := range otherKeys {
+			if visit(otherKey) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	for _, name := range names {
+		if visit("manual:" + name) {
+			break
+		}
+	}
+	if cycle == nil {
+		return false
+	}
+
+	// Start the cycle at a manual chunk
+	for i, key := range cycle {
+		if strings.HasPrefix(key, "manual:") {
+			cycle = append(cycle[i:], cycle[:i]...)
+			break
+		}
+	}
+	describe := func(key string) string {
+		if name := strings.TrimPrefix(key, "manual:"); name != key {
+			return fmt.Sprintf("manual chunk %q", name)
+		}
+		if chunk := allChunks[key]; chunk.isEntryPoint {
+			return fmt.Sprintf("the chunk for entry point %q", c.graph.Files[chunk.sourceIndex].InputFile.Source.PrettyPath)
+		}
+		return "a shared chunk"
+	}
+	notes := make([]logger.MsgData, len(cycle))
+	for i, key := range cycle {
+		otherKey := cycle[(i+1)%len(cycle)]
+		imported := chunkImports[key][otherKey]
+		notes[i] = logger.MsgData{Text: fmt.Sprintf("The file %q in %s imports the file %q in %s",
+			c.graph.Files[imported.importer].InputFile.Source.PrettyPath, describe(key),
+			c.graph.Files[imported.imported].InputFile.Source.PrettyPath, describe(otherKey))}
+	}
+	c.log.AddErrorWithNotes(nil, logger.Loc{}, fmt.Sprintf("Cannot generate %s because it would be part of a circular import between chunks",
+		describe(cycle[0])), notes)
+	return true
+}
+
+func (c *linkerContext) manualChunkNameForFile(sourceIndex uint32) (string, bool) {
+	file := &c.graph.Files[sourceIndex]
+	if len(c.options.ManualChunks) == 0 || file.IsEntryPoint() {
+		return "", false
+	}
+	keyPath := file.InputFile.Source.KeyPath
+	if keyPath.Namespace != "file" {
+		return "", false
+	}
+
+	// Find the package containing this file, if any
+	packageName := ""
+	if index := strings.LastIndex(keyPath.Text, "/node_modules/"); index != -1 {
+		packageName = keyPath.Text[index+len("/node_modules/"):]
+		slash := strings.IndexByte(packageName, '/')
+		if strings.HasPrefix(packageName, "@") && slash != -1 {
+			if next := strings.IndexByte(packageName[slash+1:], '/'); next != -1 {
+				slash += next + 1
+			} else {
+				slash = -1
+			}
+		}
+		if slash == -1 {
+			// This is a file directly inside "node_modules", not a package
+			packageName = ""
+		} else {
+			packageName = packageName[:slash]
+		}
+	}
+
+	for _, pattern := range c.options.ManualChunks {
+		text := keyPath.Text
+		if pattern.IsPackage {
+			if packageName == "" {
+				continue
+			}
+			text = packageName
+		}
+		if len(text) >= len(pattern.Prefix)+len(pattern.Suffix) &&
+			strings.HasPrefix(text, pattern.Prefix) && strings.HasSuffix(text, pattern.Suffix) {
+			return pattern.ChunkName, true
+		}
+	}
+	return "", false
+}
+
+// Shared chunks are only generated based on which entry points can reach each
+// file, which can result in many tiny chunks or a few huge ones. This merges
+// shared chunks smaller than "MinChunkSize" into other shared chunks and
+// splits shared chunks larger than "MaxChunkSize" along file boundaries.
+// Entry point chunks and manual chunks are never changed. Chunk sizes are
+// estimated using the sizes of the input files.
+//
+// None of this is allowed to introduce a cycle in the chunk import graph (see
+// "enforceNoCyclicChunkImports"). The imports between chunks aren't known yet
+// but they follow the import statements between files, so cycles are detected
+// conservatively using the files' import records instead.
+func (c *linkerContext) applyChunkSizeLimits(jsChunks map[string]chunkInfo, manualChunks map[string]chunkInfo) {
+	if c.options.MinChunkSize > 0 {
+		c.mergeSmallChunks(jsChunks, manualChunks)
+	}
+	if c.options.MaxChunkSize > 0 {
+		c.splitLargeChunks(jsChunks)
+	}
+}
+
+func (c *linkerContext) estimatedChunkSize(chunk *chunkInfo) int {
+	size := 0
+	for sourceIndex := range chunk.filesWithPartsInChunk {
+		size += len(c.graph.Files[sourceIndex].InputFile.Source.Contents)
+	}
+	return size
+}
+
+// Returns the files that this file imports, not including "import()" expressions
+func (c *linkerContext) staticallyImportedFiles(sourceIndex uint32) (result []uint32) {
+	if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr); ok {
+		for _, record := range repr.AST.ImportRecords {
+			if record.SourceIndex.IsValid() && record.Kind != ast.ImportDynamic {
+				result = append(result, record.SourceIndex.GetIndex())
+			}
+		}
+	}
+	return
+}
+
+// Returns true if evaluating this chunk in an entry point that doesn't need it
+// is unobservable, in which case it can be merged into a chunk that is loaded
+// by more entry points
+func (c *linkerContext) isChunkSideEffectFree(chunk *chunkInfo) bool {
+	for sourceIndex := range chunk.filesWithPartsInChunk {
+		file := &c.graph.Files[sourceIndex]
+		if file.InputFile.SideEffects.Kind != graph.HasSideEffects {
+			continue
+		}
+		repr := file.InputFile.Repr.(*graph.JSRepr)
+		for _, part := range repr.AST.Parts {
+			if part.IsLive && !part.CanBeRemovedIfUnused {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *linkerContext) mergeSmallChunks(jsChunks map[string]chunkInfo, manualChunks map[string]chunkInfo) {
+	// Automatic and manual chunks share this key space. Manual chunks use a
+	// prefix that can't collide with the string form of a bit set.
+	allChunks := make(map[string]*chunkInfo, len(jsChunks)+len(manualChunks))
+	for key, chunk := range jsChunks {
+		chunk := chunk
+		allChunks[key] = &chunk
+	}
+	for name, chunk := range manualChunks {
+		chunk := chunk
+		allChunks["manual:"+name] = &chunk
+	}
+
+	// Remember which files are loaded using "import()"
+	dynamicallyImported := make(map[uint32]bool)
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr); ok {
+			for _, record := range repr.AST.ImportRecords {
+				if record.SourceIndex.IsValid() && record.Kind == ast.ImportDynamic {
+					dynamicallyImported[record.SourceIndex.GetIndex()] = true
+				}
+			}
+		}
+	}
+
+	for {
+		// Determine which chunk each file is in and which chunks each chunk imports
+		chunkForFile := make(map[uint32]string)
+		for key, chunk := range allChunks {
+			for sourceIndex := range chunk.filesWithPartsInChunk {
+				chunkForFile[sourceIndex] = key
+			}
+		}
+		chunkImports := make(map[string]map[string]bool)
+		importersOfFile := make(map[uint32][]uint32)
+		for key, chunk := range allChunks {
+			imports := make(map[string]bool)
+			for sourceIndex := range chunk.filesWithPartsInChunk {
+				for _, otherSourceIndex := range c.staticallyImportedFiles(sourceIndex) {
+					if otherKey, ok := chunkForFile[otherSourceIndex]; ok {
+						importersOfFile[otherSourceIndex] = append(importersOfFile[otherSourceIndex], sourceIndex)
+
+						// Files that were copied into this chunk are local to it
+						if otherKey != key && !chunk.filesWithPartsInChunk[otherSourceIndex] {
+							imports[otherKey] = true
+						}
+					}
+				}
+			}
+			chunkImports[key] = imports
+		}
+
+		// Returns true if chunk "to" can be reached from chunk "from"
+		reaches := func(from string, to string) bool {
+			visited := make(map[string]bool)
+			var visit func(string) bool
+			visit = func(key string) bool {
+				if key == to {
+					return true
+				}
+				if visited[key] {
+					return false
+				}
+				visited[key] = true
+				for otherKey := range chunkImports[key] {
+					if visit(otherKey) {
+						return true
+					}
+				}
+				return false
+			}
+			return visit(from)
+		}
+
+		// Copying a chunk into more entry points than before must not run any
+		// new side effects
+		sideEffectFree := make(map[string]bool, len(allChunks))
+		for key, chunk := range allChunks {
+			sideEffectFree[key] = c.isChunkSideEffectFree(chunk)
+		}
+
+		// A small chunk is merged into its importers by copying it into each
+		// chunk that imports it if all of them are entry point chunks. Merging it
+		// into another shared chunk instead would make entry points load code
+		// they never imported. Each entry point still only evaluates that code
+		// once, but the copies run separately when several entry points are
+		// loaded on the same page. So this is only done for chunks without side
+		// effects and never for entry points that are loaded using "import()",
+		// since those run in the same page as the code that imports them.
+		duplicateInto := func(key string) []string {
+			chunk := allChunks[key]
+			if !sideEffectFree[key] {
+				return nil
+			}
+			for i, entryPoint := range c.graph.EntryPoints() {
+				if chunk.entryBits.HasBit(uint(i)) && dynamicallyImported[entryPoint.SourceIndex] {
+					return nil
+				}
+			}
+
+			// Find all chunks that can use code from this chunk, which includes
+			// chunks that only import it indirectly through a re-export
+			importerSet := make(map[string]bool)
+			visited := make(map[uint32]bool)
+			var visit func(uint32)
+			visit = func(sourceIndex uint32) {
+				if visited[sourceIndex] {
+					return
+				}
+				visited[sourceIndex] = true
+				if otherKey := chunkForFile[sourceIndex]; otherKey != key {
+					importerSet[otherKey] = true
+				}
+				for _, importer := range importersOfFile[sourceIndex] {
+					visit(importer)
+				}
+			}
+			for sourceIndex := range chunk.filesWithPartsInChunk {
+				visit(sourceIndex)
+			}
+			importers := make([]string, 0, len(importerSet))
+			for otherKey := range importerSet {
+				if !allChunks[otherKey].isEntryPoint {
+					return nil
+				}
+				importers = append(importers, otherKey)
+			}
+			if len(importers) == 0 {
+				return nil
+			}
+			sort.Strings(importers)
+
+			// The importers will now import everything this chunk imports, which
+			// creates a cycle if any of those chunks can reach one of the importers
+			for _, otherKey := range importers {
+				for importedKey := range chunkImports[key] {
+					if reaches(importedKey, otherKey) {
+						return nil
+					}
+				}
+			}
+			return importers
+		}
+
+		// Only automatically-generated shared chunks without the runtime can be
+		// merged. Sort them by size for determinism.
+		type candidate struct {
+			key  string
+			size int
+		}
+		var candidates []candidate
+		for key, chunk := range jsChunks {
+			if !chunk.isEntryPoint && !chunk.filesWithPartsInChunk[runtime.SourceIndex] {
+				candidates = append(candidates, candidate{key: key, size: c.estimatedChunkSize(&chunk)})
+			}
+		}
+		sort.Slice(candidates, func(i int, j int) bool {
+			a, b := candidates[i], candidates[j]
+			return a.size < b.size || (a.size == b.size && a.key < b.key)
+		})
+
+		// Copy the smallest chunk that can be merged into its importers
+		merged := false
+		for _, small := range candidates {
+			if small.size >= c.options.MinChunkSize {
+				break
+			}
+			if importers := duplicateInto(small.key); importers != nil {
+				for _, key := range importers {
+					for sourceIndex := range allChunks[small.key].filesWithPartsInChunk {
+						allChunks[key].filesWithPartsInChunk[sourceIndex] = true
+					}
+				}
+				delete(jsChunks, small.key)
+				delete(allChunks, small.key)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			break
+		}
+	}
+}
+
+func (c *linkerContext) splitLargeChunks(jsChunks map[string]chunkInfo) {
+	sortedKeys := make([]string, 0, len(jsChunks))
+	for key := range jsChunks {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, key := range sortedKeys {
+		chunk := jsChunks[key]
+		if chunk.isEntryPoint || c.estimatedChunkSize(&chunk) <= c.options.MaxChunkSize {
+			continue
+		}
+
+		// Files are ordered with dependencies before dependents. The runtime is
+		// used by everything so it must come first.
+		files, _ := c.findImportedPartsInJSOrder(&chunk)
+		if chunk.filesWithPartsInChunk[runtime.SourceIndex] {
+			order := []uint32{runtime.SourceIndex}
+			for _, sourceIndex := range files {
+				if sourceIndex != runtime.SourceIndex {
+					order = append(order, sourceIndex)
+				}
+			}
+			files = order
+		}
+		orderInChunk := make(map[uint32]int, len(files))
+		for i, sourceIndex := range files {
+			orderInChunk[sourceIndex] = i
+		}
+
+		// Cut the files into pieces at positions where no file before the cut
+		// can reach a file after the cut, since the pieces would import each
+		// other otherwise. Files outside of this chunk are followed too.
+		visited := make(map[uint32]bool)
+		latestReachable := -1
+		var visit func(uint32)
+		visit = func(sourceIndex uint32) {
+			for _, otherSourceIndex := range c.staticallyImportedFiles(sourceIndex) {
+				if !visited[otherSourceIndex] {
+					visited[otherSourceIndex] = true
+					if i, ok := orderInChunk[otherSourceIndex]; ok && i > latestReachable {
+						latestReachable = i
+					}
+					visit(otherSourceIndex)
+				}
+			}
+		}
+		var pieces [][]uint32
+		var piece []uint32
+		pieceSize := 0
+		for i, sourceIndex := range files {
+			size := len(c.graph.Files[sourceIndex].InputFile.Source.Contents)
+			if len(piece) > 0 && pieceSize+size > c.options.MaxChunkSize && latestReachable < i {
+				pieces = append(pieces, piece)
+				piece = nil
+				pieceSize = 0
+			}
+			piece = append(piece, sourceIndex)
+			pieceSize += size
+			visit(sourceIndex)
+		}
+		pieces = append(pieces, piece)
+
+		// Each piece is loaded by the same entry points as the original chunk
+		for i, files := range pieces {
+			pieceChunk := chunkInfo{
+				entryBits:             chunk.entryBits,
+				filesWithPartsInChunk: make(map[uint32]bool, len(files)),
+				chunkRepr:             &chunkReprJS{},
+			}
+			for _, sourceIndex := range files {
+				pieceChunk.filesWithPartsInChunk[sourceIndex] = true
+			}
+			if i == 0 {
+				jsChunks[key] = pieceChunk
+			} else {
+				jsChunks[fmt.Sprintf("%s.%d", key, i)] = pieceChunk
+			}
+		}
+	}
+}
+
 type chunkOrder struct {
 	sourceIndex uint32
 	distance    uint32
@@ -3134,7 +4370,7 @@ func (c *linkerContext) findImportedPartsInJSOrder(chunk *chunkInfo) (js []uint3
 		file := &c.graph.Files[sourceIndex]
 
 		if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
-			isFileInThisChunk := chunk.entryBits.Equals(file.EntryBits)
+			isFileInThisChunk := chunk.filesWithPartsInChunk[sourceIndex]
 
 			// Wrapped files can't be split because they are all inside the wrapper
 			canFileBeSplit := repr.Meta.Wrap == graph.WrapNone
@@ -3650,7 +4886,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 			}
 
 			var cjsArgs []js_ast.Expr
-			if c.options.ProfilerNames {
+			if c.options.ProfilerNames || c.options.HotModuleReplacement {
 				// "__commonJS({ 'file.js'(exports, module) { ... } })"
 				cjsArgs = []js_ast.Expr{{Data: &js_ast.EObject{Properties: []js_ast.Property{{
 					IsMethod:   !c.options.UnsupportedJSFeatures.Has(compat.ObjectExtensions),
@@ -3669,6 +4905,20 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 				Args:   cjsArgs,
 			}}
 
+			// "__federationShare('name', '1.2.3', '^1.2.3', __commonJS(...))"
+			if share := file.InputFile.FederationShare; share != nil {
+				runtimeRepr := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr)
+				value = js_ast.Expr{Data: &js_ast.ECall{
+					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: runtimeRepr.AST.NamedExports["__federationShare"].Ref}},
+					Args: []js_ast.Expr{
+						{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(share.Name)}},
+						{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(share.Version)}},
+						{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(share.Range)}},
+						value,
+					},
+				}}
+			}
+
 			// "var require_foo = __commonJS(...);"
 			stmts = append(stmtList.outsideWrapperPrefix, js_ast.Stmt{Data: &js_ast.SLocal{
 				Decls: []js_ast.Decl{{
@@ -3765,9 +5015,9 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		lineOffsetTables = dataForSourceMaps[partRange.sourceIndex].lineOffsetTables
 	}
 
-	// Indent the file if everything is wrapped in an IIFE
+	// Indent the file if everything is wrapped in a function
 	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
+	if c.options.OutputFormat.IsWrappedInFunction() {
 		indent++
 	}
 
@@ -3786,6 +5036,20 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		InputSourceMap:               inputSourceMap,
 		LineOffsetTables:             lineOffsetTables,
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
+		IsPropertyAccessSymbol:       isImportFromOtherChunk(r),
+		LoadChunkRef:                 c.loadChunkRef,
+		ChunkLoadOrder:               c.chunkLoadOrder,
+		LoadCSSRef:                   c.loadCSSRef,
+		CSSLoadOrder:                 c.cssLoadOrder,
+		ExternalGlobals:              c.externalGlobals,
+		TSEnums:                      c.graph.TSEnums,
+		TSEnumMetadataKinds:          c.graph.TSEnumMetadataKinds,
+		Federation:                   c.options.Federation,
+		FederationRequireRef:         c.federationRequireRef,
+		FederationImportRef:          c.federationImportRef,
+	}
+	if c.options.PublicPath != "" {
+		printOptions.ChunkPublicPath = joinWithPublicPath(c.options.PublicPath, "")
 	}
 	tree := repr.AST
 	tree.Directive = "" // This is handled elsewhere
@@ -3798,11 +5062,66 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	waitGroup.Done()
 }
 
+// This returns "init_foo().then(() => exports)" for an entry point that uses
+// top-level await, or just "init_foo()" if the entry point has no exports
+// The URLs in "new URL(path, import.meta.url)" are relative to the output
+// file, which only works if "import.meta.url" exists at run-time. Otherwise
+// "import.meta" becomes an empty object and the URL constructor will throw
+// unless the URL is absolute (e.g. because of the public path).
+func (c *linkerContext) warnAboutMissingImportMetaURL(file *graph.LinkerFile, record *ast.ImportRecord, url string) {
+	if c.options.OutputFormat.KeepES6ImportExportSyntax() && !c.options.UnsupportedJSFeatures.Has(compat.ImportMeta) {
+		return
+	}
+	if hasURLScheme(url) || hasURLScheme(c.options.PublicPath) {
+		return
+	}
+	var where string
+	if c.options.UnsupportedJSFeatures.Has(compat.ImportMeta) {
+		where = "the configured target environment"
+	} else {
+		where = fmt.Sprintf("the %q output format", c.options.OutputFormat.String())
+	}
+	c.log.AddRangeWarningWithNotes(file.LineColumnTracker(), record.Range,
+		fmt.Sprintf("The URL for %q will be invalid at run-time because \"import.meta.url\" is not available in %s", record.Path.Text, where),
+		[]logger.MsgData{{Text: "You can set the public path to an absolute URL such as \"https://example.com/assets/\" " +
+			"so that the URL doesn't depend on \"import.meta.url\"."}})
+}
+
+// Returns true for "https://example.com/" and "data:text/plain,x" but not for
+// "./image.png" or "/assets/image.png"
+func hasURLScheme(url string) bool {
+	colon := strings.IndexByte(url, ':')
+	return colon > 0 && !strings.ContainsAny(url[:colon], "/?#")
+}
+
+func (c *linkerContext) asyncEntryPointPromise(repr *graph.JSRepr) js_ast.Expr {
+	value := js_ast.Expr{Data: &js_ast.ECall{
+		Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
+	}}
+	if repr.Meta.ForceIncludeExportsForEntryPoint {
+		var callback js_ast.Expr
+		body := js_ast.FnBody{Stmts: []js_ast.Stmt{{Data: &js_ast.SReturn{
+			ValueOrNil: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.ExportsRef}},
+		}}}}
+		if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+			callback = js_ast.Expr{Data: &js_ast.EFunction{Fn: js_ast.Fn{Body: body}}}
+		} else {
+			callback = js_ast.Expr{Data: &js_ast.EArrow{PreferExpr: true, Body: body}}
+		}
+		value = js_ast.Expr{Data: &js_ast.ECall{
+			Target: js_ast.Expr{Data: &js_ast.EDot{Target: value, Name: "then"}},
+			Args:   []js_ast.Expr{callback},
+		}}
+	}
+	return value
+}
+
 func (c *linkerContext) generateEntryPointTailJS(
 	r renamer.Renamer,
 	toModuleRef js_ast.Ref,
-	sourceIndex uint32,
+	chunk *chunkInfo,
 ) (result compileResultJS) {
+	sourceIndex := chunk.sourceIndex
 	file := &c.graph.Files[sourceIndex]
 	repr := file.InputFile.Repr.(*graph.JSRepr)
 	var stmts []js_ast.Stmt
@@ -3817,19 +5136,43 @@ func (c *linkerContext) generateEntryPointTailJS(
 			}}}})
 		}
 
-	case config.FormatIIFE:
+	case config.FormatIIFE, config.FormatUMD, config.FormatSystem:
+		// Entry points loaded by the chunk loader must add their exports to the
+		// chunk registry
+		var registryEntry js_ast.Expr
+		if chunk.chunkRepr.(*chunkReprJS).isLoadedDynamically {
+			registryEntry = c.chunkRegistryEntry(chunk.uniqueKeyForID)
+		}
+
+		// The UMD and SystemJS wrappers always use the returned exports
+		returnsExports := len(c.options.GlobalName) > 0 || c.options.OutputFormat != config.FormatIIFE
+
 		if repr.Meta.Wrap == graph.WrapCJS {
-			if len(c.options.GlobalName) > 0 {
+			value := js_ast.Expr{Data: &js_ast.ECall{
+				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
+			}}
+			if registryEntry.Data != nil {
+				// "esbuildChunks['entry.js'] = require_foo()"
+				value = js_ast.Assign(registryEntry, value)
+			}
+			if returnsExports {
 				// "return require_foo();"
-				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Data: &js_ast.ECall{
-					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
-				}}}})
+				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{ValueOrNil: value}})
 			} else {
 				// "require_foo();"
-				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: js_ast.Expr{Data: &js_ast.ECall{
-					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
-				}}}})
+				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: value}})
+			}
+		} else if repr.Meta.Wrap == graph.WrapESM && repr.Meta.IsAsyncOrHasAsyncDependency {
+			// Entry points with top-level await are driven by a promise, which is
+			// returned so that the caller can wait for initialization to finish
+			value := c.asyncEntryPointPromise(repr)
+			if registryEntry.Data != nil {
+				// "esbuildChunks['entry.js'] = init_foo().then(() => exports)"
+				value = js_ast.Assign(registryEntry, value)
 			}
+
+			// "return init_foo().then(() => exports);"
+			stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{ValueOrNil: value}})
 		} else {
 			if repr.Meta.Wrap == graph.WrapESM {
 				// "init_foo();"
@@ -3837,7 +5180,15 @@ func (c *linkerContext) generateEntryPointTailJS(
 					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
 				}}}})
 			}
-			if repr.Meta.ForceIncludeExportsForEntryPoint && len(c.options.GlobalName) > 0 {
+			if registryEntry.Data != nil {
+				// "esbuildChunks['entry.js'] = exports;"
+				exports := js_ast.Expr{Data: &js_ast.EObject{}}
+				if repr.Meta.ForceIncludeExportsForEntryPoint {
+					exports = js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.ExportsRef}}
+				}
+				stmts = append(stmts, js_ast.AssignStmt(registryEntry, exports))
+			}
+			if repr.Meta.ForceIncludeExportsForEntryPoint && returnsExports {
 				// "return exports;"
 				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{
 					ValueOrNil: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.ExportsRef}},
@@ -3857,6 +5208,17 @@ func (c *linkerContext) generateEntryPointTailJS(
 					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
 				}},
 			))
+		} else if repr.Meta.Wrap == graph.WrapESM && repr.Meta.IsAsyncOrHasAsyncDependency {
+			// Entry points with top-level await export a promise for their exports
+			// instead, since they aren't ready until the promise is resolved:
+			// "module.exports = init_foo().then(() => exports);"
+			stmts = append(stmts, js_ast.AssignStmt(
+				js_ast.Expr{Data: &js_ast.EDot{
+					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.unboundModuleRef}},
+					Name:   "exports",
+				}},
+				c.asyncEntryPointPromise(repr),
+			))
 		} else if repr.Meta.Wrap == graph.WrapESM {
 			// "init_foo();"
 			stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: js_ast.Expr{Data: &js_ast.ECall{
@@ -3868,8 +5230,10 @@ func (c *linkerContext) generateEntryPointTailJS(
 		// a form that node can understand them. This relies on the specific behavior
 		// of this parser, which the node project uses to detect named exports in
 		// CommonJS files: https://github.com/guybedford/cjs-module-lexer. Think of
-		// this code as an annotation for that parser.
-		if c.options.Platform == config.PlatformNode && len(repr.Meta.SortedAndFilteredExportAliases) > 0 {
+		// this code as an annotation for that parser. This is omitted when the
+		// exports are a promise because the names can't be imported directly.
+		if c.options.Platform == config.PlatformNode && len(repr.Meta.SortedAndFilteredExportAliases) > 0 &&
+			!(repr.Meta.Wrap == graph.WrapESM && repr.Meta.IsAsyncOrHasAsyncDependency) {
 			// Add a comment since otherwise people will surely wonder what this is.
 			// This annotation means you can do this and have it work:
 			//
@@ -4074,9 +5438,9 @@ func (c *linkerContext) generateEntryPointTailJS(
 	tree := repr.AST
 	tree.Parts = []js_ast.Part{{Stmts: stmts}}
 
-	// Indent the file if everything is wrapped in an IIFE
+	// Indent the file if everything is wrapped in a function
 	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
+	if c.options.OutputFormat.IsWrappedInFunction() {
 		indent++
 	}
 
@@ -4091,12 +5455,41 @@ func (c *linkerContext) generateEntryPointTailJS(
 		LegalComments:                c.options.LegalComments,
 		UnsupportedFeatures:          c.options.UnsupportedJSFeatures,
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
+		IsPropertyAccessSymbol:       isImportFromOtherChunk(r),
 	}
 	result.PrintResult = js_printer.Print(tree, c.graph.Symbols, r, printOptions)
 	return
 }
 
-func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []uint32, timer *helpers.Timer) renamer.Renamer {
+// This renames symbols imported from other chunks to property accesses off of
+// the namespace objects for those chunks (e.g. "chunk.foo")
+type crossChunkRenamer struct {
+	renamer.Renamer
+	symbols js_ast.SymbolMap
+	names   map[js_ast.Ref]string
+}
+
+func (r crossChunkRenamer) NameForSymbol(ref js_ast.Ref) string {
+	if name, ok := r.names[js_ast.FollowSymbols(r.symbols, ref)]; ok {
+		return name
+	}
+	return r.Renamer.NameForSymbol(ref)
+}
+
+// Calls to symbols imported from other chunks must not use the namespace
+// object for the other chunk as "this", so the printer needs to know which
+// symbols were renamed to property accesses
+func isImportFromOtherChunk(r renamer.Renamer) func(js_ast.Ref) bool {
+	if r, ok := r.(crossChunkRenamer); ok {
+		return func(ref js_ast.Ref) bool {
+			_, ok := r.names[js_ast.FollowSymbols(r.symbols, ref)]
+			return ok
+		}
+	}
+	return nil
+}
+
+func (c *linkerContext) renameSymbolsInChunk(chunks []chunkInfo, chunk *chunkInfo, filesInOrder []uint32, timer *helpers.Timer) renamer.Renamer {
 	if c.options.MinifyIdentifiers {
 		timer.Begin("Minify symbols")
 		defer timer.End("Minify symbols")
@@ -4122,13 +5515,29 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 
 	// Make sure imports get a chance to be renamed too
 	var sortedImportsFromOtherChunks stableRefArray
-	for _, imports := range chunk.chunkRepr.(*chunkReprJS).importsFromOtherChunks {
+	chunkRepr := chunk.chunkRepr.(*chunkReprJS)
+	for otherChunkIndex, imports := range chunkRepr.importsFromOtherChunks {
 		for _, item := range imports {
 			sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
 				StableSourceIndex: c.graph.StableSourceIndices[item.ref.SourceIndex],
 				Ref:               item.ref,
 			})
 		}
+
+		// Output formats without "import" statements also import a namespace
+		if len(imports) > 0 && !c.options.OutputFormat.KeepES6ImportExportSyntax() {
+			ref := chunks[otherChunkIndex].chunkRepr.(*chunkReprJS).namespaceRef
+			sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
+				StableSourceIndex: c.graph.StableSourceIndices[ref.SourceIndex],
+				Ref:               ref,
+			})
+		}
+	}
+	if chunkRepr.usesChunkRegistry {
+		sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
+			StableSourceIndex: c.graph.StableSourceIndices[c.chunkRegistryRef.SourceIndex],
+			Ref:               c.chunkRegistryRef,
+		})
 	}
 	sort.Sort(sortedImportsFromOtherChunks)
 
@@ -4346,7 +5755,20 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 	runtimeMembers := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr).AST.ModuleScope.Members
 	toModuleRef := js_ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__toModule"].Ref)
 	runtimeRequireRef := js_ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__require"].Ref)
-	r := c.renameSymbolsInChunk(chunk, chunkRepr.filesInChunkInOrder, timer)
+	r := c.renameSymbolsInChunk(chunks, chunk, chunkRepr.filesInChunkInOrder, timer)
+
+	// Output formats without "import" statements access symbols imported from
+	// other chunks as properties of the namespace objects for those chunks
+	if !c.options.OutputFormat.KeepES6ImportExportSyntax() && len(chunkRepr.importsFromOtherChunks) > 0 {
+		names := make(map[js_ast.Ref]string)
+		for otherChunkIndex, imports := range chunkRepr.importsFromOtherChunks {
+			otherChunkRepr := chunks[otherChunkIndex].chunkRepr.(*chunkReprJS)
+			for _, item := range imports {
+				names[item.ref] = r.NameForSymbol(otherChunkRepr.namespaceRef) + "." + otherChunkRepr.exportsToOtherChunks[item.ref]
+			}
+		}
+		r = crossChunkRenamer{Renamer: r, symbols: c.graph.Symbols, names: names}
+	}
 	dataForSourceMaps := c.dataForSourceMaps()
 
 	// Note: This contains placeholders instead of what the placeholders are
@@ -4388,9 +5810,9 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 	var crossChunkPrefix []byte
 	var crossChunkSuffix []byte
 	{
-		// Indent the file if everything is wrapped in an IIFE
+		// Indent the file if everything is wrapped in a function
 		indent := 0
-		if c.options.OutputFormat == config.FormatIIFE {
+		if c.options.OutputFormat.IsWrappedInFunction() {
 			indent++
 		}
 		printOptions := js_printer.Options{
@@ -4421,12 +5843,20 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 		entryPointTail = c.generateEntryPointTailJS(
 			r,
 			toModuleRef,
-			chunk.sourceIndex,
+			chunk,
 		)
 	}
 
 	waitGroup.Wait()
 	timer.End("Print JavaScript files")
+
+	// Hot module replacement updates are generated from the code for each file
+	if c.options.HotModuleReplacement && chunk.isEntryPoint {
+		timer.Begin("Generate hot module replacement update")
+		chunk.hmrUpdate = c.generateHMRUpdate(r, chunk, compileResults)
+		timer.End("Generate hot module replacement update")
+	}
+
 	timer.Begin("Join JavaScript files")
 
 	j := helpers.Joiner{}
@@ -4488,6 +5918,15 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 		newlineBeforeComment = false
 	}
 
+	// Optionally wrap with a UMD or SystemJS module factory
+	if c.options.OutputFormat == config.FormatUMD || c.options.OutputFormat == config.FormatSystem {
+		indent = "  "
+		text := c.generateModuleFactoryPrefix(c.externalImportPathsInChunk(chunkRepr))
+		prevOffset.AdvanceString(text)
+		j.AddString(text)
+		newlineBeforeComment = false
+	}
+
 	// Put the cross-chunk prefix inside the IIFE
 	if len(crossChunkPrefix) > 0 {
 		newlineBeforeComment = true
@@ -4553,6 +5992,8 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 		if chunk.isEntryPoint {
 			entryPoint := c.graph.Files[chunk.sourceIndex].InputFile.Source.PrettyPath
 			jMeta.AddString(fmt.Sprintf("],\n      \"entryPoint\": %s,\n      \"inputs\": {", js_printer.QuoteForJSON(entryPoint, c.options.ASCIIOnly)))
+		} else if chunk.manualChunkName != "" {
+			jMeta.AddString(fmt.Sprintf("],\n      \"manualChunk\": %s,\n      \"inputs\": {", js_printer.QuoteForJSON(chunk.manualChunkName, c.options.ASCIIOnly)))
 		} else {
 			jMeta.AddString("],\n      \"inputs\": {")
 		}
@@ -4664,6 +6105,11 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 		j.AddString("})();" + newline)
 	}
 
+	// Optionally wrap with a UMD or SystemJS module factory
+	if c.options.OutputFormat == config.FormatUMD || c.options.OutputFormat == config.FormatSystem {
+		j.AddString("});" + newline)
+	}
+
 	// Make sure the file ends with a newline
 	j.EnsureNewlineAtEnd()
 
@@ -4737,6 +6183,121 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 	chunkWaitGroup.Done()
 }
 
+// A hot module replacement update is a script that registers the new versions
+// of the modules that changed since the previous build with the global module
+// registry. The registry then evaluates them again. It looks like this:
+//
+//   (() => {
+//     ...runtime code...
+//     var require_b = () => globalThis.esbuildHMR.require("b.js");
+//     var require_a = __hmrModule({
+//       "a.js"(exports, module) {
+//         var import_b = __toModule(require_b());
+//         ...
+//       }
+//     });
+//   })();
+//
+// Modules that didn't change are read from the registry instead of being
+// included again. This returns nil if there is nothing to update.
+func (c *linkerContext) generateHMRUpdate(r renamer.Renamer, chunk *chunkInfo, compileResults []compileResultJS) []byte {
+	// Hash the code generated for each module. This also catches modules that
+	// didn't change themselves but whose generated code did (e.g. because an
+	// imported symbol was renamed).
+	hashers := make(map[uint32]*xxhash.Digest)
+	for _, compileResult := range compileResults {
+		if compileResult.sourceIndex != runtime.SourceIndex {
+			hash := hashers[compileResult.sourceIndex]
+			if hash == nil {
+				hash = xxhash.New()
+				hashers[compileResult.sourceIndex] = hash
+			}
+			hash.Write(compileResult.JS)
+		}
+	}
+	hashes := make(map[string]uint64, len(hashers))
+	for sourceIndex, hash := range hashers {
+		hashes[c.graph.Files[sourceIndex].InputFile.Source.PrettyPath] = hash.Sum64()
+	}
+	entryPoint := c.graph.Files[chunk.sourceIndex].InputFile.Source.KeyPath.Text
+	changed, ok := c.hmrCache.Update(entryPoint, hashes)
+	if !ok || len(changed) == 0 {
+		return nil
+	}
+
+	// Match the indentation of the modules, which are only indented when the
+	// output format wraps everything in a function
+	indent := ""
+	space := " "
+	newline := "\n"
+	if c.options.OutputFormat.IsWrappedInFunction() {
+		indent = "  "
+	}
+	if c.options.RemoveWhitespace {
+		indent = ""
+		space = ""
+		newline = ""
+	}
+	isChanged := func(sourceIndex uint32) bool {
+		return sourceIndex != runtime.SourceIndex && changed[c.graph.Files[sourceIndex].InputFile.Source.PrettyPath]
+	}
+
+	j := helpers.Joiner{}
+	if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+		j.AddString("(function()" + space + "{" + newline)
+	} else {
+		j.AddString("(()" + space + "=>" + space + "{" + newline)
+	}
+
+	// The update brings its own copy of the runtime code
+	for _, compileResult := range compileResults {
+		if compileResult.sourceIndex == runtime.SourceIndex {
+			j.AddBytes(compileResult.JS)
+		}
+	}
+
+	// Read modules that didn't change from the registry
+	isStubbed := make(map[uint32]bool)
+	for _, compileResult := range compileResults {
+		if !isChanged(compileResult.sourceIndex) {
+			continue
+		}
+		repr := c.graph.Files[compileResult.sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		for _, record := range repr.AST.ImportRecords {
+			if !record.SourceIndex.IsValid() {
+				continue
+			}
+			otherSourceIndex := record.SourceIndex.GetIndex()
+			otherFile := &c.graph.Files[otherSourceIndex]
+			otherRepr, ok := otherFile.InputFile.Repr.(*graph.JSRepr)
+			if !ok || otherRepr.Meta.Wrap != graph.WrapCJS || isStubbed[otherSourceIndex] || isChanged(otherSourceIndex) {
+				continue
+			}
+			isStubbed[otherSourceIndex] = true
+			name := r.NameForSymbol(otherRepr.AST.WrapperRef)
+			id := js_printer.QuoteForJSON(otherFile.InputFile.Source.PrettyPath, c.options.ASCIIOnly)
+			if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+				j.AddString(fmt.Sprintf("%svar %s%s=%sfunction()%s{%sreturn globalThis.esbuildHMR.require(%s);%s};%s",
+					indent, name, space, space, space, space, id, space, newline))
+			} else {
+				j.AddString(fmt.Sprintf("%svar %s%s=%s()%s=>%sglobalThis.esbuildHMR.require(%s);%s",
+					indent, name, space, space, space, space, id, newline))
+			}
+		}
+	}
+
+	// Register the new versions of the modules that changed
+	for _, compileResult := range compileResults {
+		if isChanged(compileResult.sourceIndex) {
+			j.AddBytes(compileResult.JS)
+		}
+	}
+
+	j.AddString("})();" + newline)
+	j.EnsureNewlineAtEnd()
+	return j.Done()
+}
+
 func (c *linkerContext) generateGlobalNamePrefix() string {
 	var text string
 	prefix := c.options.GlobalName[0]
@@ -4774,6 +6335,194 @@ func (c *linkerContext) generateGlobalNamePrefix() string {
 	return text
 }
 
+// Returns the paths of all external modules imported by the live code in this
+// chunk in the order they are first imported. The UMD and SystemJS formats
+// need to load these before running the code in the chunk.
+func (c *linkerContext) externalImportPathsInChunk(chunkRepr *chunkReprJS) (paths []string) {
+	visited := make(map[string]bool)
+	for _, partRange := range chunkRepr.partsInChunkInOrder {
+		repr, ok := c.graph.Files[partRange.sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			continue
+		}
+		for partIndex := partRange.partIndexBegin; partIndex < partRange.partIndexEnd; partIndex++ {
+			part := &repr.AST.Parts[partIndex]
+			if !part.IsLive {
+				continue
+			}
+			for _, importRecordIndex := range part.ImportRecordIndices {
+				record := &repr.AST.ImportRecords[importRecordIndex]
+				if record.SourceIndex.IsValid() || record.IsUnused || record.IsChunkLoad ||
+					(record.Kind != ast.ImportStmt && record.Kind != ast.ImportRequire && record.Kind != ast.ImportDynamic) {
+					continue
+				}
+				if !visited[record.Path.Text] {
+					visited[record.Path.Text] = true
+					paths = append(paths, record.Path.Text)
+				}
+			}
+		}
+	}
+	return
+}
+
+// This returns the expression that the UMD format uses for an external module
+// when it's loaded as a plain script. This is either the global variable that
+// was configured for the module or a global variable named after the module:
+// "root.reactDom" for "react-dom"
+func (c *linkerContext) externalGlobalForUMD(path string) string {
+	// Use the global variable for this package if one was configured
+	if globalName, ok := c.options.ExternalModules.Globals[path]; ok {
+		text := string(js_printer.QuoteIdentifier(nil, globalName[0], c.options.UnsupportedJSFeatures))
+		for _, name := range globalName[1:] {
+			if js_printer.CanEscapeIdentifier(name, c.options.UnsupportedJSFeatures, c.options.ASCIIOnly) {
+				text += "." + string(js_printer.QuoteIdentifier(nil, name, c.options.UnsupportedJSFeatures))
+			} else {
+				text += "[" + string(js_printer.QuoteForJSON(name, c.options.ASCIIOnly)) + "]"
+			}
+		}
+		return text
+	}
+
+	var sb strings.Builder
+	sb.WriteString("root")
+	name := path
+	if slash := strings.LastIndexByte(name, '/'); slash != -1 && strings.HasPrefix(name, "@") {
+		name = name[slash+1:]
+	}
+	upper := false
+	var ident []rune
+	for _, ch := range name {
+		if ch == '_' || ch == '$' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9' && len(ident) > 0) {
+			if upper && len(ident) > 0 && ch >= 'a' && ch <= 'z' {
+				ch -= 'a' - 'A'
+			}
+			ident = append(ident, ch)
+			upper = false
+		} else {
+			upper = true
+		}
+	}
+	if len(ident) > 0 {
+		sb.WriteByte('.')
+		sb.WriteString(string(ident))
+	} else {
+		sb.WriteByte('[')
+		sb.Write(js_printer.QuoteForJSON(path, c.options.ASCIIOnly))
+		sb.WriteByte(']')
+	}
+	return sb.String()
+}
+
+// This generates everything before the bundled code for the UMD and SystemJS
+// formats. The bundled code ends up inside a factory function that takes a
+// "require" function for the external modules and returns the exports.
+func (c *linkerContext) generateModuleFactoryPrefix(externals []string) string {
+	var lines []string
+	space := " "
+	newline := "\n"
+	if c.options.RemoveWhitespace {
+		space = ""
+		newline = ""
+	}
+	line := func(indent int, text string) {
+		if !c.options.RemoveWhitespace {
+			text = strings.Repeat("  ", indent) + text
+		}
+		lines = append(lines, text)
+	}
+	quote := func(text string) string {
+		return string(js_printer.QuoteForJSON(text, c.options.ASCIIOnly))
+	}
+
+	// "["react", "react-dom"]"
+	deps := make([]string, len(externals))
+	for i, path := range externals {
+		deps[i] = quote(path)
+	}
+	depsArray := "[" + strings.Join(deps, ","+space) + "]"
+
+	switch c.options.OutputFormat {
+	case config.FormatUMD:
+		var requireForGlobals string
+		if len(externals) > 0 {
+			// "function(id) { return {"react": root.react}[id]; }"
+			globals := make([]string, len(externals))
+			for i, path := range externals {
+				globals[i] = deps[i] + ":" + space + c.externalGlobalForUMD(path)
+			}
+			requireForGlobals = "function(id)" + space + "{" + space + "return" + space +
+				"{" + strings.Join(globals, ","+space) + "}[id];" + space + "}"
+		}
+		factoryForGlobals := "factory(" + requireForGlobals + ")"
+		if len(c.options.GlobalName) > 0 {
+			factoryForGlobals = c.generateGlobalNameTargetForUMD() + space + "=" + space + factoryForGlobals
+		}
+		amdDeps := "[\"require\"]"
+		if len(externals) > 0 {
+			amdDeps = "[\"require\"," + space + depsArray[1:]
+		}
+		line(0, "(function(root,"+space+"factory)"+space+"{")
+		line(1, "if"+space+"(typeof define"+space+"==="+space+"\"function\""+space+"&&"+space+"define.amd)"+space+
+			"define("+amdDeps+","+space+"factory);")
+		line(1, "else if"+space+"(typeof module"+space+"==="+space+"\"object\""+space+"&&"+space+"module.exports)"+space+
+			"module.exports"+space+"="+space+"factory(require);")
+		line(1, "else "+factoryForGlobals+";")
+		line(0, "})(typeof self"+space+"!=="+space+"\"undefined\""+space+"?"+space+"self"+space+":"+space+"this,"+space+"function(require)"+space+"{")
+
+	case config.FormatSystem:
+		// SystemJS module namespace objects don't have the "__esModule" marker,
+		// so add it to make "__toModule" use their "default" export
+		line(0, "(function(deps,"+space+"factory)"+space+"{")
+		line(1, "System.register(deps,"+space+"function(_export)"+space+"{")
+		line(2, "var modules"+space+"="+space+"{};")
+		line(2, "return"+space+"{")
+		line(3, "setters:"+space+"deps.map(function(id)"+space+"{")
+		line(4, "return function(module)"+space+"{")
+		line(5, "modules[id]"+space+"="+space+"Object.assign({"+space+"__esModule:"+space+"true"+space+"},"+space+"module);")
+		line(4, "};")
+		line(3, "}),")
+		line(3, "execute:"+space+"function()"+space+"{")
+		line(4, "_export(factory(function(id)"+space+"{")
+		line(5, "return modules[id];")
+		line(4, "})"+space+"||"+space+"{});")
+		line(3, "}")
+		line(2, "};")
+		line(1, "});")
+		line(0, "})("+depsArray+","+space+"function(require)"+space+"{")
+	}
+
+	return strings.Join(lines, newline) + newline
+}
+
+// This returns the assignment target for the global name in the UMD format,
+// creating any intermediate objects: "(root.a = root.a || {}).b"
+func (c *linkerContext) generateGlobalNameTargetForUMD() string {
+	space := " "
+	if c.options.RemoveWhitespace {
+		space = ""
+	}
+	member := func(prefix string, name string) string {
+		if js_printer.CanEscapeIdentifier(name, c.options.UnsupportedJSFeatures, c.options.ASCIIOnly) {
+			if c.options.ASCIIOnly {
+				name = string(js_printer.QuoteIdentifier(nil, name, c.options.UnsupportedJSFeatures))
+			}
+			return fmt.Sprintf("%s.%s", prefix, name)
+		}
+		return fmt.Sprintf("%s[%s]", prefix, js_printer.QuoteForJSON(name, c.options.ASCIIOnly))
+	}
+	prefix := "root"
+	var parents []string
+	for _, name := range c.options.GlobalName[:len(c.options.GlobalName)-1] {
+		prefix = member(prefix, name)
+		parents = append(parents, fmt.Sprintf("%s%s=%s%s%s||%s{}", prefix, space, space, prefix, space, space))
+	}
+	if len(parents) > 0 {
+		prefix = "(" + strings.Join(parents, ","+space) + ")"
+	}
+	return member(prefix, c.options.GlobalName[len(c.options.GlobalName)-1])
+}
+
 type compileResultCSS struct {
 	css_printer.PrintResult
 
@@ -5086,6 +6835,10 @@ func (c *linkerContext) breakOutputIntoPieces(j helpers.Joiner, chunkCount uint3
 					kind = outputPieceAssetIndex
 				case 'C':
 					kind = outputPieceChunkIndex
+				case 'I':
+					kind = outputPieceChunkIDIndex
+				case 'W':
+					kind = outputPieceWorkerIndex
 				}
 				for j := 1; j < 9; j++ {
 					c := output[start+j]
@@ -5105,11 +6858,16 @@ func (c *linkerContext) breakOutputIntoPieces(j helpers.Joiner, chunkCount uint3
 				boundary = -1
 			}
 
-		case outputPieceChunkIndex:
+		case outputPieceChunkIndex, outputPieceChunkIDIndex:
 			if index >= chunkCount {
 				boundary = -1
 			}
 
+		case outputPieceWorkerIndex:
+			if _, ok := c.workerPaths[index]; !ok {
+				boundary = -1
+			}
+
 		default:
 			boundary = -1
 		}
@@ -5193,6 +6951,12 @@ func (c *linkerContext) generateIsolatedHash(chunk *chunkInfo, channel chan []by
 	if chunk.intermediateOutput.pieces != nil {
 		for _, piece := range chunk.intermediateOutput.pieces {
 			hashWriteLengthPrefixed(hash, piece.data)
+
+			// Workers have already been linked, so their final paths are known. The
+			// hash of each worker is part of its path, so include that here.
+			if piece.kind == outputPieceWorkerIndex {
+				hashWriteLengthPrefixed(hash, []byte(c.workerPaths[piece.index]))
+			}
 		}
 	} else {
 		bytes := chunk.intermediateOutput.joiner.Done()
@@ -37,6 +37,7 @@ type CacheSet struct {
 	CSSCache         CSSCache
 	JSONCache        JSONCache
 	JSCache          JSCache
+	HMRCache         HMRCache
 }
 
 func MakeCacheSet() *CacheSet {
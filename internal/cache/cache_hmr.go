@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"sync"
+)
+
+// This remembers a hash of the code generated for each module in the previous
+// build of each entry point. Hot module replacement uses this to generate
+// updates that only contain the modules that changed since the previous build.
+//
+// Unlike the other caches, this doesn't depend on the contents of any one file
+// but on the output of the whole build. It's only valid as long as the build
+// options stay the same, which is the case for incremental builds.
+type HMRCache struct {
+	mutex   sync.Mutex
+	entries map[string]map[string]uint64
+}
+
+// Returns the modules whose hash is different from the previous build of the
+// same entry point and then replaces the previous hashes with the new ones.
+// The returned boolean is false if this is the first build of this entry point,
+// in which case there is nothing to update.
+func (c *HMRCache) Update(entryPoint string, hashes map[string]uint64) (map[string]bool, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]map[string]uint64)
+	}
+	oldHashes, ok := c.entries[entryPoint]
+	c.entries[entryPoint] = hashes
+	if !ok {
+		return nil, false
+	}
+
+	changed := make(map[string]bool)
+	for id, hash := range hashes {
+		if oldHash, ok := oldHashes[id]; !ok || oldHash != hash {
+			changed[id] = true
+		}
+	}
+	return changed, true
+}
@@ -17,6 +17,39 @@ type JSXOptions struct {
 	Fragment JSXExpr
 	Parse    bool
 	Preserve bool
+
+	// If true, JSX elements are converted to calls to functions that are
+	// automatically imported from "<ImportSource>/jsx-runtime" instead of
+	// calls to "Factory". This is React's "automatic" JSX transform.
+	AutomaticRuntime bool
+	ImportSource     string // Default if empty: "react"
+	Development      bool   // Use "jsxDEV" from "<ImportSource>/jsx-dev-runtime"
+}
+
+// This is the value of the "jsx" setting in "tsconfig.json". Only the values
+// that select between the classic and automatic JSX transforms are tracked.
+type TSJSX uint8
+
+const (
+	TSJSXNone TSJSX = iota
+	TSJSXReact
+	TSJSXReactJSX
+	TSJSXReactJSXDev
+)
+
+func (options *JSXOptions) SetOptionsFromTSJSX(tsx TSJSX) {
+	switch tsx {
+	case TSJSXReact:
+		options.AutomaticRuntime = false
+		options.Development = false
+
+	case TSJSXReactJSX:
+		options.AutomaticRuntime = true
+
+	case TSJSXReactJSXDev:
+		options.AutomaticRuntime = true
+		options.Development = true
+	}
 }
 
 type JSXExpr struct {
@@ -36,6 +69,22 @@ const (
 	PlatformNeutral
 )
 
+// This is a platform other than one of the built-in ones (e.g. React Native).
+// It replaces the resolver defaults of the built-in platform in "Platform",
+// but everything else still behaves like that built-in platform.
+type CustomPlatform struct {
+	Name       string
+	Conditions []string
+	MainFields []string
+
+	// Imports of these modules are always external, with or without a "node:"
+	// prefix
+	BuiltinModules map[string]bool
+
+	// If true, the "browser" field in "package.json" is respected
+	BrowserField bool
+}
+
 type StrictOptions struct {
 	// Loose:  "class Foo { foo = 1 }" => "class Foo { constructor() { this.foo = 1; } }"
 	// Strict: "class Foo { foo = 1 }" => "class Foo { constructor() { __publicField(this, 'foo', 1); } }"
@@ -139,12 +188,46 @@ const (
 	//   export {...};
 	//
 	FormatESModule
+
+	// The UMD format works with AMD loaders, with CommonJS, and as a plain
+	// script. External imports are passed to the factory as "require":
+	//
+	//   (function(root, factory) {
+	//     if (typeof define === "function" && define.amd) define(["require", ...], factory);
+	//     else if (typeof module === "object" && module.exports) module.exports = factory(require);
+	//     else root.globalName = factory(function(id) { ... });
+	//   })(typeof self !== "undefined" ? self : this, function(require) {
+	//     ... bundled code ...
+	//     return exports;
+	//   });
+	//
+	FormatUMD
+
+	// The SystemJS format registers the exports with "System.register":
+	//
+	//   (function(factory) {
+	//     System.register([...], function(_export) {
+	//       ...
+	//       return {setters: [...], execute: function() { _export(factory(...)); }};
+	//     });
+	//   })(function(require) {
+	//     ... bundled code ...
+	//     return exports;
+	//   });
+	//
+	FormatSystem
 )
 
 func (f Format) KeepES6ImportExportSyntax() bool {
 	return f == FormatPreserve || f == FormatESModule
 }
 
+// Returns true if the bundled code is wrapped in a function. The code inside
+// the function is indented by one level.
+func (f Format) IsWrappedInFunction() bool {
+	return f == FormatIIFE || f == FormatUMD || f == FormatSystem
+}
+
 func (f Format) String() string {
 	switch f {
 	case FormatIIFE:
@@ -153,6 +236,10 @@ func (f Format) String() string {
 		return "cjs"
 	case FormatESModule:
 		return "esm"
+	case FormatUMD:
+		return "umd"
+	case FormatSystem:
+		return "system"
 	}
 	return ""
 }
@@ -164,6 +251,36 @@ type StdinInfo struct {
 	AbsResolveDir string
 }
 
+// Module federation lets independently built bundles on the same page share
+// modules at run-time through a page-wide registry of containers
+type Federation struct {
+	// The name of this build's container in the registry
+	Name string
+
+	// Maps a public module name such as "./Button" to the path of the module
+	// that the container entry exposes under that name
+	Exposes map[string]string
+
+	// Maps the name of another build's container to the URL of its container
+	// entry. Importing "name/path" reads the module exposed as "./path".
+	Remotes map[string]string
+
+	// Maps the name of a package to the range of versions that this build can
+	// accept from other builds. An empty range accepts versions compatible with
+	// the bundled copy.
+	Shared map[string]string
+}
+
+// Returns the name of the remote container that an import path refers to
+func (f *Federation) RemoteForPath(path string) (string, bool) {
+	name := path
+	if slash := strings.IndexByte(path, '/'); slash != -1 {
+		name = path[:slash]
+	}
+	_, ok := f.Remotes[name]
+	return name, ok
+}
+
 type WildcardPattern struct {
 	Prefix string
 	Suffix string
@@ -173,6 +290,42 @@ type ExternalModules struct {
 	NodeModules map[string]bool
 	AbsPaths    map[string]bool
 	Patterns    []WildcardPattern
+
+	// Maps some of the external packages in "NodeModules" to the global
+	// variable that holds them at run-time, such as "window.React" for "react".
+	// This is split into parts like "GlobalName".
+	Globals map[string][]string
+}
+
+// Files matching this pattern are placed in the named chunk when code
+// splitting instead of in the chunk for the entry points that reach them
+type ManualChunkPattern struct {
+	WildcardPattern
+	ChunkName string
+
+	// If true, this is matched against the name of the package containing the
+	// file inside a "node_modules" directory. Otherwise it's matched against the
+	// absolute path of the file.
+	IsPackage bool
+}
+
+type NodeBuiltinMode uint8
+
+const (
+	// Use the platform's behavior (external for node, resolved normally otherwise)
+	NodeBuiltinDefault NodeBuiltinMode = iota
+	NodeBuiltinExternal
+	NodeBuiltinError
+	NodeBuiltinEmpty
+	NodeBuiltinPolyfill
+)
+
+type NodeBuiltinPolicy struct {
+	Mode NodeBuiltinMode
+
+	// The package to substitute for the builtin module. This is only used with
+	// "NodeBuiltinPolyfill".
+	Polyfill string
 }
 
 type Mode uint8
@@ -210,6 +363,7 @@ type Options struct {
 	CodeSplitting     bool
 	WatchMode         bool
 	AllowOverwrite    bool
+	DedupePackages    bool
 	LegalComments     LegalComments
 
 	// If true, make sure to generate a single file that can be written to stdout
@@ -218,6 +372,8 @@ type Options struct {
 	OmitRuntimeForTests     bool
 	PreserveUnusedImportsTS bool
 	UseDefineForClassFields MaybeBool
+	ExperimentalDecorators  MaybeBool
+	EmitDecoratorMetadata   bool
 	ASCIIOnly               bool
 	KeepNames               bool
 	IgnoreDCEAnnotations    bool
@@ -228,6 +384,9 @@ type Options struct {
 	JSX      JSXOptions
 	Platform Platform
 
+	// If present, this overrides the resolver defaults for "Platform"
+	CustomPlatform *CustomPlatform
+
 	IsTargetUnconfigured   bool // If true, TypeScript's "target" setting is respected
 	UnsupportedJSFeatures  compat.JSFeature
 	UnsupportedCSSFeatures compat.CSSFeature
@@ -243,6 +402,26 @@ type Options struct {
 	AbsNodePaths    []string // The "NODE_PATH" variable from Node.js
 	ExternalModules ExternalModules
 
+	// The first matching pattern determines the chunk for a file
+	ManualChunks []ManualChunkPattern
+
+	// Shared chunks smaller than this are merged into other shared chunks and
+	// shared chunks larger than this are split. Zero means no limit.
+	MinChunkSize int
+	MaxChunkSize int
+
+	// If true, every module is wrapped in a closure that can be replaced at
+	// run-time and "import.meta.hot" is available. Rebuilds also generate an
+	// update file for each entry point with only the modules that changed.
+	HotModuleReplacement bool
+
+	// If present, this build takes part in module federation (see below)
+	Federation *Federation
+
+	// How to handle imports of individual builtin node modules, keyed by the
+	// module name without the "node:" prefix
+	NodeBuiltins map[string]NodeBuiltinPolicy
+
 	AbsOutputFile      string
 	AbsOutputDir       string
 	AbsOutputBase      string
@@ -386,7 +565,8 @@ func SubstituteTemplate(template []PathTemplate, placeholders PathPlaceholders)
 }
 
 func ShouldCallRuntimeRequire(mode Mode, outputFormat Format) bool {
-	return mode == ModeBundle && outputFormat != FormatCommonJS
+	// The CommonJS, UMD, and SystemJS formats all have a "require" function
+	return mode == ModeBundle && outputFormat != FormatCommonJS && outputFormat != FormatUMD && outputFormat != FormatSystem
 }
 
 type InjectedDefine struct {
@@ -73,6 +73,13 @@ func (fs *mockFS) ModKey(path string) (ModKey, error) {
 	return ModKey{}, errors.New("This is not available during tests")
 }
 
+// The mock file system doesn't provide modification keys. Tests that need them
+// (e.g. to test caching across builds) can wrap it and use this to make a fake
+// key from a version number that they change whenever they edit a file.
+func MockModKey(version uint64) ModKey {
+	return ModKey{inode: version}
+}
+
 func (*mockFS) IsAbs(p string) bool {
 	return path.IsAbs(p)
 }
@@ -115,6 +115,14 @@ type LinkerGraph struct {
 	// is useful as a deterministic key for sorting if you need to sort something
 	// containing a source index (such as "js_ast.Ref" symbol references).
 	StableSourceIndices []uint32
+
+	// This contains the values of all top-level TypeScript enums in the bundle.
+	// It's used to inline property accesses on enums imported from other files.
+	TSEnums map[js_ast.Ref]map[string]js_ast.TSEnumValue
+
+	// This contains the kind of the values of each enum in "TSEnums". It's used
+	// for decorator metadata of types that are enums imported from other files.
+	TSEnumMetadataKinds map[js_ast.Ref]js_ast.TSMetadataKind
 }
 
 func CloneLinkerGraph(
@@ -237,6 +245,26 @@ func CloneLinkerGraph(
 	}
 	waitGroup.Wait()
 
+	// Merge the enum values from all files into a single map
+	var tsEnums map[js_ast.Ref]map[string]js_ast.TSEnumValue
+	var tsEnumMetadataKinds map[js_ast.Ref]js_ast.TSMetadataKind
+	for _, sourceIndex := range reachableFiles {
+		if repr, ok := files[sourceIndex].InputFile.Repr.(*JSRepr); ok && len(repr.AST.TSEnums) > 0 {
+			if tsEnums == nil {
+				tsEnums = make(map[js_ast.Ref]map[string]js_ast.TSEnumValue)
+			}
+			for ref, enum := range repr.AST.TSEnums {
+				tsEnums[ref] = enum
+			}
+			for ref, kind := range repr.AST.TSEnumMetadataKinds {
+				if tsEnumMetadataKinds == nil {
+					tsEnumMetadataKinds = make(map[js_ast.Ref]js_ast.TSMetadataKind)
+				}
+				tsEnumMetadataKinds[ref] = kind
+			}
+		}
+	}
+
 	// Process dynamic entry points after merging control flow again
 	stableEntryPoints := make([]int, 0, len(dynamicImportEntryPoints))
 	for _, sourceIndex := range dynamicImportEntryPoints {
@@ -264,6 +292,8 @@ func CloneLinkerGraph(
 		Files:               files,
 		ReachableFiles:      reachableFiles,
 		StableSourceIndices: stableSourceIndices,
+		TSEnums:             tsEnums,
+		TSEnumMetadataKinds: tsEnumMetadataKinds,
 	}
 }
 
@@ -30,6 +30,20 @@ type InputFile struct {
 
 	SideEffects SideEffects
 	Loader      config.Loader
+
+	// This is present if this file is the entry point of a package that is
+	// shared with other builds through module federation
+	FederationShare *FederationShare
+}
+
+type FederationShare struct {
+	Name string
+
+	// The version of the copy in this bundle
+	Version string
+
+	// The versions that this bundle can use instead of its own copy
+	Range string
 }
 
 type OutputFile struct {
@@ -42,6 +56,9 @@ type OutputFile struct {
 	JSONMetadataChunk string
 
 	IsExecutable bool
+
+	// This is true for the hot module replacement update of a chunk
+	IsHMRUpdate bool
 }
 
 type SideEffects struct {
@@ -1,6 +1,9 @@
 package helpers
 
-import "bytes"
+import (
+	"bytes"
+	"math/bits"
+)
 
 type BitSet struct {
 	entries []byte
@@ -25,3 +28,22 @@ func (bs BitSet) Equals(other BitSet) bool {
 func (bs BitSet) String() string {
 	return string(bs.entries)
 }
+
+func (bs BitSet) Union(other BitSet) {
+	for i, entry := range other.entries {
+		bs.entries[i] |= entry
+	}
+}
+
+func (bs BitSet) Clone() BitSet {
+	return BitSet{append([]byte{}, bs.entries...)}
+}
+
+// Returns the number of bits set in this set that aren't set in the other set
+func (bs BitSet) CountNotIn(other BitSet) int {
+	count := 0
+	for i, entry := range bs.entries {
+		count += bits.OnesCount8(entry &^ other.entries[i])
+	}
+	return count
+}
@@ -260,11 +260,15 @@ const (
 	PropertyGet
 	PropertySet
 	PropertySpread
+	PropertyAutoAccessor
 )
 
 type Property struct {
-	TSDecorators []Expr
-	Key          Expr
+	// These are TypeScript's experimental decorators when the TypeScript
+	// setting "experimentalDecorators" is enabled and standard JavaScript
+	// decorators otherwise
+	Decorators []Expr
+	Key        Expr
 
 	// This is omitted for class fields
 	ValueOrNil Expr
@@ -280,6 +284,10 @@ type Property struct {
 	//
 	InitializerOrNil Expr
 
+	// This is the type annotation of a class field when "emitDecoratorMetadata"
+	// is enabled in "tsconfig.json"
+	TSMetadata TSMetadata
+
 	Kind            PropertyKind
 	IsComputed      bool
 	IsMethod        bool
@@ -298,14 +306,63 @@ type PropertyBinding struct {
 }
 
 type Arg struct {
-	TSDecorators []Expr
+	Decorators   []Expr
 	Binding      Binding
 	DefaultOrNil Expr
+	TSMetadata   TSMetadata
 
 	// "constructor(public x: boolean) {}"
 	IsTypeScriptCtorField bool
 }
 
+// TypeScript's "emitDecoratorMetadata" setting passes type annotations to
+// decorators as the runtime constructor they most likely correspond to. Type
+// annotations are otherwise discarded by the parser, so this is a summary of
+// just the part of the type annotation that can affect that constructor.
+type TSMetadata struct {
+	// This is only used for "TSMetadataIdentifier". It holds the parts of a
+	// possibly-qualified type name such as "Foo" or "Foo.Bar".
+	Names []string
+
+	// This is only used for "TSMetadataArray". It holds the element type for
+	// array types written as "T[]", which is used for rest arguments.
+	ElementOrNil *TSMetadata
+
+	Loc  logger.Loc
+	Kind TSMetadataKind
+}
+
+type TSMetadataKind uint8
+
+const (
+	// There was no type annotation
+	TSMetadataNone TSMetadataKind = iota
+
+	// These become "Object"
+	TSMetadataObject
+	TSMetadataAny
+	TSMetadataUnknown
+
+	// These become "void 0"
+	TSMetadataVoid
+	TSMetadataNever
+	TSMetadataNull
+	TSMetadataUndefined
+
+	// These become the corresponding global constructor
+	TSMetadataString
+	TSMetadataNumber
+	TSMetadataBigInt
+	TSMetadataBoolean
+	TSMetadataSymbol
+	TSMetadataFunction
+	TSMetadataArray
+
+	// This is a reference to a type by name, which may or may not also be a
+	// value at run-time
+	TSMetadataIdentifier
+)
+
 type Fn struct {
 	Name         *LocRef
 	OpenParenLoc logger.Loc
@@ -313,6 +370,10 @@ type Fn struct {
 	Body         FnBody
 	ArgumentsRef Ref
 
+	// This is the return type annotation when "emitDecoratorMetadata" is
+	// enabled in "tsconfig.json"
+	ReturnTSMetadata TSMetadata
+
 	IsAsync     bool
 	IsGenerator bool
 	HasRestArg  bool
@@ -329,7 +390,7 @@ type FnBody struct {
 
 type Class struct {
 	ClassKeyword logger.Range
-	TSDecorators []Expr
+	Decorators   []Expr
 	Name         *LocRef
 	ExtendsOrNil Expr
 	BodyLoc      logger.Loc
@@ -630,6 +691,11 @@ type EIf struct {
 	Test Expr
 	Yes  Expr
 	No   Expr
+
+	// If true, this is "typeof Foo === 'undefined' ? Object : Foo" for a type
+	// in TypeScript decorator metadata where "Foo" is imported. The printer
+	// replaces it with "Number" or "String" if "Foo" turns out to be an enum.
+	IsTSMetadataTypeCheck bool
 }
 
 type ERequireString struct {
@@ -640,6 +706,11 @@ type ERequireResolveString struct {
 	ImportRecordIndex uint32
 }
 
+// This is the path in "new URL(path, import.meta.url)"
+type EURLString struct {
+	ImportRecordIndex uint32
+}
+
 type EImportString struct {
 	ImportRecordIndex uint32
 
@@ -695,6 +766,7 @@ func (*EYield) isExpr()                {}
 func (*EIf) isExpr()                   {}
 func (*ERequireString) isExpr()        {}
 func (*ERequireResolveString) isExpr() {}
+func (*EURLString) isExpr()            {}
 func (*EImportString) isExpr()         {}
 func (*EImportCall) isExpr()           {}
 
@@ -1127,11 +1199,11 @@ type SSwitch struct {
 
 // This object represents all of these types of import statements:
 //
-//    import 'path'
-//    import {item1, item2} from 'path'
-//    import * as ns from 'path'
-//    import defaultItem, {item1, item2} from 'path'
-//    import defaultItem, * as ns from 'path'
+//	import 'path'
+//	import {item1, item2} from 'path'
+//	import * as ns from 'path'
+//	import defaultItem, {item1, item2} from 'path'
+//	import defaultItem, * as ns from 'path'
 //
 // Many parts are optional and can be combined in different ways. The only
 // restriction is that you cannot have both a clause and a star namespace.
@@ -1738,6 +1810,10 @@ type AST struct {
 	ExportKeyword        logger.Range // Does not include TypeScript-specific syntax
 	TopLevelAwaitKeyword logger.Range
 
+	// This is the first "import.meta" expression that is kept as-is instead of
+	// being substituted with something else (e.g. a define or a variable)
+	ImportMetaKeyword logger.Range
+
 	Hashbang    string
 	Directive   string
 	URLForCSS   string
@@ -1767,9 +1843,23 @@ type AST struct {
 	// call "TopLevelSymbolToParts" instead.
 	TopLevelSymbolToPartsFromParser map[Ref][]uint32
 
+	// This contains all top-level exported TypeScript enum constants. It exists
+	// to enable cross-module inlining of constant enums.
+	TSEnums map[Ref]map[string]TSEnumValue
+
+	// This contains the kind of the values of each enum in "TSEnums", which is
+	// used for decorator metadata of types that are enums from other files
+	TSEnumMetadataKinds map[Ref]TSMetadataKind
+
 	SourceMapComment logger.Span
 }
 
+// This is the compile-time value of a TypeScript enum member
+type TSEnumValue struct {
+	String []uint16 // Use this if it's not nil
+	Number float64  // Use this if "String" is nil
+}
+
 // This is a histogram of character frequencies for minification
 type CharFreq [64]int32
 
@@ -1886,6 +1976,11 @@ type NamedImport struct {
 	// It's useful to flag exported imports because if they are in a TypeScript
 	// file, we can't tell if they are a type or a value.
 	IsExported bool
+
+	// This is true if the import is only used by the type information that
+	// TypeScript's "emitDecoratorMetadata" setting passes to decorators. We
+	// can't tell if it's a type or a value either, so it's allowed to be missing.
+	IsOnlyUsedForTSMetadata bool
 }
 
 type NamedExport struct {
@@ -1914,6 +2009,13 @@ type Part struct {
 	// An estimate of the number of uses of all symbols used within this part.
 	SymbolUses map[Ref]SymbolUse
 
+	// This tracks property accesses off of imported symbols. We don't know
+	// during parsing if an imported symbol is going to be an inlined enum
+	// value or not. This is only known during linking. So we defer adding
+	// a dependency on these imported symbols until we know whether or not
+	// they are enums, and if they are, which enum values are used.
+	ImportSymbolPropertyUses map[Ref]map[string]SymbolUse
+
 	// The indices of the other parts in this file that are needed if this part
 	// is needed.
 	Dependencies []Dependency
@@ -46,6 +46,7 @@ type parser struct {
 	hasESModuleSyntax          bool
 	warnedThisIsUndefined      bool
 	topLevelAwaitKeyword       logger.Range
+	importMetaKeyword          logger.Range
 	fnOrArrowDataParse         fnOrArrowDataParse
 	fnOrArrowDataVisit         fnOrArrowDataVisit
 	fnOnlyDataVisit            fnOnlyDataVisit
@@ -66,6 +67,7 @@ type parser struct {
 	injectedDefineSymbols      []js_ast.Ref
 	injectedSymbolSources      map[js_ast.Ref]injectedSymbolSource
 	symbolUses                 map[js_ast.Ref]js_ast.SymbolUse
+	importSymbolPropertyUses   map[js_ast.Ref]map[string]js_ast.SymbolUse
 	declaredSymbols            []js_ast.DeclaredSymbol
 	runtimeImports             map[string]js_ast.Ref
 	duplicateCaseChecker       duplicateCaseChecker
@@ -75,6 +77,12 @@ type parser struct {
 	// For strict mode handling
 	hoistedRefForSloppyModeBlockFn map[js_ast.Ref]js_ast.Ref
 
+	// For lowering "let" and "const" to "var". Loop bodies that declare these
+	// bindings must be moved into a closure if a binding is captured by a
+	// nested function, since each iteration must get a separate copy.
+	capturedLexicalRefs map[js_ast.Ref]bool
+	assignedLexicalRefs map[js_ast.Ref]bool
+
 	// For lowering private methods
 	weakMapRef     js_ast.Ref
 	weakSetRef     js_ast.Ref
@@ -82,11 +90,15 @@ type parser struct {
 	privateSetters map[js_ast.Ref]js_ast.Ref
 
 	// These are for TypeScript
-	shouldFoldNumericConstants bool
-	emittedNamespaceVars       map[js_ast.Ref]bool
-	isExportedInsideNamespace  map[js_ast.Ref]js_ast.Ref
-	knownEnumValues            map[js_ast.Ref]map[string]float64
-	localTypeNames             map[string]bool
+	shouldFoldNumericConstants  bool
+	emittedNamespaceVars        map[js_ast.Ref]bool
+	isExportedInsideNamespace   map[js_ast.Ref]js_ast.Ref
+	knownEnumValues             map[js_ast.Ref]map[string]js_ast.TSEnumValue
+	tsEnums                     map[js_ast.Ref]map[string]js_ast.TSEnumValue
+	localTypeNames              map[string]bool
+	tsMetadataUseCounts         map[js_ast.Ref]uint32
+	tsEnumMetadataKinds         map[js_ast.Ref]js_ast.TSMetadataKind
+	tsEnumExportedMetadataKinds map[js_ast.Ref]js_ast.TSMetadataKind
 
 	// This is the reference to the generated function argument for the namespace,
 	// which is different than the reference to the namespace itself:
@@ -262,6 +274,25 @@ type parser struct {
 	// which are used in a brand check anywhere in the file.
 	classPrivateBrandChecksToLower map[string]bool
 
+	// When classes are lowered to ES5 constructor functions, "super" inside the
+	// class body is rewritten to reference the base class directly. The base
+	// class is passed as an argument to a wrapper function, and the symbol for
+	// that argument is created when the class is visited and stored here so the
+	// class lowering pass can find it again. The member context is handed from
+	// "visitClass" to "visitFn" for the next method that is visited.
+	classSuperRefs        map[*js_ast.Class]js_ast.Ref
+	nextClassMemberForES5 *classMemberForES5
+
+	// This is handed from "visitClass" to "visitFn" for derived class
+	// constructors, where "this" can't be used before "super()" is called
+	nextFnIsDerivedClassCtor bool
+
+	// These are the symbols imported by JSX elements when using React's
+	// "automatic" JSX transform. The runtime imports come from the
+	// "jsx-runtime" module and the legacy imports come from the package itself.
+	jsxRuntimeImports map[string]js_ast.Ref
+	jsxLegacyImports  map[string]js_ast.Ref
+
 	// Setting this to true disables warnings about code that is very likely to
 	// be a bug. This is used to ignore issues inside "node_modules" directories.
 	// This has caught real issues in the past. However, it's not esbuild's job
@@ -339,6 +370,9 @@ type optionsThatSupportStructuralEquality struct {
 	treeShaking             bool
 	preserveUnusedImportsTS bool
 	useDefineForClassFields config.MaybeBool
+	experimentalDecorators  config.MaybeBool
+	emitDecoratorMetadata   bool
+	hotModuleReplacement    bool
 }
 
 func OptionsFromConfig(options *config.Options) Options {
@@ -365,6 +399,9 @@ func OptionsFromConfig(options *config.Options) Options {
 			treeShaking:             options.TreeShaking,
 			preserveUnusedImportsTS: options.PreserveUnusedImportsTS,
 			useDefineForClassFields: options.UseDefineForClassFields,
+			experimentalDecorators:  options.ExperimentalDecorators,
+			emitDecoratorMetadata:   options.EmitDecoratorMetadata,
+			hotModuleReplacement:    options.HotModuleReplacement,
 		},
 	}
 }
@@ -398,7 +435,8 @@ func (a *Options) Equal(b *Options) bool {
 	}
 
 	// Compare "JSX"
-	if a.jsx.Parse != b.jsx.Parse || !jsxExprsEqual(a.jsx.Factory, b.jsx.Factory) || !jsxExprsEqual(a.jsx.Fragment, b.jsx.Fragment) {
+	if a.jsx.Parse != b.jsx.Parse || !jsxExprsEqual(a.jsx.Factory, b.jsx.Factory) || !jsxExprsEqual(a.jsx.Fragment, b.jsx.Fragment) ||
+		a.jsx.AutomaticRuntime != b.jsx.AutomaticRuntime || a.jsx.ImportSource != b.jsx.ImportSource || a.jsx.Development != b.jsx.Development {
 		return false
 	}
 
@@ -483,11 +521,15 @@ type fnOrArrowDataParse struct {
 	isTypeScriptDeclare bool
 	isClassStaticInit   bool
 
+	// Methods in object literals are lowered to function expressions, which
+	// can't use "super" to access properties of the object's prototype
+	isObjectLiteralMethod bool
+
 	// In TypeScript, forward declarations of functions have no bodies
 	allowMissingBodyForTypeScript bool
 
 	// Allow TypeScript decorators in function arguments
-	allowTSDecorators bool
+	allowDecorators bool
 }
 
 // This is function-specific information used during visiting. It is saved and
@@ -507,6 +549,11 @@ type fnOrArrowDataVisit struct {
 	// a try/catch statement. The assumption is that the try/catch statement is
 	// there to handle the case where the reference to "require" crashes.
 	tryBodyCount int
+
+	// This is non-nil inside the body of a loop that may be moved into a
+	// closure when lowering "let" and "const". It's used to find out whether
+	// that closure has to be a generator or an async function.
+	loopClosure *loopClosure
 }
 
 // This is function-specific information used during visiting. It is saved and
@@ -535,6 +582,11 @@ type fnOnlyDataVisit struct {
 	// will have to reference a captured variable instead of the real variable.
 	isInsideAsyncArrowFn bool
 
+	// If true, we're inside the body of a loop that may be moved into a closure
+	// when lowering "let" and "const". References to "this" and "arguments"
+	// must then be captured like they are inside lowered arrow functions.
+	isInsideLoopClosure bool
+
 	// If false, disallow "new.target" expressions. We disallow all "new.target"
 	// expressions at the top-level of the file (i.e. not inside a function or
 	// a class field). Technically since CommonJS files are wrapped in a function
@@ -553,6 +605,17 @@ type fnOnlyDataVisit struct {
 	// or a class declaration). That means the top-level module scope "this" value
 	// has been shadowed and is now inaccessible.
 	isThisNested bool
+
+	// If classes are being lowered to ES5 constructor functions, this is set
+	// inside class methods and field initializers. It's used to rewrite "super"
+	// and to replace "this" with the value returned from the base class
+	// constructor inside derived class constructors.
+	classMemberForES5 *classMemberForES5
+
+	// If true, we're inside the constructor of a class with a base class. It's
+	// not safe to reference "this" here for "__self" in JSX elements since it
+	// may come before the call to "super()".
+	isDerivedClassCtor bool
 }
 
 const bloomFilterSize = 251
@@ -1095,6 +1158,12 @@ func (p *parser) selectLocalKind(kind js_ast.LocalKind) js_ast.LocalKind {
 		return js_ast.LocalVar
 	}
 
+	// Lower "let" and "const" to "var" if they aren't supported
+	if (kind == js_ast.LocalLet && p.options.unsupportedJSFeatures.Has(compat.Let)) ||
+		(kind == js_ast.LocalConst && p.options.unsupportedJSFeatures.Has(compat.Const)) {
+		return js_ast.LocalVar
+	}
+
 	// Optimization: use "let" instead of "const" because it's shorter. This is
 	// only done when bundling because assigning to "const" is only an error when
 	// bundling.
@@ -1609,6 +1678,58 @@ func (p *parser) ignoreUsage(ref js_ast.Ref) {
 	// the value is ignored because that's what the TypeScript compiler does.
 }
 
+type jsxImport uint8
+
+const (
+	jsxImportJSX jsxImport = iota
+	jsxImportJSXS
+	jsxImportFragment
+	jsxImportCreateElement
+)
+
+func (p *parser) importJSXSymbol(loc logger.Loc, jsx jsxImport) js_ast.Expr {
+	var symbols map[string]js_ast.Ref
+	var name string
+
+	switch jsx {
+	case jsxImportJSX:
+		symbols = p.jsxRuntimeImports
+		if p.options.jsx.Development {
+			name = "jsxDEV"
+		} else {
+			name = "jsx"
+		}
+
+	case jsxImportJSXS:
+		symbols = p.jsxRuntimeImports
+		if p.options.jsx.Development {
+			name = "jsxDEV"
+		} else {
+			name = "jsxs"
+		}
+
+	case jsxImportFragment:
+		symbols = p.jsxRuntimeImports
+		name = "Fragment"
+
+	case jsxImportCreateElement:
+		symbols = p.jsxLegacyImports
+		name = "createElement"
+	}
+
+	ref, ok := symbols[name]
+	if !ok {
+		ref = p.newSymbol(js_ast.SymbolOther, name)
+		p.moduleScope.Generated = append(p.moduleScope.Generated, ref)
+		p.isImportItem[ref] = true
+		symbols[name] = ref
+	}
+	p.recordUsage(ref)
+	return p.handleIdentifier(loc, &js_ast.EIdentifier{Ref: ref}, identifierOpts{
+		wasOriginallyIdentifier: true,
+	})
+}
+
 func (p *parser) importFromRuntime(loc logger.Loc, name string) js_ast.Expr {
 	ref, ok := p.runtimeImports[name]
 	if !ok {
@@ -1698,7 +1819,6 @@ type deferredErrors struct {
 	// These are errors for expressions
 	invalidExprDefaultValue  logger.Range
 	invalidExprAfterQuestion logger.Range
-	arraySpreadFeature       logger.Range
 }
 
 func (from *deferredErrors) mergeInto(to *deferredErrors) {
@@ -1708,9 +1828,6 @@ func (from *deferredErrors) mergeInto(to *deferredErrors) {
 	if from.invalidExprAfterQuestion.Len > 0 {
 		to.invalidExprAfterQuestion = from.invalidExprAfterQuestion
 	}
-	if from.arraySpreadFeature.Len > 0 {
-		to.arraySpreadFeature = from.arraySpreadFeature
-	}
 }
 
 func (p *parser) logExprErrors(errors *deferredErrors) {
@@ -1722,10 +1839,6 @@ func (p *parser) logExprErrors(errors *deferredErrors) {
 		r := errors.invalidExprAfterQuestion
 		p.log.AddRangeError(&p.tracker, r, fmt.Sprintf("Unexpected %q", p.source.Contents[r.Loc.Start:r.Loc.Start+r.Len]))
 	}
-
-	if errors.arraySpreadFeature.Len > 0 {
-		p.markSyntaxFeature(compat.ArraySpread, errors.arraySpreadFeature)
-	}
 }
 
 // The "await" and "yield" expressions are never allowed in argument lists but
@@ -1809,13 +1922,13 @@ type propertyOpts struct {
 	isGenerator bool
 
 	// Class-related options
-	isStatic          bool
-	isTSDeclare       bool
-	isTSAbstract      bool
-	isClass           bool
-	classHasExtends   bool
-	allowTSDecorators bool
-	tsDecorators      []js_ast.Expr
+	isStatic        bool
+	isTSDeclare     bool
+	isTSAbstract    bool
+	isClass         bool
+	classHasExtends bool
+	allowDecorators bool
+	decorators      []js_ast.Expr
 }
 
 func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, errors *deferredErrors) (js_ast.Property, bool) {
@@ -1840,7 +1953,7 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 		p.lexer.Next()
 
 	case js_lexer.TPrivateIdentifier:
-		if !opts.isClass || len(opts.tsDecorators) > 0 {
+		if !opts.isClass || (len(opts.decorators) > 0 && p.useLegacyDecorators()) {
 			p.lexer.Expected(js_lexer.TIdentifier)
 		}
 		key = js_ast.Expr{Loc: p.lexer.Loc(), Data: &js_ast.EPrivateIdentifier{Ref: p.storeNameInRef(p.lexer.Identifier)}}
@@ -1848,7 +1961,6 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 
 	case js_lexer.TOpenBracket:
 		isComputed = true
-		p.markSyntaxFeature(compat.ObjectExtensions, p.lexer.Range())
 		p.lexer.Next()
 		wasIdentifier := p.lexer.Token == js_lexer.TIdentifier
 		expr := p.parseExpr(js_ast.LComma)
@@ -1920,7 +2032,6 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 					if !opts.isAsync && raw == name && !p.lexer.HasNewlineBefore {
 						opts.isAsync = true
 						opts.asyncRange = nameRange
-						p.markLoweredSyntaxFeature(compat.AsyncAwait, nameRange, compat.Generator)
 						return p.parseProperty(kind, opts, nil)
 					}
 
@@ -1930,6 +2041,11 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 						return p.parseProperty(kind, opts, nil)
 					}
 
+				case "accessor":
+					if !p.lexer.HasNewlineBefore && !opts.isAsync && opts.isClass && raw == name {
+						return p.parseProperty(js_ast.PropertyAutoAccessor, opts, nil)
+					}
+
 				case "declare":
 					if opts.isClass && p.options.ts.Parse && !opts.isTSDeclare && raw == name {
 						opts.isTSDeclare = true
@@ -2019,9 +2135,9 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 		p.skipTypeScriptTypeParameters()
 	}
 
-	// Parse a class field with an optional initial value
-	if opts.isClass && kind == js_ast.PropertyNormal && !opts.isAsync &&
-		!opts.isGenerator && p.lexer.Token != js_lexer.TOpenParen {
+	// Parse a class field or an auto-accessor with an optional initial value
+	if opts.isClass && (kind == js_ast.PropertyAutoAccessor || (kind == js_ast.PropertyNormal && !opts.isAsync &&
+		!opts.isGenerator && p.lexer.Token != js_lexer.TOpenParen)) {
 		var initializerOrNil js_ast.Expr
 
 		// Forbid the names "constructor" and "prototype" in some cases
@@ -2033,9 +2149,10 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 		}
 
 		// Skip over types
+		var tsMetadata js_ast.TSMetadata
 		if p.options.ts.Parse && p.lexer.Token == js_lexer.TColon {
 			p.lexer.Next()
-			p.skipTypeScriptType(js_ast.LLowest)
+			tsMetadata = p.skipTypeScriptType(js_ast.LLowest)
 		}
 
 		if p.lexer.Token == js_lexer.TEquals {
@@ -2056,32 +2173,41 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 				p.log.AddRangeError(&p.tracker, keyRange, fmt.Sprintf("Invalid field name %q", name))
 			}
 			var declare js_ast.SymbolKind
-			if opts.isStatic {
+			switch {
+			case kind == js_ast.PropertyAutoAccessor && opts.isStatic:
+				declare = js_ast.SymbolPrivateStaticGetSetPair
+			case kind == js_ast.PropertyAutoAccessor:
+				declare = js_ast.SymbolPrivateGetSetPair
+			case opts.isStatic:
 				declare = js_ast.SymbolPrivateStaticField
-			} else {
+			default:
 				declare = js_ast.SymbolPrivateField
 			}
 			private.Ref = p.declareSymbol(declare, key.Loc, name)
+
+			// Auto-accessors are lowered to a getter and a setter
+			if kind == js_ast.PropertyAutoAccessor {
+				p.privateGetters[private.Ref] = p.newSymbol(js_ast.SymbolOther, name[1:]+"_get")
+				p.privateSetters[private.Ref] = p.newSymbol(js_ast.SymbolOther, name[1:]+"_set")
+			}
 		}
 
 		p.lexer.ExpectOrInsertSemicolon()
 		return js_ast.Property{
-			TSDecorators:     opts.tsDecorators,
+			Decorators:       opts.decorators,
 			Kind:             kind,
 			IsComputed:       isComputed,
 			PreferQuotedKey:  preferQuotedKey,
 			IsStatic:         opts.isStatic,
 			Key:              key,
 			InitializerOrNil: initializerOrNil,
+			TSMetadata:       tsMetadata,
 		}, true
 	}
 
 	// Parse a method expression
 	if p.lexer.Token == js_lexer.TOpenParen || kind != js_ast.PropertyNormal ||
 		opts.isClass || opts.isAsync || opts.isGenerator {
-		if p.lexer.Token == js_lexer.TOpenParen && kind != js_ast.PropertyGet && kind != js_ast.PropertySet {
-			p.markSyntaxFeature(compat.ObjectExtensions, p.lexer.Range())
-		}
 		loc := p.lexer.Loc()
 		scopeIndex := p.pushScopeForParsePass(js_ast.ScopeFunctionArgs, loc)
 		isConstructor := false
@@ -2118,14 +2244,15 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 		}
 
 		fn, hadBody := p.parseFn(nil, fnOrArrowDataParse{
-			needsAsyncLoc:      key.Loc,
-			asyncRange:         opts.asyncRange,
-			await:              await,
-			yield:              yield,
-			allowSuperCall:     opts.classHasExtends && isConstructor,
-			allowSuperProperty: true,
-			allowTSDecorators:  opts.allowTSDecorators,
-			isConstructor:      isConstructor,
+			needsAsyncLoc:         key.Loc,
+			asyncRange:            opts.asyncRange,
+			await:                 await,
+			yield:                 yield,
+			allowSuperCall:        opts.classHasExtends && isConstructor,
+			allowSuperProperty:    true,
+			allowDecorators:       opts.allowDecorators && p.options.ts.Parse,
+			isConstructor:         isConstructor,
+			isObjectLiteralMethod: !opts.isClass,
 
 			// Only allow omitting the body if we're parsing TypeScript class
 			allowMissingBodyForTypeScript: p.options.ts.Parse && opts.isClass,
@@ -2201,7 +2328,7 @@ func (p *parser) parseProperty(kind js_ast.PropertyKind, opts propertyOpts, erro
 		}
 
 		return js_ast.Property{
-			TSDecorators:    opts.tsDecorators,
+			Decorators:      opts.decorators,
 			Kind:            kind,
 			IsComputed:      isComputed,
 			PreferQuotedKey: preferQuotedKey,
@@ -2322,6 +2449,7 @@ func (p *parser) parseArrowBody(args []js_ast.Arg, data fnOrArrowDataParse) *js_
 	// The ability to use "super" is inherited by arrow functions
 	data.allowSuperCall = p.fnOrArrowDataParse.allowSuperCall
 	data.allowSuperProperty = p.fnOrArrowDataParse.allowSuperProperty
+	data.isObjectLiteralMethod = p.fnOrArrowDataParse.isObjectLiteralMethod
 
 	if p.lexer.Token == js_lexer.TOpenBrace {
 		body := p.parseFnBody(data)
@@ -2408,7 +2536,6 @@ func (p *parser) parseAsyncPrefixExpr(asyncRange logger.Range, level js_ast.L, f
 				}
 
 				if isArrowFn {
-					p.markLoweredSyntaxFeature(compat.AsyncAwait, asyncRange, compat.Generator)
 					ref := p.storeNameInRef(p.lexer.Identifier)
 					arg := js_ast.Arg{Binding: js_ast.Binding{Loc: p.lexer.Loc(), Data: &js_ast.BIdentifier{Ref: ref}}}
 					p.lexer.Next()
@@ -2450,10 +2577,7 @@ func (p *parser) parseFnExpr(loc logger.Loc, isAsync bool, asyncRange logger.Ran
 	p.lexer.Next()
 	isGenerator := p.lexer.Token == js_lexer.TAsterisk
 	if isGenerator {
-		p.markSyntaxFeature(compat.Generator, p.lexer.Range())
 		p.lexer.Next()
-	} else if isAsync {
-		p.markLoweredSyntaxFeature(compat.AsyncAwait, asyncRange, compat.Generator)
 	}
 	var name *js_ast.LocRef
 
@@ -2535,7 +2659,6 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 
 		if isSpread {
 			spreadRange = p.lexer.Range()
-			p.markSyntaxFeature(compat.RestArgument, spreadRange)
 			p.lexer.Next()
 		}
 
@@ -2596,10 +2719,6 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 		var invalidLog invalidLog
 		args := []js_ast.Arg{}
 
-		if opts.isAsync {
-			p.markLoweredSyntaxFeature(compat.AsyncAwait, opts.asyncRange, compat.Generator)
-		}
-
 		// First, try converting the expressions to bindings
 		for _, item := range items {
 			isSpread := false
@@ -2633,11 +2752,6 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 				panic(js_lexer.LexerPanic{})
 			}
 
-			// Also report syntax features used in bindings
-			for _, entry := range invalidLog.syntaxFeatures {
-				p.markSyntaxFeature(entry.feature, entry.token)
-			}
-
 			await := allowIdent
 			if opts.isAsync {
 				await = allowExpr
@@ -2693,13 +2807,7 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 }
 
 type invalidLog struct {
-	invalidTokens  []logger.Range
-	syntaxFeatures []syntaxFeature
-}
-
-type syntaxFeature struct {
-	feature compat.JSFeature
-	token   logger.Range
+	invalidTokens []logger.Range
 }
 
 func (p *parser) convertExprToBindingAndInitializer(
@@ -2715,11 +2823,6 @@ func (p *parser) convertExprToBindingAndInitializer(
 		equalsRange := p.source.RangeOfOperatorBefore(initializerOrNil.Loc, "=")
 		if isSpread {
 			p.log.AddRangeError(&p.tracker, equalsRange, "A rest argument cannot have a default initializer")
-		} else {
-			invalidLog.syntaxFeatures = append(invalidLog.syntaxFeatures, syntaxFeature{
-				feature: compat.DefaultArgument,
-				token:   equalsRange,
-			})
 		}
 	}
 	return binding, initializerOrNil, invalidLog
@@ -2744,14 +2847,13 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 		if e.IsParenthesized {
 			invalidLog.invalidTokens = append(invalidLog.invalidTokens, p.source.RangeOfOperatorBefore(expr.Loc, "("))
 		}
-		p.markSyntaxFeature(compat.Destructuring, p.source.RangeOfOperatorAfter(expr.Loc, "["))
 		items := []js_ast.ArrayBinding{}
 		isSpread := false
 		for _, item := range e.Items {
 			if i, ok := item.Data.(*js_ast.ESpread); ok {
 				isSpread = true
 				item = i.Value
-				if _, ok := item.Data.(*js_ast.EIdentifier); !ok {
+				if _, ok := item.Data.(*js_ast.EIdentifier); !ok && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 					p.markSyntaxFeature(compat.NestedRestBinding, p.source.RangeOfOperatorAfter(item.Loc, "["))
 				}
 			}
@@ -2772,7 +2874,6 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 		if e.IsParenthesized {
 			invalidLog.invalidTokens = append(invalidLog.invalidTokens, p.source.RangeOfOperatorBefore(expr.Loc, "("))
 		}
-		p.markSyntaxFeature(compat.Destructuring, p.source.RangeOfOperatorAfter(expr.Loc, "{"))
 		properties := []js_ast.PropertyBinding{}
 		for _, item := range e.Properties {
 			if item.IsMethod || item.Kind == js_ast.PropertyGet || item.Kind == js_ast.PropertySet {
@@ -2806,7 +2907,7 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 type exprFlag uint8
 
 const (
-	exprFlagTSDecorator exprFlag = 1 << iota
+	exprFlagDecorator exprFlag = 1 << iota
 	exprFlagForLoopInit
 	exprFlagForAwaitLoopInit
 )
@@ -2827,6 +2928,11 @@ func (p *parser) parsePrefix(level js_ast.L, errors *deferredErrors, flags exprF
 
 		case js_lexer.TDot, js_lexer.TOpenBracket:
 			if p.fnOrArrowDataParse.allowSuperProperty {
+				if p.fnOrArrowDataParse.isObjectLiteralMethod && p.options.unsupportedJSFeatures.Has(compat.ObjectExtensions) {
+					where, notes := p.prettyPrintTargetEnvironment(compat.ObjectExtensions)
+					p.log.AddRangeErrorWithNotes(&p.tracker, superRange, fmt.Sprintf(
+						"Transforming \"super\" in object literal methods to %s is not supported yet", where), notes)
+				}
 				return js_ast.Expr{Loc: loc, Data: js_ast.ESuperShared}
 			}
 		}
@@ -3096,33 +3202,19 @@ func (p *parser) parsePrefix(level js_ast.L, errors *deferredErrors, flags exprF
 		return p.parseFnExpr(loc, false /* isAsync */, logger.Range{})
 
 	case js_lexer.TClass:
-		classKeyword := p.lexer.Range()
-		p.markSyntaxFeature(compat.Class, classKeyword)
-		p.lexer.Next()
-		var name *js_ast.LocRef
-
-		p.pushScopeForParsePass(js_ast.ScopeClassName, loc)
+		return p.parseClassExpr(nil)
 
-		// Parse an optional class name
-		if p.lexer.Token == js_lexer.TIdentifier {
-			if nameText := p.lexer.Identifier; !p.options.ts.Parse || nameText != "implements" {
-				if p.fnOrArrowDataParse.await != allowIdent && nameText == "await" {
-					p.log.AddRangeError(&p.tracker, p.lexer.Range(), "Cannot use \"await\" as an identifier here")
-				}
-				name = &js_ast.LocRef{Loc: p.lexer.Loc(), Ref: p.newSymbol(js_ast.SymbolOther, nameText)}
-				p.lexer.Next()
-			}
+	case js_lexer.TAt:
+		// Standard decorators can also be used on class expressions. This isn't
+		// supported by TypeScript's experimental decorators.
+		if p.useLegacyDecorators() {
+			p.lexer.Unexpected()
 		}
-
-		// Even anonymous classes can have TypeScript type parameters
-		if p.options.ts.Parse {
-			p.skipTypeScriptTypeParameters()
+		decorators := p.parseDecorators()
+		if p.lexer.Token != js_lexer.TClass {
+			p.lexer.Expected(js_lexer.TClass)
 		}
-
-		class := p.parseClass(classKeyword, name, parseClassOpts{})
-
-		p.popScope()
-		return js_ast.Expr{Loc: loc, Data: &js_ast.EClass{Class: class}}
+		return p.parseClassExpr(decorators)
 
 	case js_lexer.TNew:
 		p.lexer.Next()
@@ -3165,11 +3257,6 @@ func (p *parser) parsePrefix(level js_ast.L, errors *deferredErrors, flags exprF
 				items = append(items, js_ast.Expr{Loc: p.lexer.Loc(), Data: js_ast.EMissingShared})
 
 			case js_lexer.TDotDotDot:
-				if errors != nil {
-					errors.arraySpreadFeature = p.lexer.Range()
-				} else {
-					p.markSyntaxFeature(compat.ArraySpread, p.lexer.Range())
-				}
 				dotsLoc := p.lexer.Loc()
 				p.lexer.Next()
 				item := p.parseExprOrBindings(js_ast.LComma, &selfErrors)
@@ -3728,7 +3815,7 @@ func (p *parser) parseSuffix(left js_ast.Expr, level js_ast.L, errors *deferredE
 			//   }
 			//
 			// This matches the behavior of the TypeScript compiler.
-			if (flags & exprFlagTSDecorator) != 0 {
+			if (flags & exprFlagDecorator) != 0 {
 				return left
 			}
 
@@ -4221,7 +4308,6 @@ func (p *parser) parseExprOrLetStmt(opts parseStmtOpts) (js_ast.Expr, js_ast.Stm
 			if opts.lexicalDecl != lexicalDeclAllowAll {
 				p.forbidLexicalDecl(letRange.Loc)
 			}
-			p.markSyntaxFeature(compat.Let, letRange)
 			decls := p.parseAndDeclareDecls(js_ast.SymbolOther, opts)
 			return js_ast.Expr{}, js_ast.Stmt{Loc: letRange.Loc, Data: &js_ast.SLocal{
 				Kind:     js_ast.LocalLet,
@@ -4248,7 +4334,6 @@ func (p *parser) parseCallArgs() []js_ast.Expr {
 		loc := p.lexer.Loc()
 		isSpread := p.lexer.Token == js_lexer.TDotDotDot
 		if isSpread {
-			p.markSyntaxFeature(compat.RestArgument, p.lexer.Range())
 			p.lexer.Next()
 		}
 		arg := p.parseExpr(js_ast.LComma)
@@ -4795,7 +4880,6 @@ func (p *parser) parseBinding() js_ast.Binding {
 		return js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}
 
 	case js_lexer.TOpenBracket:
-		p.markSyntaxFeature(compat.Destructuring, p.lexer.Range())
 		p.lexer.Next()
 		isSingleLine := !p.lexer.HasNewlineBefore
 		items := []js_ast.ArrayBinding{}
@@ -4814,8 +4898,9 @@ func (p *parser) parseBinding() js_ast.Binding {
 					p.lexer.Next()
 					hasSpread = true
 
-					// This was a bug in the ES2015 spec that was fixed in ES2016
-					if p.lexer.Token != js_lexer.TIdentifier {
+					// This was a bug in the ES2015 spec that was fixed in ES2016. It's
+					// not a problem if all destructuring is being lowered anyway.
+					if p.lexer.Token != js_lexer.TIdentifier && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 						p.markSyntaxFeature(compat.NestedRestBinding, p.lexer.Range())
 					}
 				}
@@ -4862,7 +4947,6 @@ func (p *parser) parseBinding() js_ast.Binding {
 		}}
 
 	case js_lexer.TOpenBrace:
-		p.markSyntaxFeature(compat.Destructuring, p.lexer.Range())
 		p.lexer.Next()
 		isSingleLine := !p.lexer.HasNewlineBefore
 		properties := []js_ast.PropertyBinding{}
@@ -4910,10 +4994,6 @@ func (p *parser) parseBinding() js_ast.Binding {
 }
 
 func (p *parser) parseFn(name *js_ast.LocRef, data fnOrArrowDataParse) (fn js_ast.Fn, hadBody bool) {
-	if data.await == allowExpr && data.yield == allowExpr {
-		p.markSyntaxFeature(compat.AsyncGenerator, data.asyncRange)
-	}
-
 	fn.Name = name
 	fn.HasRestArg = false
 	fn.IsAsync = data.await == allowExpr
@@ -4954,17 +5034,23 @@ func (p *parser) parseFn(name *js_ast.LocRef, data fnOrArrowDataParse) (fn js_as
 			continue
 		}
 
-		var tsDecorators []js_ast.Expr
-		if data.allowTSDecorators {
-			tsDecorators = p.parseTypeScriptDecorators()
+		var decorators []js_ast.Expr
+		if data.allowDecorators && p.lexer.Token == js_lexer.TAt {
+			if !p.useLegacyDecorators() {
+				p.log.AddRangeError(&p.tracker, p.lexer.Range(),
+					"Parameter decorators only work when experimental decorators are enabled")
+			}
+			decorators = p.parseDecorators()
 		}
 
+		isRestArg := false
 		if !fn.HasRestArg && p.lexer.Token == js_lexer.TDotDotDot {
-			p.markSyntaxFeature(compat.RestArgument, p.lexer.Range())
 			p.lexer.Next()
 			fn.HasRestArg = true
+			isRestArg = true
 		}
 
+		var tsMetadata js_ast.TSMetadata
 		isTypeScriptCtorField := false
 		isIdentifier := p.lexer.Token == js_lexer.TIdentifier
 		text := p.lexer.Identifier
@@ -5000,7 +5086,16 @@ func (p *parser) parseFn(name *js_ast.LocRef, data fnOrArrowDataParse) (fn js_as
 			// "function foo(a: any) {}"
 			if p.lexer.Token == js_lexer.TColon {
 				p.lexer.Next()
-				p.skipTypeScriptType(js_ast.LLowest)
+				tsMetadata = p.skipTypeScriptType(js_ast.LLowest)
+
+				// TypeScript uses the element type for "function foo(...a: any[]) {}"
+				if isRestArg {
+					if tsMetadata.ElementOrNil != nil {
+						tsMetadata = *tsMetadata.ElementOrNil
+					} else {
+						tsMetadata = js_ast.TSMetadata{}
+					}
+				}
 			}
 		}
 
@@ -5008,15 +5103,15 @@ func (p *parser) parseFn(name *js_ast.LocRef, data fnOrArrowDataParse) (fn js_as
 
 		var defaultValueOrNil js_ast.Expr
 		if !fn.HasRestArg && p.lexer.Token == js_lexer.TEquals {
-			p.markSyntaxFeature(compat.DefaultArgument, p.lexer.Range())
 			p.lexer.Next()
 			defaultValueOrNil = p.parseExpr(js_ast.LComma)
 		}
 
 		fn.Args = append(fn.Args, js_ast.Arg{
-			TSDecorators: tsDecorators,
+			Decorators:   decorators,
 			Binding:      arg,
 			DefaultOrNil: defaultValueOrNil,
+			TSMetadata:   tsMetadata,
 
 			// We need to track this because it affects code generation
 			IsTypeScriptCtorField: isTypeScriptCtorField,
@@ -5053,7 +5148,7 @@ func (p *parser) parseFn(name *js_ast.LocRef, data fnOrArrowDataParse) (fn js_as
 	// "function foo(): any {}"
 	if p.options.ts.Parse && p.lexer.Token == js_lexer.TColon {
 		p.lexer.Next()
-		p.skipTypeScriptReturnType()
+		fn.ReturnTSMetadata = p.skipTypeScriptReturnType()
 	}
 
 	// "function foo(): any;"
@@ -5099,7 +5194,6 @@ func (p *parser) parseClassStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt
 	var name *js_ast.LocRef
 	classKeyword := p.lexer.Range()
 	if p.lexer.Token == js_lexer.TClass {
-		p.markSyntaxFeature(compat.Class, classKeyword)
 		p.lexer.Next()
 	} else {
 		p.lexer.Expected(js_lexer.TClass)
@@ -5124,11 +5218,11 @@ func (p *parser) parseClassStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt
 	}
 
 	classOpts := parseClassOpts{
-		allowTSDecorators:   true,
+		allowDecorators:     true,
 		isTypeScriptDeclare: opts.isTypeScriptDeclare,
 	}
-	if opts.tsDecorators != nil {
-		classOpts.tsDecorators = opts.tsDecorators.values
+	if opts.decorators != nil {
+		classOpts.decorators = opts.decorators.values
 	}
 	scopeIndex := p.pushScopeForParsePass(js_ast.ScopeClassName, loc)
 	class := p.parseClass(classKeyword, name, classOpts)
@@ -5147,9 +5241,67 @@ func (p *parser) parseClassStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt
 	return js_ast.Stmt{Loc: loc, Data: &js_ast.SClass{Class: class, IsExport: opts.isExport}}
 }
 
+// TypeScript files use TypeScript's experimental decorators unless they have
+// been disabled with "experimentalDecorators": false in "tsconfig.json", in
+// which case they use the JavaScript decorators proposal like JavaScript files.
+func (p *parser) useLegacyDecorators() bool {
+	return p.options.ts.Parse && p.options.experimentalDecorators != config.False
+}
+
+func (p *parser) parseDecorators() []js_ast.Expr {
+	var decorators []js_ast.Expr
+	for p.lexer.Token == js_lexer.TAt {
+		p.lexer.Next()
+
+		// Parse a new/call expression with "exprFlagDecorator" so we ignore
+		// EIndex expressions, since they may be part of a computed property:
+		//
+		//   class Foo {
+		//     @foo ['computed']() {}
+		//   }
+		//
+		// This matches the behavior of the TypeScript compiler.
+		decorators = append(decorators, p.parseExprWithFlags(js_ast.LNew, exprFlagDecorator))
+	}
+	return decorators
+}
+
+func (p *parser) parseClassExpr(decorators []js_ast.Expr) js_ast.Expr {
+	loc := p.lexer.Loc()
+	classKeyword := p.lexer.Range()
+	p.lexer.Next()
+	var name *js_ast.LocRef
+
+	p.pushScopeForParsePass(js_ast.ScopeClassName, loc)
+
+	// Parse an optional class name
+	if p.lexer.Token == js_lexer.TIdentifier {
+		if nameText := p.lexer.Identifier; !p.options.ts.Parse || nameText != "implements" {
+			if p.fnOrArrowDataParse.await != allowIdent && nameText == "await" {
+				p.log.AddRangeError(&p.tracker, p.lexer.Range(), "Cannot use \"await\" as an identifier here")
+			}
+			name = &js_ast.LocRef{Loc: p.lexer.Loc(), Ref: p.newSymbol(js_ast.SymbolOther, nameText)}
+			p.lexer.Next()
+		}
+	}
+
+	// Even anonymous classes can have TypeScript type parameters
+	if p.options.ts.Parse {
+		p.skipTypeScriptTypeParameters()
+	}
+
+	class := p.parseClass(classKeyword, name, parseClassOpts{
+		decorators:      decorators,
+		allowDecorators: !p.useLegacyDecorators(),
+	})
+
+	p.popScope()
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EClass{Class: class}}
+}
+
 type parseClassOpts struct {
-	tsDecorators        []js_ast.Expr
-	allowTSDecorators   bool
+	decorators          []js_ast.Expr
+	allowDecorators     bool
 	isTypeScriptDeclare bool
 }
 
@@ -5199,9 +5351,9 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 	scopeIndex := p.pushScopeForParsePass(js_ast.ScopeClassBody, bodyLoc)
 
 	opts := propertyOpts{
-		isClass:           true,
-		allowTSDecorators: classOpts.allowTSDecorators,
-		classHasExtends:   extendsOrNil.Data != nil,
+		isClass:         true,
+		allowDecorators: classOpts.allowDecorators,
+		classHasExtends: extendsOrNil.Data != nil,
 	}
 	hasConstructor := false
 
@@ -5213,10 +5365,10 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 
 		// Parse decorators for this property
 		firstDecoratorLoc := p.lexer.Loc()
-		if opts.allowTSDecorators {
-			opts.tsDecorators = p.parseTypeScriptDecorators()
+		if opts.allowDecorators {
+			opts.decorators = p.parseDecorators()
 		} else {
-			opts.tsDecorators = nil
+			opts.decorators = nil
 		}
 
 		// This property may turn out to be a type in TypeScript, which should be ignored
@@ -5225,8 +5377,12 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 
 			// Forbid decorators on class constructors
 			if key, ok := property.Key.Data.(*js_ast.EString); ok && js_lexer.UTF16EqualsString(key.Value, "constructor") {
-				if len(opts.tsDecorators) > 0 {
-					p.log.AddError(&p.tracker, firstDecoratorLoc, "TypeScript does not allow decorators on class constructors")
+				if len(opts.decorators) > 0 {
+					if p.useLegacyDecorators() {
+						p.log.AddError(&p.tracker, firstDecoratorLoc, "TypeScript does not allow decorators on class constructors")
+					} else {
+						p.log.AddError(&p.tracker, firstDecoratorLoc, "Decorators are not allowed on class constructors")
+					}
 				}
 				if property.IsMethod && !property.IsStatic && !property.IsComputed {
 					if hasConstructor {
@@ -5252,7 +5408,7 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 	p.lexer.Expect(js_lexer.TCloseBrace)
 	return js_ast.Class{
 		ClassKeyword: classKeyword,
-		TSDecorators: classOpts.tsDecorators,
+		Decorators:   classOpts.decorators,
 		Name:         name,
 		ExtendsOrNil: extendsOrNil,
 		BodyLoc:      bodyLoc,
@@ -5342,10 +5498,7 @@ func (p *parser) parsePath() (logger.Loc, string, *[]ast.AssertEntry) {
 func (p *parser) parseFnStmt(loc logger.Loc, opts parseStmtOpts, isAsync bool, asyncRange logger.Range) js_ast.Stmt {
 	isGenerator := p.lexer.Token == js_lexer.TAsterisk
 	if isGenerator {
-		p.markSyntaxFeature(compat.Generator, p.lexer.Range())
 		p.lexer.Next()
-	} else if isAsync {
-		p.markLoweredSyntaxFeature(compat.AsyncAwait, asyncRange, compat.Generator)
 	}
 
 	switch opts.lexicalDecl {
@@ -5449,7 +5602,7 @@ func (p *parser) parseFnStmt(loc logger.Loc, opts parseStmtOpts, isAsync bool, a
 	return js_ast.Stmt{Loc: loc, Data: &js_ast.SFunction{Fn: fn, IsExport: opts.isExport}}
 }
 
-type deferredTSDecorators struct {
+type deferredDecorators struct {
 	values []js_ast.Expr
 
 	// If this turns out to be a "declare class" statement, we need to undo the
@@ -5467,7 +5620,7 @@ const (
 )
 
 type parseStmtOpts struct {
-	tsDecorators        *deferredTSDecorators
+	decorators          *deferredDecorators
 	lexicalDecl         lexicalDecl
 	isModuleScope       bool
 	isNamespaceScope    bool
@@ -5502,13 +5655,13 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 		// "@decorator export default abstract class Foo {}"
 		// "@decorator export declare class Foo {}"
 		// "@decorator export declare abstract class Foo {}"
-		if opts.tsDecorators != nil && p.lexer.Token != js_lexer.TClass && p.lexer.Token != js_lexer.TDefault &&
+		if opts.decorators != nil && p.lexer.Token != js_lexer.TClass && p.lexer.Token != js_lexer.TDefault &&
 			!p.lexer.IsContextualKeyword("abstract") && !p.lexer.IsContextualKeyword("declare") {
 			p.lexer.Expected(js_lexer.TClass)
 		}
 
 		switch p.lexer.Token {
-		case js_lexer.TClass, js_lexer.TConst, js_lexer.TFunction, js_lexer.TVar:
+		case js_lexer.TClass, js_lexer.TConst, js_lexer.TFunction, js_lexer.TVar, js_lexer.TAt:
 			opts.isExport = true
 			return p.parseStmt(opts)
 
@@ -5608,7 +5761,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			// TypeScript decorators only work on class declarations
 			// "@decorator export default class Foo {}"
 			// "@decorator export default abstract class Foo {}"
-			if opts.tsDecorators != nil && p.lexer.Token != js_lexer.TClass && !p.lexer.IsContextualKeyword("abstract") {
+			if opts.decorators != nil && p.lexer.Token != js_lexer.TClass && !p.lexer.IsContextualKeyword("abstract") {
 				p.lexer.Expected(js_lexer.TClass)
 			}
 
@@ -5644,9 +5797,11 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 					DefaultName: defaultName, Value: js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: expr}}}}
 			}
 
-			if p.lexer.Token == js_lexer.TFunction || p.lexer.Token == js_lexer.TClass || p.lexer.IsContextualKeyword("interface") {
+			// "export default @decorator class Foo {}"
+			if p.lexer.Token == js_lexer.TFunction || p.lexer.Token == js_lexer.TClass || p.lexer.Token == js_lexer.TAt ||
+				p.lexer.IsContextualKeyword("interface") {
 				stmt := p.parseStmt(parseStmtOpts{
-					tsDecorators:   opts.tsDecorators,
+					decorators:     opts.decorators,
 					isNameOptional: true,
 					lexicalDecl:    lexicalDeclAllowAll,
 				})
@@ -5682,9 +5837,9 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 			// Handle the default export of an abstract class in TypeScript
 			if p.options.ts.Parse && isIdentifier && name == "abstract" {
-				if _, ok := expr.Data.(*js_ast.EIdentifier); ok && (p.lexer.Token == js_lexer.TClass || opts.tsDecorators != nil) {
+				if _, ok := expr.Data.(*js_ast.EIdentifier); ok && (p.lexer.Token == js_lexer.TClass || opts.decorators != nil) {
 					stmt := p.parseClassStmt(loc, parseStmtOpts{
-						tsDecorators:   opts.tsDecorators,
+						decorators:     opts.decorators,
 						isNameOptional: true,
 					})
 
@@ -5795,44 +5950,40 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 	case js_lexer.TAt:
 		// Parse decorators before class statements, which are potentially exported
-		if p.options.ts.Parse {
-			scopeIndex := len(p.scopesInOrder)
-			tsDecorators := p.parseTypeScriptDecorators()
+		scopeIndex := len(p.scopesInOrder)
+		decorators := p.parseDecorators()
 
-			// If this turns out to be a "declare class" statement, we need to undo the
-			// scopes that were potentially pushed while parsing the decorator arguments.
-			// That can look like any one of the following:
-			//
-			//   "@decorator declare class Foo {}"
-			//   "@decorator declare abstract class Foo {}"
-			//   "@decorator export declare class Foo {}"
-			//   "@decorator export declare abstract class Foo {}"
-			//
-			opts.tsDecorators = &deferredTSDecorators{
-				values:     tsDecorators,
-				scopeIndex: scopeIndex,
-			}
-
-			// "@decorator class Foo {}"
-			// "@decorator abstract class Foo {}"
-			// "@decorator declare class Foo {}"
-			// "@decorator declare abstract class Foo {}"
-			// "@decorator export class Foo {}"
-			// "@decorator export abstract class Foo {}"
-			// "@decorator export declare class Foo {}"
-			// "@decorator export declare abstract class Foo {}"
-			// "@decorator export default class Foo {}"
-			// "@decorator export default abstract class Foo {}"
-			if p.lexer.Token != js_lexer.TClass && p.lexer.Token != js_lexer.TExport &&
-				!p.lexer.IsContextualKeyword("abstract") && !p.lexer.IsContextualKeyword("declare") {
-				p.lexer.Expected(js_lexer.TClass)
-			}
+		// If this turns out to be a "declare class" statement, we need to undo the
+		// scopes that were potentially pushed while parsing the decorator arguments.
+		// That can look like any one of the following:
+		//
+		//   "@decorator declare class Foo {}"
+		//   "@decorator declare abstract class Foo {}"
+		//   "@decorator export declare class Foo {}"
+		//   "@decorator export declare abstract class Foo {}"
+		//
+		opts.decorators = &deferredDecorators{
+			values:     decorators,
+			scopeIndex: scopeIndex,
+		}
 
-			return p.parseStmt(opts)
+		// "@decorator class Foo {}"
+		// "@decorator abstract class Foo {}"
+		// "@decorator declare class Foo {}"
+		// "@decorator declare abstract class Foo {}"
+		// "@decorator export class Foo {}"
+		// "@decorator export abstract class Foo {}"
+		// "@decorator export declare class Foo {}"
+		// "@decorator export declare abstract class Foo {}"
+		// "@decorator export default class Foo {}"
+		// "@decorator export default abstract class Foo {}"
+		// "export @decorator class Foo {}"
+		if p.lexer.Token != js_lexer.TClass && (opts.isExport || p.lexer.Token != js_lexer.TExport) &&
+			(!p.options.ts.Parse || (!p.lexer.IsContextualKeyword("abstract") && !p.lexer.IsContextualKeyword("declare"))) {
+			p.lexer.Expected(js_lexer.TClass)
 		}
 
-		p.lexer.Unexpected()
-		return js_ast.Stmt{}
+		return p.parseStmt(opts)
 
 	case js_lexer.TClass:
 		if opts.lexicalDecl != lexicalDeclAllowAll {
@@ -5854,7 +6005,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 		if opts.lexicalDecl != lexicalDeclAllowAll {
 			p.forbidLexicalDecl(loc)
 		}
-		p.markSyntaxFeature(compat.Const, p.lexer.Range())
 		p.lexer.Next()
 
 		if p.options.ts.Parse && p.lexer.Token == js_lexer.TEnum {
@@ -6062,8 +6212,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 				p.log.AddRangeError(&p.tracker, awaitRange, "Cannot use \"await\" outside an async function")
 				isForAwait = false
 			} else {
-				didGenerateError := p.markSyntaxFeature(compat.ForAwait, awaitRange)
-				if p.fnOrArrowDataParse.isTopLevel && !didGenerateError {
+				if p.fnOrArrowDataParse.isTopLevel {
 					p.topLevelAwaitKeyword = awaitRange
 					p.markSyntaxFeature(compat.TopLevelAwait, awaitRange)
 				}
@@ -6095,7 +6244,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			initOrNil = js_ast.Stmt{Loc: initLoc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}}
 
 		case js_lexer.TConst:
-			p.markSyntaxFeature(compat.Const, p.lexer.Range())
 			p.lexer.Next()
 			decls = p.parseAndDeclareDecls(js_ast.SymbolConst, parseStmtOpts{})
 			initOrNil = js_ast.Stmt{Loc: initLoc, Data: &js_ast.SLocal{Kind: js_ast.LocalConst, Decls: decls}}
@@ -6134,7 +6282,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 				}
 			}
 			p.forbidInitializers(decls, "of", false)
-			p.markSyntaxFeature(compat.ForOf, p.lexer.Range())
 			p.lexer.Next()
 			value := p.parseExpr(js_ast.LComma)
 			p.lexer.Expect(js_lexer.TCloseParen)
@@ -6446,7 +6593,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 		if isIdentifier {
 			if ident, ok := expr.Data.(*js_ast.EIdentifier); ok {
-				if p.lexer.Token == js_lexer.TColon && opts.tsDecorators == nil {
+				if p.lexer.Token == js_lexer.TColon && opts.decorators == nil {
 					p.pushScopeForParsePass(js_ast.ScopeLabel, loc)
 					defer p.popScope()
 
@@ -6486,7 +6633,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 						return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
 
 					case "abstract":
-						if p.lexer.Token == js_lexer.TClass || opts.tsDecorators != nil {
+						if p.lexer.Token == js_lexer.TClass || opts.decorators != nil {
 							return p.parseClassStmt(loc, opts)
 						}
 
@@ -6505,7 +6652,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 						// "@decorator declare class Foo {}"
 						// "@decorator declare abstract class Foo {}"
-						if opts.tsDecorators != nil && p.lexer.Token != js_lexer.TClass && !p.lexer.IsContextualKeyword("abstract") {
+						if opts.decorators != nil && p.lexer.Token != js_lexer.TClass && !p.lexer.IsContextualKeyword("abstract") {
 							p.lexer.Expected(js_lexer.TClass)
 						}
 
@@ -6520,8 +6667,8 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 						// "declare const x: any"
 						stmt := p.parseStmt(opts)
-						if opts.tsDecorators != nil {
-							p.discardScopesUpTo(opts.tsDecorators.scopeIndex)
+						if opts.decorators != nil {
+							p.discardScopesUpTo(opts.decorators.scopeIndex)
 						}
 
 						// Unlike almost all uses of "declare", statements that use
@@ -6739,6 +6886,10 @@ func (p *parser) generateTempRef(declare generateTempRefArg, optionalName string
 	ref := p.newSymbol(js_ast.SymbolOther, optionalName)
 	if declare == tempRefNeedsDeclare {
 		p.tempRefsToDeclare = append(p.tempRefsToDeclare, tempRef{ref: ref})
+	} else if scope == p.moduleScope {
+		// The caller declares this temporary itself, but top-level symbols must
+		// still be part of the current part or they won't be renamed when bundling
+		p.declaredSymbols = append(p.declaredSymbols, js_ast.DeclaredSymbol{Ref: ref, IsTopLevel: true})
 	}
 	scope.Generated = append(scope.Generated, ref)
 	return ref
@@ -6772,12 +6923,14 @@ type findSymbolResult struct {
 	ref               js_ast.Ref
 	declareLoc        logger.Loc
 	isInsideWithScope bool
+	isInsideClosure   bool
 }
 
 func (p *parser) findSymbol(loc logger.Loc, name string) findSymbolResult {
 	var ref js_ast.Ref
 	var declareLoc logger.Loc
 	isInsideWithScope := false
+	isInsideClosure := false
 	didForbidArguments := false
 	s := p.currentScope
 
@@ -6801,6 +6954,11 @@ func (p *parser) findSymbol(loc logger.Loc, name string) findSymbolResult {
 			break
 		}
 
+		// Track if the reference is from a nested function or class body
+		if s.Kind == js_ast.ScopeFunctionArgs || s.Kind == js_ast.ScopeClassBody {
+			isInsideClosure = true
+		}
+
 		s = s.Parent
 		if s == nil {
 			// Allocate an "unbound" symbol
@@ -6822,7 +6980,7 @@ func (p *parser) findSymbol(loc logger.Loc, name string) findSymbolResult {
 
 	// Track how many times we've referenced this symbol
 	p.recordUsage(ref)
-	return findSymbolResult{ref, declareLoc, isInsideWithScope}
+	return findSymbolResult{ref, declareLoc, isInsideWithScope, isInsideClosure}
 }
 
 func (p *parser) findLabelSymbol(loc logger.Loc, name string) (ref js_ast.Ref, isLoop bool, ok bool) {
@@ -7074,6 +7232,7 @@ func (p *parser) visitStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
 		kind := js_ast.LocalLet
 		if p.options.unsupportedJSFeatures.Has(compat.Let) {
 			kind = js_ast.LocalVar
+			p.hoistLoweredLexicalDecls(letDecls)
 		}
 		before = append(before[:0], js_ast.Stmt{Loc: letDecls[0].ValueOrNil.Loc, Data: &js_ast.SLocal{Kind: kind, Decls: letDecls}})
 		if len(varDecls) > 0 {
@@ -7873,13 +8032,18 @@ func (p *parser) substituteSingleUseSymbolInExpr(
 	return expr, substituteFailure
 }
 
-func (p *parser) visitLoopBody(stmt js_ast.Stmt) js_ast.Stmt {
+// The head scope is the scope for the "for" loop header, if any. The returned
+// closure is non-nil if the loop body may have to be moved into a closure when
+// lowering "let" and "const".
+func (p *parser) visitLoopBody(stmt js_ast.Stmt, headScope *js_ast.Scope) (js_ast.Stmt, *loopClosure) {
 	oldIsInsideLoop := p.fnOrArrowDataVisit.isInsideLoop
 	p.fnOrArrowDataVisit.isInsideLoop = true
 	p.loopBody = stmt.Data
+	closure := p.pushLoopClosure(stmt, headScope)
 	stmt = p.visitSingleStmt(stmt, stmtsLoopBody)
+	p.popLoopClosure(closure)
 	p.fnOrArrowDataVisit.isInsideLoop = oldIsInsideLoop
-	return stmt
+	return stmt, closure
 }
 
 func (p *parser) visitSingleStmt(stmt js_ast.Stmt, kind stmtsKind) js_ast.Stmt {
@@ -7936,7 +8100,11 @@ func (p *parser) visitForLoopInit(stmt js_ast.Stmt, isInOrOf bool) js_ast.Stmt {
 			}
 		}
 		s.Decls = p.lowerObjectRestInDecls(s.Decls)
+		isLexical := s.Kind != js_ast.LocalVar
 		s.Kind = p.selectLocalKind(s.Kind)
+		if isLexical && s.Kind == js_ast.LocalVar {
+			p.hoistLoweredLexicalDecls(s.Decls)
+		}
 
 	default:
 		panic("Internal error")
@@ -8663,13 +8831,35 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		}
 
 		p.currentScope.Label = js_ast.LocRef{Loc: s.Name.Loc, Ref: ref}
-		switch s.Stmt.Data.(type) {
-		case *js_ast.SFor, *js_ast.SForIn, *js_ast.SForOf, *js_ast.SWhile, *js_ast.SDoWhile:
+		isLoweredForOfLoop := false
+		switch s2 := s.Stmt.Data.(type) {
+		case *js_ast.SForOf:
+			isLoweredForOfLoop = s2.IsAwait || p.options.unsupportedJSFeatures.Has(compat.ForOf)
+			p.currentScope.LabelStmtIsLoop = true
+		case *js_ast.SFor, *js_ast.SForIn, *js_ast.SWhile, *js_ast.SDoWhile:
 			p.currentScope.LabelStmtIsLoop = true
 		}
+		isLoop := p.currentScope.LabelStmtIsLoop
 		s.Stmt = p.visitSingleStmt(s.Stmt, stmtsNormal)
 		p.popScope()
 
+		// Lowered loops may have statements that must come before the loop, such
+		// as the closure for the loop body. These must come before the label too.
+		if block, ok := s.Stmt.Data.(*js_ast.SBlock); ok && isLoop && len(block.Stmts) > 1 {
+			last := len(block.Stmts) - 1
+			stmts = append(stmts, block.Stmts[:last]...)
+			s.Stmt = block.Stmts[last]
+		}
+
+		// Lowered "for-of" and "for await" loops are wrapped in a "try" statement,
+		// so the label must be moved onto the loop inside
+		if try, ok := s.Stmt.Data.(*js_ast.STry); ok && len(try.Body) == 1 {
+			if _, ok := try.Body[0].Data.(*js_ast.SFor); ok && isLoweredForOfLoop {
+				try.Body[0] = js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SLabel{Name: s.Name, Stmt: try.Body[0]}}
+				stmt = s.Stmt
+			}
+		}
+
 	case *js_ast.SLocal:
 		for i := range s.Decls {
 			d := &s.Decls[i]
@@ -8732,10 +8922,24 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		}
 
 		s.Decls = p.lowerObjectRestInDecls(s.Decls)
+		isLexical := s.Kind != js_ast.LocalVar
 		s.Kind = p.selectLocalKind(s.Kind)
 
+		// Lowered "let" and "const" declarations keep their own names instead of
+		// being relocated, and must be reset each time a loop body runs again
+		if isLexical && s.Kind == js_ast.LocalVar {
+			p.hoistLoweredLexicalDecls(s.Decls)
+			if p.fnOrArrowDataVisit.isInsideLoop {
+				for i := range s.Decls {
+					if d := &s.Decls[i]; d.ValueOrNil.Data == nil {
+						d.ValueOrNil = js_ast.Expr{Loc: d.Binding.Loc, Data: js_ast.EUndefinedShared}
+					}
+				}
+			}
+		}
+
 		// Potentially relocate "var" declarations to the top level
-		if s.Kind == js_ast.LocalVar {
+		if s.Kind == js_ast.LocalVar && !isLexical {
 			if assign, ok := p.maybeRelocateVarsToTopLevel(s.Decls, relocateVarsNormal); ok {
 				if assign.Data != nil {
 					stmts = append(stmts, assign)
@@ -8770,7 +8974,11 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			}
 		}
 
-		if s.ValueOrNil.Data != nil {
+		if s.ValueOrNil.Data == nil && !p.fnOrArrowDataVisit.isArrow && p.fnOnlyDataVisit.classMemberForES5 != nil &&
+			p.fnOnlyDataVisit.classMemberForES5.isDerivedCtor {
+			// "return" => "return _this" inside a lowered derived class constructor
+			s.ValueOrNil = js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EIdentifier{Ref: p.captureThis()}}
+		} else if s.ValueOrNil.Data != nil {
 			s.ValueOrNil = p.visitExpr(s.ValueOrNil)
 
 			// Returning undefined is implicit except when inside an async generator
@@ -8815,8 +9023,10 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		p.popScope()
 
 	case *js_ast.SWhile:
+		var closure *loopClosure
 		s.Test = p.visitExpr(s.Test)
-		s.Body = p.visitLoopBody(s.Body)
+		s.Body, closure = p.visitLoopBody(s.Body, nil)
+		stmts = append(stmts, p.lowerLoopClosure(closure, &s.Body, nil)...)
 
 		if p.options.mangleSyntax {
 			s.Test = p.simplifyBooleanExpr(s.Test)
@@ -8834,8 +9044,10 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		}
 
 	case *js_ast.SDoWhile:
-		s.Body = p.visitLoopBody(s.Body)
+		var closure *loopClosure
+		s.Body, closure = p.visitLoopBody(s.Body, nil)
 		s.Test = p.visitExpr(s.Test)
+		stmts = append(stmts, p.lowerLoopClosure(closure, &s.Body, nil)...)
 
 		if p.options.mangleSyntax {
 			s.Test = p.simplifyBooleanExpr(s.Test)
@@ -8886,7 +9098,9 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		}
 
 	case *js_ast.SFor:
+		var closure *loopClosure
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, stmt.Loc)
+		isLexical := isLexicalDecl(s.InitOrNil)
 		if s.InitOrNil.Data != nil {
 			p.visitForLoopInit(s.InitOrNil, false)
 		}
@@ -8907,11 +9121,11 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		if s.UpdateOrNil.Data != nil {
 			s.UpdateOrNil = p.visitExpr(s.UpdateOrNil)
 		}
-		s.Body = p.visitLoopBody(s.Body)
+		s.Body, closure = p.visitLoopBody(s.Body, p.currentScope)
 
 		// Potentially relocate "var" declarations to the top level. Note that this
 		// must be done inside the scope of the for loop or they won't be relocated.
-		if s.InitOrNil.Data != nil {
+		if s.InitOrNil.Data != nil && !isLexical {
 			if init, ok := s.InitOrNil.Data.(*js_ast.SLocal); ok && init.Kind == js_ast.LocalVar {
 				if assign, ok := p.maybeRelocateVarsToTopLevel(init.Decls, relocateVarsNormal); ok {
 					if assign.Data != nil {
@@ -8924,16 +9138,19 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		}
 
 		p.popScope()
+		stmts = append(stmts, p.lowerLoopClosure(closure, &s.Body, s)...)
 
 		if p.options.mangleSyntax {
 			mangleFor(s)
 		}
 
 	case *js_ast.SForIn:
+		var closure *loopClosure
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, stmt.Loc)
+		isLexical := isLexicalDecl(s.Init)
 		p.visitForLoopInit(s.Init, true)
 		s.Value = p.visitExpr(s.Value)
-		s.Body = p.visitLoopBody(s.Body)
+		s.Body, closure = p.visitLoopBody(s.Body, p.currentScope)
 
 		// Check for a variable initializer
 		if local, ok := s.Init.Data.(*js_ast.SLocal); ok && local.Kind == js_ast.LocalVar && len(local.Decls) == 1 {
@@ -8952,7 +9169,7 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 
 		// Potentially relocate "var" declarations to the top level. Note that this
 		// must be done inside the scope of the for loop or they won't be relocated.
-		if init, ok := s.Init.Data.(*js_ast.SLocal); ok && init.Kind == js_ast.LocalVar {
+		if init, ok := s.Init.Data.(*js_ast.SLocal); ok && init.Kind == js_ast.LocalVar && !isLexical {
 			if replacement, ok := p.maybeRelocateVarsToTopLevel(init.Decls, relocateVarsForInOrForOf); ok {
 				s.Init = replacement
 			}
@@ -8961,16 +9178,19 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		p.popScope()
 
 		p.lowerObjectRestInForLoopInit(s.Init, &s.Body)
+		stmts = append(stmts, p.lowerLoopClosure(closure, &s.Body, nil)...)
 
 	case *js_ast.SForOf:
+		var closure *loopClosure
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, stmt.Loc)
+		isLexical := isLexicalDecl(s.Init)
 		p.visitForLoopInit(s.Init, true)
 		s.Value = p.visitExpr(s.Value)
-		s.Body = p.visitLoopBody(s.Body)
+		s.Body, closure = p.visitLoopBody(s.Body, p.currentScope)
 
 		// Potentially relocate "var" declarations to the top level. Note that this
 		// must be done inside the scope of the for loop or they won't be relocated.
-		if init, ok := s.Init.Data.(*js_ast.SLocal); ok && init.Kind == js_ast.LocalVar {
+		if init, ok := s.Init.Data.(*js_ast.SLocal); ok && init.Kind == js_ast.LocalVar && !isLexical {
 			if replacement, ok := p.maybeRelocateVarsToTopLevel(init.Decls, relocateVarsForInOrForOf); ok {
 				s.Init = replacement
 			}
@@ -8979,6 +9199,13 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		p.popScope()
 
 		p.lowerObjectRestInForLoopInit(s.Init, &s.Body)
+		stmts = append(stmts, p.lowerLoopClosure(closure, &s.Body, nil)...)
+
+		// Lower "for-of" and "for await" loops
+		if (s.IsAwait && p.options.unsupportedJSFeatures.Has(compat.ForAwait)) ||
+			(!s.IsAwait && p.options.unsupportedJSFeatures.Has(compat.ForOf)) {
+			return append(stmts, p.lowerForOfToIteratorLoop(stmt.Loc, s, nil))
+		}
 
 	case *js_ast.STry:
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, stmt.Loc)
@@ -9082,7 +9309,14 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		return stmts
 
 	case *js_ast.SEnum:
-		p.recordDeclaredSymbol(s.Name.Ref)
+		// Follow the link chain in case this enum was merged with a later
+		// declaration, so that all blocks of the enum declare the same symbol
+		nameRef := s.Name.Ref
+		for p.symbols[nameRef.InnerIndex].Link != js_ast.InvalidRef {
+			nameRef = p.symbols[nameRef.InnerIndex].Link
+		}
+
+		p.recordDeclaredSymbol(nameRef)
 		p.pushScopeForVisitPass(js_ast.ScopeEntry, stmt.Loc)
 		defer p.popScope()
 		p.recordDeclaredSymbol(s.Arg)
@@ -9103,10 +9337,11 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		nextNumericValue := float64(0)
 		hasNumericValue := true
 		valueExprs := []js_ast.Expr{}
+		valuesCanBeRemovedIfUnused := true
 
 		// Track values so they can be used by constant folding. We need to follow
 		// links here in case the enum was merged with a preceding namespace.
-		valuesSoFar := make(map[string]float64)
+		valuesSoFar := make(map[string]js_ast.TSEnumValue)
 		p.knownEnumValues[s.Name.Ref] = valuesSoFar
 		p.knownEnumValues[s.Arg] = valuesSoFar
 
@@ -9125,16 +9360,20 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			if value.ValueOrNil.Data != nil {
 				value.ValueOrNil = p.visitExpr(value.ValueOrNil)
 				hasNumericValue = false
+				if !p.exprCanBeRemovedIfUnused(value.ValueOrNil) {
+					valuesCanBeRemovedIfUnused = false
+				}
 				switch e := value.ValueOrNil.Data.(type) {
 				case *js_ast.ENumber:
-					valuesSoFar[name] = e.Value
+					valuesSoFar[name] = js_ast.TSEnumValue{Number: e.Value}
 					hasNumericValue = true
 					nextNumericValue = e.Value + 1
 				case *js_ast.EString:
+					valuesSoFar[name] = js_ast.TSEnumValue{String: e.Value}
 					hasStringValue = true
 				}
 			} else if hasNumericValue {
-				valuesSoFar[name] = nextNumericValue
+				valuesSoFar[name] = js_ast.TSEnumValue{Number: nextNumericValue}
 				value.ValueOrNil = js_ast.Expr{Loc: value.Loc, Data: &js_ast.ENumber{Value: nextNumericValue}}
 				nextNumericValue++
 			} else {
@@ -9195,9 +9434,45 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			}
 		}
 
+		// Make the values of top-level enums available to other files so that
+		// property accesses on imported enums can be inlined during linking
+		if p.currentScope.Parent == p.moduleScope {
+			if p.tsEnums == nil {
+				p.tsEnums = make(map[js_ast.Ref]map[string]js_ast.TSEnumValue)
+			}
+			if existing, ok := p.tsEnums[nameRef]; ok {
+				// TypeScript allows splitting an enum into multiple blocks
+				for name, value := range valuesSoFar {
+					existing[name] = value
+				}
+			} else {
+				p.tsEnums[nameRef] = valuesSoFar
+			}
+
+			// Decorator metadata in other files also needs the kind of the values
+			if kind, ok := p.tsEnumMetadataKinds[s.Name.Ref]; ok {
+				if p.tsEnumExportedMetadataKinds == nil {
+					p.tsEnumExportedMetadataKinds = make(map[js_ast.Ref]js_ast.TSMetadataKind)
+				}
+				if existing, ok := p.tsEnumExportedMetadataKinds[nameRef]; ok && existing != kind {
+					kind = js_ast.TSMetadataObject
+				}
+				p.tsEnumExportedMetadataKinds[nameRef] = kind
+			}
+		}
+
 		// Wrap this enum definition in a closure
 		stmts = p.generateClosureForTypeScriptNamespaceOrEnum(
 			stmts, stmt.Loc, s.IsExport, s.Name.Loc, s.Name.Ref, s.Arg, valueStmts)
+
+		// The closure only assigns to properties of the enum object. If none of
+		// the values have side effects, it can be removed if the enum is unused.
+		// This lets enums that have been completely inlined be tree-shaken away.
+		if valuesCanBeRemovedIfUnused {
+			if closure, ok := stmts[len(stmts)-1].Data.(*js_ast.SExpr); ok {
+				closure.DoesNotAffectTreeShaking = true
+			}
+		}
 		return stmts
 
 	case *js_ast.SNamespace:
@@ -9477,15 +9752,15 @@ func (p *parser) captureValueWithPossibleSideEffects(
 	}, wrapFunc
 }
 
-func (p *parser) visitTSDecorators(tsDecorators []js_ast.Expr) []js_ast.Expr {
-	for i, decorator := range tsDecorators {
-		tsDecorators[i] = p.visitExpr(decorator)
+func (p *parser) visitDecorators(decorators []js_ast.Expr) []js_ast.Expr {
+	for i, decorator := range decorators {
+		decorators[i] = p.visitExpr(decorator)
 	}
-	return tsDecorators
+	return decorators
 }
 
 func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class) js_ast.Ref {
-	class.TSDecorators = p.visitTSDecorators(class.TSDecorators)
+	class.Decorators = p.visitDecorators(class.Decorators)
 
 	if class.Name != nil {
 		p.recordDeclaredSymbol(class.Name.Ref)
@@ -9583,9 +9858,23 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class) js_ast
 	p.pushScopeForVisitPass(js_ast.ScopeClassBody, class.BodyLoc)
 	defer p.popScope()
 
+	// If this class will be lowered to an ES5 constructor function, "super"
+	// will become a reference to the argument of the wrapper function that
+	// holds the base class
+	lowerToES5 := p.options.unsupportedJSFeatures.Has(compat.Class)
+	superRef := js_ast.InvalidRef
+	if lowerToES5 && class.ExtendsOrNil.Data != nil {
+		superRef = p.newSymbol(js_ast.SymbolOther, "_super")
+		p.currentScope.Generated = append(p.currentScope.Generated, superRef)
+		if p.classSuperRefs == nil {
+			p.classSuperRefs = make(map[*js_ast.Class]js_ast.Ref)
+		}
+		p.classSuperRefs[class] = superRef
+	}
+
 	for i := range class.Properties {
 		property := &class.Properties[i]
-		property.TSDecorators = p.visitTSDecorators(property.TSDecorators)
+		property.Decorators = p.visitDecorators(property.Decorators)
 		private, isPrivate := property.Key.Data.(*js_ast.EPrivateIdentifier)
 
 		// Special-case EPrivateIdentifier to allow it here
@@ -9625,10 +9914,22 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class) js_ast
 		// The value of "this" is shadowed inside property values
 		oldIsThisCaptured := p.fnOnlyDataVisit.isThisNested
 		oldThis := p.fnOnlyDataVisit.thisClassStaticRef
+		oldClassMember := p.fnOnlyDataVisit.classMemberForES5
 		p.fnOnlyDataVisit.isThisNested = true
 		p.fnOnlyDataVisit.isNewTargetAllowed = true
 		p.fnOnlyDataVisit.thisClassStaticRef = nil
 
+		// Remember how to rewrite "super" and "this" inside this property
+		var classMember *classMemberForES5
+		if lowerToES5 {
+			classMember = &classMemberForES5{superRef: superRef, isStatic: property.IsStatic}
+			if property.IsMethod && !property.IsStatic && !property.IsComputed && superRef != js_ast.InvalidRef {
+				if str, ok := property.Key.Data.(*js_ast.EString); ok && js_lexer.UTF16EqualsString(str.Value, "constructor") {
+					classMember.isDerivedCtor = true
+				}
+			}
+		}
+
 		// We need to explicitly assign the name to the property initializer if it
 		// will be transformed such that it is no longer an inline initializer.
 		nameToKeep := ""
@@ -9643,6 +9944,14 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class) js_ast
 		}
 
 		if property.ValueOrNil.Data != nil {
+			if property.IsMethod {
+				p.nextClassMemberForES5 = classMember
+				if class.ExtendsOrNil.Data != nil && !property.IsStatic && !property.IsComputed {
+					if str, ok := property.Key.Data.(*js_ast.EString); ok && js_lexer.UTF16EqualsString(str.Value, "constructor") {
+						p.nextFnIsDerivedClassCtor = true
+					}
+				}
+			}
 			if nameToKeep != "" {
 				wasAnonymousNamedExpr := p.isAnonymousNamedExpr(property.ValueOrNil)
 				property.ValueOrNil = p.maybeKeepExprSymbolName(p.visitExpr(property.ValueOrNil), nameToKeep, wasAnonymousNamedExpr)
@@ -9651,7 +9960,11 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class) js_ast
 			}
 		}
 
+		p.nextClassMemberForES5 = nil
+		p.nextFnIsDerivedClassCtor = false
+
 		if property.InitializerOrNil.Data != nil {
+			p.fnOnlyDataVisit.classMemberForES5 = classMember
 			if property.IsStatic && replaceThisInStaticFieldInit {
 				// Replace "this" with the class name inside static property initializers
 				p.fnOnlyDataVisit.thisClassStaticRef = &shadowRef
@@ -9667,6 +9980,7 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class) js_ast
 		// Restore "this" so it will take the inherited value in property keys
 		p.fnOnlyDataVisit.thisClassStaticRef = oldThis
 		p.fnOnlyDataVisit.isThisNested = oldIsThisCaptured
+		p.fnOnlyDataVisit.classMemberForES5 = oldClassMember
 
 		// Restore the ability to use "arguments" in decorators and computed properties
 		p.currentScope.ForbidArguments = false
@@ -9751,7 +10065,7 @@ func (p *parser) visitArgs(args []js_ast.Arg, opts visitArgsOpts) {
 
 	for i := range args {
 		arg := &args[i]
-		arg.TSDecorators = p.visitTSDecorators(arg.TSDecorators)
+		arg.Decorators = p.visitDecorators(arg.Decorators)
 		p.visitBinding(arg.Binding, bindingOpts{
 			duplicateArgCheck: duplicateArgCheck,
 		})
@@ -10071,13 +10385,32 @@ func (p *parser) maybeRewritePropertyAccess(
 		// If this is a known enum value, inline the value of the enum
 		if p.options.ts.Parse {
 			if enumValueMap, ok := p.knownEnumValues[id.Ref]; ok {
-				if number, ok := enumValueMap[name]; ok {
-					return js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: number}}, true
+				if value, ok := enumValueMap[name]; ok {
+					// Don't count this as a use of the enum so it can be tree-shaken
+					p.ignoreUsage(id.Ref)
+					return p.tsEnumValueToExpr(loc, value), true
 				}
 			}
 		}
 	}
 
+	// Remember property accesses off of imported symbols so the linker can
+	// avoid depending on the imported symbol if it turns out to be an enum
+	// whose values can all be inlined
+	if id, ok := target.Data.(*js_ast.EImportIdentifier); ok && assignTarget == js_ast.AssignTargetNone && !isDeleteTarget && !p.isControlFlowDead {
+		if p.importSymbolPropertyUses == nil {
+			p.importSymbolPropertyUses = make(map[js_ast.Ref]map[string]js_ast.SymbolUse)
+		}
+		properties := p.importSymbolPropertyUses[id.Ref]
+		if properties == nil {
+			properties = make(map[string]js_ast.SymbolUse)
+			p.importSymbolPropertyUses[id.Ref] = properties
+		}
+		use := properties[name]
+		use.CountEstimate++
+		properties[name] = use
+	}
+
 	// Attempt to simplify statically-determined object literal property accesses
 	if !isCallTarget && p.options.mangleSyntax && assignTarget == js_ast.AssignTargetNone {
 		if object, ok := target.Data.(*js_ast.EObject); ok {
@@ -10600,12 +10933,23 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			return value, exprOut{}
 		}
 
+		// Inside a derived class constructor that will be lowered into an ES5
+		// constructor function, "this" is the value returned by the base class
+		if member := p.fnOnlyDataVisit.classMemberForES5; member != nil && member.isDerivedCtor {
+			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: p.captureThis()}}, exprOut{}
+		}
+
 		// Capture "this" inside arrow functions that will be lowered into normal
 		// function expressions for older language environments
 		if p.fnOrArrowDataVisit.isArrow && p.options.unsupportedJSFeatures.Has(compat.Arrow) && p.fnOnlyDataVisit.isThisNested {
 			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: p.captureThis()}}, exprOut{}
 		}
 
+		// Capture "this" inside loop bodies that may be moved into a closure
+		if p.fnOnlyDataVisit.isInsideLoopClosure && p.fnOnlyDataVisit.isThisNested {
+			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: p.captureThis()}}, exprOut{}
+		}
+
 	case *js_ast.EImportMeta:
 		isDeleteTarget := e == p.deleteTarget
 		isCallTarget := e == p.callTarget
@@ -10632,6 +10976,11 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: p.importMetaRef}}, exprOut{}
 		}
 
+		// Remember that "import.meta" is present in the output
+		if p.importMetaKeyword.Len == 0 {
+			p.importMetaKeyword = js_lexer.RangeOfIdentifier(p.source, expr.Loc)
+		}
+
 	case *js_ast.ESpread:
 		e.Value = p.visitExpr(e.Value)
 
@@ -10645,6 +10994,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		result := p.findSymbol(expr.Loc, name)
 		e.MustKeepDueToWithStmt = result.isInsideWithScope
 		e.Ref = result.ref
+		p.checkLoweredLexicalReference(expr.Loc, name, result, in.assignTarget)
 
 		// Handle assigning to a constant
 		if in.assignTarget != js_ast.AssignTargetNone {
@@ -10655,8 +11005,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 					fmt.Sprintf("%q was declared a constant here", name))}
 
 				// Make this an error when bundling because we may need to convert this
-				// "const" into a "var" during bundling.
-				if p.options.mode == config.ModeBundle {
+				// "const" into a "var" during bundling. The same is true when "const"
+				// is being converted into "var" because it's not supported.
+				if p.options.mode == config.ModeBundle || p.options.unsupportedJSFeatures.Has(compat.Const) {
 					p.log.AddRangeErrorWithNotes(&p.tracker, r, fmt.Sprintf("Cannot assign to %q because it is a constant", name), notes)
 				} else {
 					p.log.AddRangeWarningWithNotes(&p.tracker, r, fmt.Sprintf("This assignment will throw because %q is a constant", name), notes)
@@ -10749,10 +11100,28 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				p.symbols[tag.Ref.InnerIndex].MustStartWithCapitalLetterForJSX = true
 			}
 		} else {
+			// Even with the automatic runtime, "<div {...props} key={key} />" must
+			// use "createElement()" because separating "key" from the props would
+			// change which value for "key" wins
+			shouldUseCreateElement := !p.options.jsx.AutomaticRuntime
+			if !shouldUseCreateElement {
+				seenPropsSpread := false
+				for _, property := range e.Properties {
+					if property.Kind == js_ast.PropertySpread {
+						seenPropsSpread = true
+					} else if str, ok := property.Key.Data.(*js_ast.EString); ok && seenPropsSpread && js_lexer.UTF16EqualsString(str.Value, "key") {
+						shouldUseCreateElement = true
+						break
+					}
+				}
+			}
+
 			// A missing tag is a fragment
 			if e.TagOrNil.Data == nil {
 				var value js_ast.Expr
-				if len(p.options.jsx.Fragment.Parts) > 0 {
+				if p.options.jsx.AutomaticRuntime {
+					value = p.importJSXSymbol(expr.Loc, jsxImportFragment)
+				} else if len(p.options.jsx.Fragment.Parts) > 0 {
 					value = p.jsxStringsToMemberExpression(expr.Loc, p.options.jsx.Fragment.Parts)
 				} else if constant := p.options.jsx.Fragment.Constant; constant != nil {
 					value = js_ast.Expr{Loc: expr.Loc, Data: constant}
@@ -10760,24 +11129,134 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				e.TagOrNil = value
 			}
 
-			// Arguments to createElement()
+			if shouldUseCreateElement {
+				// Arguments to createElement()
+				args := []js_ast.Expr{e.TagOrNil}
+				if len(e.Properties) > 0 {
+					args = append(args, p.lowerObjectSpread(expr.Loc, &js_ast.EObject{
+						Properties: e.Properties,
+					}))
+				} else {
+					args = append(args, js_ast.Expr{Loc: expr.Loc, Data: js_ast.ENullShared})
+				}
+				if len(e.Children) > 0 {
+					args = append(args, e.Children...)
+				}
+
+				// Call createElement()
+				var target js_ast.Expr
+				if p.options.jsx.AutomaticRuntime {
+					target = p.importJSXSymbol(expr.Loc, jsxImportCreateElement)
+				} else {
+					target = p.jsxStringsToMemberExpression(expr.Loc, p.options.jsx.Factory.Parts)
+					p.warnAboutImportNamespaceCall(target, exprKindCall)
+				}
+				return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ECall{
+					Target: target,
+					Args:   args,
+
+					// Enable tree shaking
+					CanBeUnwrappedIfUnused: !p.options.ignoreDCEAnnotations,
+				}}, exprOut{}
+			}
+
+			// Arguments to jsx()
 			args := []js_ast.Expr{e.TagOrNil}
-			if len(e.Properties) > 0 {
-				args = append(args, p.lowerObjectSpread(expr.Loc, &js_ast.EObject{
-					Properties: e.Properties,
-				}))
-			} else {
-				args = append(args, js_ast.Expr{Loc: expr.Loc, Data: js_ast.ENullShared})
+
+			// The "key" prop is passed as a separate argument
+			properties := make([]js_ast.Property, 0, len(e.Properties)+1)
+			key := js_ast.Expr{Loc: expr.Loc, Data: js_ast.EUndefinedShared}
+			hasKey := false
+			for _, property := range e.Properties {
+				if str, ok := property.Key.Data.(*js_ast.EString); ok && property.Kind != js_ast.PropertySpread {
+					switch name := js_lexer.UTF16ToString(str.Value); name {
+					case "key":
+						if property.WasShorthand {
+							r := js_lexer.RangeOfIdentifier(p.source, property.Key.Loc)
+							p.log.AddRangeError(&p.tracker, r,
+								"Please provide an explicit value for \"key\" when using React's \"automatic\" JSX transform")
+							continue
+						}
+						key = property.ValueOrNil
+						hasKey = true
+						continue
+
+					case "__source", "__self":
+						// These are generated automatically by the development runtime
+						if p.options.jsx.Development {
+							r := js_lexer.RangeOfIdentifier(p.source, property.Key.Loc)
+							p.log.AddRangeError(&p.tracker, r,
+								fmt.Sprintf("Duplicate %q prop found when using React's \"automatic\" JSX transform", name))
+							continue
+						}
+					}
+				}
+				properties = append(properties, property)
 			}
+
+			// Children are passed as the "children" prop. Multiple children are
+			// passed as an array, which the runtime treats as static children.
+			isStaticChildren := len(e.Children) > 1
 			if len(e.Children) > 0 {
-				args = append(args, e.Children...)
+				children := e.Children[0]
+				if isStaticChildren {
+					children = js_ast.Expr{Loc: children.Loc, Data: &js_ast.EArray{Items: e.Children}}
+				}
+				properties = append(properties, js_ast.Property{
+					Key:        js_ast.Expr{Loc: children.Loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("children")}},
+					ValueOrNil: children,
+				})
 			}
+			args = append(args, p.lowerObjectSpread(expr.Loc, &js_ast.EObject{
+				Properties: properties,
+			}))
+
+			// The key is only optional for the production runtime
+			if hasKey || p.options.jsx.Development {
+				args = append(args, key)
+			}
+
+			if p.options.jsx.Development {
+				// "isStaticChildren"
+				args = append(args, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EBoolean{Value: isStaticChildren}})
+
+				// "__source"
+				location := logger.LocationOrNil(&p.tracker, logger.Range{Loc: expr.Loc})
+				args = append(args, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EObject{
+					Properties: []js_ast.Property{
+						{
+							Key:        js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("fileName")}},
+							ValueOrNil: js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16(p.source.PrettyPath)}},
+						},
+						{
+							Key:        js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("lineNumber")}},
+							ValueOrNil: js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENumber{Value: float64(location.Line)}},
+						},
+						{
+							Key:        js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("columnNumber")}},
+							ValueOrNil: js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENumber{Value: float64(location.Column + 1)}},
+						},
+					},
+				}})
 
-			// Call createElement()
-			target := p.jsxStringsToMemberExpression(expr.Loc, p.options.jsx.Factory.Parts)
-			p.warnAboutImportNamespaceCall(target, exprKindCall)
+				// "__self" is omitted where "this" would be undefined or where it's
+				// not safe to reference "this" (i.e. before "super()" is called)
+				if !p.fnOnlyDataVisit.isDerivedClassCtor {
+					if p.fnOnlyDataVisit.isThisNested {
+						args = append(args, p.visitExpr(js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EThis{}}))
+					} else if p.options.mode == config.ModePassThrough {
+						args = append(args, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EThis{}})
+					}
+				}
+			}
+
+			// Call jsx()
+			jsx := jsxImportJSX
+			if isStaticChildren {
+				jsx = jsxImportJSXS
+			}
 			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ECall{
-				Target: target,
+				Target: p.importJSXSymbol(expr.Loc, jsx),
 				Args:   args,
 
 				// Enable tree shaking
@@ -10869,6 +11348,16 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			break
 		}
 
+		// Lower "super.prop = value" inside an ES5 class so that setters are
+		// called with the right "this" and other values end up on "this"
+		if e.Op == js_ast.BinOpAssign && p.fnOnlyDataVisit.classMemberForES5 != nil {
+			if key, ok := extractSuperProperty(e.Left); ok {
+				key = p.visitExpr(key)
+				e.Right = p.visitExpr(e.Right)
+				return p.lowerSuperPropertySetES5(e.Left.Loc, key, e.Right), exprOut{}
+			}
+		}
+
 		isCallTarget := e == p.callTarget
 		isTemplateTag := e == p.templateTag
 		isStmtExpr := e == p.stmtExprValue
@@ -11413,6 +11902,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 
 		// Lower "super[prop]" if necessary
 		if !isCallTarget && p.shouldLowerSuperPropertyAccess(e.Target) {
+			if in.assignTarget != js_ast.AssignTargetNone && p.fnOnlyDataVisit.classMemberForES5 != nil {
+				return p.lowerSuperPropertyAssignTargetES5(expr.Loc, e.Index), exprOut{}
+			}
 			return p.lowerSuperPropertyAccess(expr.Loc, e.Index), exprOut{}
 		}
 
@@ -11614,6 +12106,14 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 		}
 
+		// Substitute "import.meta.hot" with the hot module replacement API of the
+		// module, which is passed to the CommonJS closure that wraps each module
+		if _, ok := e.Target.Data.(*js_ast.EImportMeta); ok && e.Name == "hot" && p.options.hotModuleReplacement {
+			p.recordUsage(p.moduleRef)
+			e.Target = js_ast.Expr{Loc: e.Target.Loc, Data: &js_ast.EIdentifier{Ref: p.moduleRef}}
+			return expr, exprOut{}
+		}
+
 		// Track ".then().catch()" chains
 		if isCallTarget && p.thenCatchChain.nextTarget == e {
 			if e.Name == "catch" {
@@ -11637,6 +12137,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		// Lower "super.prop" if necessary
 		if !isCallTarget && p.shouldLowerSuperPropertyAccess(e.Target) {
 			key := js_ast.Expr{Loc: e.NameLoc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16(e.Name)}}
+			if in.assignTarget != js_ast.AssignTargetNone && p.fnOnlyDataVisit.classMemberForES5 != nil {
+				return p.lowerSuperPropertyAssignTargetES5(expr.Loc, key), exprOut{}
+			}
 			return p.lowerSuperPropertyAccess(expr.Loc, key), exprOut{}
 		}
 
@@ -11736,29 +12239,43 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		e.Value = p.visitExpr(e.Value)
 
 		// "await" expressions turn into "yield" expressions when lowering
-		if p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) {
-			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EYield{ValueOrNil: e.Value}}, exprOut{}
+		value := p.lowerAwait(expr.Loc, e.Value)
+		_, isAwait := value.Data.(*js_ast.EAwait)
+		for c := p.fnOrArrowDataVisit.loopClosure; c != nil; c = c.parent {
+			c.hasAwait = c.hasAwait || isAwait
+			c.hasYield = c.hasYield || !isAwait
 		}
+		return value, exprOut{}
 
 	case *js_ast.EYield:
 		if e.ValueOrNil.Data != nil {
 			e.ValueOrNil = p.visitExpr(e.ValueOrNil)
 		}
+		for c := p.fnOrArrowDataVisit.loopClosure; c != nil; c = c.parent {
+			c.hasYield = true
+		}
+
+		// "yield* x" in a lowered async generator delegates to an async iterator
+		if e.IsStar && p.fnOrArrowDataVisit.isAsync && p.options.unsupportedJSFeatures.Has(compat.AsyncGenerator) {
+			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EYield{ValueOrNil: js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENew{
+				Target: p.importFromRuntime(expr.Loc, "__awaitValue"),
+				Args:   []js_ast.Expr{e.ValueOrNil, {Loc: expr.Loc, Data: &js_ast.ENumber{Value: 1}}},
+			}}}}, exprOut{}
+		}
 
 	case *js_ast.EArray:
 		if in.assignTarget != js_ast.AssignTargetNone {
 			if e.CommaAfterSpread.Start != 0 {
 				p.log.AddRangeError(&p.tracker, logger.Range{Loc: e.CommaAfterSpread, Len: 1}, "Unexpected \",\" after rest pattern")
 			}
-			p.markSyntaxFeature(compat.Destructuring, logger.Range{Loc: expr.Loc, Len: 1})
 		}
-		hasSpread := false
+		hasSpreadItem := false
 		for i, item := range e.Items {
 			switch e2 := item.Data.(type) {
 			case *js_ast.EMissing:
 			case *js_ast.ESpread:
 				e2.Value, _ = p.visitExprInOut(e2.Value, exprIn{assignTarget: in.assignTarget})
-				hasSpread = true
+				hasSpreadItem = true
 			case *js_ast.EBinary:
 				if in.assignTarget != js_ast.AssignTargetNone && e2.Op == js_ast.BinOpAssign {
 					wasAnonymousNamedExpr := p.isAnonymousNamedExpr(e2.Right)
@@ -11780,16 +12297,20 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		}
 
 		// "[1, ...[2, 3], 4]" => "[1, 2, 3, 4]"
-		if p.options.mangleSyntax && hasSpread && in.assignTarget == js_ast.AssignTargetNone {
+		if p.options.mangleSyntax && hasSpreadItem && in.assignTarget == js_ast.AssignTargetNone {
 			e.Items = inlineSpreadsOfArrayLiterals(e.Items)
 		}
 
+		// "[a, ...b]" => "[a].concat(__toArray(b))"
+		if in.assignTarget == js_ast.AssignTargetNone && p.options.unsupportedJSFeatures.Has(compat.ArraySpread) && hasSpread(e.Items) {
+			return p.lowerSpreadToArray(expr.Loc, e.Items, true), exprOut{}
+		}
+
 	case *js_ast.EObject:
 		if in.assignTarget != js_ast.AssignTargetNone {
 			if e.CommaAfterSpread.Start != 0 {
 				p.log.AddRangeError(&p.tracker, logger.Range{Loc: e.CommaAfterSpread, Len: 1}, "Unexpected \",\" after rest pattern")
 			}
-			p.markSyntaxFeature(compat.Destructuring, logger.Range{Loc: expr.Loc, Len: 1})
 		}
 		hasSpread := false
 		hasProto := false
@@ -12157,11 +12678,11 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		e.Target = target
 		p.warnAboutImportNamespaceCall(e.Target, exprKindCall)
 
-		hasSpread := false
+		hasSpreadArg := false
 		for i, arg := range e.Args {
 			arg = p.visitExpr(arg)
 			if _, ok := arg.Data.(*js_ast.ESpread); ok {
-				hasSpread = true
+				hasSpreadArg = true
 			}
 			e.Args[i] = arg
 		}
@@ -12207,7 +12728,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		}
 
 		// "foo(1, ...[2, 3], 4)" => "foo(1, 2, 3, 4)"
-		if p.options.mangleSyntax && hasSpread && in.assignTarget == js_ast.AssignTargetNone {
+		if p.options.mangleSyntax && hasSpreadArg && in.assignTarget == js_ast.AssignTargetNone {
 			e.Args = inlineSpreadsOfArrayLiterals(e.Args)
 		}
 
@@ -12279,17 +12800,21 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			if target, loc, private := p.extractPrivateIndex(e.Target); private != nil {
 				// "foo.#bar(123)" => "__privateGet(_a = foo, #bar).call(_a, 123)"
 				targetFunc, targetWrapFunc := p.captureValueWithPossibleSideEffects(target.Loc, 2, target, valueCouldBeMutated)
-				return targetWrapFunc(js_ast.Expr{Loc: target.Loc, Data: &js_ast.ECall{
-					Target: js_ast.Expr{Loc: target.Loc, Data: &js_ast.EDot{
-						Target:  p.lowerPrivateGet(targetFunc(), loc, private),
-						Name:    "call",
-						NameLoc: target.Loc,
-					}},
-					Args:                   append([]js_ast.Expr{targetFunc()}, e.Args...),
-					CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
-				}}), exprOut{}
+				return targetWrapFunc(p.callWithThisArg(target.Loc, p.lowerPrivateGet(targetFunc(), loc, private),
+					targetFunc(), e.Args, e.CanBeUnwrappedIfUnused)), exprOut{}
 			}
 			p.maybeLowerSuperPropertyAccessInsideCall(e)
+
+			// "super(a, b)" => "_this = _super.call(this, a, b) || this"
+			if _, ok := e.Target.Data.(*js_ast.ESuper); ok && p.fnOnlyDataVisit.classMemberForES5 != nil &&
+				p.fnOnlyDataVisit.classMemberForES5.isDerivedCtor {
+				return p.lowerSuperCallES5(expr.Loc, e.Args), exprOut{}
+			}
+
+			// "foo(...a)" => "foo.apply(void 0, __toArray(a))"
+			if _, ok := e.Target.Data.(*js_ast.ESuper); !ok && p.options.unsupportedJSFeatures.Has(compat.ArraySpread) && hasSpread(e.Args) {
+				return p.lowerCallSpread(expr.Loc, e), exprOut{}
+			}
 		}
 
 		// Track calls to require() so we can use them while bundling
@@ -12367,10 +12892,30 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		e.Target = p.visitExpr(e.Target)
 		p.warnAboutImportNamespaceCall(e.Target, exprKindNew)
 
+		// Check for "new URL(path, import.meta.url)" before visiting the
+		// arguments since "import.meta" may be substituted during the visit
+		isNewURL := p.isNewURLWithImportMeta(e)
+
 		for i, arg := range e.Args {
 			e.Args[i] = p.visitExpr(arg)
 		}
 
+		// Treat the path as an import of an asset when bundling
+		if isNewURL {
+			str := e.Args[0].Data.(*js_ast.EString)
+			importRecordIndex := p.addImportRecord(ast.ImportNewURL, e.Args[0].Loc, js_lexer.UTF16ToString(str.Value), nil)
+			p.importRecordsForCurrentPart = append(p.importRecordsForCurrentPart, importRecordIndex)
+			e.Args[0] = js_ast.Expr{Loc: e.Args[0].Loc, Data: &js_ast.EURLString{ImportRecordIndex: importRecordIndex}}
+		}
+
+		// The URL passed to "new Worker()" is bundled as a separate entry point
+		p.maybeMarkWorkerURL(e)
+
+		// "new Foo(...a)" => "__construct(Foo, __toArray(a))"
+		if p.options.unsupportedJSFeatures.Has(compat.ArraySpread) && hasSpread(e.Args) {
+			return p.callRuntime(expr.Loc, "__construct", []js_ast.Expr{e.Target, p.lowerSpreadToArray(expr.Loc, e.Args, false)}), exprOut{}
+		}
+
 	case *js_ast.EArrow:
 		oldFnOrArrowData := p.fnOrArrowDataVisit
 		p.fnOrArrowDataVisit = fnOrArrowDataVisit{
@@ -12396,7 +12941,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		p.pushScopeForVisitPass(js_ast.ScopeFunctionBody, e.Body.Loc)
 		e.Body.Stmts = p.visitStmtsAndPrependTempRefs(e.Body.Stmts, prependTempRefsOpts{kind: stmtsFnBody})
 		p.popScope()
-		p.lowerFunction(&e.IsAsync, &e.Args, e.Body.Loc, &e.Body.Stmts, &e.PreferExpr, &e.HasRestArg, true /* isArrow */)
+		p.lowerFunction(&e.IsAsync, nil, &e.Args, e.Body.Loc, &e.Body.Stmts, &e.PreferExpr, &e.HasRestArg, true /* isArrow */)
 		p.popScope()
 
 		if p.options.mangleSyntax && len(e.Body.Stmts) == 1 {
@@ -12452,6 +12997,61 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 	return expr, exprOut{}
 }
 
+// Returns true for "new URL('./path', import.meta.url)" where "URL" is the
+// global. Only relative paths are considered since other strings are either
+// absolute URLs or would be resolved relative to the page instead.
+func (p *parser) isNewURLWithImportMeta(e *js_ast.ENew) bool {
+	if p.options.mode != config.ModeBundle || p.isControlFlowDead || len(e.Args) != 2 || !p.isGlobalIdentifier(e.Target, "URL") {
+		return false
+	}
+	if dot, ok := e.Args[1].Data.(*js_ast.EDot); !ok || dot.Name != "url" {
+		return false
+	} else if _, ok := dot.Target.Data.(*js_ast.EImportMeta); !ok {
+		return false
+	}
+	str, ok := e.Args[0].Data.(*js_ast.EString)
+	if !ok {
+		return false
+	}
+	path := js_lexer.UTF16ToString(str.Value)
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
+}
+
+// Changes the import record for "new Worker(new URL(path, import.meta.url))"
+// and for "new SharedWorker(...)" to a worker import. Passing the option
+// "{ type: 'module' }" makes the worker a module worker.
+func (p *parser) maybeMarkWorkerURL(e *js_ast.ENew) {
+	if len(e.Args) < 1 || len(e.Args) > 2 || (!p.isGlobalIdentifier(e.Target, "Worker") && !p.isGlobalIdentifier(e.Target, "SharedWorker")) {
+		return
+	}
+	url, ok := e.Args[0].Data.(*js_ast.ENew)
+	if !ok || len(url.Args) != 2 {
+		return
+	}
+	str, ok := url.Args[0].Data.(*js_ast.EURLString)
+	if !ok {
+		return
+	}
+	kind := ast.ImportWorker
+	if len(e.Args) == 2 {
+		if object, ok := e.Args[1].Data.(*js_ast.EObject); ok {
+			for _, property := range object.Properties {
+				if key, ok := property.Key.Data.(*js_ast.EString); ok && js_lexer.UTF16EqualsString(key.Value, "type") {
+					if value, ok := property.ValueOrNil.Data.(*js_ast.EString); ok && js_lexer.UTF16EqualsString(value.Value, "module") {
+						kind = ast.ImportModuleWorker
+					}
+				}
+			}
+		}
+	}
+	p.importRecords[str.ImportRecordIndex].Kind = kind
+}
+
+func (p *parser) isGlobalIdentifier(expr js_ast.Expr, name string) bool {
+	id, ok := expr.Data.(*js_ast.EIdentifier)
+	return ok && p.symbols[id.Ref.InnerIndex].Kind == js_ast.SymbolUnbound && p.symbols[id.Ref.InnerIndex].OriginalName == name
+}
+
 func (p *parser) warnAboutImportNamespaceCall(target js_ast.Expr, kind importNamespaceCallKind) {
 	if p.options.outputFormat != config.FormatPreserve {
 		if id, ok := target.Data.(*js_ast.EIdentifier); ok && p.importItemsForNamespace[id.Ref] != nil {
@@ -12549,7 +13149,7 @@ func (p *parser) handleIdentifier(loc logger.Loc, e *js_ast.EIdentifier, opts id
 	if p.fnOnlyDataVisit.argumentsRef != nil && ref == *p.fnOnlyDataVisit.argumentsRef {
 		isInsideUnsupportedArrow := p.fnOrArrowDataVisit.isArrow && p.options.unsupportedJSFeatures.Has(compat.Arrow)
 		isInsideUnsupportedAsyncArrow := p.fnOnlyDataVisit.isInsideAsyncArrowFn && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait)
-		if isInsideUnsupportedArrow || isInsideUnsupportedAsyncArrow {
+		if isInsideUnsupportedArrow || isInsideUnsupportedAsyncArrow || p.fnOnlyDataVisit.isInsideLoopClosure {
 			return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.captureArguments()}}
 		}
 	}
@@ -12576,8 +13176,8 @@ func (p *parser) handleIdentifier(loc logger.Loc, e *js_ast.EIdentifier, opts id
 
 			// If this is a known enum value, inline the value of the enum
 			if enumValueMap, ok := p.knownEnumValues[nsRef]; ok {
-				if number, ok := enumValueMap[name]; ok {
-					return js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: number}}
+				if value, ok := enumValueMap[name]; ok {
+					return p.tsEnumValueToExpr(loc, value)
 				}
 			}
 
@@ -12619,7 +13219,11 @@ func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc) {
 		isThisNested:       true,
 		isNewTargetAllowed: true,
 		argumentsRef:       &fn.ArgumentsRef,
+		classMemberForES5:  p.nextClassMemberForES5,
+		isDerivedClassCtor: p.nextFnIsDerivedClassCtor,
 	}
+	p.nextClassMemberForES5 = nil
+	p.nextFnIsDerivedClassCtor = false
 
 	if fn.Name != nil {
 		p.recordDeclaredSymbol(fn.Name.Ref)
@@ -12637,7 +13241,8 @@ func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc) {
 	}
 	fn.Body.Stmts = p.visitStmtsAndPrependTempRefs(fn.Body.Stmts, prependTempRefsOpts{fnBodyLoc: &fn.Body.Loc, kind: stmtsFnBody})
 	p.popScope()
-	p.lowerFunction(&fn.IsAsync, &fn.Args, fn.Body.Loc, &fn.Body.Stmts, nil, &fn.HasRestArg, false /* isArrow */)
+	p.lowerDerivedCtorBodyES5(&fn.Body)
+	p.lowerFunction(&fn.IsAsync, &fn.IsGenerator, &fn.Args, fn.Body.Loc, &fn.Body.Stmts, nil, &fn.HasRestArg, false /* isArrow */)
 	p.popScope()
 
 	p.fnOrArrowDataVisit = oldFnOrArrowData
@@ -12996,10 +13601,11 @@ func (p *parser) scanForImportsAndExports(stmts []js_ast.Stmt) (result importsEx
 
 				if s.DefaultName != nil {
 					p.namedImports[s.DefaultName.Ref] = js_ast.NamedImport{
-						Alias:             "default",
-						AliasLoc:          s.DefaultName.Loc,
-						NamespaceRef:      s.NamespaceRef,
-						ImportRecordIndex: s.ImportRecordIndex,
+						Alias:                   "default",
+						AliasLoc:                s.DefaultName.Loc,
+						NamespaceRef:            s.NamespaceRef,
+						ImportRecordIndex:       s.ImportRecordIndex,
+						IsOnlyUsedForTSMetadata: p.isOnlyUsedForTSMetadata(s.DefaultName.Ref),
 					}
 				}
 
@@ -13015,10 +13621,11 @@ func (p *parser) scanForImportsAndExports(stmts []js_ast.Stmt) (result importsEx
 				if s.Items != nil {
 					for _, item := range *s.Items {
 						p.namedImports[item.Name.Ref] = js_ast.NamedImport{
-							Alias:             item.Alias,
-							AliasLoc:          item.AliasLoc,
-							NamespaceRef:      s.NamespaceRef,
-							ImportRecordIndex: s.ImportRecordIndex,
+							Alias:                   item.Alias,
+							AliasLoc:                item.AliasLoc,
+							NamespaceRef:            s.NamespaceRef,
+							ImportRecordIndex:       s.ImportRecordIndex,
+							IsOnlyUsedForTSMetadata: p.isOnlyUsedForTSMetadata(item.Name.Ref),
 						}
 					}
 				}
@@ -13118,6 +13725,7 @@ func (p *parser) scanForImportsAndExports(stmts []js_ast.Stmt) (result importsEx
 
 func (p *parser) appendPart(parts []js_ast.Part, stmts []js_ast.Stmt) []js_ast.Part {
 	p.symbolUses = make(map[js_ast.Ref]js_ast.SymbolUse)
+	p.importSymbolPropertyUses = nil
 	p.declaredSymbols = nil
 	p.importRecordsForCurrentPart = nil
 	p.scopesForCurrentPart = nil
@@ -13126,6 +13734,7 @@ func (p *parser) appendPart(parts []js_ast.Part, stmts []js_ast.Stmt) []js_ast.P
 		Stmts:      p.visitStmtsAndPrependTempRefs(stmts, prependTempRefsOpts{}),
 		SymbolUses: p.symbolUses,
 	}
+	part.ImportSymbolPropertyUses = p.importSymbolPropertyUses
 
 	// Insert any relocated variable statements now
 	if len(p.relocatedTopLevelVars) > 0 {
@@ -13728,6 +14337,8 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 		allowIn:           true,
 		options:           *options,
 		runtimeImports:    make(map[string]js_ast.Ref),
+		jsxRuntimeImports: make(map[string]js_ast.Ref),
+		jsxLegacyImports:  make(map[string]js_ast.Ref),
 		promiseRef:        js_ast.InvalidRef,
 		afterArrowBodyLoc: logger.Loc{Start: -1},
 
@@ -13740,8 +14351,10 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 		// These are for TypeScript
 		emittedNamespaceVars:      make(map[js_ast.Ref]bool),
 		isExportedInsideNamespace: make(map[js_ast.Ref]js_ast.Ref),
-		knownEnumValues:           make(map[js_ast.Ref]map[string]float64),
+		knownEnumValues:           make(map[js_ast.Ref]map[string]js_ast.TSEnumValue),
 		localTypeNames:            make(map[string]bool),
+		tsMetadataUseCounts:       make(map[js_ast.Ref]uint32),
+		tsEnumMetadataKinds:       make(map[js_ast.Ref]js_ast.TSMetadataKind),
 
 		// These are for handling ES6 imports and exports
 		importItemsForNamespace: make(map[js_ast.Ref]map[string]js_ast.LocRef),
@@ -13770,6 +14383,8 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 var defaultJSXFactory = []string{"React", "createElement"}
 var defaultJSXFragment = []string{"React", "Fragment"}
 
+const defaultJSXImportSource = "react"
+
 func Parse(log logger.Log, source logger.Source, options Options) (result js_ast.AST, ok bool) {
 	ok = true
 	defer func() {
@@ -13788,6 +14403,9 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 	if len(options.jsx.Fragment.Parts) == 0 && options.jsx.Fragment.Constant == nil {
 		options.jsx.Fragment = config.JSXExpr{Parts: defaultJSXFragment}
 	}
+	if options.jsx.ImportSource == "" {
+		options.jsx.ImportSource = defaultJSXImportSource
+	}
 
 	if !options.ts.Parse {
 		// Non-TypeScript files always get the real JavaScript class field behavior
@@ -13907,7 +14525,7 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 				}
 			}
 		}
-		before = p.generateImportStmt(file.Source.KeyPath.Text, exportsNoConflict, file.Source.Index, before, symbols)
+		before = p.generateImportStmt(file.Source.KeyPath.Text, exportsNoConflict, ast.MakeIndex32(file.Source.Index), before, symbols)
 	}
 
 	// Bind symbols in a second pass over the AST. I started off doing this in a
@@ -14110,19 +14728,50 @@ func (p *parser) prepareForVisitPass() {
 		p.importMetaRef = js_ast.InvalidRef
 	}
 
-	// Handle "@jsx" and "@jsxFrag" pragmas now that lexing is done
+	// Handle "@jsx", "@jsxFrag", "@jsxRuntime", and "@jsxImportSource" pragmas
+	// now that lexing is done
 	if p.options.jsx.Parse {
-		if expr, ok := ParseJSXExpr(p.lexer.JSXFactoryPragmaComment.Text, JSXFactory); !ok {
-			p.log.AddRangeWarning(&p.tracker, p.lexer.JSXFactoryPragmaComment.Range,
-				fmt.Sprintf("Invalid JSX factory: %s", p.lexer.JSXFactoryPragmaComment.Text))
-		} else if len(expr.Parts) > 0 {
-			p.options.jsx.Factory = expr
+		if jsxRuntime := p.lexer.JSXRuntimePragmaComment; jsxRuntime.Text != "" {
+			switch jsxRuntime.Text {
+			case "automatic":
+				p.options.jsx.AutomaticRuntime = true
+			case "classic":
+				p.options.jsx.AutomaticRuntime = false
+			default:
+				p.log.AddRangeWarning(&p.tracker, jsxRuntime.Range,
+					fmt.Sprintf("Invalid JSX runtime: %s", jsxRuntime.Text))
+			}
 		}
-		if expr, ok := ParseJSXExpr(p.lexer.JSXFragmentPragmaComment.Text, JSXFragment); !ok {
-			p.log.AddRangeWarning(&p.tracker, p.lexer.JSXFragmentPragmaComment.Range,
-				fmt.Sprintf("Invalid JSX fragment: %s", p.lexer.JSXFragmentPragmaComment.Text))
-		} else if len(expr.Parts) > 0 || expr.Constant != nil {
-			p.options.jsx.Fragment = expr
+
+		if p.options.jsx.AutomaticRuntime {
+			if factory := p.lexer.JSXFactoryPragmaComment; factory.Text != "" {
+				p.log.AddRangeWarning(&p.tracker, factory.Range,
+					"The JSX factory cannot be set when using React's \"automatic\" JSX transform")
+			}
+			if fragment := p.lexer.JSXFragmentPragmaComment; fragment.Text != "" {
+				p.log.AddRangeWarning(&p.tracker, fragment.Range,
+					"The JSX fragment cannot be set when using React's \"automatic\" JSX transform")
+			}
+			if importSource := p.lexer.JSXImportSourcePragmaComment; importSource.Text != "" {
+				p.options.jsx.ImportSource = importSource.Text
+			}
+		} else {
+			if expr, ok := ParseJSXExpr(p.lexer.JSXFactoryPragmaComment.Text, JSXFactory); !ok {
+				p.log.AddRangeWarning(&p.tracker, p.lexer.JSXFactoryPragmaComment.Range,
+					fmt.Sprintf("Invalid JSX factory: %s", p.lexer.JSXFactoryPragmaComment.Text))
+			} else if len(expr.Parts) > 0 {
+				p.options.jsx.Factory = expr
+			}
+			if expr, ok := ParseJSXExpr(p.lexer.JSXFragmentPragmaComment.Text, JSXFragment); !ok {
+				p.log.AddRangeWarning(&p.tracker, p.lexer.JSXFragmentPragmaComment.Range,
+					fmt.Sprintf("Invalid JSX fragment: %s", p.lexer.JSXFragmentPragmaComment.Text))
+			} else if len(expr.Parts) > 0 || expr.Constant != nil {
+				p.options.jsx.Fragment = expr
+			}
+			if importSource := p.lexer.JSXImportSourcePragmaComment; importSource.Text != "" {
+				p.log.AddRangeWarning(&p.tracker, importSource.Range,
+					"The JSX import source cannot be set without also enabling React's \"automatic\" JSX transform")
+			}
 		}
 	}
 }
@@ -14217,7 +14866,7 @@ func (p *parser) computeCharacterFrequency() *js_ast.CharFreq {
 func (p *parser) generateImportStmt(
 	path string,
 	imports []string,
-	sourceIndex uint32,
+	sourceIndex ast.Index32,
 	parts []js_ast.Part,
 	symbols map[string]js_ast.Ref,
 ) []js_ast.Part {
@@ -14226,7 +14875,7 @@ func (p *parser) generateImportStmt(
 	declaredSymbols := make([]js_ast.DeclaredSymbol, len(imports))
 	clauseItems := make([]js_ast.ClauseItem, len(imports))
 	importRecordIndex := p.addImportRecord(ast.ImportStmt, logger.Loc{}, path, nil)
-	p.importRecords[importRecordIndex].SourceIndex = ast.MakeIndex32(sourceIndex)
+	p.importRecords[importRecordIndex].SourceIndex = sourceIndex
 
 	// Create per-import information
 	for i, alias := range imports {
@@ -14254,16 +14903,41 @@ func (p *parser) generateImportStmt(
 	})
 }
 
+func sortedKeysOfMapStringRef(in map[string]js_ast.Ref) []string {
+	keys := make([]string, 0, len(in))
+	for key := range in {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (p *parser) toAST(parts []js_ast.Part, hashbang string, directive string) js_ast.AST {
 	// Insert an import statement for any runtime imports we generated
 	if len(p.runtimeImports) > 0 && !p.options.omitRuntimeForTests {
 		// Sort the imports for determinism
-		keys := make([]string, 0, len(p.runtimeImports))
-		for key := range p.runtimeImports {
-			keys = append(keys, key)
+		keys := sortedKeysOfMapStringRef(p.runtimeImports)
+		parts = p.generateImportStmt("<runtime>", keys, ast.MakeIndex32(runtime.SourceIndex), parts, p.runtimeImports)
+	}
+
+	// Insert import statements for any JSX runtime imports we generated. These
+	// are resolved like any other import path and go at the top of the file.
+	var jsxParts []js_ast.Part
+	if len(p.jsxRuntimeImports) > 0 {
+		keys := sortedKeysOfMapStringRef(p.jsxRuntimeImports)
+		path := p.options.jsx.ImportSource + "/jsx-runtime"
+		if p.options.jsx.Development {
+			path = p.options.jsx.ImportSource + "/jsx-dev-runtime"
 		}
-		sort.Strings(keys)
-		parts = p.generateImportStmt("<runtime>", keys, runtime.SourceIndex, parts, p.runtimeImports)
+		jsxParts = p.generateImportStmt(path, keys, ast.Index32{}, jsxParts, p.jsxRuntimeImports)
+	}
+	if len(p.jsxLegacyImports) > 0 {
+		keys := sortedKeysOfMapStringRef(p.jsxLegacyImports)
+		jsxParts = p.generateImportStmt(p.options.jsx.ImportSource, keys, ast.Index32{}, jsxParts, p.jsxLegacyImports)
+	}
+	if len(jsxParts) > 0 {
+		// Keep the namespace export part first
+		parts = append(parts[:js_ast.NSExportPartIndex+1], append(jsxParts, parts[js_ast.NSExportPartIndex+1:]...)...)
 	}
 
 	// Handle import paths after the whole file has been visited because we need
@@ -14444,6 +15118,8 @@ func (p *parser) toAST(parts []js_ast.Part, hashbang string, directive string) j
 		NamedExports:                    p.namedExports,
 		NestedScopeSlotCounts:           nestedScopeSlotCounts,
 		TopLevelSymbolToPartsFromParser: p.topLevelSymbolToParts,
+		TSEnums:                         p.tsEnums,
+		TSEnumMetadataKinds:             p.tsEnumExportedMetadataKinds,
 		ExportStarImportRecords:         p.exportStarImportRecords,
 		ImportRecords:                   p.importRecords,
 		ApproximateLineCount:            int32(p.lexer.ApproximateNewlineCount) + 1,
@@ -14457,5 +15133,6 @@ func (p *parser) toAST(parts []js_ast.Part, hashbang string, directive string) j
 		ImportKeyword:        p.es6ImportKeyword,
 		ExportKeyword:        p.es6ExportKeyword,
 		TopLevelAwaitKeyword: p.topLevelAwaitKeyword,
+		ImportMetaKeyword:    p.importMetaKeyword,
 	}
 }
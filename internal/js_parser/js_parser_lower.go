@@ -6,6 +6,7 @@ package js_parser
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/trustelem/esbuild/internal/compat"
 	"github.com/trustelem/esbuild/internal/config"
@@ -31,7 +32,15 @@ func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (di
 	didGenerateError = true
 
 	if !p.options.unsupportedJSFeatures.Has(feature) {
-		if feature == compat.TopLevelAwait && !p.options.outputFormat.KeepES6ImportExportSyntax() {
+		if feature == compat.TopLevelAwait && p.options.hotModuleReplacement {
+			p.log.AddRangeError(&p.tracker, r, "Top-level await is currently not supported with hot module replacement")
+			return
+		}
+
+		// Modules with top-level await are wrapped in async closures when the
+		// output format is CommonJS or IIFE
+		if feature == compat.TopLevelAwait && !p.options.outputFormat.KeepES6ImportExportSyntax() &&
+			p.options.outputFormat != config.FormatCommonJS && p.options.outputFormat != config.FormatIIFE {
 			p.log.AddRangeError(&p.tracker, r, fmt.Sprintf(
 				"Top-level await is currently not supported with the %q output format", p.options.outputFormat.String()))
 			return
@@ -45,51 +54,12 @@ func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (di
 	where, notes := p.prettyPrintTargetEnvironment(feature)
 
 	switch feature {
-	case compat.DefaultArgument:
-		name = "default arguments"
-
-	case compat.RestArgument:
-		name = "rest arguments"
-
-	case compat.ArraySpread:
-		name = "array spread"
-
-	case compat.ForOf:
-		name = "for-of loops"
-
 	case compat.ObjectAccessors:
 		name = "object accessors"
 
-	case compat.ObjectExtensions:
-		name = "object literal extensions"
-
-	case compat.Destructuring:
-		name = "destructuring"
-
 	case compat.NewTarget:
 		name = "new.target"
 
-	case compat.Const:
-		name = "const"
-
-	case compat.Let:
-		name = "let"
-
-	case compat.Class:
-		name = "class syntax"
-
-	case compat.Generator:
-		name = "generator functions"
-
-	case compat.AsyncAwait:
-		name = "async functions"
-
-	case compat.AsyncGenerator:
-		name = "async generator functions"
-
-	case compat.ForAwait:
-		name = "for-await loops"
-
 	case compat.NestedRestBinding:
 		name = "non-identifier array rest patterns"
 
@@ -210,12 +180,6 @@ func (p *parser) markStrictModeFeature(feature strictModeFeature, r logger.Range
 
 // Mark the feature if "loweredFeature" is unsupported. This is used when one
 // feature is implemented in terms of another feature.
-func (p *parser) markLoweredSyntaxFeature(feature compat.JSFeature, r logger.Range, loweredFeature compat.JSFeature) {
-	if p.options.unsupportedJSFeatures.Has(loweredFeature) {
-		p.markSyntaxFeature(feature, r)
-	}
-}
-
 func (p *parser) privateSymbolNeedsToBeLowered(private *js_ast.EPrivateIdentifier) bool {
 	symbol := &p.symbols[private.Ref.InnerIndex]
 	return p.options.unsupportedJSFeatures.Has(symbol.Kind.Feature()) || symbol.PrivateSymbolMustBeLowered
@@ -239,6 +203,7 @@ func (p *parser) captureArguments() js_ast.Ref {
 
 func (p *parser) lowerFunction(
 	isAsync *bool,
+	isGenerator *bool,
 	args *[]js_ast.Arg,
 	bodyLoc logger.Loc,
 	bodyStmts *[]js_ast.Stmt,
@@ -246,10 +211,66 @@ func (p *parser) lowerFunction(
 	hasRestArg *bool,
 	isArrow bool,
 ) {
-	// Lower object rest binding patterns in function arguments
-	if p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
-		var prefixStmts []js_ast.Stmt
+	// Lower rest arguments. The rest array is created in the outermost function
+	// body even if the body is moved into a nested function below, since the
+	// nested function may not be passed the same "arguments" object:
+	//
+	//   "function foo(a, ...b) {}" => "function foo(a) { var b = [].slice.call(arguments, 1); }"
+	//
+	// A binding pattern for the rest argument is destructured after the default
+	// values for the other arguments have been assigned.
+	lowerDefaults := p.options.unsupportedJSFeatures.Has(compat.DefaultArgument)
+	lowerPatterns := p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) || p.options.unsupportedJSFeatures.Has(compat.Destructuring)
+	var restStmt js_ast.Stmt
+	var restPatternStmt js_ast.Stmt
+	if *hasRestArg && p.options.unsupportedJSFeatures.Has(compat.RestArgument) {
+		last := len(*args) - 1
+		rest := (*args)[last]
+		*args = (*args)[:last]
+		*hasRestArg = false
+
+		// This always refers to the "arguments" of the function being lowered,
+		// even for arrow functions since they become function expressions
+		argumentsRef := p.newSymbol(js_ast.SymbolUnbound, "arguments")
+		loc := rest.Binding.Loc
+		value := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+					Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EArray{}},
+					Name:    "slice",
+					NameLoc: loc,
+				}},
+				Name:    "call",
+				NameLoc: loc,
+			}},
+			Args: []js_ast.Expr{
+				{Loc: loc, Data: &js_ast.EIdentifier{Ref: argumentsRef}},
+				{Loc: loc, Data: &js_ast.ENumber{Value: float64(last)}},
+			},
+		}}
+		binding := rest.Binding
+		if _, ok := binding.Data.(*js_ast.BIdentifier); !ok {
+			ref := p.generateTempRef(tempRefNoDeclare, "")
+			init := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+			p.recordUsage(ref)
+			decls := []js_ast.Decl{{Binding: rest.Binding, ValueOrNil: init}}
+			if lowerPatterns && p.shouldLowerBindingPattern(rest.Binding) {
+				if lowered, ok := p.lowerObjectRestToDecls(js_ast.ConvertBindingToExpr(rest.Binding, nil), init, nil); ok {
+					decls = lowered
+				}
+			}
+			restPatternStmt = js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}}
+			binding = js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}
+		}
+		restStmt = js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{{Binding: binding, ValueOrNil: value}}}}
+	}
 
+	// Lower default values and object rest binding patterns in function
+	// arguments. All binding patterns are lowered if destructuring isn't
+	// supported.
+	var prefixStmts []js_ast.Stmt
+	var argsStmts []js_ast.Stmt
+	if lowerDefaults || lowerPatterns {
 		// Lower each argument individually instead of lowering all arguments
 		// together. There is a correctness tradeoff here around default values
 		// for function arguments, with no right answer.
@@ -280,8 +301,51 @@ func (p *parser) lowerFunction(
 		// This transform chooses to lower each argument individually with the
 		// thinking that perhaps scope matters more in real-world code than side
 		// effect order.
+		//
+		// Default values are lowered in the same order as the arguments so that
+		// each default value can reference the arguments before it:
+		//
+		//   // Original code
+		//   function foo({a} = {}, b = a) {}
+		//
+		//   // Lowered code
+		//   function foo(_a, b) {
+		//     if (_a === void 0) _a = {};
+		//     var a = _a.a;
+		//     if (b === void 0) b = a;
+		//   }
+		//
+		// The arguments from the first default value on don't count towards the
+		// "length" property of the function, so they are read from "arguments"
+		// instead. This is done in the outermost function body for the same
+		// reason as for rest arguments:
+		//
+		//   // Original code
+		//   function foo(a, b = a + 1, c = () => b) {}
+		//
+		//   // Lowered code
+		//   function foo(a) {
+		//     var b = arguments[1], c = arguments[2];
+		//     if (b === void 0) b = a + 1;
+		//     if (c === void 0) c = function() {
+		//       return b;
+		//     };
+		//   }
+		//
+		firstOptional := len(*args)
+		if lowerDefaults && !*hasRestArg {
+			for i, arg := range *args {
+				if arg.DefaultOrNil.Data != nil {
+					firstOptional = i
+					break
+				}
+			}
+		}
+		argumentsRef := js_ast.InvalidRef
 		for i, arg := range *args {
-			if bindingHasObjectRest(arg.Binding) {
+			var patternStmt js_ast.Stmt
+
+			if lowerPatterns && p.shouldLowerBindingPattern(arg.Binding) {
 				ref := p.generateTempRef(tempRefNoDeclare, "")
 				target := js_ast.ConvertBindingToExpr(arg.Binding, nil)
 				init := js_ast.Expr{Loc: arg.Binding.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
@@ -292,19 +356,74 @@ func (p *parser) lowerFunction(
 					(*args)[i].Binding.Data = &js_ast.BIdentifier{Ref: ref}
 
 					// Append a variable declaration to the function body
-					prefixStmts = append(prefixStmts, js_ast.Stmt{Loc: arg.Binding.Loc,
-						Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}})
+					patternStmt = js_ast.Stmt{Loc: arg.Binding.Loc,
+						Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}}
 				}
 			}
-		}
 
-		if len(prefixStmts) > 0 {
-			*bodyStmts = append(prefixStmts, *bodyStmts...)
+			// The default value must be assigned before the binding pattern is
+			// destructured, so move the binding pattern into the body too
+			if _, ok := (*args)[i].Binding.Data.(*js_ast.BIdentifier); !ok && (i >= firstOptional || (lowerDefaults && arg.DefaultOrNil.Data != nil)) {
+				loc := arg.Binding.Loc
+				ref := p.generateTempRef(tempRefNoDeclare, "")
+				patternStmt = js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{{
+					Binding:    (*args)[i].Binding,
+					ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}},
+				}}}}
+				p.recordUsage(ref)
+				(*args)[i].Binding.Data = &js_ast.BIdentifier{Ref: ref}
+			}
+
+			// "function foo(a, b) {}" => "function foo(a) { var b = arguments[1]; }"
+			if i >= firstOptional {
+				loc := arg.Binding.Loc
+				if argumentsRef == js_ast.InvalidRef {
+					argumentsRef = p.newSymbol(js_ast.SymbolUnbound, "arguments")
+				}
+				p.recordUsage(argumentsRef)
+				argsStmts = append(argsStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{{
+					Binding: (*args)[i].Binding,
+					ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EIndex{
+						Target: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: argumentsRef}},
+						Index:  js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: float64(i)}},
+					}},
+				}}}})
+			}
+
+			// "function foo(a = b) {}" => "function foo(a) { if (a === void 0) a = b; }"
+			if lowerDefaults && arg.DefaultOrNil.Data != nil {
+				loc := arg.Binding.Loc
+				id := (*args)[i].Binding.Data.(*js_ast.BIdentifier)
+				p.recordUsage(id.Ref)
+				p.recordUsage(id.Ref)
+				prefixStmts = append(prefixStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{
+					Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+						Op:    js_ast.BinOpStrictEq,
+						Left:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: id.Ref}},
+						Right: js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared},
+					}},
+					Yes: js_ast.AssignStmt(js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: id.Ref}}, arg.DefaultOrNil),
+				}})
+				(*args)[i].DefaultOrNil = js_ast.Expr{}
+			}
+
+			if patternStmt.Data != nil {
+				prefixStmts = append(prefixStmts, patternStmt)
+			}
 		}
+		*args = (*args)[:firstOptional]
+	}
+	if restPatternStmt.Data != nil {
+		prefixStmts = append(prefixStmts, restPatternStmt)
+	}
+	if len(prefixStmts) > 0 {
+		*bodyStmts = append(prefixStmts, *bodyStmts...)
 	}
 
-	// Lower async functions
-	if p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) && *isAsync {
+	// Lower async functions and async generator functions
+	isAsyncGenerator := *isAsync && isGenerator != nil && *isGenerator
+	if *isAsync && ((!isAsyncGenerator && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait)) ||
+		(isAsyncGenerator && p.options.unsupportedJSFeatures.Has(compat.AsyncGenerator))) {
 		// Use the shortened form if we're an arrow function
 		if preferExpr != nil {
 			*preferExpr = true
@@ -395,8 +514,14 @@ func (p *parser) lowerFunction(
 
 			// Forward all arguments from the outer function to the inner function
 			if !isArrow {
-				// Normal functions can just use "arguments" to forward everything
-				forwardedArgs = js_ast.Expr{Loc: bodyLoc, Data: &js_ast.EIdentifier{Ref: *p.fnOnlyDataVisit.argumentsRef}}
+				// Normal functions can just use "arguments" to forward everything.
+				// This uses a separate symbol when the inner generator is lowered
+				// because that captures the original "arguments" symbol instead.
+				argumentsRef := *p.fnOnlyDataVisit.argumentsRef
+				if p.options.unsupportedJSFeatures.Has(compat.Generator) {
+					argumentsRef = p.newSymbol(js_ast.SymbolUnbound, "arguments")
+				}
+				forwardedArgs = js_ast.Expr{Loc: bodyLoc, Data: &js_ast.EIdentifier{Ref: argumentsRef}}
 			} else {
 				// Arrow functions can't use "arguments", so we need to forward
 				// the arguments manually.
@@ -433,9 +558,21 @@ func (p *parser) lowerFunction(
 			}
 		}
 
+		// The generator may need to be lowered too
+		if p.options.unsupportedJSFeatures.Has(compat.Generator) {
+			fn.IsGenerator = false
+			fn.Body.Stmts = p.lowerGeneratorBody(bodyLoc, fn.Body.Stmts, !usesArgumentsRef)
+		}
+
 		// "async function foo(a, b) { stmts }" => "function foo(a, b) { return __async(this, null, function* () { stmts }) }"
+		// "async function* foo(a, b) { stmts }" => "function foo(a, b) { return __asyncGen(this, null, function* () { stmts }) }"
+		helper := "__async"
+		if isAsyncGenerator {
+			helper = "__asyncGen"
+			*isGenerator = false
+		}
 		*isAsync = false
-		callAsync := p.callRuntime(bodyLoc, "__async", []js_ast.Expr{
+		callAsync := p.callRuntime(bodyLoc, helper, []js_ast.Expr{
 			thisValue,
 			forwardedArgs,
 			{Loc: bodyLoc, Data: &js_ast.EFunction{Fn: fn}},
@@ -471,7 +608,23 @@ func (p *parser) lowerFunction(
 		} else {
 			*bodyStmts = []js_ast.Stmt{returnStmt}
 		}
+		if restStmt.Data != nil {
+			*bodyStmts = append([]js_ast.Stmt{restStmt}, *bodyStmts...)
+		}
+		*bodyStmts = append(argsStmts, *bodyStmts...)
+		return
+	}
+
+	// Lower generator functions
+	if isGenerator != nil && *isGenerator && p.options.unsupportedJSFeatures.Has(compat.Generator) {
+		*isGenerator = false
+		*bodyStmts = p.lowerGeneratorBody(bodyLoc, *bodyStmts, false /* skipArguments */)
+	}
+
+	if restStmt.Data != nil {
+		*bodyStmts = append([]js_ast.Stmt{restStmt}, *bodyStmts...)
 	}
+	*bodyStmts = append(argsStmts, *bodyStmts...)
 }
 
 func (p *parser) lowerOptionalChain(expr js_ast.Expr, in exprIn, childOut exprOut) (js_ast.Expr, exprOut) {
@@ -696,15 +849,7 @@ flatten:
 			// a property access, invoke the function using ".call(this, ...args)" to
 			// explicitly provide the value for "this".
 			if i == len(chain)-1 && thisArg.Data != nil {
-				result = js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
-					Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
-						Target:  result,
-						Name:    "call",
-						NameLoc: loc,
-					}},
-					Args:                   append([]js_ast.Expr{thisArg}, e.Args...),
-					CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
-				}}
+				result = p.callWithThisArg(loc, result, thisArg, e.Args, e.CanBeUnwrappedIfUnused)
 				break
 			}
 
@@ -713,24 +858,21 @@ flatten:
 			// the property access target that was stashed away earlier as the value
 			// for "this" for the call. Example for this case: "foo.#bar?.()"
 			if privateThisFunc != nil {
-				result = privateThisWrapFunc(js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
-					Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
-						Target:  result,
-						Name:    "call",
-						NameLoc: loc,
-					}},
-					Args:                   append([]js_ast.Expr{privateThisFunc()}, e.Args...),
-					CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
-				}})
+				result = privateThisWrapFunc(p.callWithThisArg(loc, result, privateThisFunc(), e.Args, e.CanBeUnwrappedIfUnused))
 				privateThisFunc = nil
 				break
 			}
 
-			result = js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			call := &js_ast.ECall{
 				Target:                 result,
 				Args:                   e.Args,
 				CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
-			}}
+			}
+			if p.options.unsupportedJSFeatures.Has(compat.ArraySpread) && hasSpread(e.Args) {
+				result = p.lowerCallSpread(loc, call)
+			} else {
+				result = js_ast.Expr{Loc: loc, Data: call}
+			}
 
 		case *js_ast.EUnary:
 			result = js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{
@@ -773,14 +915,7 @@ flatten:
 }
 
 func (p *parser) lowerParenthesizedOptionalChain(loc logger.Loc, e *js_ast.ECall, childOut exprOut) js_ast.Expr {
-	return childOut.thisArgWrapFunc(js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
-		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
-			Target:  e.Target,
-			Name:    "call",
-			NameLoc: loc,
-		}},
-		Args: append(append(make([]js_ast.Expr, 0, len(e.Args)+1), childOut.thisArgFunc()), e.Args...),
-	}})
+	return childOut.thisArgWrapFunc(p.callWithThisArg(loc, e.Target, childOut.thisArgFunc(), e.Args, false))
 }
 
 func (p *parser) lowerAssignmentOperator(value js_ast.Expr, callback func(js_ast.Expr, js_ast.Expr) js_ast.Expr) js_ast.Expr {
@@ -970,7 +1105,7 @@ func (p *parser) lowerObjectSpread(loc logger.Loc, e *js_ast.EObject) js_ast.Exp
 	}
 
 	if !needsLowering {
-		return js_ast.Expr{Loc: loc, Data: e}
+		return p.lowerObjectExtensions(loc, e)
 	}
 
 	var result js_ast.Expr
@@ -985,17 +1120,17 @@ func (p *parser) lowerObjectSpread(loc logger.Loc, e *js_ast.EObject) js_ast.Exp
 		if len(properties) > 0 || result.Data == nil {
 			if result.Data == nil {
 				// "{a, ...b}" => "__spreadValues({a}, b)"
-				result = js_ast.Expr{Loc: loc, Data: &js_ast.EObject{
+				result = p.lowerObjectExtensions(loc, &js_ast.EObject{
 					Properties:   properties,
 					IsSingleLine: e.IsSingleLine,
-				}}
+				})
 			} else {
 				// "{...a, b, ...c}" => "__spreadValues(__spreadProps(__spreadValues({}, a), {b}), c)"
 				result = p.callRuntime(loc, "__spreadProps",
-					[]js_ast.Expr{result, {Loc: loc, Data: &js_ast.EObject{
+					[]js_ast.Expr{result, p.lowerObjectExtensions(loc, &js_ast.EObject{
 						Properties:   properties,
 						IsSingleLine: e.IsSingleLine,
-					}}})
+					})})
 			}
 			properties = []js_ast.Property{}
 		}
@@ -1006,12 +1141,193 @@ func (p *parser) lowerObjectSpread(loc logger.Loc, e *js_ast.EObject) js_ast.Exp
 
 	if len(properties) > 0 {
 		// "{...a, b}" => "__spreadProps(__spreadValues({}, a), {b})"
-		result = p.callRuntime(loc, "__spreadProps", []js_ast.Expr{result, {Loc: loc, Data: &js_ast.EObject{
+		result = p.callRuntime(loc, "__spreadProps", []js_ast.Expr{result, p.lowerObjectExtensions(loc, &js_ast.EObject{
 			Properties:   properties,
 			IsSingleLine: e.IsSingleLine,
-		}}})
+		})})
+	}
+
+	return result
+}
+
+// Methods in object literals become function expressions. Properties with
+// computed keys, and all properties after them, are defined one at a time
+// to preserve the order of evaluation and of the keys:
+//
+//   // Original code
+//   x = {a, b() {}, [c]: d, get e() {}}
+//
+//   // Lowered code
+//   x = (_a = {a: a, b: function() {}}, __objProp(_a, c, d), __objProp(_a, "e", function() {}, 1), _a)
+//
+func (p *parser) lowerObjectExtensions(loc logger.Loc, e *js_ast.EObject) js_ast.Expr {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectExtensions) {
+		return js_ast.Expr{Loc: loc, Data: e}
+	}
+
+	// The printer already takes care of shorthand properties
+	firstComputed := -1
+	for i := range e.Properties {
+		property := &e.Properties[i]
+		if property.Kind == js_ast.PropertyNormal {
+			property.IsMethod = false
+		}
+		if property.IsComputed && firstComputed == -1 {
+			firstComputed = i
+		}
+	}
+	if firstComputed == -1 {
+		return js_ast.Expr{Loc: loc, Data: e}
+	}
+
+	tempRef := p.generateTempRef(tempRefNeedsDeclare, "")
+	ident := func() js_ast.Expr {
+		p.recordUsage(tempRef)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: tempRef}}
+	}
+	result := js_ast.Assign(ident(), js_ast.Expr{Loc: loc, Data: &js_ast.EObject{
+		Properties:   e.Properties[:firstComputed],
+		IsSingleLine: e.IsSingleLine,
+	}})
+
+	for _, property := range e.Properties[firstComputed:] {
+		key := property.Key
+		if !property.IsComputed {
+			// "{[a]: b, __proto__: c}" => "(_a = {}, __objProp(_a, a, b), _a.__proto__ = c, _a)"
+			if str, ok := key.Data.(*js_ast.EString); ok && js_lexer.UTF16EqualsString(str.Value, "__proto__") &&
+				property.Kind == js_ast.PropertyNormal && !property.WasShorthand {
+				result = js_ast.JoinWithComma(result, js_ast.Assign(
+					js_ast.Expr{Loc: key.Loc, Data: &js_ast.EDot{Target: ident(), Name: "__proto__", NameLoc: key.Loc}},
+					property.ValueOrNil))
+				continue
+			}
+		}
+
+		args := []js_ast.Expr{ident(), key, property.ValueOrNil}
+		switch property.Kind {
+		case js_ast.PropertyGet:
+			args = append(args, js_ast.Expr{Loc: key.Loc, Data: &js_ast.ENumber{Value: 1}})
+		case js_ast.PropertySet:
+			args = append(args, js_ast.Expr{Loc: key.Loc, Data: &js_ast.ENumber{Value: 2}})
+		}
+		result = js_ast.JoinWithComma(result, p.callRuntime(key.Loc, "__objProp", args))
+	}
+
+	return js_ast.JoinWithComma(result, ident())
+}
+
+// Spread elements in array literals and spread arguments in calls are lowered
+// by concatenating arrays. Each spread value is converted to an array first
+// since it can be any iterable:
+//
+//   "[a, ...b, c]" => "[a].concat(__toArray(b), [c])"
+//   "f(a, ...b)" => "f.apply(void 0, [a].concat(__toArray(b)))"
+//
+// The returned array may be the same object as the spread value unless
+// "mustCopy" is true.
+func (p *parser) lowerSpreadToArray(loc logger.Loc, items []js_ast.Expr, mustCopy bool) js_ast.Expr {
+	var parts []js_ast.Expr
+	var chunk []js_ast.Expr
+	for _, item := range items {
+		if spread, ok := item.Data.(*js_ast.ESpread); ok {
+			if chunk != nil {
+				parts = append(parts, js_ast.Expr{Loc: chunk[0].Loc, Data: &js_ast.EArray{Items: chunk, IsSingleLine: true}})
+				chunk = nil
+			}
+
+			// Functions can be applied to "arguments" directly
+			if id, ok := spread.Value.Data.(*js_ast.EIdentifier); ok && len(items) == 1 && !mustCopy &&
+				p.fnOnlyDataVisit.argumentsRef != nil && id.Ref == *p.fnOnlyDataVisit.argumentsRef {
+				return spread.Value
+			}
+			parts = append(parts, p.callRuntime(item.Loc, "__toArray", []js_ast.Expr{spread.Value}))
+		} else {
+			chunk = append(chunk, item)
+		}
+	}
+	if chunk != nil {
+		parts = append(parts, js_ast.Expr{Loc: chunk[0].Loc, Data: &js_ast.EArray{Items: chunk, IsSingleLine: true}})
+	}
+
+	// "[...a]" => "[].concat(__toArray(a))"
+	if _, ok := parts[0].Data.(*js_ast.EArray); !ok && mustCopy {
+		parts = append([]js_ast.Expr{{Loc: loc, Data: &js_ast.EArray{}}}, parts...)
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: parts[0], Name: "concat", NameLoc: loc}},
+		Args:   parts[1:],
+	}}
+}
+
+func hasSpread(items []js_ast.Expr) bool {
+	for _, item := range items {
+		if _, ok := item.Data.(*js_ast.ESpread); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// This generates "target.call(thisArg, ...args)" but uses "apply" instead if
+// there are spread arguments that need to be lowered
+func (p *parser) callWithThisArg(loc logger.Loc, target js_ast.Expr, thisArg js_ast.Expr, args []js_ast.Expr, canBeUnwrappedIfUnused bool) js_ast.Expr {
+	if hasSpread(args) && p.options.unsupportedJSFeatures.Has(compat.ArraySpread) {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			Target:                 js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: target, Name: "apply", NameLoc: loc}},
+			Args:                   []js_ast.Expr{thisArg, p.lowerSpreadToArray(loc, args, false)},
+			CanBeUnwrappedIfUnused: canBeUnwrappedIfUnused,
+		}}
+	}
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target:                 js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: target, Name: "call", NameLoc: loc}},
+		Args:                   append([]js_ast.Expr{thisArg}, args...),
+		CanBeUnwrappedIfUnused: canBeUnwrappedIfUnused,
+	}}
+}
+
+// "o.f(...a)" => "o.f.apply(o, __toArray(a))"
+// "f(...a)" => "f.apply(void 0, __toArray(a))"
+func (p *parser) lowerCallSpread(loc logger.Loc, call *js_ast.ECall) js_ast.Expr {
+	var thisArg js_ast.Expr
+	var wrapFunc func(js_ast.Expr) js_ast.Expr
+	target := call.Target
+
+	switch t := target.Data.(type) {
+	case *js_ast.EDot:
+		if _, ok := t.Target.Data.(*js_ast.ESuper); ok {
+			thisArg = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+			break
+		}
+		targetFunc, targetWrapFunc := p.captureValueWithPossibleSideEffects(loc, 2, t.Target, valueDefinitelyNotMutated)
+		target = js_ast.Expr{Loc: target.Loc, Data: &js_ast.EDot{Target: targetFunc(), Name: t.Name, NameLoc: t.NameLoc}}
+		thisArg = targetFunc()
+		wrapFunc = targetWrapFunc
+
+	case *js_ast.EIndex:
+		if _, ok := t.Target.Data.(*js_ast.ESuper); ok {
+			thisArg = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+			break
+		}
+		targetFunc, targetWrapFunc := p.captureValueWithPossibleSideEffects(loc, 2, t.Target, valueDefinitelyNotMutated)
+		target = js_ast.Expr{Loc: target.Loc, Data: &js_ast.EIndex{Target: targetFunc(), Index: t.Index}}
+		thisArg = targetFunc()
+		wrapFunc = targetWrapFunc
+
+	default:
+		thisArg = js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
 	}
 
+	result := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target:                 js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: target, Name: "apply", NameLoc: loc}},
+		Args:                   []js_ast.Expr{thisArg, p.lowerSpreadToArray(loc, call.Args, false)},
+		CanBeUnwrappedIfUnused: call.CanBeUnwrappedIfUnused,
+	}}
+	if wrapFunc != nil {
+		result = wrapFunc(result)
+	}
 	return result
 }
 
@@ -1194,15 +1510,38 @@ func exprHasObjectRest(expr js_ast.Expr) bool {
 	return false
 }
 
+// Returns true if this binding must be lowered, which is the case for binding
+// patterns containing object rest patterns if object rest isn't supported and
+// for all binding patterns if destructuring isn't supported
+func (p *parser) shouldLowerBindingPattern(binding js_ast.Binding) bool {
+	if p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
+		_, ok := binding.Data.(*js_ast.BIdentifier)
+		return !ok
+	}
+	return p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && bindingHasObjectRest(binding)
+}
+
+// This is the same as "shouldLowerBindingPattern" but for assignment targets
+func (p *parser) shouldLowerAssignPattern(expr js_ast.Expr) bool {
+	if p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
+		switch expr.Data.(type) {
+		case *js_ast.EArray, *js_ast.EObject:
+			return true
+		}
+		return false
+	}
+	return p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && exprHasObjectRest(expr)
+}
+
 func (p *parser) lowerObjectRestInDecls(decls []js_ast.Decl) []js_ast.Decl {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 		return decls
 	}
 
 	// Don't do any allocations if there are no object rest patterns. We want as
 	// little overhead as possible in the common case.
 	for i, decl := range decls {
-		if decl.ValueOrNil.Data != nil && bindingHasObjectRest(decl.Binding) {
+		if decl.ValueOrNil.Data != nil && p.shouldLowerBindingPattern(decl.Binding) {
 			clone := append([]js_ast.Decl{}, decls[:i]...)
 			for _, decl := range decls[i:] {
 				if decl.ValueOrNil.Data != nil {
@@ -1223,7 +1562,7 @@ func (p *parser) lowerObjectRestInDecls(decls []js_ast.Decl) []js_ast.Decl {
 }
 
 func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stmt) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 		return
 	}
 
@@ -1233,7 +1572,7 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 	case *js_ast.SExpr:
 		// "for ({...x} in y) {}"
 		// "for ({...x} of y) {}"
-		if exprHasObjectRest(s.Value) {
+		if p.shouldLowerAssignPattern(s.Value) {
 			ref := p.generateTempRef(tempRefNeedsDeclare, "")
 			if expr, ok := p.lowerAssign(s.Value, js_ast.Expr{Loc: init.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, objRestReturnValueIsUnused); ok {
 				s.Value.Data = &js_ast.EIdentifier{Ref: ref}
@@ -1244,7 +1583,7 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 	case *js_ast.SLocal:
 		// "for (let {...x} in y) {}"
 		// "for (let {...x} of y) {}"
-		if len(s.Decls) == 1 && bindingHasObjectRest(s.Decls[0].Binding) {
+		if len(s.Decls) == 1 && p.shouldLowerBindingPattern(s.Decls[0].Binding) {
 			ref := p.generateTempRef(tempRefNoDeclare, "")
 			decl := js_ast.Decl{Binding: s.Decls[0].Binding, ValueOrNil: js_ast.Expr{Loc: init.Loc, Data: &js_ast.EIdentifier{Ref: ref}}}
 			p.recordUsage(ref)
@@ -1267,18 +1606,25 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 }
 
 func (p *parser) lowerObjectRestInCatchBinding(catch *js_ast.Catch) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 		return
 	}
 
-	if catch.BindingOrNil.Data != nil && bindingHasObjectRest(catch.BindingOrNil) {
+	if catch.BindingOrNil.Data != nil && p.shouldLowerBindingPattern(catch.BindingOrNil) {
 		ref := p.generateTempRef(tempRefNoDeclare, "")
 		decl := js_ast.Decl{Binding: catch.BindingOrNil, ValueOrNil: js_ast.Expr{Loc: catch.BindingOrNil.Loc, Data: &js_ast.EIdentifier{Ref: ref}}}
 		p.recordUsage(ref)
 		decls := p.lowerObjectRestInDecls([]js_ast.Decl{decl})
 		catch.BindingOrNil.Data = &js_ast.BIdentifier{Ref: ref}
+
+		// Use "var" if "let" isn't available. This is ok because the renamer
+		// avoids shadowing, so the variables can't collide with anything else.
+		kind := js_ast.LocalLet
+		if p.options.unsupportedJSFeatures.Has(compat.Let) {
+			kind = js_ast.LocalVar
+		}
 		stmts := make([]js_ast.Stmt, 0, 1+len(catch.Body))
-		stmts = append(stmts, js_ast.Stmt{Loc: catch.BindingOrNil.Loc, Data: &js_ast.SLocal{Kind: js_ast.LocalLet, Decls: decls}})
+		stmts = append(stmts, js_ast.Stmt{Loc: catch.BindingOrNil.Loc, Data: &js_ast.SLocal{Kind: kind, Decls: decls}})
 		catch.Body = append(stmts, catch.Body...)
 	}
 }
@@ -1397,7 +1743,9 @@ func (p *parser) lowerObjectRestHelper(
 	declare generateTempRefArg,
 	mode objRestMode,
 ) (wrapFunc func(js_ast.Expr) js_ast.Expr, ok bool) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	// Every binding pattern is lowered if destructuring isn't supported
+	lowerAllPatterns := p.options.unsupportedJSFeatures.Has(compat.Destructuring)
+	if !lowerAllPatterns && !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
 		return nil, false
 	}
 
@@ -1437,7 +1785,7 @@ func (p *parser) lowerObjectRestHelper(
 		return found
 	}
 	findRestBindings(rootExpr)
-	if len(containsRestBinding) == 0 {
+	if len(containsRestBinding) == 0 && !lowerAllPatterns {
 		return nil, false
 	}
 
@@ -1655,10 +2003,162 @@ func (p *parser) lowerObjectRestHelper(
 		}
 	}
 
-	visit(rootExpr, rootInit, nil)
+	if lowerAllPatterns {
+		p.lowerDestructuring(rootExpr, rootInit, assign, declare)
+	} else {
+		visit(rootExpr, rootInit, nil)
+	}
 	return wrapFunc, true
 }
 
+// This lowers a binding pattern into a sequence of assignments to each of its
+// targets, which is necessary when destructuring isn't supported at all:
+//
+//   // Input:
+//   let {a, b: [c, d = 1], ...e} = f
+//
+//   // Output:
+//   let _a = f, a = _a.a, _b = __toArray(_a.b, 2), c = _b[0], _c = _b[1],
+//     d = _c === void 0 ? 1 : _c, e = __objRest(_a, ["a", "b"]);
+//
+// Values that are referenced more than once are stored in temporaries so that
+// they are only evaluated once. Array patterns go through "__toArray" so that
+// iterables other than arrays can still be destructured.
+func (p *parser) lowerDestructuring(
+	rootExpr js_ast.Expr,
+	rootInit js_ast.Expr,
+	assign func(js_ast.Expr, js_ast.Expr),
+	declare generateTempRefArg,
+) {
+	temps := make(map[js_ast.Ref]bool)
+
+	// Temporaries are never reassigned, so they don't need to be captured again
+	captureValue := func(value js_ast.Expr, uses int) func() js_ast.Expr {
+		if id, ok := value.Data.(*js_ast.EIdentifier); ok && temps[id.Ref] {
+			return func() js_ast.Expr {
+				p.recordUsage(id.Ref)
+				return js_ast.Expr{Loc: value.Loc, Data: &js_ast.EIdentifier{Ref: id.Ref}}
+			}
+		}
+
+		// The value must still be evaluated if the pattern has no targets
+		if uses == 1 {
+			return func() js_ast.Expr { return value }
+		}
+
+		ref := p.generateTempRef(declare, "")
+		temps[ref] = true
+		assign(js_ast.Expr{Loc: value.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, value)
+		p.recordUsage(ref)
+		return func() js_ast.Expr {
+			p.recordUsage(ref)
+			return js_ast.Expr{Loc: value.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
+		}
+	}
+
+	// "a = b" => "_a = b, a = _a === void 0 ? c : _a"
+	withDefault := func(loc logger.Loc, value js_ast.Expr, defaultValue js_ast.Expr) js_ast.Expr {
+		captured := captureValue(value, 2)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIf{
+			Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+				Op:    js_ast.BinOpStrictEq,
+				Left:  captured(),
+				Right: js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared},
+			}},
+			Yes: defaultValue,
+			No:  captured(),
+		}}
+	}
+
+	var visit func(js_ast.Expr, js_ast.Expr)
+	visit = func(expr js_ast.Expr, init js_ast.Expr) {
+		switch e := expr.Data.(type) {
+		case *js_ast.EBinary:
+			if e.Op == js_ast.BinOpAssign {
+				visit(e.Left, withDefault(expr.Loc, init, e.Right))
+				return
+			}
+
+		case *js_ast.EArray:
+			// "[a, , ...b] = c" => "_a = __toArray(c), a = _a[0], b = _a.slice(2)"
+			uses := 0
+			hasSpread := false
+			for _, item := range e.Items {
+				switch item.Data.(type) {
+				case *js_ast.EMissing:
+					continue
+				case *js_ast.ESpread:
+					hasSpread = true
+				}
+				uses++
+			}
+			args := []js_ast.Expr{init}
+			if !hasSpread {
+				// Only take as many items from the iterator as necessary
+				args = append(args, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENumber{Value: float64(len(e.Items))}})
+			}
+			array := captureValue(p.callRuntime(init.Loc, "__toArray", args), uses)
+			for i, item := range e.Items {
+				index := js_ast.Expr{Loc: item.Loc, Data: &js_ast.ENumber{Value: float64(i)}}
+				switch item2 := item.Data.(type) {
+				case *js_ast.EMissing:
+				case *js_ast.ESpread:
+					visit(item2.Value, js_ast.Expr{Loc: item.Loc, Data: &js_ast.ECall{
+						Target: js_ast.Expr{Loc: item.Loc, Data: &js_ast.EDot{Target: array(), Name: "slice", NameLoc: item.Loc}},
+						Args:   []js_ast.Expr{index},
+					}})
+				default:
+					visit(item, js_ast.Expr{Loc: item.Loc, Data: &js_ast.EIndex{Target: array(), Index: index}})
+				}
+			}
+			return
+
+		case *js_ast.EObject:
+			// "{a, [b]: c, ...d} = e" => "_a = e, a = _a.a, c = _a[_b = b], d = __objRest(_a, ["a", __restKey(_b)])"
+			last := len(e.Properties) - 1
+			endsWithRestBinding := last >= 0 && e.Properties[last].Kind == js_ast.PropertySpread
+			object := captureValue(init, len(e.Properties))
+			var capturedKeys []func() js_ast.Expr
+			for _, property := range e.Properties {
+				if property.Kind == js_ast.PropertySpread {
+					keysToExclude := make([]js_ast.Expr, len(capturedKeys))
+					for i, capturedKey := range capturedKeys {
+						keysToExclude[i] = capturedKey()
+					}
+					loc := property.ValueOrNil.Loc
+					visit(property.ValueOrNil, p.callRuntime(loc, "__objRest", []js_ast.Expr{object(),
+						{Loc: loc, Data: &js_ast.EArray{Items: keysToExclude, IsSingleLine: e.IsSingleLine}}}))
+					continue
+				}
+
+				// Save a copy of this key so the rest binding can exclude it
+				key := property.Key
+				if endsWithRestBinding {
+					var capturedKey func() js_ast.Expr
+					key, capturedKey = p.captureKeyForObjectRest(key)
+					capturedKeys = append(capturedKeys, capturedKey)
+				}
+
+				var value js_ast.Expr
+				if str, ok := key.Data.(*js_ast.EString); ok && js_lexer.IsIdentifierUTF16(str.Value) {
+					value = js_ast.Expr{Loc: key.Loc, Data: &js_ast.EDot{Target: object(), Name: js_lexer.UTF16ToString(str.Value), NameLoc: key.Loc}}
+				} else {
+					value = js_ast.Expr{Loc: key.Loc, Data: &js_ast.EIndex{Target: object(), Index: key}}
+				}
+				if property.InitializerOrNil.Data != nil {
+					value = withDefault(key.Loc, value, property.InitializerOrNil)
+				}
+				visit(property.ValueOrNil, value)
+			}
+			return
+		}
+
+		assign(expr, init)
+	}
+
+	visit(rootExpr, rootInit)
+}
+
 // Save a copy of the key for the call to "__objRest" later on. Certain
 // expressions can be converted to keys more efficiently than others.
 func (p *parser) captureKeyForObjectRest(originalKey js_ast.Expr) (finalKey js_ast.Expr, capturedKey func() js_ast.Expr) {
@@ -1708,6 +2208,8 @@ type classLoweringInfo struct {
 	avoidTDZ                bool
 	lowerAllInstanceFields  bool
 	lowerAllStaticFields    bool
+	lowerStandardDecorators bool
+	autoAccessorCount       int
 }
 
 func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLoweringInfo) {
@@ -1722,6 +2224,32 @@ func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLowe
 	// Safari workaround: Automatically avoid TDZ issues when bundling
 	result.avoidTDZ = p.options.mode == config.ModeBundle && p.currentScope.Parent == nil
 
+	// ES5 constructor functions can't contain fields, so all fields must be
+	// moved into the constructor or after the class if classes are lowered
+	if p.options.unsupportedJSFeatures.Has(compat.Class) {
+		result.lowerAllInstanceFields = true
+		result.lowerAllStaticFields = true
+	}
+
+	// JavaScript decorators and auto-accessors aren't supported by any engine
+	// yet so they are always lowered. The initializers they introduce run
+	// alongside field initializers, so all fields must be lowered too.
+	if !p.useLegacyDecorators() && len(class.Decorators) > 0 {
+		result.lowerStandardDecorators = true
+	}
+	for _, prop := range class.Properties {
+		if !p.useLegacyDecorators() && len(prop.Decorators) > 0 {
+			result.lowerStandardDecorators = true
+		}
+		if prop.Kind == js_ast.PropertyAutoAccessor {
+			result.autoAccessorCount++
+		}
+	}
+	if result.lowerStandardDecorators || result.autoAccessorCount > 0 {
+		result.lowerAllInstanceFields = true
+		result.lowerAllStaticFields = true
+	}
+
 	// Conservatively lower fields of a given type (instance or static) when any
 	// member of that type needs to be lowered. This must be done to preserve
 	// evaluation order. For example:
@@ -1900,22 +2428,279 @@ func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLowe
 	return
 }
 
-// Lower class fields for environments that don't support them. This either
-// takes a statement or an expression.
-func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast.Ref) ([]js_ast.Stmt, js_ast.Expr) {
-	type classKind uint8
-	const (
-		classKindExpr classKind = iota
-		classKindStmt
-		classKindExportStmt
-		classKindExportDefaultStmt
-	)
-
-	// Unpack the class from the statement or expression
-	var kind classKind
-	var class *js_ast.Class
-	var classLoc logger.Loc
-	var defaultName js_ast.LocRef
+// TypeScript's "emitDecoratorMetadata" setting adds "design:type",
+// "design:paramtypes", and "design:returntype" metadata to the decorators of
+// each decorated element. This returns the extra decorators for each property
+// followed by the extra decorators for the class itself.
+func (p *parser) computeDecoratorMetadata(class *js_ast.Class) ([][]js_ast.Expr, []js_ast.Expr) {
+	propertyMetadata := make([][]js_ast.Expr, len(class.Properties))
+	var ctor *js_ast.Fn
+
+	for i, prop := range class.Properties {
+		hasDecorators := len(prop.Decorators) > 0
+		fn, _ := prop.ValueOrNil.Data.(*js_ast.EFunction)
+		if prop.IsMethod && fn != nil {
+			// Parameter decorators of the constructor are applied to the class
+			if key, ok := prop.Key.Data.(*js_ast.EString); ok && !prop.IsStatic && js_lexer.UTF16EqualsString(key.Value, "constructor") {
+				ctor = &fn.Fn
+				continue
+			}
+			for _, arg := range fn.Fn.Args {
+				if len(arg.Decorators) > 0 {
+					hasDecorators = true
+				}
+			}
+		}
+		if !hasDecorators || (prop.IsMethod && fn == nil) {
+			continue
+		}
+		loc := prop.Key.Loc
+
+		switch {
+		case !prop.IsMethod:
+			propertyMetadata[i] = []js_ast.Expr{
+				p.decorateMetadata(loc, "design:type", p.serializeTSMetadata(loc, prop.TSMetadata)),
+			}
+
+		case prop.Kind == js_ast.PropertyGet || prop.Kind == js_ast.PropertySet:
+			// Both accessors use the type of the setter's argument if there is a
+			// setter and the getter's return type otherwise
+			getter, setter := findAccessorPair(class, prop)
+			var typeMetadata js_ast.TSMetadata
+			if setter != nil && len(setter.Args) > 0 {
+				typeMetadata = setter.Args[0].TSMetadata
+			}
+			if typeMetadata.Kind == js_ast.TSMetadataNone && getter != nil {
+				typeMetadata = getter.ReturnTSMetadata
+			}
+			paramsFn := &fn.Fn
+			if setter != nil {
+				paramsFn = setter
+			}
+			propertyMetadata[i] = []js_ast.Expr{
+				p.decorateMetadata(loc, "design:type", p.serializeTSMetadata(loc, typeMetadata)),
+				p.decorateMetadata(loc, "design:paramtypes", p.serializeTSParamTypes(loc, paramsFn)),
+			}
+
+		default:
+			// Methods without a return type are serialized as "void 0" instead of
+			// "Object", except for async methods which always return a promise
+			var returnType js_ast.Expr
+			if fn.Fn.ReturnTSMetadata.Kind != js_ast.TSMetadataNone {
+				returnType = p.serializeTSMetadata(loc, fn.Fn.ReturnTSMetadata)
+			} else if fn.Fn.IsAsync {
+				returnType = p.visitExpr(js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef("Promise")}})
+			} else {
+				returnType = js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+			}
+			propertyMetadata[i] = []js_ast.Expr{
+				p.decorateMetadata(loc, "design:type", p.serializeTSMetadata(loc, js_ast.TSMetadata{Kind: js_ast.TSMetadataFunction})),
+				p.decorateMetadata(loc, "design:paramtypes", p.serializeTSParamTypes(loc, &fn.Fn)),
+				p.decorateMetadata(loc, "design:returntype", returnType),
+			}
+		}
+	}
+
+	// Classes only have metadata if they have a constructor
+	var classMetadata []js_ast.Expr
+	if ctor != nil {
+		hasDecorators := len(class.Decorators) > 0
+		for _, arg := range ctor.Args {
+			if len(arg.Decorators) > 0 {
+				hasDecorators = true
+			}
+		}
+		if hasDecorators {
+			loc := class.BodyLoc
+			classMetadata = []js_ast.Expr{p.decorateMetadata(loc, "design:paramtypes", p.serializeTSParamTypes(loc, ctor))}
+		}
+	}
+
+	return propertyMetadata, classMetadata
+}
+
+func findAccessorPair(class *js_ast.Class, prop js_ast.Property) (getter *js_ast.Fn, setter *js_ast.Fn) {
+	for _, other := range class.Properties {
+		if (other.Kind != js_ast.PropertyGet && other.Kind != js_ast.PropertySet) ||
+			other.IsStatic != prop.IsStatic || other.IsComputed != prop.IsComputed {
+			continue
+		}
+		if fn, ok := other.ValueOrNil.Data.(*js_ast.EFunction); ok && accessorKeysAreEqual(other.Key.Data, prop.Key.Data) {
+			if other.Kind == js_ast.PropertyGet {
+				getter = &fn.Fn
+			} else {
+				setter = &fn.Fn
+			}
+		}
+	}
+	return
+}
+
+func accessorKeysAreEqual(a js_ast.E, b js_ast.E) bool {
+	switch k := a.(type) {
+	case *js_ast.EString:
+		if other, ok := b.(*js_ast.EString); ok {
+			return js_lexer.UTF16EqualsUTF16(k.Value, other.Value)
+		}
+
+	case *js_ast.ENumber:
+		if other, ok := b.(*js_ast.ENumber); ok {
+			return k.Value == other.Value
+		}
+	}
+	return false
+}
+
+func (p *parser) decorateMetadata(loc logger.Loc, key string, value js_ast.Expr) js_ast.Expr {
+	return p.callRuntime(loc, "__decorateMetadata", []js_ast.Expr{
+		{Loc: loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16(key)}},
+		value,
+	})
+}
+
+func (p *parser) serializeTSParamTypes(loc logger.Loc, fn *js_ast.Fn) js_ast.Expr {
+	items := make([]js_ast.Expr, 0, len(fn.Args))
+	for _, arg := range fn.Args {
+		items = append(items, p.serializeTSMetadata(arg.Binding.Loc, arg.TSMetadata))
+	}
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EArray{Items: items, IsSingleLine: true}}
+}
+
+// This converts a type annotation into the constructor that TypeScript would
+// pass as metadata. There is no type information, so references to other types
+// are only used if they turn out to exist at run-time:
+//
+//	"x: Foo" => "typeof Foo === 'undefined' ? Object : Foo"
+//
+// This is different from the TypeScript compiler, which uses type information
+// to determine whether "Foo" is a class or an interface. It also means that
+// references to enums from other files pass the enum object instead of
+// "Number" or "String". Enums from the same file are handled correctly.
+func (p *parser) serializeTSMetadata(loc logger.Loc, metadata js_ast.TSMetadata) js_ast.Expr {
+	var names []string
+	maybeUndefined := false
+
+	switch metadata.Kind {
+	case js_ast.TSMetadataVoid, js_ast.TSMetadataNever, js_ast.TSMetadataNull, js_ast.TSMetadataUndefined:
+		return js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+
+	case js_ast.TSMetadataString:
+		names = []string{"String"}
+
+	case js_ast.TSMetadataNumber:
+		names = []string{"Number"}
+
+	case js_ast.TSMetadataBoolean:
+		names = []string{"Boolean"}
+
+	case js_ast.TSMetadataFunction:
+		names = []string{"Function"}
+
+	case js_ast.TSMetadataArray:
+		names = []string{"Array"}
+
+	case js_ast.TSMetadataBigInt, js_ast.TSMetadataSymbol:
+		// These may not exist in older environments
+		if metadata.Kind == js_ast.TSMetadataBigInt {
+			names = []string{"BigInt"}
+		} else {
+			names = []string{"Symbol"}
+		}
+		maybeUndefined = p.options.unsupportedJSFeatures.Has(compat.BigInt)
+
+	case js_ast.TSMetadataIdentifier:
+		names = metadata.Names
+		maybeUndefined = true
+		if metadata.Loc.Start != 0 {
+			loc = metadata.Loc
+		}
+	}
+
+	if len(names) == 0 {
+		names = []string{"Object"}
+	}
+
+	// Generate "Foo.Bar"
+	valueOfLength := func(count int) js_ast.Expr {
+		value := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef(names[0])}}
+		for _, name := range names[1:count] {
+			value = js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: value, Name: name, NameLoc: loc}}
+		}
+		return value
+	}
+	value := valueOfLength(len(names))
+
+	// Generate "typeof Foo === 'undefined' || typeof Foo.Bar === 'undefined' ? Object : Foo.Bar"
+	if maybeUndefined {
+		var test js_ast.Expr
+		for i := range names {
+			check := js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+				Op:    js_ast.BinOpStrictEq,
+				Left:  js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{Op: js_ast.UnOpTypeof, Value: valueOfLength(i + 1)}},
+				Right: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("undefined")}},
+			}}
+			if test.Data == nil {
+				test = check
+			} else {
+				test = js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{Op: js_ast.BinOpLogicalOr, Left: test, Right: check}}
+			}
+		}
+		value = js_ast.Expr{Loc: loc, Data: &js_ast.EIf{
+			Test: test,
+			Yes:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef("Object")}},
+			No:   value,
+		}}
+	}
+
+	value = p.visitExpr(value)
+
+	// Count references to imports so they can be missing if they are types
+	if len(names) == 1 && maybeUndefined {
+		if e, ok := value.Data.(*js_ast.EIf); ok {
+			switch no := e.No.Data.(type) {
+			case *js_ast.EImportIdentifier:
+				p.tsMetadataUseCounts[no.Ref] += 2
+				e.IsTSMetadataTypeCheck = true
+
+			case *js_ast.EIdentifier:
+				// Local interfaces and type aliases don't exist at run-time
+				if p.symbols[no.Ref.InnerIndex].Kind == js_ast.SymbolUnbound && p.localTypeNames[names[0]] {
+					return p.visitExpr(js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef("Object")}})
+				}
+
+				// Local enums are serialized by the kind of their values
+				if kind, ok := p.tsEnumMetadataKinds[no.Ref]; ok {
+					return p.serializeTSMetadata(loc, js_ast.TSMetadata{Kind: kind})
+				}
+			}
+		}
+	}
+
+	return value
+}
+
+// Imports that are only referenced by decorator metadata may be types
+func (p *parser) isOnlyUsedForTSMetadata(ref js_ast.Ref) bool {
+	count := p.tsMetadataUseCounts[ref]
+	return count != 0 && count == p.symbols[ref.InnerIndex].UseCountEstimate
+}
+
+// Lower class fields for environments that don't support them. This either
+// takes a statement or an expression.
+func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast.Ref) ([]js_ast.Stmt, js_ast.Expr) {
+	type classKind uint8
+	const (
+		classKindExpr classKind = iota
+		classKindStmt
+		classKindExportStmt
+		classKindExportDefaultStmt
+	)
+
+	// Unpack the class from the statement or expression
+	var kind classKind
+	var class *js_ast.Class
+	var classLoc logger.Loc
+	var defaultName js_ast.LocRef
 	var nameToKeep string
 	if stmt.Data == nil {
 		e, _ := expr.Data.(*js_ast.EClass)
@@ -1963,6 +2748,15 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		classLoc = stmt.Loc
 	}
 
+	// Classes may also need to be converted into ES5 constructor functions.
+	// This happens last, after everything else has been moved out of the class.
+	lowerToES5 := p.options.unsupportedJSFeatures.Has(compat.Class)
+	superRef := js_ast.InvalidRef
+	if ref, ok := p.classSuperRefs[class]; ok {
+		superRef = ref
+	}
+	var es5Call *js_ast.ECall
+
 	var ctor *js_ast.EFunction
 	var parameterFields []js_ast.Stmt
 	var instanceMembers []js_ast.Stmt
@@ -1977,6 +2771,21 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 	var instanceDecorators []js_ast.Expr
 	var staticDecorators []js_ast.Expr
 
+	// These are for JavaScript decorators, which are applied after the class body
+	// in this order: static methods and accessors, instance methods and
+	// accessors, static fields, and then instance fields. The initializers of
+	// decorated fields and auto-accessors are numbered in that same order once
+	// all decorators have been collected.
+	type elementDecorator struct {
+		call      js_ast.Expr
+		initIndex *js_ast.ENumber
+	}
+	var staticElementDecorators []elementDecorator
+	var instanceElementDecorators []elementDecorator
+	var staticFieldDecorators []elementDecorator
+	var instanceFieldDecorators []elementDecorator
+	var elementDecorators []js_ast.Expr
+
 	// These are only for class expressions that need to be captured
 	var nameFunc func() js_ast.Expr
 	var wrapFunc func(js_ast.Expr) js_ast.Expr
@@ -1996,7 +2805,13 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 			// outside the class body.
 			classExpr := &js_ast.EClass{Class: *class}
 			class = &classExpr.Class
-			nameFunc, wrapFunc = p.captureValueWithPossibleSideEffects(classLoc, 2, js_ast.Expr{Loc: classLoc, Data: classExpr}, valueDefinitelyNotMutated)
+			value := js_ast.Expr{Loc: classLoc, Data: classExpr}
+			if lowerToES5 {
+				// This will be filled in with the ES5 constructor function later on
+				es5Call = &js_ast.ECall{}
+				value.Data = es5Call
+			}
+			nameFunc, wrapFunc = p.captureValueWithPossibleSideEffects(classLoc, 2, value, valueDefinitelyNotMutated)
 			expr = nameFunc()
 			didCaptureClassExpr = true
 			name := nameFunc()
@@ -2039,20 +2854,45 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 
 	classLoweringInfo := p.computeClassLoweringInfo(class)
 
-	for _, prop := range class.Properties {
+	// JavaScript decorators store their initializers in an array that is
+	// created before the decorators are applied
+	decoratorsRef := js_ast.InvalidRef
+	if classLoweringInfo.lowerStandardDecorators {
+		decoratorsRef = p.generateTempRef(tempRefNeedsDeclare, "_init")
+	}
+	decoratorsExpr := func(loc logger.Loc) js_ast.Expr {
+		p.recordUsage(decoratorsRef)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: decoratorsRef}}
+	}
+
+	// This must be computed before the class body is filtered below
+	var decoratorMetadata [][]js_ast.Expr
+	var classDecoratorMetadata []js_ast.Expr
+	if p.options.emitDecoratorMetadata && p.useLegacyDecorators() {
+		decoratorMetadata, classDecoratorMetadata = p.computeDecoratorMetadata(class)
+	}
+
+	// Auto-accessors are replaced by both a getter and a setter, so the class
+	// body can't be filtered in place if there are any
+	properties := class.Properties
+	if classLoweringInfo.autoAccessorCount > 0 {
+		class.Properties = make([]js_ast.Property, len(properties)+classLoweringInfo.autoAccessorCount)
+	}
+
+	for i, prop := range properties {
 		// Merge parameter decorators with method decorators
-		if p.options.ts.Parse && prop.IsMethod {
+		if p.useLegacyDecorators() && prop.IsMethod {
 			if fn, ok := prop.ValueOrNil.Data.(*js_ast.EFunction); ok {
 				isConstructor := false
 				if key, ok := prop.Key.Data.(*js_ast.EString); ok {
 					isConstructor = js_lexer.UTF16EqualsString(key.Value, "constructor")
 				}
 				for i, arg := range fn.Fn.Args {
-					for _, decorator := range arg.TSDecorators {
+					for _, decorator := range arg.Decorators {
 						// Generate a call to "__decorateParam()" for this parameter decorator
-						var decorators *[]js_ast.Expr = &prop.TSDecorators
+						var decorators *[]js_ast.Expr = &prop.Decorators
 						if isConstructor {
-							decorators = &class.TSDecorators
+							decorators = &class.Decorators
 						}
 						*decorators = append(*decorators,
 							p.callRuntime(decorator.Loc, "__decorateParam", []js_ast.Expr{
@@ -2065,6 +2905,11 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 			}
 		}
 
+		// Type metadata comes after all other decorators
+		if decoratorMetadata != nil {
+			prop.Decorators = append(prop.Decorators, decoratorMetadata[i]...)
+		}
+
 		// The TypeScript class field transform requires removing fields without
 		// initializers. If the field is removed, then we only need the key for
 		// its side effects and we don't need a temporary reference for the key.
@@ -2072,8 +2917,9 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		// strict class field initialization, so we shouldn't either.
 		private, _ := prop.Key.Data.(*js_ast.EPrivateIdentifier)
 		mustLowerPrivate := private != nil && p.privateSymbolNeedsToBeLowered(private)
-		shouldOmitFieldInitializer := p.options.ts.Parse && !prop.IsMethod && prop.InitializerOrNil.Data == nil &&
-			!classLoweringInfo.useDefineForClassFields && !mustLowerPrivate
+		isAutoAccessor := prop.Kind == js_ast.PropertyAutoAccessor
+		shouldOmitFieldInitializer := p.options.ts.Parse && !prop.IsMethod && !isAutoAccessor && prop.InitializerOrNil.Data == nil &&
+			!classLoweringInfo.useDefineForClassFields && !mustLowerPrivate && (p.useLegacyDecorators() || len(prop.Decorators) == 0)
 
 		// Class fields must be lowered if the environment doesn't support them
 		mustLowerField := false
@@ -2088,10 +2934,10 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		// Make sure the order of computed property keys doesn't change. These
 		// expressions have side effects and must be evaluated in order.
 		keyExprNoSideEffects := prop.Key
-		if prop.IsComputed && (len(prop.TSDecorators) > 0 ||
+		if prop.IsComputed && (len(prop.Decorators) > 0 ||
 			mustLowerField || computedPropertyCache.Data != nil) {
 			needsKey := true
-			if len(prop.TSDecorators) == 0 && (prop.IsMethod || shouldOmitFieldInitializer) {
+			if len(prop.Decorators) == 0 && (prop.IsMethod || shouldOmitFieldInitializer) {
 				needsKey = false
 			}
 
@@ -2110,34 +2956,38 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 			// If this is a computed method, the property value will be used
 			// immediately. In this case we inline all computed properties so far to
 			// make sure all computed properties before this one are evaluated first.
-			if !mustLowerField {
+			// The same goes for auto-accessors, which become a getter and a setter.
+			if !mustLowerField || isAutoAccessor {
 				prop.Key = computedPropertyCache
 				computedPropertyCache = js_ast.Expr{}
 			}
 		}
 
 		// Handle decorators
-		if p.options.ts.Parse {
-			// Generate a single call to "__decorateClass()" for this property
-			if len(prop.TSDecorators) > 0 {
-				loc := prop.Key.Loc
-
-				// Clone the key for the property descriptor
-				var descriptorKey js_ast.Expr
-				switch k := keyExprNoSideEffects.Data.(type) {
-				case *js_ast.ENumber:
-					descriptorKey = js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: k.Value}}
-				case *js_ast.EString:
-					descriptorKey = js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: k.Value}}
-				case *js_ast.EIdentifier:
-					descriptorKey = js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: k.Ref}}
-				default:
-					panic("Internal error")
-				}
+		var decoratorInitIndex *js_ast.ENumber
+		if len(prop.Decorators) > 0 {
+			loc := prop.Key.Loc
+
+			// Clone the key for the property descriptor
+			var descriptorKey js_ast.Expr
+			switch k := keyExprNoSideEffects.Data.(type) {
+			case *js_ast.ENumber:
+				descriptorKey = js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: k.Value}}
+			case *js_ast.EString:
+				descriptorKey = js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: k.Value}}
+			case *js_ast.EIdentifier:
+				descriptorKey = js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: k.Ref}}
+			case *js_ast.EPrivateIdentifier:
+				descriptorKey = js_ast.Expr{Loc: loc, Data: &js_ast.EString{
+					Value: js_lexer.StringToUTF16(p.symbols[k.Ref.InnerIndex].OriginalName)}}
+			default:
+				panic("Internal error")
+			}
 
+			if p.useLegacyDecorators() {
 				// This code tells "__decorateClass()" if the descriptor should be undefined
 				descriptorKind := float64(1)
-				if !prop.IsMethod {
+				if !prop.IsMethod && !isAutoAccessor {
 					descriptorKind = 2
 				}
 
@@ -2149,8 +2999,9 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 					target = js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: nameFunc(), Name: "prototype", NameLoc: loc}}
 				}
 
+				// Generate a single call to "__decorateClass()" for this property
 				decorator := p.callRuntime(loc, "__decorateClass", []js_ast.Expr{
-					{Loc: loc, Data: &js_ast.EArray{Items: prop.TSDecorators}},
+					{Loc: loc, Data: &js_ast.EArray{Items: prop.Decorators}},
 					target,
 					descriptorKey,
 					{Loc: loc, Data: &js_ast.ENumber{Value: descriptorKind}},
@@ -2162,7 +3013,257 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 				} else {
 					instanceDecorators = append(instanceDecorators, decorator)
 				}
+			} else {
+				// These flags tell "__decorateElement()" what kind of element this is
+				isField := !prop.IsMethod && !isAutoAccessor
+				var flags float64
+				switch {
+				case isField:
+					flags = 5
+				case isAutoAccessor:
+					flags = 4
+				case prop.Kind == js_ast.PropertyGet:
+					flags = 2
+				case prop.Kind == js_ast.PropertySet:
+					flags = 3
+				default:
+					flags = 1
+				}
+				if prop.IsStatic {
+					flags += 8
+				}
+				if private != nil {
+					flags += 16
+				}
+				args := []js_ast.Expr{
+					decoratorsExpr(loc),
+					{Loc: loc, Data: &js_ast.ENumber{Value: flags}},
+					descriptorKey,
+					{Loc: loc, Data: &js_ast.EArray{Items: prop.Decorators}},
+				}
+
+				// Private elements pass the functions that implement them along with
+				// the private brand, and are given back the decorated functions
+				var fnRef js_ast.Ref
+				if private == nil {
+					// Public elements other than fields are decorated in place on the
+					// prototype or on the class
+					if !isField {
+						if prop.IsStatic {
+							args = append(args, nameFunc())
+						} else {
+							args = append(args, js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: nameFunc(), Name: "prototype", NameLoc: loc}})
+						}
+					}
+				} else {
+					var target js_ast.Expr
+					switch {
+					case isField:
+						target = js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+					case isAutoAccessor:
+						getterRef := p.privateGetters[private.Ref]
+						setterRef := p.privateSetters[private.Ref]
+						p.recordUsage(getterRef)
+						p.recordUsage(setterRef)
+						target = js_ast.Expr{Loc: loc, Data: &js_ast.EObject{Properties: []js_ast.Property{
+							{Key: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("get")}},
+								ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: getterRef}}},
+							{Key: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("set")}},
+								ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: setterRef}}},
+						}}}
+					default:
+						if prop.Kind == js_ast.PropertySet {
+							fnRef = p.privateSetters[private.Ref]
+						} else {
+							fnRef = p.privateGetters[private.Ref]
+						}
+						p.recordUsage(fnRef)
+						target = js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: fnRef}}
+					}
+					args = append(args, target, js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: private.Ref}})
+				}
+				decorator := p.callRuntime(loc, "__decorateElement", args)
+
+				// Store the decorated functions of private elements
+				if private != nil && !isField {
+					if isAutoAccessor {
+						// "_a = __decorateElement(...), foo_get = _a.get, foo_set = _a.set"
+						descRef := p.generateTempRef(tempRefNeedsDeclare, "")
+						decorator = js_ast.Assign(js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: descRef}}, decorator)
+						for _, accessor := range []struct {
+							ref  js_ast.Ref
+							name string
+						}{{p.privateGetters[private.Ref], "get"}, {p.privateSetters[private.Ref], "set"}} {
+							decorator = js_ast.JoinWithComma(decorator, js_ast.Assign(
+								js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: accessor.ref}},
+								js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+									Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: descRef}},
+									Name:    accessor.name,
+									NameLoc: loc,
+								}},
+							))
+							p.recordUsage(accessor.ref)
+							p.recordUsage(descRef)
+						}
+						p.recordUsage(descRef)
+					} else {
+						decorator = js_ast.Assign(js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: fnRef}}, decorator)
+						p.recordUsage(fnRef)
+					}
+				}
+
+				// Fields and auto-accessors also get an index for their initializers
+				element := elementDecorator{call: decorator}
+				if isField || isAutoAccessor {
+					decoratorInitIndex = &js_ast.ENumber{}
+					element.initIndex = decoratorInitIndex
+				}
+				switch {
+				case isField && prop.IsStatic:
+					staticFieldDecorators = append(staticFieldDecorators, element)
+				case isField:
+					instanceFieldDecorators = append(instanceFieldDecorators, element)
+				case prop.IsStatic:
+					staticElementDecorators = append(staticElementDecorators, element)
+				default:
+					instanceElementDecorators = append(instanceElementDecorators, element)
+				}
+			}
+		}
+
+		// Auto-accessors are lowered to a getter and a setter that use a private
+		// field to store the value:
+		//
+		//   class Foo {
+		//     accessor foo = 123
+		//   }
+		//
+		// This becomes:
+		//
+		//   var _foo;
+		//   class Foo {
+		//     constructor() {
+		//       __privateAdd(this, _foo, 123);
+		//     }
+		//     get foo() {
+		//       return __privateGet(this, _foo);
+		//     }
+		//     set foo(value) {
+		//       __privateSet(this, _foo, value);
+		//     }
+		//   }
+		//   _foo = new WeakMap();
+		//
+		if isAutoAccessor {
+			loc := prop.Key.Loc
+
+			// Generate a new symbol for the storage of this auto-accessor
+			var storageName string
+			if private != nil {
+				storageName = "_" + p.symbols[private.Ref.InnerIndex].OriginalName[1:]
+			} else if key, ok := prop.Key.Data.(*js_ast.EString); ok && !prop.IsComputed && js_lexer.IsIdentifierUTF16(key.Value) {
+				storageName = "_" + js_lexer.UTF16ToString(key.Value)
+			}
+			storageRef := p.generateTempRef(tempRefNeedsDeclare, storageName)
+			storageExpr := func() js_ast.Expr {
+				p.recordUsage(storageRef)
+				return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: storageRef}}
+			}
+
+			// Initialize the storage to a new WeakMap
+			if p.weakMapRef == js_ast.InvalidRef {
+				p.weakMapRef = p.newSymbol(js_ast.SymbolUnbound, "WeakMap")
+				p.moduleScope.Generated = append(p.moduleScope.Generated, p.weakMapRef)
+			}
+			privateMembers = append(privateMembers, js_ast.Assign(
+				storageExpr(),
+				js_ast.Expr{Loc: loc, Data: &js_ast.ENew{Target: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.weakMapRef}}}},
+			))
+
+			// Determine where to store the value
+			var target js_ast.Expr
+			if prop.IsStatic {
+				target = nameFunc()
+			} else {
+				target = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+			}
+
+			// Add every newly-constructed instance into the storage
+			init := prop.InitializerOrNil
+			if init.Data == nil {
+				init = js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+			}
+			if decoratorInitIndex != nil {
+				init = p.callRuntime(loc, "__runInitializers", []js_ast.Expr{
+					decoratorsExpr(loc), {Loc: loc, Data: decoratorInitIndex}, target, init})
+			}
+			memberExpr := p.callRuntime(loc, "__privateAdd", []js_ast.Expr{target, storageExpr(), init})
+			if prop.IsStatic {
+				staticMembers = append(staticMembers, memberExpr)
+			} else {
+				instanceMembers = append(instanceMembers, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: memberExpr}})
+			}
+
+			// Generate the getter and the setter
+			valueRef := p.newSymbol(js_ast.SymbolOther, "value")
+			p.currentScope.Generated = append(p.currentScope.Generated, valueRef)
+			getter := js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+				Body: js_ast.FnBody{Loc: loc, Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SReturn{
+					ValueOrNil: p.callRuntime(loc, "__privateGet", []js_ast.Expr{{Loc: loc, Data: js_ast.EThisShared}, storageExpr()}),
+				}}}},
+			}}}
+			setter := js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+				Args: []js_ast.Arg{{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: valueRef}}}},
+				Body: js_ast.FnBody{Loc: loc, Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SExpr{
+					Value: p.callRuntime(loc, "__privateSet", []js_ast.Expr{
+						{Loc: loc, Data: js_ast.EThisShared}, storageExpr(), {Loc: loc, Data: &js_ast.EIdentifier{Ref: valueRef}}}),
+				}}}},
+			}}}
+
+			if private != nil {
+				// Private auto-accessors use the storage as their private brand and
+				// move the getter and the setter outside the class body
+				p.symbols[private.Ref.InnerIndex].Link = storageRef
+				for _, accessor := range []struct {
+					ref js_ast.Ref
+					fn  js_ast.Expr
+				}{{p.privateGetters[private.Ref], getter}, {p.privateSetters[private.Ref], setter}} {
+					methodRef := p.generateTempRef(tempRefNeedsDeclare, "_")
+					p.symbols[methodRef.InnerIndex].Link = accessor.ref
+					privateMembers = append(privateMembers, js_ast.Assign(
+						js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: methodRef}},
+						accessor.fn,
+					))
+				}
+				continue
 			}
+
+			// Public auto-accessors keep the getter and the setter in the class body.
+			// The setter reuses the key of the getter, which has no side effects.
+			getterProp := prop
+			getterProp.Kind = js_ast.PropertyGet
+			getterProp.IsMethod = true
+			getterProp.Decorators = nil
+			getterProp.InitializerOrNil = js_ast.Expr{}
+			getterProp.ValueOrNil = getter
+			setterProp := getterProp
+			setterProp.Kind = js_ast.PropertySet
+			setterProp.ValueOrNil = setter
+			switch k := keyExprNoSideEffects.Data.(type) {
+			case *js_ast.EString:
+				setterProp.Key = js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: k.Value}}
+			case *js_ast.ENumber:
+				setterProp.Key = js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: k.Value}}
+			case *js_ast.EIdentifier:
+				setterProp.Key = js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: k.Ref}}
+				p.recordUsage(k.Ref)
+			default:
+				setterProp.Key = keyExprNoSideEffects
+			}
+			class.Properties[end] = getterProp
+			class.Properties[end+1] = setterProp
+			end += 2
+			continue
 		}
 
 		// Handle lowering of instance and static fields. Move their initializers
@@ -2191,6 +3292,19 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 					init = js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
 				}
 
+				// Decorated fields pass their initial value through the initializers
+				// returned by their decorators
+				if decoratorInitIndex != nil {
+					var self js_ast.Expr
+					if prop.IsStatic {
+						self = nameFunc()
+					} else {
+						self = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+					}
+					init = p.callRuntime(loc, "__runInitializers", []js_ast.Expr{
+						decoratorsExpr(loc), {Loc: loc, Data: decoratorInitIndex}, self, init})
+				}
+
 				// Generate the assignment target
 				var memberExpr js_ast.Expr
 				if mustLowerPrivate {
@@ -2355,6 +3469,32 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 
 	// Finish the filtering operation
 	class.Properties = class.Properties[:end]
+	class.Decorators = append(class.Decorators, classDecoratorMetadata...)
+
+	// Apply JavaScript decorators in order and number the initializers of
+	// fields and auto-accessors to match. The extra initializers of decorated
+	// elements run before the fields of the same kind are initialized.
+	if classLoweringInfo.lowerStandardDecorators {
+		elementDecorators = append(elementDecorators, js_ast.Assign(decoratorsExpr(classLoc), p.callRuntime(classLoc, "__decoratorStart", nil)))
+		nextInitIndex := 3
+		for _, list := range [][]elementDecorator{staticElementDecorators, instanceElementDecorators, staticFieldDecorators, instanceFieldDecorators} {
+			for _, element := range list {
+				if element.initIndex != nil {
+					element.initIndex.Value = float64(nextInitIndex)
+					nextInitIndex++
+				}
+				elementDecorators = append(elementDecorators, element.call)
+			}
+		}
+		if len(staticElementDecorators) > 0 || len(staticFieldDecorators) > 0 {
+			staticPrivateMethods = append(staticPrivateMethods, p.callRuntime(classLoc, "__runInitializers", []js_ast.Expr{
+				decoratorsExpr(classLoc), {Loc: classLoc, Data: &js_ast.ENumber{Value: 1}}, nameFunc()}))
+		}
+		if len(instanceElementDecorators) > 0 || len(instanceFieldDecorators) > 0 {
+			instancePrivateMethods = append(instancePrivateMethods, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SExpr{Value: p.callRuntime(classLoc, "__runInitializers", []js_ast.Expr{
+				decoratorsExpr(classLoc), {Loc: classLoc, Data: &js_ast.ENumber{Value: 2}}, {Loc: classLoc, Data: js_ast.EThisShared}})}})
+		}
+	}
 
 	// Insert instance field initializers into the constructor
 	if len(parameterFields) > 0 || len(instancePrivateMethods) > 0 || len(instanceMembers) > 0 {
@@ -2373,10 +3513,35 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 			if class.ExtendsOrNil.Data != nil {
 				argumentsRef := p.newSymbol(js_ast.SymbolUnbound, "arguments")
 				p.currentScope.Generated = append(p.currentScope.Generated, argumentsRef)
-				ctor.Fn.Body.Stmts = append(ctor.Fn.Body.Stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: classLoc, Data: &js_ast.ECall{
-					Target: js_ast.Expr{Loc: classLoc, Data: js_ast.ESuperShared},
-					Args:   []js_ast.Expr{{Loc: classLoc, Data: &js_ast.ESpread{Value: js_ast.Expr{Loc: classLoc, Data: &js_ast.EIdentifier{Ref: argumentsRef}}}}},
-				}}}})
+				if lowerToES5 {
+					// "var _this = _super.apply(this, arguments) || this"
+					thisRef := p.newSymbol(js_ast.SymbolHoisted, "_this")
+					p.currentScope.Generated = append(p.currentScope.Generated, thisRef)
+					ctor.Fn.Body.Stmts = append(ctor.Fn.Body.Stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SLocal{
+						Kind: js_ast.LocalVar,
+						Decls: []js_ast.Decl{{
+							Binding: js_ast.Binding{Loc: classLoc, Data: &js_ast.BIdentifier{Ref: thisRef}},
+							ValueOrNil: js_ast.Expr{Loc: classLoc, Data: &js_ast.EBinary{
+								Op: js_ast.BinOpLogicalOr,
+								Left: js_ast.Expr{Loc: classLoc, Data: &js_ast.ECall{
+									Target: js_ast.Expr{Loc: classLoc, Data: &js_ast.EDot{
+										Target:  js_ast.Expr{Loc: classLoc, Data: &js_ast.EIdentifier{Ref: superRef}},
+										Name:    "apply",
+										NameLoc: classLoc,
+									}},
+									Args: []js_ast.Expr{{Loc: classLoc, Data: js_ast.EThisShared}, {Loc: classLoc, Data: &js_ast.EIdentifier{Ref: argumentsRef}}},
+								}},
+								Right: js_ast.Expr{Loc: classLoc, Data: js_ast.EThisShared},
+							}},
+						}},
+					}}, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: classLoc, Data: &js_ast.EIdentifier{Ref: thisRef}}}})
+					p.recordUsage(superRef)
+				} else {
+					ctor.Fn.Body.Stmts = append(ctor.Fn.Body.Stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: classLoc, Data: &js_ast.ECall{
+						Target: js_ast.Expr{Loc: classLoc, Data: js_ast.ESuperShared},
+						Args:   []js_ast.Expr{{Loc: classLoc, Data: &js_ast.ESpread{Value: js_ast.Expr{Loc: classLoc, Data: &js_ast.EIdentifier{Ref: argumentsRef}}}}},
+					}}}})
+				}
 			}
 		}
 
@@ -2384,7 +3549,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		stmtsFrom := ctor.Fn.Body.Stmts
 		stmtsTo := []js_ast.Stmt{}
 		for i, stmt := range stmtsFrom {
-			if js_ast.IsSuperCall(stmt) {
+			if js_ast.IsSuperCall(stmt) || (lowerToES5 && isSuperCallES5(stmt, superRef)) {
 				stmtsTo = append(stmtsTo, stmtsFrom[0:i+1]...)
 				stmtsFrom = stmtsFrom[i+1:]
 				break
@@ -2408,6 +3573,29 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		}
 	}
 
+	// JavaScript class decorators replace the class once its static fields have
+	// been initialized. Then the extra initializers of the class are run.
+	decorateClass := func(name js_ast.Expr) []js_ast.Expr {
+		className := js_ast.Expr{Loc: classLoc, Data: js_ast.EUndefinedShared}
+		if nameToKeep != "" {
+			className.Data = &js_ast.EString{Value: js_lexer.StringToUTF16(nameToKeep)}
+		}
+		return []js_ast.Expr{
+			js_ast.Assign(name, p.callRuntime(classLoc, "__decorateElement", []js_ast.Expr{
+				decoratorsExpr(classLoc),
+				{Loc: classLoc, Data: &js_ast.ENumber{Value: 0}},
+				className,
+				{Loc: classLoc, Data: &js_ast.EArray{Items: class.Decorators}},
+				nameFunc(),
+			})),
+			p.callRuntime(classLoc, "__runInitializers", []js_ast.Expr{
+				decoratorsExpr(classLoc),
+				{Loc: classLoc, Data: &js_ast.ENumber{Value: 0}},
+				nameFunc(),
+			}),
+		}
+	}
+
 	// Pack the class back into an expression. We don't need to handle TypeScript
 	// decorators for class expressions because TypeScript doesn't support them.
 	if kind == classKindExpr {
@@ -2415,10 +3603,20 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		// before joining "expr" with any other expressions
 		var nameToJoin js_ast.Expr
 		if didCaptureClassExpr || computedPropertyCache.Data != nil ||
-			len(privateMembers) > 0 || len(staticPrivateMethods) > 0 || len(staticMembers) > 0 {
+			len(privateMembers) > 0 || len(staticPrivateMethods) > 0 || len(staticMembers) > 0 ||
+			len(elementDecorators) > 0 {
 			nameToJoin = nameFunc()
 		}
 
+		// Replace the class with the ES5 constructor function
+		if lowerToES5 {
+			if es5Call != nil {
+				*es5Call = *p.lowerClassToES5(classLoc, class, superRef)
+			} else {
+				expr = js_ast.Expr{Loc: expr.Loc, Data: p.lowerClassToES5(classLoc, class, superRef)}
+			}
+		}
+
 		// Optionally preserve the name
 		if p.options.keepNames && nameToKeep != "" {
 			expr = p.keepExprSymbolName(expr, nameToKeep)
@@ -2431,12 +3629,20 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		for _, value := range privateMembers {
 			expr = js_ast.JoinWithComma(expr, value)
 		}
+		for _, value := range elementDecorators {
+			expr = js_ast.JoinWithComma(expr, value)
+		}
 		for _, value := range staticPrivateMethods {
 			expr = js_ast.JoinWithComma(expr, value)
 		}
 		for _, value := range staticMembers {
 			expr = js_ast.JoinWithComma(expr, value)
 		}
+		if len(class.Decorators) > 0 {
+			for _, value := range decorateClass(nameFunc()) {
+				expr = js_ast.JoinWithComma(expr, value)
+			}
+		}
 
 		// Finally join "expr" with the variable that holds the class object
 		if nameToJoin.Data != nil {
@@ -2459,7 +3665,8 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 			len(staticMembers) > 0 ||
 			len(instanceDecorators) > 0 ||
 			len(staticDecorators) > 0 ||
-			len(class.TSDecorators) > 0)
+			len(elementDecorators) > 0 ||
+			len(class.Decorators) > 0)
 
 	// Optionally preserve the name
 	var keepNameStmt js_ast.Stmt
@@ -2473,7 +3680,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 	var stmts []js_ast.Stmt
 	var nameForClassDecorators js_ast.LocRef
 	generatedLocalStmt := false
-	if len(class.TSDecorators) > 0 || hasPotentialShadowCaptureEscape || classLoweringInfo.avoidTDZ {
+	if len(class.Decorators) > 0 || hasPotentialShadowCaptureEscape || classLoweringInfo.avoidTDZ {
 		generatedLocalStmt = true
 		name := nameFunc()
 		nameRef := name.Data.(*js_ast.EIdentifier).Ref
@@ -2481,8 +3688,13 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		classExpr := js_ast.EClass{Class: *class}
 		class = &classExpr.Class
 		init := js_ast.Expr{Loc: classLoc, Data: &classExpr}
+		localKind := p.selectLocalKind
+		if lowerToES5 {
+			init.Data = p.lowerClassToES5(classLoc, class, superRef)
+			localKind = func(js_ast.LocalKind) js_ast.LocalKind { return js_ast.LocalVar }
+		}
 
-		if hasPotentialShadowCaptureEscape && len(class.TSDecorators) == 0 {
+		if hasPotentialShadowCaptureEscape && len(class.Decorators) == 0 {
 			// If something captures the shadowing name and escapes the class body,
 			// make a new constant to store the class and forward that value to a
 			// mutable alias. That way if the alias is mutated, everything bound to
@@ -2518,9 +3730,12 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 			captureRef := p.newSymbol(js_ast.SymbolOther, p.symbols[shadowRef.InnerIndex].OriginalName)
 			p.currentScope.Generated = append(p.currentScope.Generated, captureRef)
 			p.recordDeclaredSymbol(captureRef)
+			if localKind(js_ast.LocalConst) == js_ast.LocalVar {
+				p.hoistLoweredLexicalSymbol(captureRef)
+			}
 			p.mergeSymbols(shadowRef, captureRef)
 			stmts = append(stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SLocal{
-				Kind: p.selectLocalKind(js_ast.LocalConst),
+				Kind: localKind(js_ast.LocalConst),
 				Decls: []js_ast.Decl{{
 					Binding:    js_ast.Binding{Loc: name.Loc, Data: &js_ast.BIdentifier{Ref: captureRef}},
 					ValueOrNil: init,
@@ -2540,14 +3755,42 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 		}
 
 		// Generate the variable statement that will represent the class statement
+		if localKind(js_ast.LocalLet) == js_ast.LocalVar {
+			p.hoistLoweredLexicalSymbol(nameRef)
+		}
 		stmts = append(stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SLocal{
-			Kind:     p.selectLocalKind(js_ast.LocalLet),
+			Kind:     localKind(js_ast.LocalLet),
 			IsExport: kind == classKindExportStmt,
 			Decls: []js_ast.Decl{{
 				Binding:    js_ast.Binding{Loc: name.Loc, Data: &js_ast.BIdentifier{Ref: nameRef}},
 				ValueOrNil: init,
 			}},
 		}})
+	} else if lowerToES5 {
+		// "class Foo {}" => "var Foo = (function() { ... })()"
+		if kind == classKindExportDefaultStmt {
+			nameFunc()
+		}
+		p.hoistLoweredLexicalSymbol(class.Name.Ref)
+		stmts = append(stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SLocal{
+			Kind:     js_ast.LocalVar,
+			IsExport: kind == classKindExportStmt,
+			Decls: []js_ast.Decl{{
+				Binding:    js_ast.Binding{Loc: class.Name.Loc, Data: &js_ast.BIdentifier{Ref: class.Name.Ref}},
+				ValueOrNil: js_ast.Expr{Loc: classLoc, Data: p.lowerClassToES5(classLoc, class, superRef)},
+			}},
+		}})
+		if kind == classKindExportDefaultStmt {
+			stmts = append(stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SExportClause{
+				Items: []js_ast.ClauseItem{{Alias: "default", Name: defaultName}},
+			}})
+		}
+
+		// The shadowing name inside the class statement should be the same as
+		// the class statement name itself
+		if shadowRef != js_ast.InvalidRef {
+			p.mergeSymbols(shadowRef, class.Name.Ref)
+		}
 	} else {
 		switch kind {
 		case classKindStmt:
@@ -2579,6 +3822,9 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 	for _, expr := range privateMembers {
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
+	for _, expr := range elementDecorators {
+		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
+	}
 	for _, expr := range staticPrivateMethods {
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
@@ -2591,11 +3837,16 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 	for _, expr := range staticDecorators {
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
-	if len(class.TSDecorators) > 0 {
+	if len(class.Decorators) > 0 && classLoweringInfo.lowerStandardDecorators {
+		for _, expr := range decorateClass(js_ast.Expr{Loc: nameForClassDecorators.Loc, Data: &js_ast.EIdentifier{Ref: nameForClassDecorators.Ref}}) {
+			stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
+		}
+		p.recordUsage(nameForClassDecorators.Ref)
+	} else if len(class.Decorators) > 0 {
 		stmts = append(stmts, js_ast.AssignStmt(
 			js_ast.Expr{Loc: nameForClassDecorators.Loc, Data: &js_ast.EIdentifier{Ref: nameForClassDecorators.Ref}},
 			p.callRuntime(classLoc, "__decorateClass", []js_ast.Expr{
-				{Loc: classLoc, Data: &js_ast.EArray{Items: class.TSDecorators}},
+				{Loc: classLoc, Data: &js_ast.EArray{Items: class.Decorators}},
 				{Loc: nameForClassDecorators.Loc, Data: &js_ast.EIdentifier{Ref: nameForClassDecorators.Ref}},
 			}),
 		))
@@ -2619,13 +3870,183 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, shadowRef js_ast
 	return stmts, js_ast.Expr{}
 }
 
-func (p *parser) lowerTemplateLiteral(loc logger.Loc, e *js_ast.ETemplate) js_ast.Expr {
-	// If there is no tag, turn this into normal string concatenation
-	if e.TagOrNil.Data == nil {
-		var value js_ast.Expr
-
-		// Handle the head
-		value = js_ast.Expr{Loc: loc, Data: &js_ast.EString{
+// Convert a class into an ES5 constructor function. This is done after the
+// class fields and private members have already been moved out of the class
+// body, so only the constructor, methods, and accessors remain. The result is
+// a call to a wrapper function that takes the base class as an argument:
+//
+//   (function(_super) {
+//     __extendClass(Foo, _super);
+//     function Foo() {
+//       return _super.apply(this, arguments) || this;
+//     }
+//     __defMethod(Foo.prototype, "foo", function() {
+//       return _super.prototype.foo.call(this);
+//     });
+//     return Foo;
+//   })(Bar)
+//
+func (p *parser) lowerClassToES5(loc logger.Loc, class *js_ast.Class, superRef js_ast.Ref) *js_ast.ECall {
+	canBeRemovedIfUnused := p.classCanBeRemovedIfUnused(*class)
+
+	// The constructor function needs a name even if the class doesn't have one
+	nameLoc := loc
+	var nameRef js_ast.Ref
+	if class.Name != nil {
+		nameLoc = class.Name.Loc
+		nameRef = class.Name.Ref
+	} else {
+		nameRef = p.newSymbol(js_ast.SymbolOther, "_class")
+		p.currentScope.Generated = append(p.currentScope.Generated, nameRef)
+	}
+	nameFunc := func() js_ast.Expr {
+		p.recordUsage(nameRef)
+		return js_ast.Expr{Loc: nameLoc, Data: &js_ast.EIdentifier{Ref: nameRef}}
+	}
+
+	var stmts []js_ast.Stmt
+	var args []js_ast.Arg
+	var argValues []js_ast.Expr
+	if class.ExtendsOrNil.Data != nil {
+		args = []js_ast.Arg{{Binding: js_ast.Binding{Loc: class.ExtendsOrNil.Loc, Data: &js_ast.BIdentifier{Ref: superRef}}}}
+		argValues = []js_ast.Expr{class.ExtendsOrNil}
+
+		// Built-in classes such as "Error" and "Array" return a new object when
+		// they are called instead of initializing "this", which would lose the
+		// subclass prototype. Global base classes may be built-in classes, so
+		// they are wrapped in something that constructs the right object:
+		// "class Foo extends Error {}" => "(function(_super) { ... })(__wrapNativeSuper(Error))"
+		if id, ok := class.ExtendsOrNil.Data.(*js_ast.EIdentifier); ok && p.symbols[id.Ref.InnerIndex].Kind == js_ast.SymbolUnbound {
+			wrapped := p.callRuntime(class.ExtendsOrNil.Loc, "__wrapNativeSuper", []js_ast.Expr{class.ExtendsOrNil})
+			wrapped.Data.(*js_ast.ECall).CanBeUnwrappedIfUnused = canBeRemovedIfUnused
+			argValues[0] = wrapped
+		}
+		p.recordUsage(superRef)
+		stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: p.callRuntime(loc, "__extendClass", []js_ast.Expr{
+			nameFunc(),
+			{Loc: class.ExtendsOrNil.Loc, Data: &js_ast.EIdentifier{Ref: superRef}},
+		})}})
+	}
+
+	// The class constructor becomes the constructor function
+	var ctor js_ast.Fn
+	ctorLoc := loc
+	ctorIndex := -1
+	for i, prop := range class.Properties {
+		if prop.IsMethod && !prop.IsStatic && !prop.IsComputed {
+			if str, ok := prop.Key.Data.(*js_ast.EString); ok && js_lexer.UTF16EqualsString(str.Value, "constructor") {
+				if fn, ok := prop.ValueOrNil.Data.(*js_ast.EFunction); ok {
+					ctor = fn.Fn
+					ctorLoc = prop.Key.Loc
+					ctorIndex = i
+					break
+				}
+			}
+		}
+	}
+	if ctorIndex == -1 && class.ExtendsOrNil.Data != nil {
+		// "return _super.apply(this, arguments) || this"
+		argumentsRef := p.newSymbol(js_ast.SymbolUnbound, "arguments")
+		p.currentScope.Generated = append(p.currentScope.Generated, argumentsRef)
+		p.recordUsage(superRef)
+		ctor.Body.Stmts = []js_ast.Stmt{{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+			Op: js_ast.BinOpLogicalOr,
+			Left: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+					Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: superRef}},
+					Name:    "apply",
+					NameLoc: loc,
+				}},
+				Args: []js_ast.Expr{{Loc: loc, Data: js_ast.EThisShared}, {Loc: loc, Data: &js_ast.EIdentifier{Ref: argumentsRef}}},
+			}},
+			Right: js_ast.Expr{Loc: loc, Data: js_ast.EThisShared},
+		}}}}}
+	}
+	ctor.Name = &js_ast.LocRef{Loc: nameLoc, Ref: nameRef}
+
+	// Class constructors must throw when they are called without "new":
+	// "function Foo() { __classCallCheck(this, Foo); ... }"
+	p.recordUsage(nameRef)
+	ctor.Body.Stmts = append([]js_ast.Stmt{{Loc: ctorLoc, Data: &js_ast.SExpr{Value: p.callRuntime(ctorLoc, "__classCallCheck", []js_ast.Expr{
+		{Loc: ctorLoc, Data: js_ast.EThisShared},
+		{Loc: ctorLoc, Data: &js_ast.EIdentifier{Ref: nameRef}},
+	})}}}, ctor.Body.Stmts...)
+	stmts = append(stmts, js_ast.Stmt{Loc: ctorLoc, Data: &js_ast.SFunction{Fn: ctor}})
+
+	// Methods and accessors are defined on the prototype or on the constructor
+	// itself. They are non-enumerable, just like they are for real classes.
+	for i := 0; i < len(class.Properties); i++ {
+		prop := class.Properties[i]
+		if i == ctorIndex || !prop.IsMethod {
+			continue
+		}
+		keyLoc := prop.Key.Loc
+		target := nameFunc()
+		if !prop.IsStatic {
+			target = js_ast.Expr{Loc: keyLoc, Data: &js_ast.EDot{Target: target, Name: "prototype", NameLoc: keyLoc}}
+		}
+
+		if prop.Kind != js_ast.PropertyGet && prop.Kind != js_ast.PropertySet {
+			stmts = append(stmts, js_ast.Stmt{Loc: keyLoc, Data: &js_ast.SExpr{Value: p.callRuntime(keyLoc, "__defMethod", []js_ast.Expr{
+				target,
+				prop.Key,
+				prop.ValueOrNil,
+			})}})
+			continue
+		}
+
+		getter := js_ast.Expr{Loc: keyLoc, Data: js_ast.EUndefinedShared}
+		setter := js_ast.Expr{Loc: keyLoc, Data: js_ast.EUndefinedShared}
+		if prop.Kind == js_ast.PropertyGet {
+			getter = prop.ValueOrNil
+		} else {
+			setter = prop.ValueOrNil
+		}
+
+		// Combine an adjacent getter and setter with the same name into one call
+		if i+1 < len(class.Properties) && !prop.IsComputed {
+			next := class.Properties[i+1]
+			if next.IsMethod && next.IsStatic == prop.IsStatic && !next.IsComputed &&
+				(next.Kind == js_ast.PropertyGet || next.Kind == js_ast.PropertySet) && next.Kind != prop.Kind {
+				if a, ok := prop.Key.Data.(*js_ast.EString); ok {
+					if b, ok := next.Key.Data.(*js_ast.EString); ok && js_lexer.UTF16EqualsUTF16(a.Value, b.Value) {
+						if next.Kind == js_ast.PropertyGet {
+							getter = next.ValueOrNil
+						} else {
+							setter = next.ValueOrNil
+						}
+						i++
+					}
+				}
+			}
+		}
+
+		stmts = append(stmts, js_ast.Stmt{Loc: keyLoc, Data: &js_ast.SExpr{Value: p.callRuntime(keyLoc, "__defAccessor", []js_ast.Expr{
+			target,
+			prop.Key,
+			getter,
+			setter,
+		})}})
+	}
+
+	stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: nameFunc()}})
+	return &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+			Args: args,
+			Body: js_ast.FnBody{Loc: class.BodyLoc, Stmts: stmts},
+		}}},
+		Args:                   argValues,
+		CanBeUnwrappedIfUnused: canBeRemovedIfUnused,
+	}
+}
+
+func (p *parser) lowerTemplateLiteral(loc logger.Loc, e *js_ast.ETemplate) js_ast.Expr {
+	// If there is no tag, turn this into normal string concatenation
+	if e.TagOrNil.Data == nil {
+		var value js_ast.Expr
+
+		// Handle the head
+		value = js_ast.Expr{Loc: loc, Data: &js_ast.EString{
 			Value:          e.HeadCooked,
 			LegacyOctalLoc: e.LegacyOctalLoc,
 		}}
@@ -2721,14 +4142,92 @@ func (p *parser) lowerTemplateLiteral(loc logger.Loc, e *js_ast.ETemplate) js_as
 }
 
 func (p *parser) shouldLowerSuperPropertyAccess(expr js_ast.Expr) bool {
-	if p.fnOrArrowDataVisit.isAsync && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) {
+	if p.fnOnlyDataVisit.classMemberForES5 != nil ||
+		(p.fnOrArrowDataVisit.isAsync && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait)) {
 		_, isSuper := expr.Data.(*js_ast.ESuper)
 		return isSuper
 	}
 	return false
 }
 
+// Returns the object that a "super" property lookup starts from inside a
+// lowered ES5 class. Getters and setters found there must still be called
+// with the current "this" as the receiver.
+func (p *parser) superLookupStartForES5(loc logger.Loc) (js_ast.Expr, bool) {
+	member := p.fnOnlyDataVisit.classMemberForES5
+	if member.isStatic && p.fnOnlyDataVisit.thisClassStaticRef != nil {
+		// Static field initializers are moved outside of the wrapper function,
+		// so the base class must be found using the class itself instead:
+		// "super.foo" => "__superGet(__getProtoOf(Foo), 'foo', Foo)"
+		return p.callRuntime(loc, "__getProtoOf", []js_ast.Expr{p.visitExpr(js_ast.Expr{Loc: loc, Data: js_ast.EThisShared})}), true
+	}
+	if member.superRef == js_ast.InvalidRef {
+		p.log.AddRangeError(&p.tracker, js_lexer.RangeOfIdentifier(p.source, loc),
+			"Transforming \"super\" in a class without a base class to the configured target environment is not supported yet")
+		return js_ast.Expr{}, false
+	}
+	p.recordUsage(member.superRef)
+	target := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: member.superRef}}
+	if !member.isStatic {
+		target = js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: target, Name: "prototype", NameLoc: loc}}
+	}
+	return target, true
+}
+
+// Returns the unvisited key of "super.foo" or "super[foo]"
+func extractSuperProperty(expr js_ast.Expr) (js_ast.Expr, bool) {
+	switch e := expr.Data.(type) {
+	case *js_ast.EDot:
+		if _, ok := e.Target.Data.(*js_ast.ESuper); ok {
+			return js_ast.Expr{Loc: e.NameLoc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16(e.Name)}}, true
+		}
+	case *js_ast.EIndex:
+		if _, ok := e.Target.Data.(*js_ast.ESuper); ok {
+			return e.Index, true
+		}
+	}
+	return js_ast.Expr{}, false
+}
+
+func (p *parser) lowerSuperPropertySetES5(loc logger.Loc, key js_ast.Expr, value js_ast.Expr) js_ast.Expr {
+	target, ok := p.superLookupStartForES5(loc)
+	if !ok {
+		return value
+	}
+
+	// "super.foo = bar" => "__superSet(_super.prototype, 'foo', bar, this)"
+	return p.callRuntime(loc, "__superSet", []js_ast.Expr{target, key, value,
+		p.visitExpr(js_ast.Expr{Loc: loc, Data: js_ast.EThisShared})})
+}
+
+func (p *parser) lowerSuperPropertyAssignTargetES5(loc logger.Loc, key js_ast.Expr) js_ast.Expr {
+	target, ok := p.superLookupStartForES5(loc)
+	if !ok {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EObject{}}
+	}
+
+	// "super.foo++" => "__superWrapper(_super.prototype, 'foo', this)._++"
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+		Target: p.callRuntime(loc, "__superWrapper", []js_ast.Expr{target, key,
+			p.visitExpr(js_ast.Expr{Loc: loc, Data: js_ast.EThisShared})}),
+		NameLoc: loc,
+		Name:    "_",
+	}}
+}
+
 func (p *parser) lowerSuperPropertyAccess(loc logger.Loc, key js_ast.Expr) js_ast.Expr {
+	// "super.foo" => "__superGet(_super.prototype, 'foo', this)" inside a
+	// lowered ES5 class. Reading from the base class prototype directly would
+	// call getters with the wrong "this".
+	if p.fnOnlyDataVisit.classMemberForES5 != nil {
+		target, ok := p.superLookupStartForES5(loc)
+		if !ok {
+			return js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+		}
+		return p.callRuntime(loc, "__superGet", []js_ast.Expr{target, key,
+			p.visitExpr(js_ast.Expr{Loc: loc, Data: js_ast.EThisShared})})
+	}
+
 	if p.fnOrArrowDataVisit.superIndexRef == nil {
 		ref := p.newSymbol(js_ast.SymbolOther, "__super")
 		p.fnOrArrowDataVisit.superIndexRef = &ref
@@ -2763,13 +4262,141 @@ func (p *parser) maybeLowerSuperPropertyAccessInsideCall(call *js_ast.ECall) {
 	}
 
 	// "super.foo(a, b)" => "__superIndex('foo').call(this, a, b)"
-	call.Target.Data = &js_ast.EDot{
-		Target:  p.lowerSuperPropertyAccess(call.Target.Loc, key),
-		NameLoc: key.Loc,
-		Name:    "call",
-	}
 	thisExpr := js_ast.Expr{Loc: call.Target.Loc, Data: js_ast.EThisShared}
-	call.Args = append([]js_ast.Expr{thisExpr}, call.Args...)
+	if p.fnOnlyDataVisit.classMemberForES5 != nil {
+		// The value of "this" may need to be substituted in a lowered ES5 class
+		thisExpr = p.visitExpr(thisExpr)
+	}
+	lowered := p.callWithThisArg(call.Target.Loc, p.lowerSuperPropertyAccess(call.Target.Loc, key), thisExpr, call.Args, false).Data.(*js_ast.ECall)
+	call.Target = lowered.Target
+	call.Args = lowered.Args
+}
+
+// Information about the class member that is currently being visited when
+// classes are being lowered to ES5 constructor functions
+type classMemberForES5 struct {
+	// This is the argument of the wrapper function that holds the base class.
+	// It's invalid if the class doesn't have a base class.
+	superRef js_ast.Ref
+
+	isStatic      bool
+	isDerivedCtor bool
+}
+
+// Lower a "super()" call inside the constructor of a derived class that will
+// be lowered to an ES5 constructor function. The result of the base class
+// constructor replaces "this" for the rest of the constructor:
+//
+//   "super(a, b)" => "_this = _super.call(this, a, b) || this"
+//   "super(...arguments)" => "_this = _super.apply(this, arguments) || this"
+//
+func (p *parser) lowerSuperCallES5(loc logger.Loc, args []js_ast.Expr) js_ast.Expr {
+	member := p.fnOnlyDataVisit.classMemberForES5
+	thisRef := p.captureThis()
+	p.recordUsage(member.superRef)
+
+	// Use the captured value inside arrow functions since they may be lowered
+	thisValue := func() js_ast.Expr {
+		if p.fnOrArrowDataVisit.isArrow {
+			return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: thisRef}}
+		}
+		return js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+	}
+
+	method := "call"
+	callArgs := append([]js_ast.Expr{thisValue()}, args...)
+	if hasSpread(args) {
+		method = "apply"
+		callArgs = []js_ast.Expr{thisValue(), p.lowerSpreadToArray(loc, args, false)}
+	}
+
+	return js_ast.Assign(
+		js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: thisRef}},
+		js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+			Op: js_ast.BinOpLogicalOr,
+			Left: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+					Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: member.superRef}},
+					Name:    method,
+					NameLoc: loc,
+				}},
+				Args: callArgs,
+			}},
+			Right: thisValue(),
+		}},
+	)
+}
+
+// The constructor of a derived class that is lowered to an ES5 constructor
+// function must return the value that replaced "this"
+func (p *parser) lowerDerivedCtorBodyES5(body *js_ast.FnBody) {
+	member := p.fnOnlyDataVisit.classMemberForES5
+	if member == nil || !member.isDerivedCtor || p.fnOnlyDataVisit.thisCaptureRef == nil {
+		return
+	}
+	thisRef := *p.fnOnlyDataVisit.thisCaptureRef
+
+	// "var _this = this; _this = _super.call(this) || this;" => "var _this = _super.call(this) || this;"
+	if len(body.Stmts) > 1 {
+		if local, ok := body.Stmts[0].Data.(*js_ast.SLocal); ok {
+			if expr, ok := body.Stmts[1].Data.(*js_ast.SExpr); ok {
+				if assign, ok := expr.Value.Data.(*js_ast.EBinary); ok && assign.Op == js_ast.BinOpAssign {
+					if id, ok := assign.Left.Data.(*js_ast.EIdentifier); ok && id.Ref == thisRef {
+						for i, decl := range local.Decls {
+							if b, ok := decl.Binding.Data.(*js_ast.BIdentifier); ok && b.Ref == thisRef {
+								if _, ok := decl.ValueOrNil.Data.(*js_ast.EThis); ok {
+									local.Decls[i].ValueOrNil = assign.Right
+									body.Stmts = append(body.Stmts[:1], body.Stmts[2:]...)
+								}
+								break
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Add "return _this" to the end unless it's already there
+	if n := len(body.Stmts); n > 0 {
+		if _, ok := body.Stmts[n-1].Data.(*js_ast.SReturn); ok {
+			return
+		}
+	}
+	body.Stmts = append(body.Stmts, js_ast.Stmt{Loc: body.Loc, Data: &js_ast.SReturn{
+		ValueOrNil: js_ast.Expr{Loc: body.Loc, Data: &js_ast.EIdentifier{Ref: thisRef}},
+	}})
+}
+
+// Super calls are rewritten when classes are lowered to ES5 constructor
+// functions. This detects both "_this = _super.call(this) || this" and
+// "var _this = _super.apply(this, arguments) || this".
+func isSuperCallES5(stmt js_ast.Stmt, superRef js_ast.Ref) bool {
+	var values []js_ast.Expr
+	switch s := stmt.Data.(type) {
+	case *js_ast.SExpr:
+		if assign, ok := s.Value.Data.(*js_ast.EBinary); ok && assign.Op == js_ast.BinOpAssign {
+			values = append(values, assign.Right)
+		}
+
+	case *js_ast.SLocal:
+		for _, decl := range s.Decls {
+			values = append(values, decl.ValueOrNil)
+		}
+	}
+
+	for _, value := range values {
+		if or, ok := value.Data.(*js_ast.EBinary); ok && or.Op == js_ast.BinOpLogicalOr {
+			if call, ok := or.Left.Data.(*js_ast.ECall); ok {
+				if dot, ok := call.Target.Data.(*js_ast.EDot); ok {
+					if id, ok := dot.Target.Data.(*js_ast.EIdentifier); ok && id.Ref == superRef {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
 }
 
 func couldPotentiallyThrow(data js_ast.E) bool {
@@ -2780,3 +4407,1908 @@ func couldPotentiallyThrow(data js_ast.E) bool {
 	}
 	return true
 }
+
+// Generator functions are lowered to a state machine that is driven by the
+// "__stateMachine" runtime helper. The function body is split into numbered
+// cases inside a "switch" statement and each case returns an instruction for
+// the runtime (see the comment on "__stateMachine" for the list):
+//
+//   function* foo() {
+//     var x = yield 1;
+//     return x;
+//   }
+//
+// This turns into:
+//
+//   function foo() {
+//     var x;
+//     return __stateMachine(this, function(_a) {
+//       switch (_a.label) {
+//         case 0:
+//           return [4, 1];
+//         case 1:
+//           x = _a.sent();
+//           return [2, x];
+//       }
+//     });
+//   }
+//
+// Local variables are hoisted out of the state machine so their values
+// survive across resumptions. Statements that don't contain "yield" are kept
+// mostly as-is, except that "return" and jumps out of the statement must be
+// turned into instructions for the runtime.
+const (
+	generatorOpReturn     = 2
+	generatorOpJump       = 3
+	generatorOpYield      = 4
+	generatorOpYieldStar  = 5
+	generatorOpEndFinally = 7
+)
+
+type generatorLabel int
+
+type generatorJumpTarget struct {
+	labelRefs     []js_ast.Ref
+	breakLabel    generatorLabel
+	continueLabel generatorLabel // This is -1 if "continue" isn't allowed
+	isLabelOnly   bool           // Unlabeled "break" skips over these
+}
+
+type nativeJumpTarget struct {
+	labelRef js_ast.Ref
+	isLoop   bool
+	isSwitch bool
+}
+
+type generatorLowering struct {
+	p        *parser
+	stateRef js_ast.Ref
+
+	// Each case of the generated "switch" statement
+	cases      [][]js_ast.Stmt
+	labelCases []int
+	labelUses  [][]*js_ast.ENumber
+
+	// Declarations that are moved to the enclosing function
+	hoistedRefs  []js_ast.Ref
+	hoistedSet   map[js_ast.Ref]bool
+	hoistedStmts []js_ast.Stmt
+
+	// The jump targets for the transformed and the untransformed statements
+	jumps         []generatorJumpTarget
+	nativeJumps   []nativeJumpTarget
+	pendingLabels []js_ast.Ref
+}
+
+func (p *parser) lowerGeneratorBody(loc logger.Loc, stmts []js_ast.Stmt, skipArguments bool) []js_ast.Stmt {
+	g := generatorLowering{
+		p:          p,
+		stateRef:   p.generateTempRef(tempRefNoDeclare, ""),
+		cases:      [][]js_ast.Stmt{nil},
+		hoistedSet: make(map[js_ast.Ref]bool),
+	}
+
+	// Keep directives at the top of the enclosing function
+	var prefix []js_ast.Stmt
+	for len(stmts) > 0 {
+		if _, ok := stmts[0].Data.(*js_ast.SDirective); !ok {
+			break
+		}
+		prefix = append(prefix, stmts[0])
+		stmts = stmts[1:]
+	}
+
+	g.stmts(stmts)
+	if !g.isTerminated() {
+		g.emit(g.instruction(loc, generatorOpReturn, js_ast.Expr{}))
+	}
+
+	// Resolve labels to case numbers
+	for label, uses := range g.labelUses {
+		for _, number := range uses {
+			number.Value = float64(g.labelCases[label])
+		}
+	}
+
+	// Generate the body of the state machine. A "switch" statement is only
+	// needed if there is more than one case.
+	var body []js_ast.Stmt
+	if len(g.cases) == 1 {
+		body = g.cases[0]
+	} else {
+		cases := make([]js_ast.Case, len(g.cases))
+		for i, stmts := range g.cases {
+			cases[i] = js_ast.Case{
+				ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: float64(i)}},
+				Body:       stmts,
+			}
+		}
+		body = []js_ast.Stmt{{Loc: loc, Data: &js_ast.SSwitch{
+			Test:    g.state(loc, "label"),
+			BodyLoc: loc,
+			Cases:   cases,
+		}}}
+	}
+
+	// Capture "arguments" since the state machine is in a nested function
+	var decls []js_ast.Decl
+	if argumentsRef := p.fnOnlyDataVisit.argumentsRef; !skipArguments && argumentsRef != nil &&
+		p.symbolUses[*argumentsRef].CountEstimate > 0 {
+		captureRef := p.newSymbol(js_ast.SymbolHoisted, "_arguments")
+		p.currentScope.Generated = append(p.currentScope.Generated, captureRef)
+		p.mergeSymbols(*argumentsRef, captureRef)
+		decls = append(decls, js_ast.Decl{
+			Binding:    js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: captureRef}},
+			ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.newSymbol(js_ast.SymbolUnbound, "arguments")}},
+		})
+	}
+	for _, ref := range g.hoistedRefs {
+		decls = append(decls, js_ast.Decl{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}})
+	}
+	if len(decls) > 0 {
+		prefix = append(prefix, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}})
+	}
+	prefix = append(prefix, g.hoistedStmts...)
+
+	// "function* foo() { stmts }" => "function foo() { return __stateMachine(this, function(_a) { stmts }) }"
+	p.recordUsage(g.stateRef)
+	return append(prefix, js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: p.callRuntime(loc, "__stateMachine", []js_ast.Expr{
+		{Loc: loc, Data: js_ast.EThisShared},
+		{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+			Args:         []js_ast.Arg{{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: g.stateRef}}}},
+			Body:         js_ast.FnBody{Loc: loc, Stmts: body},
+			ArgumentsRef: js_ast.InvalidRef,
+		}}},
+	})}})
+}
+
+func (g *generatorLowering) emit(stmt js_ast.Stmt) {
+	// Anything after a "return" or "throw" in the same case is unreachable
+	if g.isTerminated() {
+		return
+	}
+	last := len(g.cases) - 1
+	g.cases[last] = append(g.cases[last], stmt)
+}
+
+func (g *generatorLowering) isTerminated() bool {
+	stmts := g.cases[len(g.cases)-1]
+	if len(stmts) == 0 {
+		return false
+	}
+	switch stmts[len(stmts)-1].Data.(type) {
+	case *js_ast.SReturn, *js_ast.SThrow:
+		return true
+	}
+	return false
+}
+
+func (g *generatorLowering) newLabel() generatorLabel {
+	g.labelCases = append(g.labelCases, -1)
+	g.labelUses = append(g.labelUses, nil)
+	return generatorLabel(len(g.labelCases) - 1)
+}
+
+// This starts a new case for the label. The runtime only updates the current
+// label when yielding or jumping, so falling through into the next case must
+// update it explicitly.
+func (g *generatorLowering) mark(loc logger.Loc, label generatorLabel) {
+	last := len(g.cases) - 1
+	if len(g.cases[last]) > 0 {
+		if !g.isTerminated() {
+			g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(
+				g.state(loc, "label"),
+				js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: float64(len(g.cases))}},
+			)}})
+		}
+		g.cases = append(g.cases, nil)
+		last++
+	}
+	g.labelCases[label] = last
+}
+
+func (g *generatorLowering) labelExpr(loc logger.Loc, label generatorLabel) js_ast.Expr {
+	number := &js_ast.ENumber{}
+	g.labelUses[label] = append(g.labelUses[label], number)
+	return js_ast.Expr{Loc: loc, Data: number}
+}
+
+func (g *generatorLowering) state(loc logger.Loc, name string) js_ast.Expr {
+	g.p.recordUsage(g.stateRef)
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+		Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: g.stateRef}},
+		Name:    name,
+		NameLoc: loc,
+	}}
+}
+
+func (g *generatorLowering) instruction(loc logger.Loc, op int, valueOrNil js_ast.Expr) js_ast.Stmt {
+	items := []js_ast.Expr{{Loc: loc, Data: &js_ast.ENumber{Value: float64(op)}}}
+	if valueOrNil.Data != nil {
+		items = append(items, valueOrNil)
+	}
+	return js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EArray{Items: items, IsSingleLine: true}}}}
+}
+
+func (g *generatorLowering) jump(loc logger.Loc, label generatorLabel) js_ast.Stmt {
+	return g.instruction(loc, generatorOpJump, g.labelExpr(loc, label))
+}
+
+func (g *generatorLowering) jumpIf(loc logger.Loc, test js_ast.Expr, label generatorLabel) {
+	g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{Test: test, Yes: g.jump(loc, label)}})
+}
+
+func (g *generatorLowering) newTemp() js_ast.Ref {
+	ref := g.p.generateTempRef(tempRefNoDeclare, "")
+	g.hoist(ref)
+	return ref
+}
+
+func (g *generatorLowering) hoist(ref js_ast.Ref) {
+	if !g.hoistedSet[ref] {
+		g.hoistedSet[ref] = true
+		g.hoistedRefs = append(g.hoistedRefs, ref)
+	}
+}
+
+func (g *generatorLowering) ident(loc logger.Loc, ref js_ast.Ref) js_ast.Expr {
+	g.p.recordUsage(ref)
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+}
+
+func (g *generatorLowering) unsupported(loc logger.Loc, what string) {
+	where, notes := g.p.prettyPrintTargetEnvironment(compat.Generator)
+	g.p.log.AddRangeErrorWithNotes(&g.p.tracker, logger.Range{Loc: loc}, fmt.Sprintf(
+		"Transforming %s in generator functions to %s is not supported yet", what, where), notes)
+}
+
+// This evaluates the expression now and stores the value in a temporary
+// variable so that it's not affected by code that runs in later cases
+func (g *generatorLowering) spill(expr js_ast.Expr) js_ast.Expr {
+	switch expr.Data.(type) {
+	case *js_ast.ENumber, *js_ast.EString, *js_ast.EBoolean, *js_ast.ENull,
+		*js_ast.EUndefined, *js_ast.EThis, *js_ast.EMissing, *js_ast.ERegExp:
+		return expr
+	}
+	ref := g.newTemp()
+	g.emit(js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: js_ast.Assign(g.ident(expr.Loc, ref), expr)}})
+	return g.ident(expr.Loc, ref)
+}
+
+// This hoists the variables in the bindings and returns an assignment for
+// the initializers, if any
+func (g *generatorLowering) hoistDecls(decls []js_ast.Decl, lowerValue func(js_ast.Expr) js_ast.Expr) (expr js_ast.Expr) {
+	for _, decl := range decls {
+		for _, id := range findIdentifiers(decl.Binding, nil) {
+			g.hoist(id.Binding.Data.(*js_ast.BIdentifier).Ref)
+		}
+		if decl.ValueOrNil.Data != nil {
+			value := decl.ValueOrNil
+			if lowerValue != nil {
+				value = lowerValue(value)
+			}
+			expr = js_ast.JoinWithComma(expr, js_ast.Assign(js_ast.ConvertBindingToExpr(decl.Binding, nil), value))
+		}
+	}
+	return
+}
+
+// This hoists a loop initializer such as "var x" in "for (var x in y)"
+func (g *generatorLowering) hoistLoopInit(init js_ast.Stmt, onlyVar bool) js_ast.Stmt {
+	if local, ok := init.Data.(*js_ast.SLocal); ok && (!onlyVar || local.Kind == js_ast.LocalVar) {
+		if expr := g.hoistDecls(local.Decls, nil); expr.Data != nil {
+			return js_ast.Stmt{Loc: init.Loc, Data: &js_ast.SExpr{Value: expr}}
+		}
+		if len(local.Decls) == 1 {
+			return js_ast.Stmt{Loc: init.Loc, Data: &js_ast.SExpr{Value: js_ast.ConvertBindingToExpr(local.Decls[0].Binding, nil)}}
+		}
+		return js_ast.Stmt{}
+	}
+	return init
+}
+
+func (g *generatorLowering) stmts(stmts []js_ast.Stmt) {
+	for _, stmt := range stmts {
+		g.stmt(stmt)
+	}
+}
+
+func (g *generatorLowering) stmt(stmt js_ast.Stmt) {
+	// Function declarations are moved to the enclosing function
+	if _, ok := stmt.Data.(*js_ast.SFunction); ok {
+		g.hoistedStmts = append(g.hoistedStmts, stmt)
+		return
+	}
+
+	// Variable declarations are moved to the enclosing function
+	if s, ok := stmt.Data.(*js_ast.SLocal); ok {
+		if expr := g.hoistDecls(s.Decls, g.expr); expr.Data != nil {
+			g.emit(js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: expr}})
+		}
+		return
+	}
+
+	// Class declarations become assignments to a hoisted variable
+	if s, ok := stmt.Data.(*js_ast.SClass); ok && !stmtContainsYield(stmt) {
+		g.hoist(s.Class.Name.Ref)
+		g.emit(js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: js_ast.Assign(
+			g.ident(s.Class.Name.Loc, s.Class.Name.Ref),
+			js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EClass{Class: s.Class}},
+		)}})
+		return
+	}
+
+	// Blocks are flattened since their variables are hoisted anyway
+	if s, ok := stmt.Data.(*js_ast.SBlock); ok {
+		g.stmts(s.Stmts)
+		return
+	}
+
+	// Statements without "yield" can mostly be kept as-is
+	if !stmtContainsYield(stmt) {
+		if stmt = g.nativeStmt(stmt); stmt.Data != nil {
+			g.emit(stmt)
+		}
+		return
+	}
+
+	labels := g.pendingLabels
+	g.pendingLabels = nil
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SExpr:
+		if value := g.expr(s.Value); !g.p.exprCanBeRemovedIfUnused(value) {
+			g.emit(js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: value}})
+		}
+
+	case *js_ast.SReturn:
+		value := s.ValueOrNil
+		if value.Data != nil {
+			value = g.expr(value)
+		}
+		g.emit(g.instruction(stmt.Loc, generatorOpReturn, value))
+
+	case *js_ast.SThrow:
+		g.emit(js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SThrow{Value: g.expr(s.Value)}})
+
+	case *js_ast.SIf:
+		test := g.expr(s.Test)
+		if !stmtContainsYield(s.Yes) && (s.NoOrNil.Data == nil || !stmtContainsYield(s.NoOrNil)) {
+			s.Test = test
+			g.emit(g.nativeStmt(stmt))
+			break
+		}
+		endLabel := g.newLabel()
+		elseLabel := endLabel
+		if s.NoOrNil.Data != nil {
+			elseLabel = g.newLabel()
+		}
+		g.jumpIf(stmt.Loc, js_ast.Not(test), elseLabel)
+		g.stmt(s.Yes)
+		if s.NoOrNil.Data != nil {
+			g.emit(g.jump(stmt.Loc, endLabel))
+			g.mark(s.NoOrNil.Loc, elseLabel)
+			g.stmt(s.NoOrNil)
+		}
+		g.mark(stmt.Loc, endLabel)
+
+	case *js_ast.SLabel:
+		switch s.Stmt.Data.(type) {
+		case *js_ast.SFor, *js_ast.SForIn, *js_ast.SWhile, *js_ast.SDoWhile, *js_ast.SLabel:
+			// Loops handle their own labels so "continue" works
+			g.pendingLabels = append(labels, s.Name.Ref)
+			g.stmt(s.Stmt)
+
+		case *js_ast.SForOf:
+			g.stmt(g.p.lowerForOfToIteratorLoop(s.Stmt.Loc, s.Stmt.Data.(*js_ast.SForOf), &s.Name))
+
+		default:
+			breakLabel := g.newLabel()
+			g.jumps = append(g.jumps, generatorJumpTarget{
+				labelRefs:     append(labels, s.Name.Ref),
+				breakLabel:    breakLabel,
+				continueLabel: -1,
+				isLabelOnly:   true,
+			})
+			g.stmt(s.Stmt)
+			g.jumps = g.jumps[:len(g.jumps)-1]
+			g.mark(stmt.Loc, breakLabel)
+		}
+
+	case *js_ast.SWhile:
+		loopLabel := g.newLabel()
+		breakLabel := g.newLabel()
+		g.mark(stmt.Loc, loopLabel)
+		g.jumpIf(stmt.Loc, js_ast.Not(g.expr(s.Test)), breakLabel)
+		g.loopBody(labels, s.Body, breakLabel, loopLabel)
+		g.emit(g.jump(stmt.Loc, loopLabel))
+		g.mark(stmt.Loc, breakLabel)
+
+	case *js_ast.SDoWhile:
+		loopLabel := g.newLabel()
+		continueLabel := g.newLabel()
+		breakLabel := g.newLabel()
+		g.mark(stmt.Loc, loopLabel)
+		g.loopBody(labels, s.Body, breakLabel, continueLabel)
+		g.mark(s.Test.Loc, continueLabel)
+		g.jumpIf(stmt.Loc, g.expr(s.Test), loopLabel)
+		g.mark(stmt.Loc, breakLabel)
+
+	case *js_ast.SFor:
+		if s.InitOrNil.Data != nil {
+			g.stmt(s.InitOrNil)
+		}
+		loopLabel := g.newLabel()
+		continueLabel := g.newLabel()
+		breakLabel := g.newLabel()
+		g.mark(stmt.Loc, loopLabel)
+		if s.TestOrNil.Data != nil {
+			g.jumpIf(stmt.Loc, js_ast.Not(g.expr(s.TestOrNil)), breakLabel)
+		}
+		g.loopBody(labels, s.Body, breakLabel, continueLabel)
+		g.mark(stmt.Loc, continueLabel)
+		if s.UpdateOrNil.Data != nil {
+			g.emit(js_ast.Stmt{Loc: s.UpdateOrNil.Loc, Data: &js_ast.SExpr{Value: g.expr(s.UpdateOrNil)}})
+		}
+		g.emit(g.jump(stmt.Loc, loopLabel))
+		g.mark(stmt.Loc, breakLabel)
+
+	case *js_ast.SForIn:
+		// The keys must be collected up front since the loop is split up:
+		//
+		//   for (x in obj) body
+		//
+		// This turns into something like this:
+		//
+		//   _b = obj, _c = [];
+		//   for (_d in _b) _c.push(_d);
+		//   _e = 0;
+		//   while (_e < _c.length) { x = _c[_e]; if (x in _b) body; _e++ }
+		//
+		objRef := g.newTemp()
+		keysRef := g.newTemp()
+		keyRef := g.newTemp()
+		indexRef := g.newTemp()
+		loc := stmt.Loc
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.JoinWithComma(
+			js_ast.Assign(g.ident(loc, objRef), g.expr(s.Value)),
+			js_ast.Assign(g.ident(loc, keysRef), js_ast.Expr{Loc: loc, Data: &js_ast.EArray{}}),
+		)}})
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SForIn{
+			Init:  js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: g.ident(loc, keyRef)}},
+			Value: g.ident(loc, objRef),
+			Body: js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.ident(loc, keysRef), Name: "push", NameLoc: loc}},
+				Args:   []js_ast.Expr{g.ident(loc, keyRef)},
+			}}}},
+		}})
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(
+			g.ident(loc, indexRef), js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: 0}})}})
+		loopLabel := g.newLabel()
+		continueLabel := g.newLabel()
+		breakLabel := g.newLabel()
+		g.mark(loc, loopLabel)
+		g.jumpIf(loc, js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+			Op:    js_ast.BinOpGe,
+			Left:  g.ident(loc, indexRef),
+			Right: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.ident(loc, keysRef), Name: "length", NameLoc: loc}},
+		}}, breakLabel)
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(g.ident(loc, keyRef), js_ast.Expr{Loc: loc, Data: &js_ast.EIndex{
+			Target: g.ident(loc, keysRef),
+			Index:  g.ident(loc, indexRef),
+		}})}})
+		g.jumpIf(loc, js_ast.Not(js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+			Op:    js_ast.BinOpIn,
+			Left:  g.ident(loc, keyRef),
+			Right: g.ident(loc, objRef),
+		}}), continueLabel)
+		var target js_ast.Expr
+		if init := g.hoistLoopInit(s.Init, false); init.Data != nil {
+			target = init.Data.(*js_ast.SExpr).Value
+		}
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: g.expr(js_ast.Assign(target, g.ident(loc, keyRef)))}})
+		g.loopBody(labels, s.Body, breakLabel, continueLabel)
+		g.mark(loc, continueLabel)
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{
+			Op:    js_ast.UnOpPostInc,
+			Value: g.ident(loc, indexRef),
+		}}}})
+		g.emit(g.jump(loc, loopLabel))
+		g.mark(loc, breakLabel)
+
+	case *js_ast.SForOf:
+		g.stmt(g.p.lowerForOfToIteratorLoop(stmt.Loc, s, nil))
+
+	case *js_ast.SSwitch:
+		test := g.spill(g.expr(s.Test))
+		breakLabel := g.newLabel()
+		defaultLabel := breakLabel
+		caseLabels := make([]generatorLabel, len(s.Cases))
+		for i, c := range s.Cases {
+			caseLabels[i] = g.newLabel()
+			if c.ValueOrNil.Data == nil {
+				defaultLabel = caseLabels[i]
+			} else {
+				g.jumpIf(c.ValueOrNil.Loc, js_ast.Expr{Loc: c.ValueOrNil.Loc, Data: &js_ast.EBinary{
+					Op:    js_ast.BinOpStrictEq,
+					Left:  test,
+					Right: g.expr(c.ValueOrNil),
+				}}, caseLabels[i])
+			}
+		}
+		g.emit(g.jump(stmt.Loc, defaultLabel))
+		g.jumps = append(g.jumps, generatorJumpTarget{labelRefs: labels, breakLabel: breakLabel, continueLabel: -1})
+		for i, c := range s.Cases {
+			g.mark(stmt.Loc, caseLabels[i])
+			g.stmts(c.Body)
+		}
+		g.jumps = g.jumps[:len(g.jumps)-1]
+		g.mark(stmt.Loc, breakLabel)
+
+	case *js_ast.STry:
+		// The runtime keeps a stack of the active "try" blocks
+		tryLabel := g.newLabel()
+		catchLabel := generatorLabel(-1)
+		finallyLabel := generatorLabel(-1)
+		if s.Catch != nil {
+			catchLabel = g.newLabel()
+		}
+		if s.Finally != nil {
+			finallyLabel = g.newLabel()
+		}
+		endLabel := g.newLabel()
+		g.mark(stmt.Loc, tryLabel)
+		region := []js_ast.Expr{g.labelExpr(stmt.Loc, tryLabel)}
+		for _, label := range []generatorLabel{catchLabel, finallyLabel} {
+			if label == -1 {
+				region = append(region, js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EMissing{}})
+			} else {
+				region = append(region, g.labelExpr(stmt.Loc, label))
+			}
+		}
+		region = append(region, g.labelExpr(stmt.Loc, endLabel))
+		g.emit(js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.ECall{
+			Target: js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EDot{Target: g.state(stmt.Loc, "trys"), Name: "push", NameLoc: stmt.Loc}},
+			Args:   []js_ast.Expr{{Loc: stmt.Loc, Data: &js_ast.EArray{Items: region, IsSingleLine: true}}},
+		}}}})
+		g.stmts(s.Body)
+		g.emit(g.jump(stmt.Loc, endLabel))
+		if s.Catch != nil {
+			g.mark(s.Catch.Loc, catchLabel)
+			sent := js_ast.Expr{Loc: s.Catch.Loc, Data: &js_ast.ECall{Target: g.state(s.Catch.Loc, "sent")}}
+			if s.Catch.BindingOrNil.Data != nil {
+				g.emit(js_ast.Stmt{Loc: s.Catch.Loc, Data: &js_ast.SExpr{Value: g.hoistDecls(
+					[]js_ast.Decl{{Binding: s.Catch.BindingOrNil, ValueOrNil: sent}}, nil)}})
+			}
+			g.stmts(s.Catch.Body)
+			g.emit(g.jump(s.Catch.Loc, endLabel))
+		}
+		if s.Finally != nil {
+			g.mark(s.Finally.Loc, finallyLabel)
+			g.stmts(s.Finally.Stmts)
+			g.emit(g.instruction(s.Finally.Loc, generatorOpEndFinally, js_ast.Expr{}))
+		}
+		g.mark(stmt.Loc, endLabel)
+
+	default:
+		g.unsupported(stmt.Loc, "this statement")
+	}
+}
+
+func (g *generatorLowering) loopBody(labels []js_ast.Ref, body js_ast.Stmt, breakLabel generatorLabel, continueLabel generatorLabel) {
+	g.jumps = append(g.jumps, generatorJumpTarget{labelRefs: labels, breakLabel: breakLabel, continueLabel: continueLabel})
+	g.stmt(body)
+	g.jumps = g.jumps[:len(g.jumps)-1]
+}
+
+// This converts a statement without "yield" that is kept as-is. Any "return"
+// statements and jumps to transformed statements must be turned into
+// instructions for the runtime.
+func (g *generatorLowering) nativeStmt(stmt js_ast.Stmt) js_ast.Stmt {
+	switch s := stmt.Data.(type) {
+	case *js_ast.SReturn:
+		return g.instruction(stmt.Loc, generatorOpReturn, s.ValueOrNil)
+
+	case *js_ast.SBreak:
+		for i := len(g.nativeJumps) - 1; i >= 0; i-- {
+			if target := g.nativeJumps[i]; s.Label != nil && target.labelRef == s.Label.Ref ||
+				s.Label == nil && (target.isLoop || target.isSwitch) {
+				return stmt
+			}
+		}
+		for i := len(g.jumps) - 1; i >= 0; i-- {
+			if target := g.jumps[i]; s.Label != nil && hasRef(target.labelRefs, s.Label.Ref) ||
+				s.Label == nil && !target.isLabelOnly {
+				return g.jump(stmt.Loc, target.breakLabel)
+			}
+		}
+
+	case *js_ast.SContinue:
+		for i := len(g.nativeJumps) - 1; i >= 0; i-- {
+			if target := g.nativeJumps[i]; target.isLoop && (s.Label == nil || target.labelRef == s.Label.Ref) {
+				return stmt
+			}
+		}
+		for i := len(g.jumps) - 1; i >= 0; i-- {
+			if target := g.jumps[i]; target.continueLabel != -1 && (s.Label == nil || hasRef(target.labelRefs, s.Label.Ref)) {
+				return g.jump(stmt.Loc, target.continueLabel)
+			}
+		}
+
+	case *js_ast.SLocal:
+		if s.Kind == js_ast.LocalVar {
+			if expr := g.hoistDecls(s.Decls, nil); expr.Data != nil {
+				return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: expr}}
+			}
+			return js_ast.Stmt{}
+		}
+
+	case *js_ast.SBlock:
+		s.Stmts = g.nativeStmts(s.Stmts)
+
+	case *js_ast.SIf:
+		s.Yes = g.nativeStmtOrEmpty(s.Yes)
+		if s.NoOrNil.Data != nil {
+			s.NoOrNil = g.nativeStmt(s.NoOrNil)
+		}
+
+	case *js_ast.SLabel:
+		isLoop := false
+		switch s.Stmt.Data.(type) {
+		case *js_ast.SFor, *js_ast.SForIn, *js_ast.SForOf, *js_ast.SWhile, *js_ast.SDoWhile:
+			isLoop = true
+		}
+		g.nativeJumps = append(g.nativeJumps, nativeJumpTarget{labelRef: s.Name.Ref, isLoop: isLoop})
+		s.Stmt = g.nativeStmtOrEmpty(s.Stmt)
+		g.nativeJumps = g.nativeJumps[:len(g.nativeJumps)-1]
+
+	case *js_ast.SFor:
+		if s.InitOrNil.Data != nil {
+			s.InitOrNil = g.hoistLoopInit(s.InitOrNil, true)
+		}
+		s.Body = g.nativeLoopBody(s.Body)
+
+	case *js_ast.SForIn:
+		s.Init = g.hoistLoopInit(s.Init, true)
+		s.Body = g.nativeLoopBody(s.Body)
+
+	case *js_ast.SForOf:
+		s.Init = g.hoistLoopInit(s.Init, true)
+		s.Body = g.nativeLoopBody(s.Body)
+
+	case *js_ast.SWhile:
+		s.Body = g.nativeLoopBody(s.Body)
+
+	case *js_ast.SDoWhile:
+		s.Body = g.nativeLoopBody(s.Body)
+
+	case *js_ast.SWith:
+		s.Body = g.nativeStmtOrEmpty(s.Body)
+
+	case *js_ast.SSwitch:
+		g.nativeJumps = append(g.nativeJumps, nativeJumpTarget{labelRef: js_ast.InvalidRef, isSwitch: true})
+		for i := range s.Cases {
+			s.Cases[i].Body = g.nativeStmts(s.Cases[i].Body)
+		}
+		g.nativeJumps = g.nativeJumps[:len(g.nativeJumps)-1]
+
+	case *js_ast.STry:
+		s.Body = g.nativeStmts(s.Body)
+		if s.Catch != nil {
+			s.Catch.Body = g.nativeStmts(s.Catch.Body)
+		}
+		if s.Finally != nil {
+			s.Finally.Stmts = g.nativeStmts(s.Finally.Stmts)
+		}
+	}
+
+	return stmt
+}
+
+func (g *generatorLowering) nativeStmts(stmts []js_ast.Stmt) []js_ast.Stmt {
+	end := 0
+	for _, stmt := range stmts {
+		if stmt = g.nativeStmt(stmt); stmt.Data != nil {
+			stmts[end] = stmt
+			end++
+		}
+	}
+	return stmts[:end]
+}
+
+func (g *generatorLowering) nativeStmtOrEmpty(stmt js_ast.Stmt) js_ast.Stmt {
+	if result := g.nativeStmt(stmt); result.Data != nil {
+		return result
+	}
+	return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SEmpty{}}
+}
+
+func (g *generatorLowering) nativeLoopBody(body js_ast.Stmt) js_ast.Stmt {
+	g.nativeJumps = append(g.nativeJumps, nativeJumpTarget{labelRef: js_ast.InvalidRef, isLoop: true})
+	body = g.nativeStmtOrEmpty(body)
+	g.nativeJumps = g.nativeJumps[:len(g.nativeJumps)-1]
+	return body
+}
+
+func hasRef(refs []js_ast.Ref, ref js_ast.Ref) bool {
+	for _, it := range refs {
+		if it == ref {
+			return true
+		}
+	}
+	return false
+}
+
+var generatorCompoundAssignOps = map[js_ast.OpCode]js_ast.OpCode{
+	js_ast.BinOpAddAssign:        js_ast.BinOpAdd,
+	js_ast.BinOpSubAssign:        js_ast.BinOpSub,
+	js_ast.BinOpMulAssign:        js_ast.BinOpMul,
+	js_ast.BinOpDivAssign:        js_ast.BinOpDiv,
+	js_ast.BinOpRemAssign:        js_ast.BinOpRem,
+	js_ast.BinOpPowAssign:        js_ast.BinOpPow,
+	js_ast.BinOpShlAssign:        js_ast.BinOpShl,
+	js_ast.BinOpShrAssign:        js_ast.BinOpShr,
+	js_ast.BinOpUShrAssign:       js_ast.BinOpUShr,
+	js_ast.BinOpBitwiseOrAssign:  js_ast.BinOpBitwiseOr,
+	js_ast.BinOpBitwiseAndAssign: js_ast.BinOpBitwiseAnd,
+	js_ast.BinOpBitwiseXorAssign: js_ast.BinOpBitwiseXor,
+}
+
+var generatorLogicalAssignOps = map[js_ast.OpCode]js_ast.OpCode{
+	js_ast.BinOpNullishCoalescingAssign: js_ast.BinOpNullishCoalescing,
+	js_ast.BinOpLogicalOrAssign:         js_ast.BinOpLogicalOr,
+	js_ast.BinOpLogicalAndAssign:        js_ast.BinOpLogicalAnd,
+}
+
+// This returns an expression that can be evaluated in the current case. Each
+// "yield" ends the current case and becomes a call to "_a.sent()" in the next
+// case. Anything that is evaluated before a "yield" is stored in a temporary
+// variable to preserve the order of evaluation.
+func (g *generatorLowering) expr(expr js_ast.Expr) js_ast.Expr {
+	if !exprContainsYield(expr) {
+		return expr
+	}
+
+	switch e := expr.Data.(type) {
+	case *js_ast.EYield:
+		op := generatorOpYield
+		if e.IsStar {
+			op = generatorOpYieldStar
+		}
+		value := e.ValueOrNil
+		if value.Data != nil {
+			value = g.expr(value)
+		}
+		g.emit(g.instruction(expr.Loc, op, value))
+		g.mark(expr.Loc, g.newLabel())
+		return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ECall{Target: g.state(expr.Loc, "sent")}}
+
+	case *js_ast.EBinary:
+		rightHasYield := exprContainsYield(e.Right)
+
+		switch e.Op {
+		case js_ast.BinOpComma:
+			if left := g.expr(e.Left); !g.p.exprCanBeRemovedIfUnused(left) {
+				g.emit(js_ast.Stmt{Loc: left.Loc, Data: &js_ast.SExpr{Value: left}})
+			}
+			return g.expr(e.Right)
+
+		case js_ast.BinOpLogicalAnd, js_ast.BinOpLogicalOr, js_ast.BinOpNullishCoalescing:
+			if !rightHasYield {
+				e.Left = g.expr(e.Left)
+				return expr
+			}
+
+			// "a && (yield b)" => "_b = a; if (!_b) jump end; _b = yield b; end:"
+			temp := g.newTemp()
+			g.emit(js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: js_ast.Assign(g.ident(expr.Loc, temp), g.expr(e.Left))}})
+			endLabel := g.newLabel()
+			test := g.ident(expr.Loc, temp)
+			switch e.Op {
+			case js_ast.BinOpLogicalAnd:
+				test = js_ast.Not(test)
+			case js_ast.BinOpNullishCoalescing:
+				test = js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EBinary{Op: js_ast.BinOpLooseNe, Left: test, Right: js_ast.Expr{Loc: expr.Loc, Data: js_ast.ENullShared}}}
+			}
+			g.jumpIf(expr.Loc, test, endLabel)
+			g.emit(js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: js_ast.Assign(g.ident(expr.Loc, temp), g.expr(e.Right))}})
+			g.mark(expr.Loc, endLabel)
+			return g.ident(expr.Loc, temp)
+		}
+
+		if e.Op.BinaryAssignTarget() == js_ast.AssignTargetNone {
+			e.Left = g.expr(e.Left)
+			if rightHasYield {
+				e.Left = g.spill(e.Left)
+			}
+			e.Right = g.expr(e.Right)
+			return expr
+		}
+
+		// "a ||= yield b" => "a || (a = yield b)"
+		if op, ok := generatorLogicalAssignOps[e.Op]; ok {
+			if _, ok := e.Left.Data.(*js_ast.EIdentifier); !ok {
+				g.unsupported(expr.Loc, "this logical assignment")
+				return expr
+			}
+			return g.expr(js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EBinary{Op: op, Left: e.Left, Right: js_ast.Assign(e.Left, e.Right)}})
+		}
+
+		// "a.b += yield c" => "_b = a, _c = _b.b; _b.b = _c + (yield c)"
+		target := g.assignTarget(e.Left, rightHasYield)
+		if op, ok := generatorCompoundAssignOps[e.Op]; ok && rightHasYield {
+			old := g.spill(cloneAssignTarget(target))
+			return js_ast.Assign(target, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EBinary{Op: op, Left: old, Right: g.expr(e.Right)}})
+		}
+		e.Left = target
+		e.Right = g.expr(e.Right)
+		return expr
+
+	case *js_ast.EUnary:
+		if e.Op.UnaryAssignTarget() != js_ast.AssignTargetNone {
+			e.Value = g.assignTarget(e.Value, false)
+		} else {
+			e.Value = g.expr(e.Value)
+		}
+		return expr
+
+	case *js_ast.EIf:
+		if !exprContainsYield(e.Yes) && !exprContainsYield(e.No) {
+			e.Test = g.expr(e.Test)
+			return expr
+		}
+
+		// "a ? yield b : c" => "if (!a) jump no; _b = yield b; jump end; no: _b = c; end:"
+		temp := g.newTemp()
+		noLabel := g.newLabel()
+		endLabel := g.newLabel()
+		g.jumpIf(expr.Loc, js_ast.Not(g.expr(e.Test)), noLabel)
+		g.emit(js_ast.Stmt{Loc: e.Yes.Loc, Data: &js_ast.SExpr{Value: js_ast.Assign(g.ident(e.Yes.Loc, temp), g.expr(e.Yes))}})
+		g.emit(g.jump(expr.Loc, endLabel))
+		g.mark(e.No.Loc, noLabel)
+		g.emit(js_ast.Stmt{Loc: e.No.Loc, Data: &js_ast.SExpr{Value: js_ast.Assign(g.ident(e.No.Loc, temp), g.expr(e.No))}})
+		g.mark(expr.Loc, endLabel)
+		return g.ident(expr.Loc, temp)
+
+	case *js_ast.ECall:
+		if e.OptionalChain != js_ast.OptionalChainNone {
+			break
+		}
+		if argsHaveYield := exprsContainYield(e.Args); argsHaveYield {
+			// Keep the value of "this" for method calls
+			switch target := e.Target.Data.(type) {
+			case *js_ast.EDot:
+				if target.OptionalChain == js_ast.OptionalChainNone {
+					target.Target = g.spill(g.expr(target.Target))
+				}
+			case *js_ast.EIndex:
+				if target.OptionalChain == js_ast.OptionalChainNone {
+					target.Target = g.spill(g.expr(target.Target))
+					target.Index = g.spill(g.expr(target.Index))
+				}
+			case *js_ast.EIdentifier, *js_ast.ESuper:
+			default:
+				e.Target = g.spill(g.expr(e.Target))
+			}
+		} else {
+			e.Target = g.expr(e.Target)
+		}
+		g.exprs(exprPointers(e.Args))
+		return expr
+
+	case *js_ast.ENew:
+		e.Target = g.expr(e.Target)
+		if exprsContainYield(e.Args) {
+			e.Target = g.spill(e.Target)
+		}
+		g.exprs(exprPointers(e.Args))
+		return expr
+
+	case *js_ast.EDot:
+		if e.OptionalChain != js_ast.OptionalChainNone {
+			break
+		}
+		e.Target = g.expr(e.Target)
+		return expr
+
+	case *js_ast.EIndex:
+		if e.OptionalChain != js_ast.OptionalChainNone {
+			break
+		}
+		g.exprs([]*js_ast.Expr{&e.Target, &e.Index})
+		return expr
+
+	case *js_ast.EArray:
+		g.exprs(exprPointers(e.Items))
+		return expr
+
+	case *js_ast.EObject:
+		var parts []*js_ast.Expr
+		for i := range e.Properties {
+			property := &e.Properties[i]
+			if property.IsComputed {
+				parts = append(parts, &property.Key)
+			}
+			if property.ValueOrNil.Data != nil {
+				parts = append(parts, &property.ValueOrNil)
+			}
+		}
+		g.exprs(parts)
+		return expr
+
+	case *js_ast.ESpread:
+		e.Value = g.expr(e.Value)
+		return expr
+
+	case *js_ast.ETemplate:
+		var parts []*js_ast.Expr
+		if e.TagOrNil.Data != nil {
+			parts = append(parts, &e.TagOrNil)
+		}
+		for i := range e.Parts {
+			parts = append(parts, &e.Parts[i].Value)
+		}
+		g.exprs(parts)
+		return expr
+
+	case *js_ast.EImportCall:
+		parts := []*js_ast.Expr{&e.Expr}
+		if e.OptionsOrNil.Data != nil {
+			parts = append(parts, &e.OptionsOrNil)
+		}
+		g.exprs(parts)
+		return expr
+	}
+
+	g.unsupported(expr.Loc, "this expression")
+	return expr
+}
+
+// This lowers a list of expressions that are evaluated from left to right
+func (g *generatorLowering) exprs(exprs []*js_ast.Expr) {
+	last := -1
+	for i, expr := range exprs {
+		if exprContainsYield(*expr) {
+			last = i
+		}
+	}
+	for i := 0; i <= last; i++ {
+		if i < last {
+			*exprs[i] = g.spill(g.expr(*exprs[i]))
+		} else {
+			*exprs[i] = g.expr(*exprs[i])
+		}
+	}
+}
+
+func exprPointers(exprs []js_ast.Expr) []*js_ast.Expr {
+	pointers := make([]*js_ast.Expr, 0, len(exprs))
+	for i := range exprs {
+		switch e := exprs[i].Data.(type) {
+		case *js_ast.EMissing:
+		case *js_ast.ESpread:
+			pointers = append(pointers, &e.Value)
+		default:
+			pointers = append(pointers, &exprs[i])
+		}
+	}
+	return pointers
+}
+
+func (g *generatorLowering) assignTarget(target js_ast.Expr, spillParts bool) js_ast.Expr {
+	switch e := target.Data.(type) {
+	case *js_ast.EIdentifier:
+		return target
+
+	case *js_ast.EDot:
+		e.Target = g.expr(e.Target)
+		if spillParts {
+			e.Target = g.spill(e.Target)
+		}
+		return target
+
+	case *js_ast.EIndex:
+		e.Target = g.expr(e.Target)
+		if spillParts || exprContainsYield(e.Index) {
+			e.Target = g.spill(e.Target)
+		}
+		e.Index = g.expr(e.Index)
+		if spillParts {
+			e.Index = g.spill(e.Index)
+		}
+		return target
+	}
+
+	if exprContainsYield(target) {
+		g.unsupported(target.Loc, "this assignment target")
+	}
+	return target
+}
+
+func cloneAssignTarget(target js_ast.Expr) js_ast.Expr {
+	switch e := target.Data.(type) {
+	case *js_ast.EDot:
+		clone := *e
+		return js_ast.Expr{Loc: target.Loc, Data: &clone}
+
+	case *js_ast.EIndex:
+		clone := *e
+		return js_ast.Expr{Loc: target.Loc, Data: &clone}
+	}
+	return target
+}
+
+func exprsContainYield(exprs []js_ast.Expr) bool {
+	for _, expr := range exprs {
+		if exprContainsYield(expr) {
+			return true
+		}
+	}
+	return false
+}
+
+func propertiesContainYield(properties []js_ast.Property) bool {
+	for _, property := range properties {
+		if exprContainsYield(property.Key) || exprContainsYield(property.ValueOrNil) || exprContainsYield(property.InitializerOrNil) {
+			return true
+		}
+	}
+	return false
+}
+
+// This doesn't look inside nested functions since "yield" there belongs to
+// the nested function instead
+func exprContainsYield(expr js_ast.Expr) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EYield:
+		return true
+
+	case *js_ast.EAwait:
+		return exprContainsYield(e.Value)
+
+	case *js_ast.EArray:
+		return exprsContainYield(e.Items)
+
+	case *js_ast.EUnary:
+		return exprContainsYield(e.Value)
+
+	case *js_ast.EBinary:
+		return exprContainsYield(e.Left) || exprContainsYield(e.Right)
+
+	case *js_ast.EIf:
+		return exprContainsYield(e.Test) || exprContainsYield(e.Yes) || exprContainsYield(e.No)
+
+	case *js_ast.ENew:
+		return exprContainsYield(e.Target) || exprsContainYield(e.Args)
+
+	case *js_ast.ECall:
+		return exprContainsYield(e.Target) || exprsContainYield(e.Args)
+
+	case *js_ast.EDot:
+		return exprContainsYield(e.Target)
+
+	case *js_ast.EIndex:
+		return exprContainsYield(e.Target) || exprContainsYield(e.Index)
+
+	case *js_ast.EObject:
+		return propertiesContainYield(e.Properties)
+
+	case *js_ast.ESpread:
+		return exprContainsYield(e.Value)
+
+	case *js_ast.ETemplate:
+		if exprContainsYield(e.TagOrNil) {
+			return true
+		}
+		for _, part := range e.Parts {
+			if exprContainsYield(part.Value) {
+				return true
+			}
+		}
+
+	case *js_ast.EClass:
+		return exprContainsYield(e.Class.ExtendsOrNil) || propertiesContainYield(e.Class.Properties)
+
+	case *js_ast.EJSXElement:
+		return exprContainsYield(e.TagOrNil) || propertiesContainYield(e.Properties) || exprsContainYield(e.Children)
+
+	case *js_ast.EImportCall:
+		return exprContainsYield(e.Expr) || exprContainsYield(e.OptionsOrNil)
+	}
+
+	return false
+}
+
+func stmtsContainYield(stmts []js_ast.Stmt) bool {
+	for _, stmt := range stmts {
+		if stmtContainsYield(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func stmtContainsYield(stmt js_ast.Stmt) bool {
+	switch s := stmt.Data.(type) {
+	case *js_ast.SBlock:
+		return stmtsContainYield(s.Stmts)
+
+	case *js_ast.SExpr:
+		return exprContainsYield(s.Value)
+
+	case *js_ast.SReturn:
+		return exprContainsYield(s.ValueOrNil)
+
+	case *js_ast.SThrow:
+		return exprContainsYield(s.Value)
+
+	case *js_ast.SLocal:
+		for _, decl := range s.Decls {
+			if exprContainsYield(decl.ValueOrNil) {
+				return true
+			}
+		}
+
+	case *js_ast.SClass:
+		return exprContainsYield(s.Class.ExtendsOrNil) || propertiesContainYield(s.Class.Properties)
+
+	case *js_ast.SIf:
+		return exprContainsYield(s.Test) || stmtContainsYield(s.Yes) || stmtContainsYield(s.NoOrNil)
+
+	case *js_ast.SFor:
+		return stmtContainsYield(s.InitOrNil) || exprContainsYield(s.TestOrNil) ||
+			exprContainsYield(s.UpdateOrNil) || stmtContainsYield(s.Body)
+
+	case *js_ast.SForIn:
+		return stmtContainsYield(s.Init) || exprContainsYield(s.Value) || stmtContainsYield(s.Body)
+
+	case *js_ast.SForOf:
+		return stmtContainsYield(s.Init) || exprContainsYield(s.Value) || stmtContainsYield(s.Body)
+
+	case *js_ast.SWhile:
+		return exprContainsYield(s.Test) || stmtContainsYield(s.Body)
+
+	case *js_ast.SDoWhile:
+		return stmtContainsYield(s.Body) || exprContainsYield(s.Test)
+
+	case *js_ast.SWith:
+		return exprContainsYield(s.Value) || stmtContainsYield(s.Body)
+
+	case *js_ast.SLabel:
+		return stmtContainsYield(s.Stmt)
+
+	case *js_ast.STry:
+		return stmtsContainYield(s.Body) || (s.Catch != nil && stmtsContainYield(s.Catch.Body)) ||
+			(s.Finally != nil && stmtsContainYield(s.Finally.Stmts))
+
+	case *js_ast.SSwitch:
+		if exprContainsYield(s.Test) {
+			return true
+		}
+		for _, c := range s.Cases {
+			if exprContainsYield(c.ValueOrNil) || stmtsContainYield(c.Body) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// This lowers a "for-of" or "for await" loop to a "for" loop that uses the
+// iterator protocol directly:
+//
+//   try {
+//     for (var iter = __forAwait(y), more, temp, error; more = !(temp = await iter.next()).done; more = false) {
+//       var x = temp.value;
+//     }
+//   } catch (temp) {
+//     error = [temp];
+//   } finally {
+//     try {
+//       more && (temp = iter.return) && await temp.call(iter);
+//     } finally {
+//       if (error) throw error[0];
+//     }
+//   }
+//
+// The label of the loop, if any, is moved onto the inner "for" loop. Regular
+// "for-of" loops over an array literal skip the iterator protocol entirely:
+//
+//   for (var _i = 0, _a = [1, 2]; _i < _a.length; _i++) {
+//     var x = _a[_i];
+//   }
+//
+func (p *parser) lowerForOfToIteratorLoop(loc logger.Loc, s *js_ast.SForOf, label *js_ast.LocRef) js_ast.Stmt {
+	if array, ok := s.Value.Data.(*js_ast.EArray); ok && !s.IsAwait && !hasSpread(array.Items) {
+		return p.lowerForOfArrayLiteral(loc, s, label)
+	}
+
+	iterRef := p.generateTempRef(tempRefNoDeclare, "iter")
+	moreRef := p.generateTempRef(tempRefNoDeclare, "more")
+	tempRef := p.generateTempRef(tempRefNoDeclare, "temp")
+	errorRef := p.generateTempRef(tempRefNoDeclare, "error")
+	ident := func(ref js_ast.Ref) js_ast.Expr {
+		p.recordUsage(ref)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+	}
+	binding := func(ref js_ast.Ref) js_ast.Binding {
+		return js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}
+	}
+	await := func(value js_ast.Expr) js_ast.Expr {
+		if s.IsAwait {
+			return p.lowerAwait(loc, value)
+		}
+		return value
+	}
+	helper := "__getIterator"
+	if s.IsAwait {
+		helper = "__forAwait"
+	}
+
+	// Assign the value to the loop variable at the start of the body
+	body := forOfBodyWithValue(s, js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: ident(tempRef), Name: "value", NameLoc: loc}})
+
+	loop := js_ast.Stmt{Loc: loc, Data: &js_ast.SFor{
+		InitOrNil: js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{
+			{Binding: binding(iterRef), ValueOrNil: p.callRuntime(loc, helper, []js_ast.Expr{s.Value})},
+			{Binding: binding(moreRef)},
+			{Binding: binding(tempRef)},
+			{Binding: binding(errorRef)},
+		}}},
+		TestOrNil: js_ast.Assign(ident(moreRef), js_ast.Not(js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+			Target: js_ast.Assign(ident(tempRef), await(js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: ident(iterRef), Name: "next", NameLoc: loc}},
+			}})),
+			Name:    "done",
+			NameLoc: loc,
+		}})),
+		UpdateOrNil: js_ast.Assign(ident(moreRef), js_ast.Expr{Loc: loc, Data: &js_ast.EBoolean{Value: false}}),
+		Body:        js_ast.Stmt{Loc: s.Body.Loc, Data: &js_ast.SBlock{Stmts: body}},
+	}}
+	if label != nil {
+		loop = js_ast.Stmt{Loc: loc, Data: &js_ast.SLabel{Name: *label, Stmt: loop}}
+	}
+
+	// "more && (temp = iter.return) && await temp.call(iter)"
+	closeIter := js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+		Op: js_ast.BinOpLogicalAnd,
+		Left: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+			Op:    js_ast.BinOpLogicalAnd,
+			Left:  ident(moreRef),
+			Right: js_ast.Assign(ident(tempRef), js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: ident(iterRef), Name: "return", NameLoc: loc}}),
+		}},
+		Right: await(js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: ident(tempRef), Name: "call", NameLoc: loc}},
+			Args:   []js_ast.Expr{ident(iterRef)},
+		}}),
+	}}
+
+	return js_ast.Stmt{Loc: loc, Data: &js_ast.STry{
+		BodyLoc: loc,
+		Body:    []js_ast.Stmt{loop},
+		Catch: &js_ast.Catch{
+			Loc:          loc,
+			BindingOrNil: binding(tempRef),
+			Body: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(
+				ident(errorRef), js_ast.Expr{Loc: loc, Data: &js_ast.EArray{Items: []js_ast.Expr{ident(tempRef)}, IsSingleLine: true}})}}},
+		},
+		Finally: &js_ast.Finally{Loc: loc, Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.STry{
+			BodyLoc: loc,
+			Body:    []js_ast.Stmt{{Loc: loc, Data: &js_ast.SExpr{Value: closeIter}}},
+			Finally: &js_ast.Finally{Loc: loc, Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SIf{
+				Test: ident(errorRef),
+				Yes: js_ast.Stmt{Loc: loc, Data: &js_ast.SThrow{Value: js_ast.Expr{Loc: loc, Data: &js_ast.EIndex{
+					Target: ident(errorRef),
+					Index:  js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: 0}},
+				}}}},
+			}}}},
+		}}}},
+	}}
+}
+
+func (p *parser) lowerForOfArrayLiteral(loc logger.Loc, s *js_ast.SForOf, label *js_ast.LocRef) js_ast.Stmt {
+	indexRef := p.generateTempRef(tempRefNoDeclare, "")
+	arrayRef := p.generateTempRef(tempRefNoDeclare, "")
+	ident := func(ref js_ast.Ref) js_ast.Expr {
+		p.recordUsage(ref)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+	}
+
+	body := forOfBodyWithValue(s, js_ast.Expr{Loc: loc, Data: &js_ast.EIndex{Target: ident(arrayRef), Index: ident(indexRef)}})
+	loop := js_ast.Stmt{Loc: loc, Data: &js_ast.SFor{
+		InitOrNil: js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{
+			{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: indexRef}}, ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: 0}}},
+			{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: arrayRef}}, ValueOrNil: s.Value},
+		}}},
+		TestOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+			Op:    js_ast.BinOpLt,
+			Left:  ident(indexRef),
+			Right: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: ident(arrayRef), Name: "length", NameLoc: loc}},
+		}},
+		UpdateOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{Op: js_ast.UnOpPostInc, Value: ident(indexRef)}},
+		Body:        js_ast.Stmt{Loc: s.Body.Loc, Data: &js_ast.SBlock{Stmts: body}},
+	}}
+	if label != nil {
+		loop = js_ast.Stmt{Loc: loc, Data: &js_ast.SLabel{Name: *label, Stmt: loop}}
+	}
+	return loop
+}
+
+// Returns the body of a "for-of" loop with the loop variable assigned to the
+// given value at the start
+func forOfBodyWithValue(s *js_ast.SForOf, value js_ast.Expr) []js_ast.Stmt {
+	var assign js_ast.Stmt
+	if local, ok := s.Init.Data.(*js_ast.SLocal); ok {
+		assign = js_ast.Stmt{Loc: s.Init.Loc, Data: &js_ast.SLocal{Kind: local.Kind, Decls: []js_ast.Decl{
+			{Binding: local.Decls[0].Binding, ValueOrNil: value},
+		}}}
+	} else {
+		assign = js_ast.Stmt{Loc: s.Init.Loc, Data: &js_ast.SExpr{Value: js_ast.Assign(s.Init.Data.(*js_ast.SExpr).Value, value)}}
+	}
+	body := []js_ast.Stmt{assign}
+	if block, ok := s.Body.Data.(*js_ast.SBlock); ok {
+		body = append(body, block.Stmts...)
+	} else {
+		body = append(body, s.Body)
+	}
+	return body
+}
+
+// Lowered "await" expressions become "yield" expressions inside the generator
+// that the async function is moved into. Async generators mark them with
+// "__awaitValue" to tell them apart from their own "yield" expressions.
+func (p *parser) lowerAwait(loc logger.Loc, value js_ast.Expr) js_ast.Expr {
+	if p.fnOrArrowDataVisit.isGenerator && p.options.unsupportedJSFeatures.Has(compat.AsyncGenerator) {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EYield{ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.ENew{
+			Target: p.importFromRuntime(loc, "__awaitValue"),
+			Args:   []js_ast.Expr{value},
+		}}}}
+	}
+	if p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EYield{ValueOrNil: value}}
+	}
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EAwait{Value: value}}
+}
+
+func isLexicalDecl(stmt js_ast.Stmt) bool {
+	local, ok := stmt.Data.(*js_ast.SLocal)
+	return ok && local.Kind != js_ast.LocalVar
+}
+
+// Returns true if this symbol was declared using "let", "const", or "class"
+// and that declaration is being converted to "var"
+func (p *parser) isLoweredLexicalSymbol(ref js_ast.Ref) bool {
+	switch p.symbols[ref.InnerIndex].Kind {
+	case js_ast.SymbolOther, js_ast.SymbolClass:
+		return p.options.unsupportedJSFeatures.Has(compat.Let)
+
+	case js_ast.SymbolConst:
+		return p.options.unsupportedJSFeatures.Has(compat.Const)
+	}
+	return false
+}
+
+// When "let" and "const" are converted to "var", the symbols they declare are
+// no longer scoped to the enclosing block. They are added to the enclosing
+// function scope too so that the renamer gives them a name that doesn't
+// collide with any other symbol in that function. Otherwise two "let"
+// declarations in sibling blocks could end up sharing the same "var".
+func (p *parser) hoistLoweredLexicalDecls(decls []js_ast.Decl) {
+	for _, decl := range decls {
+		for _, id := range findIdentifiers(decl.Binding, nil) {
+			p.hoistLoweredLexicalSymbol(id.Binding.Data.(*js_ast.BIdentifier).Ref)
+		}
+	}
+}
+
+func (p *parser) hoistLoweredLexicalSymbol(ref js_ast.Ref) {
+	scope := p.currentScope
+	if scope.Kind.StopsHoisting() {
+		return
+	}
+	for !scope.Kind.StopsHoisting() {
+		scope = scope.Parent
+	}
+	scope.Generated = append(scope.Generated, ref)
+
+	// Top-level symbols must be part of the current part or they won't be
+	// renamed when bundling
+	if scope == p.moduleScope {
+		p.declaredSymbols = append(p.declaredSymbols, js_ast.DeclaredSymbol{Ref: ref, IsTopLevel: true})
+	}
+}
+
+// The run-time checks for "let" and "const" bindings go away when they are
+// converted to "var", so some of them are done at compile time instead. This
+// also tracks which bindings are captured by closures and which are assigned
+// to, which is needed to lower loops that declare these bindings.
+func (p *parser) checkLoweredLexicalReference(loc logger.Loc, name string, result findSymbolResult, assignTarget js_ast.AssignTarget) {
+	if !p.isLoweredLexicalSymbol(result.ref) {
+		return
+	}
+
+	// References from inside a closure can happen at any time, but other
+	// references that come before the declaration are in the temporal dead
+	// zone if they are evaluated. This is only a warning because they may be
+	// in code that is never evaluated, such as an "if" branch.
+	if result.isInsideClosure {
+		if p.capturedLexicalRefs == nil {
+			p.capturedLexicalRefs = make(map[js_ast.Ref]bool)
+		}
+		p.capturedLexicalRefs[result.ref] = true
+	} else if loc.Start < result.declareLoc.Start {
+		feature := compat.Let
+		if p.symbols[result.ref.InnerIndex].Kind == js_ast.SymbolConst {
+			feature = compat.Const
+		}
+		where, notes := p.prettyPrintTargetEnvironment(feature)
+		r := js_lexer.RangeOfIdentifier(p.source, loc)
+		p.log.AddRangeWarningWithNotes(&p.tracker, r, fmt.Sprintf("This access will not throw an error because %q is converted to \"var\" for %s", name, where),
+			append([]logger.MsgData{logger.RangeData(&p.tracker, js_lexer.RangeOfIdentifier(p.source, result.declareLoc),
+				fmt.Sprintf("%q is declared here", name))}, notes...))
+	}
+
+	if assignTarget != js_ast.AssignTargetNone {
+		if p.assignedLexicalRefs == nil {
+			p.assignedLexicalRefs = make(map[js_ast.Ref]bool)
+		}
+		p.assignedLexicalRefs[result.ref] = true
+	}
+}
+
+// Each iteration of a loop gets a new copy of the "let" and "const" bindings
+// declared in that loop. This matters when a binding is captured by a closure,
+// since each closure must observe its own copy. This isn't possible with "var"
+// so the loop body is moved into a function instead, which is then called once
+// per iteration:
+//
+//   for (let i = 0; i < 3; i++) {
+//     fns.push(() => i);
+//     if (done()) break;
+//   }
+//
+// becomes:
+//
+//   var _loop = function(i) {
+//     fns.push(() => i);
+//     if (done()) return "break";
+//   };
+//   for (var i = 0; i < 3; i++) {
+//     var _ret = _loop(i);
+//     if (_ret === "break") break;
+//   }
+//
+// Since the loop body is only known to be moved after it has been visited, a
+// loop closure is pushed for every loop that declares one of these bindings
+// and contains a closure. References to "this" and "arguments" inside such a
+// loop are always captured.
+type loopClosure struct {
+	parent    *loopClosure
+	headScope *js_ast.Scope
+	scopes    []*js_ast.Scope
+
+	// The loop body must become a generator function if it contains "yield"
+	// (including lowered "await") or an async function if it contains "await"
+	hasYield bool
+	hasAwait bool
+
+	wasInsideLoopClosure bool
+}
+
+func (p *parser) pushLoopClosure(body js_ast.Stmt, headScope *js_ast.Scope) *loopClosure {
+	if !p.options.unsupportedJSFeatures.Has(compat.Let) && !p.options.unsupportedJSFeatures.Has(compat.Const) {
+		return nil
+	}
+
+	closure := &loopClosure{
+		parent:               p.fnOrArrowDataVisit.loopClosure,
+		headScope:            headScope,
+		wasInsideLoopClosure: p.fnOnlyDataVisit.isInsideLoopClosure,
+	}
+
+	// The scope of a "for" loop header contains the scope of the body. Other
+	// loops only have a scope for the body if the body is a block.
+	if headScope != nil {
+		closure.scopes = append(closure.scopes, headScope)
+	} else if _, ok := body.Data.(*js_ast.SBlock); ok && len(p.scopesInOrder) > 0 && p.scopesInOrder[0].loc == body.Loc {
+		closure.scopes = append(closure.scopes, p.scopesInOrder[0].scope)
+	}
+
+	// Only bother if the body could possibly need to be moved into a closure
+	var refs []js_ast.Ref
+	hasClosure := false
+	for _, scope := range closure.scopes {
+		refs = p.loopClosureCandidates(scope, refs, &hasClosure)
+	}
+	if len(refs) == 0 || !hasClosure {
+		return nil
+	}
+
+	p.fnOrArrowDataVisit.loopClosure = closure
+	p.fnOnlyDataVisit.isInsideLoopClosure = true
+	return closure
+}
+
+func (p *parser) popLoopClosure(closure *loopClosure) {
+	if closure != nil {
+		p.fnOrArrowDataVisit.loopClosure = closure.parent
+		p.fnOnlyDataVisit.isInsideLoopClosure = closure.wasInsideLoopClosure
+	}
+}
+
+// This returns the lowered lexical symbols declared in a loop, not including
+// the ones inside nested functions and classes since those get a new copy
+// each time the function is called anyway
+func (p *parser) loopClosureCandidates(scope *js_ast.Scope, refs []js_ast.Ref, hasClosure *bool) []js_ast.Ref {
+	for _, member := range scope.Members {
+		if p.isLoweredLexicalSymbol(member.Ref) {
+			refs = append(refs, member.Ref)
+		}
+	}
+	for _, child := range scope.Children {
+		switch child.Kind {
+		case js_ast.ScopeFunctionArgs, js_ast.ScopeClassName, js_ast.ScopeClassBody:
+			*hasClosure = true
+		default:
+			refs = p.loopClosureCandidates(child, refs, hasClosure)
+		}
+	}
+	return refs
+}
+
+// This moves the loop body into a closure if any of the bindings declared in
+// the loop are captured. The returned statements must come before the loop.
+// The "for" loop is passed separately since its header bindings must be copied
+// back out of the closure after each iteration.
+func (p *parser) lowerLoopClosure(closure *loopClosure, body *js_ast.Stmt, forLoop *js_ast.SFor) []js_ast.Stmt {
+	if closure == nil {
+		return nil
+	}
+
+	// Check whether any binding is actually captured
+	var refs []js_ast.Ref
+	hasClosure := false
+	for _, scope := range closure.scopes {
+		refs = p.loopClosureCandidates(scope, refs, &hasClosure)
+	}
+	isCaptured := false
+	for _, ref := range refs {
+		if p.capturedLexicalRefs[ref] {
+			isCaptured = true
+			break
+		}
+	}
+	if !isCaptured {
+		return nil
+	}
+
+	loc := body.Loc
+	ident := func(ref js_ast.Ref) js_ast.Expr {
+		p.recordUsage(ref)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+	}
+
+	// Bindings in the loop header are passed to the closure as arguments
+	var headRefs []js_ast.Ref
+	if closure.headScope != nil {
+		var members []js_ast.ScopeMember
+		for _, member := range closure.headScope.Members {
+			if p.isLoweredLexicalSymbol(member.Ref) {
+				members = append(members, member)
+			}
+		}
+		sort.Slice(members, func(i int, j int) bool { return members[i].Loc.Start < members[j].Loc.Start })
+		for _, member := range members {
+			headRefs = append(headRefs, member.Ref)
+		}
+	}
+
+	// Bindings in the header of a "for" loop that are assigned to must be
+	// copied back out so that the next iteration starts with the new value
+	l := loopClosureLowering{p: p, label: js_ast.InvalidRef, innerLabels: make(map[js_ast.Ref]bool)}
+	var outRefs []js_ast.Ref
+	var writeBackRefs []js_ast.Ref
+	if forLoop != nil {
+		for _, ref := range headRefs {
+			if p.assignedLexicalRefs[ref] {
+				outRefs = append(outRefs, p.generateTempRef(tempRefNoDeclare, "_"+p.symbols[ref.InnerIndex].OriginalName))
+				writeBackRefs = append(writeBackRefs, ref)
+			}
+		}
+	}
+	l.writeBack = func() (stmts []js_ast.Stmt) {
+		for i, ref := range writeBackRefs {
+			stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(ident(outRefs[i]), ident(ref))}})
+		}
+		return
+	}
+
+	// Jumps to the label on this loop are equivalent to unlabeled jumps
+	if p.currentScope.Kind == js_ast.ScopeLabel && p.currentScope.LabelStmtIsLoop {
+		l.label = p.currentScope.Label.Ref
+	}
+
+	// Generate the closure
+	var stmts []js_ast.Stmt
+	if block, ok := body.Data.(*js_ast.SBlock); ok {
+		stmts = block.Stmts
+	} else {
+		stmts = []js_ast.Stmt{*body}
+	}
+	stmts = append(l.stmts(stmts, false, false), l.writeBack()...)
+	args := make([]js_ast.Arg, len(headRefs))
+	for i, ref := range headRefs {
+		args[i] = js_ast.Arg{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}}
+	}
+	fn := js_ast.Fn{
+		Args:        args,
+		Body:        js_ast.FnBody{Loc: loc, Stmts: stmts},
+		IsAsync:     closure.hasAwait,
+		IsGenerator: closure.hasYield,
+	}
+	if fn.IsGenerator && p.options.unsupportedJSFeatures.Has(compat.Generator) {
+		fn.IsGenerator = false
+		fn.Body.Stmts = p.lowerGeneratorBody(loc, fn.Body.Stmts, true)
+	}
+	loopRef := p.generateTempRef(tempRefNoDeclare, "_loop")
+	decls := l.hoisted
+	for _, ref := range outRefs {
+		decls = append(decls, js_ast.Decl{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}})
+	}
+	decls = append(decls, js_ast.Decl{
+		Binding:    js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: loopRef}},
+		ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: fn}},
+	})
+
+	// Call the closure and handle any jumps out of the loop body
+	callArgs := make([]js_ast.Expr, len(headRefs))
+	for i, ref := range headRefs {
+		callArgs[i] = ident(ref)
+	}
+	call := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{Target: ident(loopRef), Args: callArgs}}
+	if closure.hasYield {
+		call.Data = &js_ast.EYield{ValueOrNil: call, IsStar: true}
+	} else if closure.hasAwait {
+		call.Data = &js_ast.EAwait{Value: call}
+	}
+	var bodyStmts []js_ast.Stmt
+	if len(l.exits) == 0 && !l.hasReturn {
+		bodyStmts = append(bodyStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: call}})
+	} else {
+		retRef := p.generateTempRef(tempRefNoDeclare, "_ret")
+		bodyStmts = append(bodyStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{{
+			Binding:    js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: retRef}},
+			ValueOrNil: call,
+		}}}})
+		for _, exit := range l.exits {
+			bodyStmts = append(bodyStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{
+				Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+					Op:    js_ast.BinOpStrictEq,
+					Left:  ident(retRef),
+					Right: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16(exit.value)}},
+				}},
+				Yes: exit.jump,
+			}})
+		}
+		if l.hasReturn {
+			bodyStmts = append(bodyStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{
+				Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+					Op:    js_ast.BinOpStrictEq,
+					Left:  js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{Op: js_ast.UnOpTypeof, Value: ident(retRef)}},
+					Right: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("object")}},
+				}},
+				Yes: js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+					Target:  ident(retRef),
+					Name:    "v",
+					NameLoc: loc,
+				}}}},
+			}})
+		}
+	}
+	for i, ref := range writeBackRefs {
+		bodyStmts = append(bodyStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(ident(ref), ident(outRefs[i]))}})
+	}
+	*body = js_ast.Stmt{Loc: loc, Data: &js_ast.SBlock{Stmts: bodyStmts}}
+
+	return []js_ast.Stmt{{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}}}
+}
+
+type loopClosureExit struct {
+	value string
+	jump  js_ast.Stmt
+}
+
+type loopClosureLowering struct {
+	p           *parser
+	label       js_ast.Ref
+	innerLabels map[js_ast.Ref]bool
+	writeBack   func() []js_ast.Stmt
+	exits       []loopClosureExit
+	hoisted     []js_ast.Decl
+	hasReturn   bool
+}
+
+// This rewrites the jumps in a loop body that has been moved into a closure.
+// It doesn't look inside nested functions since jumps there can't leave the
+// function. "var" declarations are moved out of the closure since they belong
+// to the enclosing function.
+func (l *loopClosureLowering) stmts(stmts []js_ast.Stmt, isInsideLoop bool, isInsideSwitch bool) []js_ast.Stmt {
+	result := make([]js_ast.Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		result = l.stmt(result, stmt, isInsideLoop, isInsideSwitch)
+	}
+	return result
+}
+
+func (l *loopClosureLowering) single(stmt js_ast.Stmt, isInsideLoop bool, isInsideSwitch bool) js_ast.Stmt {
+	if stmt.Data == nil {
+		return stmt
+	}
+	stmts := l.stmt(nil, stmt, isInsideLoop, isInsideSwitch)
+	if len(stmts) == 1 {
+		return stmts[0]
+	}
+	if len(stmts) == 0 {
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SEmpty{}}
+	}
+	return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SBlock{Stmts: stmts}}
+}
+
+func (l *loopClosureLowering) exit(loc logger.Loc, value string, jump js_ast.Stmt) js_ast.Stmt {
+	isNew := true
+	for _, exit := range l.exits {
+		if exit.value == value {
+			isNew = false
+			break
+		}
+	}
+	if isNew {
+		l.exits = append(l.exits, loopClosureExit{value: value, jump: jump})
+	}
+	return js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16(value)}}}}
+}
+
+func (l *loopClosureLowering) stmt(result []js_ast.Stmt, stmt js_ast.Stmt, isInsideLoop bool, isInsideSwitch bool) []js_ast.Stmt {
+	switch s := stmt.Data.(type) {
+	case *js_ast.SReturn:
+		// "return x" => "return { v: x }"
+		l.hasReturn = true
+		value := s.ValueOrNil
+		if value.Data == nil {
+			value = js_ast.Expr{Loc: stmt.Loc, Data: js_ast.EUndefinedShared}
+		}
+		return append(result, js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EObject{
+			Properties: []js_ast.Property{{
+				Key:        js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16("v")}},
+				ValueOrNil: value,
+			}},
+			IsSingleLine: true,
+		}}}})
+
+	case *js_ast.SBreak:
+		if s.Label == nil {
+			if isInsideLoop || isInsideSwitch {
+				break
+			}
+			return append(result, l.exit(stmt.Loc, "break", js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SBreak{}}))
+		}
+		if l.innerLabels[s.Label.Ref] {
+			break
+		}
+		if s.Label.Ref == l.label {
+			return append(result, l.exit(stmt.Loc, "break", js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SBreak{}}))
+		}
+		name := l.p.symbols[s.Label.Ref.InnerIndex].OriginalName
+		return append(result, l.exit(stmt.Loc, "break|"+name, stmt))
+
+	case *js_ast.SContinue:
+		if s.Label == nil {
+			if isInsideLoop {
+				break
+			}
+		} else if l.innerLabels[s.Label.Ref] {
+			break
+		} else if s.Label.Ref != l.label {
+			name := l.p.symbols[s.Label.Ref.InnerIndex].OriginalName
+			return append(result, l.exit(stmt.Loc, "continue|"+name, stmt))
+		}
+
+		// Continuing this loop is the same as returning from the closure
+		result = append(result, l.writeBack()...)
+		return append(result, js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SReturn{}})
+
+	case *js_ast.SLocal:
+		if l.isVar(s) {
+			if value := l.hoistVars(s.Decls, false); value.Data != nil {
+				result = append(result, js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: value}})
+			}
+			return result
+		}
+
+	case *js_ast.SBlock:
+		s.Stmts = l.stmts(s.Stmts, isInsideLoop, isInsideSwitch)
+
+	case *js_ast.SIf:
+		s.Yes = l.single(s.Yes, isInsideLoop, isInsideSwitch)
+		s.NoOrNil = l.single(s.NoOrNil, isInsideLoop, isInsideSwitch)
+
+	case *js_ast.SLabel:
+		l.innerLabels[s.Name.Ref] = true
+		s.Stmt = l.single(s.Stmt, isInsideLoop, isInsideSwitch)
+
+	case *js_ast.SWith:
+		s.Body = l.single(s.Body, isInsideLoop, isInsideSwitch)
+
+	case *js_ast.SFor:
+		if local, ok := s.InitOrNil.Data.(*js_ast.SLocal); ok && l.isVar(local) {
+			if value := l.hoistVars(local.Decls, false); value.Data != nil {
+				s.InitOrNil = js_ast.Stmt{Loc: s.InitOrNil.Loc, Data: &js_ast.SExpr{Value: value}}
+			} else {
+				s.InitOrNil = js_ast.Stmt{}
+			}
+		}
+		s.Body = l.single(s.Body, true, false)
+
+	case *js_ast.SForIn:
+		if local, ok := s.Init.Data.(*js_ast.SLocal); ok && l.isVar(local) {
+			s.Init = js_ast.Stmt{Loc: s.Init.Loc, Data: &js_ast.SExpr{Value: l.hoistVars(local.Decls, true)}}
+		}
+		s.Body = l.single(s.Body, true, false)
+
+	case *js_ast.SForOf:
+		if local, ok := s.Init.Data.(*js_ast.SLocal); ok && l.isVar(local) {
+			s.Init = js_ast.Stmt{Loc: s.Init.Loc, Data: &js_ast.SExpr{Value: l.hoistVars(local.Decls, true)}}
+		}
+		s.Body = l.single(s.Body, true, false)
+
+	case *js_ast.SWhile:
+		s.Body = l.single(s.Body, true, false)
+
+	case *js_ast.SDoWhile:
+		s.Body = l.single(s.Body, true, false)
+
+	case *js_ast.STry:
+		s.Body = l.stmts(s.Body, isInsideLoop, isInsideSwitch)
+		if s.Catch != nil {
+			s.Catch.Body = l.stmts(s.Catch.Body, isInsideLoop, isInsideSwitch)
+		}
+		if s.Finally != nil {
+			s.Finally.Stmts = l.stmts(s.Finally.Stmts, isInsideLoop, isInsideSwitch)
+		}
+
+	case *js_ast.SSwitch:
+		for i := range s.Cases {
+			s.Cases[i].Body = l.stmts(s.Cases[i].Body, isInsideLoop, true)
+		}
+	}
+
+	return append(result, stmt)
+}
+
+// Lowered "let" and "const" declarations are also "var" declarations by now,
+// but those should stay inside the closure
+func (l *loopClosureLowering) isVar(s *js_ast.SLocal) bool {
+	if s.Kind != js_ast.LocalVar || len(s.Decls) == 0 {
+		return false
+	}
+	ids := findIdentifiers(s.Decls[0].Binding, nil)
+	return len(ids) > 0 && l.p.symbols[ids[0].Binding.Data.(*js_ast.BIdentifier).Ref.InnerIndex].Kind == js_ast.SymbolHoisted
+}
+
+func (l *loopClosureLowering) hoistVars(decls []js_ast.Decl, isInOrOf bool) (value js_ast.Expr) {
+	wrapIdentifier := func(loc logger.Loc, ref js_ast.Ref) js_ast.Expr {
+		l.p.recordUsage(ref)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+	}
+	for _, decl := range decls {
+		l.hoisted = findIdentifiers(decl.Binding, l.hoisted)
+		binding := js_ast.ConvertBindingToExpr(decl.Binding, wrapIdentifier)
+		if decl.ValueOrNil.Data != nil {
+			value = js_ast.JoinWithComma(value, js_ast.Assign(binding, decl.ValueOrNil))
+		} else if isInOrOf {
+			value = js_ast.JoinWithComma(value, binding)
+		}
+	}
+	return
+}
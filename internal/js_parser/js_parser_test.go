@@ -149,6 +149,35 @@ func expectPrintedJSX(t *testing.T, contents string, expected string) {
 	})
 }
 
+func expectPrintedJSXAutomatic(t *testing.T, options JSXAutomaticTestOptions, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expected, config.Options{
+		JSX: config.JSXOptions{
+			Parse:            true,
+			AutomaticRuntime: true,
+			Development:      options.Development,
+			ImportSource:     options.ImportSource,
+		},
+	})
+}
+
+func expectParseErrorJSXAutomatic(t *testing.T, options JSXAutomaticTestOptions, contents string, expected string) {
+	t.Helper()
+	expectParseErrorCommon(t, contents, expected, config.Options{
+		JSX: config.JSXOptions{
+			Parse:            true,
+			AutomaticRuntime: true,
+			Development:      options.Development,
+			ImportSource:     options.ImportSource,
+		},
+	})
+}
+
+type JSXAutomaticTestOptions struct {
+	Development  bool
+	ImportSource string
+}
+
 func expectParseErrorTargetJSX(t *testing.T, esVersion int, contents string, expected string) {
 	t.Helper()
 	expectParseErrorCommon(t, contents, expected, config.Options{
@@ -1673,6 +1702,44 @@ func TestClassFields(t *testing.T) {
 	expectPrinted(t, "class Foo { static ['prototype'] = 1 }", "class Foo {\n  static [\"prototype\"] = 1;\n}\n")
 }
 
+func TestAutoAccessors(t *testing.T) {
+	expectPrinted(t, "class Foo { accessor a }", "var _a;\nclass Foo {\n  constructor() {\n    __privateAdd(this, _a, void 0);\n  }\n  get a() {\n    return __privateGet(this, _a);\n  }\n  set a(value) {\n    __privateSet(this, _a, value);\n  }\n}\n_a = new WeakMap();\n")
+	expectPrinted(t, "class Foo { accessor a = 1; static accessor b = 2 }", "var _a, _b;\nclass Foo {\n  constructor() {\n    __privateAdd(this, _a, 1);\n  }\n  get a() {\n    return __privateGet(this, _a);\n  }\n  set a(value) {\n    __privateSet(this, _a, value);\n  }\n  static get b() {\n    return __privateGet(this, _b);\n  }\n  static set b(value) {\n    __privateSet(this, _b, value);\n  }\n}\n_a = new WeakMap();\n_b = new WeakMap();\n__privateAdd(Foo, _b, 2);\n")
+	expectPrinted(t, "class Foo { accessor [a()] = 1; [b()] = 2 }", "var _a, _b, _c;\nclass Foo {\n  constructor() {\n    __privateAdd(this, _b, 1);\n    __publicField(this, _c, 2);\n  }\n  get [_a = a()]() {\n    return __privateGet(this, _b);\n  }\n  set [_a](value) {\n    __privateSet(this, _b, value);\n  }\n}\n_c = b();\n_b = new WeakMap();\n")
+	expectPrinted(t, "class Foo { accessor #a = 1; foo() { return this.#a++ } }", "var _a, a_get, a_set;\nclass Foo {\n  constructor() {\n    __privateAdd(this, _a, 1);\n  }\n  foo() {\n    return __privateWrapper(this, _a, a_set, a_get)._++;\n  }\n}\n_a = new WeakMap();\na_get = function() {\n  return __privateGet(this, _a);\n};\na_set = function(value) {\n  __privateSet(this, _a, value);\n};\n")
+	expectPrinted(t, "class Foo { accessor\na }", "class Foo {\n  accessor;\n  a;\n}\n")
+	expectPrinted(t, "class Foo { accessor() {} static accessor = 1 }", "class Foo {\n  accessor() {\n  }\n  static accessor = 1;\n}\n")
+
+	expectParseError(t, "class Foo { accessor a() {} }", "<stdin>: error: Expected \";\" but found \"(\"\n")
+	expectParseError(t, "class Foo { accessor *a() {} }", "<stdin>: error: Unexpected \"*\"\n")
+	expectParseError(t, "class Foo { accessor constructor }", "<stdin>: error: Invalid field name \"constructor\"\n")
+	expectParseError(t, "class Foo { accessor #a; #a }", "<stdin>: error: \"#a\" has already been declared\n<stdin>: note: \"#a\" was originally declared here\n")
+	expectParseError(t, "({ accessor a: 1 })", "<stdin>: error: Expected \"}\" but found \"a\"\n")
+}
+
+func TestDecorators(t *testing.T) {
+	expectPrinted(t, "@dec class Foo {}", "var _init;\nlet Foo = class {\n};\n_init = __decoratorStart();\nFoo = __decorateElement(_init, 0, \"Foo\", [\n  dec\n], Foo);\n__runInitializers(_init, 0, Foo);\n")
+	expectPrinted(t, "@a @b.c @d() @(e) class Foo {}", "var _init;\nlet Foo = class {\n};\n_init = __decoratorStart();\nFoo = __decorateElement(_init, 0, \"Foo\", [\n  a,\n  b.c,\n  d(),\n  e\n], Foo);\n__runInitializers(_init, 0, Foo);\n")
+	expectPrinted(t, "export @dec class Foo {}", "var _init;\nexport let Foo = class {\n};\n_init = __decoratorStart();\nFoo = __decorateElement(_init, 0, \"Foo\", [\n  dec\n], Foo);\n__runInitializers(_init, 0, Foo);\n")
+	expectPrinted(t, "export default @dec class {}", "var _init;\nlet stdin_default = class {\n};\n_init = __decoratorStart();\nstdin_default = __decorateElement(_init, 0, \"default\", [\n  dec\n], stdin_default);\n__runInitializers(_init, 0, stdin_default);\nexport {\n  stdin_default as default\n};\n")
+	expectPrinted(t, "x = @dec class {}", "var _init, _a;\nx = (_a = class {\n}, _init = __decoratorStart(), _a = __decorateElement(_init, 0, void 0, [\n  dec\n], _a), __runInitializers(_init, 0, _a), _a);\n")
+	expectPrinted(t, "class Foo { @dec foo() {} @dec static bar() {} }", "var _init;\nclass Foo {\n  constructor() {\n    __runInitializers(_init, 2, this);\n  }\n  foo() {\n  }\n  static bar() {\n  }\n}\n_init = __decoratorStart();\n__decorateElement(_init, 9, \"bar\", [\n  dec\n], Foo);\n__decorateElement(_init, 1, \"foo\", [\n  dec\n], Foo.prototype);\n__runInitializers(_init, 1, Foo);\n")
+	expectPrinted(t, "class Foo { @dec get foo() {} @dec set foo(x) {} }", "var _init;\nclass Foo {\n  constructor() {\n    __runInitializers(_init, 2, this);\n  }\n  get foo() {\n  }\n  set foo(x) {\n  }\n}\n_init = __decoratorStart();\n__decorateElement(_init, 2, \"foo\", [\n  dec\n], Foo.prototype);\n__decorateElement(_init, 3, \"foo\", [\n  dec\n], Foo.prototype);\n")
+	expectPrinted(t, "class Foo { @dec foo = 1; @dec static bar = 2 }", "var _init;\nclass Foo {\n  constructor() {\n    __runInitializers(_init, 2, this);\n    __publicField(this, \"foo\", __runInitializers(_init, 4, this, 1));\n  }\n}\n_init = __decoratorStart();\n__decorateElement(_init, 13, \"bar\", [\n  dec\n]);\n__decorateElement(_init, 5, \"foo\", [\n  dec\n]);\n__runInitializers(_init, 1, Foo);\n__publicField(Foo, \"bar\", __runInitializers(_init, 3, Foo, 2));\n")
+	expectPrinted(t, "class Foo { @dec accessor foo = 1 }", "var _init, _foo;\nclass Foo {\n  constructor() {\n    __runInitializers(_init, 2, this);\n    __privateAdd(this, _foo, __runInitializers(_init, 3, this, 1));\n  }\n  get foo() {\n    return __privateGet(this, _foo);\n  }\n  set foo(value) {\n    __privateSet(this, _foo, value);\n  }\n}\n_foo = new WeakMap();\n_init = __decoratorStart();\n__decorateElement(_init, 4, \"foo\", [\n  dec\n], Foo.prototype);\n")
+	expectPrinted(t, "class Foo { @dec [foo()]() {} }", "var _init, _a;\nclass Foo {\n  constructor() {\n    __runInitializers(_init, 2, this);\n  }\n  [_a = foo()]() {\n  }\n}\n_init = __decoratorStart();\n__decorateElement(_init, 1, _a, [\n  dec\n], Foo.prototype);\n")
+	expectPrinted(t, "class Foo { @dec #foo() {} @dec #bar = 1 }", "var _init, _foo, foo_fn, _bar;\nclass Foo {\n  constructor() {\n    __privateAdd(this, _foo);\n    __runInitializers(_init, 2, this);\n    __privateAdd(this, _bar, __runInitializers(_init, 3, this, 1));\n  }\n}\n_foo = new WeakSet();\nfoo_fn = function() {\n};\n_bar = new WeakMap();\n_init = __decoratorStart();\nfoo_fn = __decorateElement(_init, 17, \"#foo\", [\n  dec\n], foo_fn, _foo);\n__decorateElement(_init, 21, \"#bar\", [\n  dec\n], void 0, _bar);\n")
+	expectPrinted(t, "class Foo { @dec accessor #foo = 1 }", "var _init, _a, _foo, foo_get, foo_set;\nclass Foo {\n  constructor() {\n    __runInitializers(_init, 2, this);\n    __privateAdd(this, _foo, __runInitializers(_init, 3, this, 1));\n  }\n}\n_foo = new WeakMap();\nfoo_get = function() {\n  return __privateGet(this, _foo);\n};\nfoo_set = function(value) {\n  __privateSet(this, _foo, value);\n};\n_init = __decoratorStart();\n_a = __decorateElement(_init, 20, \"#foo\", [\n  dec\n], {\n  get: foo_get,\n  set: foo_set\n}, _foo), foo_get = _a.get, foo_set = _a.set;\n")
+
+	expectParseError(t, "@dec function foo() {}", "<stdin>: error: Expected \"class\" but found \"function\"\n")
+	expectParseError(t, "@dec x = 1", "<stdin>: error: Expected \"class\" but found \"x\"\n")
+	expectParseError(t, "@a export @b class Foo {}", "<stdin>: error: Expected \"class\" but found \"@\"\n")
+	expectParseError(t, "x = @dec function() {}", "<stdin>: error: Expected \"class\" but found \"function\"\n")
+	expectParseError(t, "class Foo { @dec constructor() {} }", "<stdin>: error: Decorators are not allowed on class constructors\n")
+	expectParseError(t, "class Foo { foo(@dec x) {} }", "<stdin>: error: Expected identifier but found \"@\"\n")
+	expectParseError(t, "({ @dec foo() {} })", "<stdin>: error: Expected identifier but found \"@\"\n")
+}
+
 func TestGenerator(t *testing.T) {
 	expectParseError(t, "(class { * foo })", "<stdin>: error: Expected \"(\" but found \"}\"\n")
 	expectParseError(t, "(class { * *foo() {} })", "<stdin>: error: Unexpected \"*\"\n")
@@ -4320,6 +4387,56 @@ func TestJSXPragmas(t *testing.T) {
 	expectPrintedJSX(t, "/* @jsxFrag a.b.c */\n<></>", "/* @__PURE__ */ React.createElement(a.b.c, null);\n")
 }
 
+func TestJSXAutomatic(t *testing.T) {
+	// Prod, without imports
+	p := JSXAutomaticTestOptions{}
+	expectPrintedJSXAutomatic(t, p, "<div>></div>", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {\n  children: \">\"\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<div>{1}}</div>", "import {\n  jsxs\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsxs(\"div\", {\n  children: [\n    1,\n    \"}\"\n  ]\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<div key={true} />", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {}, true);\n")
+	expectPrintedJSXAutomatic(t, p, "<div key=\"key\" />", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {}, \"key\");\n")
+	expectPrintedJSXAutomatic(t, p, "<div key=\"key\" {...props} />", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {\n  ...props\n}, \"key\");\n")
+	expectPrintedJSXAutomatic(t, p, "<div {...props} key=\"key\" />", "import {\n  createElement\n} from \"react\";\n/* @__PURE__ */ createElement(\"div\", {\n  ...props,\n  key: \"key\"\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<>></>", "import {\n  Fragment,\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(Fragment, {\n  children: \">\"\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<><a/><b/></>", "import {\n  Fragment,\n  jsx,\n  jsxs\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsxs(Fragment, {\n  children: [\n    /* @__PURE__ */ jsx(\"a\", {}),\n    /* @__PURE__ */ jsx(\"b\", {})\n  ]\n});\n")
+
+	expectParseErrorJSXAutomatic(t, p, "<div key/>",
+		"<stdin>: error: Please provide an explicit value for \"key\" when using React's \"automatic\" JSX transform\n")
+	expectPrintedJSXAutomatic(t, p, "<div __self={this} __source={source} />", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {\n  __self: this,\n  __source: source\n});\n")
+
+	// Prod, with imports
+	expectPrintedJSXAutomatic(t, p, "import * as React from 'react'; <div/>", "import {\n  jsx\n} from \"react/jsx-runtime\";\nimport * as React from \"react\";\n/* @__PURE__ */ jsx(\"div\", {});\n")
+	expectPrintedJSXAutomatic(t, p, "import { jsx } from 'irrelevant'; <div/>", "import {\n  jsx\n} from \"react/jsx-runtime\";\nimport { jsx } from \"irrelevant\";\n/* @__PURE__ */ jsx(\"div\", {});\n")
+	expectPrintedJSXAutomatic(t, p, "import { Fragment } from 'irrelevant'; <></>", "import {\n  Fragment,\n  jsx\n} from \"react/jsx-runtime\";\nimport { Fragment } from \"irrelevant\";\n/* @__PURE__ */ jsx(Fragment, {});\n")
+
+	// Dev
+	d := JSXAutomaticTestOptions{Development: true}
+	expectPrintedJSXAutomatic(t, d, "<div>></div>", "import {\n  jsxDEV\n} from \"react/jsx-dev-runtime\";\n/* @__PURE__ */ jsxDEV(\"div\", {\n  children: \">\"\n}, void 0, false, {\n  fileName: \"<stdin>\",\n  lineNumber: 1,\n  columnNumber: 1\n}, this);\n")
+	expectPrintedJSXAutomatic(t, d, "<div key=\"key\">\n  <a/>\n  <b/>\n</div>", "import {\n  jsxDEV\n} from \"react/jsx-dev-runtime\";\n/* @__PURE__ */ jsxDEV(\"div\", {\n  children: [\n    /* @__PURE__ */ jsxDEV(\"a\", {}, void 0, false, {\n      fileName: \"<stdin>\",\n      lineNumber: 2,\n      columnNumber: 3\n    }, this),\n    /* @__PURE__ */ jsxDEV(\"b\", {}, void 0, false, {\n      fileName: \"<stdin>\",\n      lineNumber: 3,\n      columnNumber: 3\n    }, this)\n  ]\n}, \"key\", true, {\n  fileName: \"<stdin>\",\n  lineNumber: 1,\n  columnNumber: 1\n}, this);\n")
+	expectPrintedJSXAutomatic(t, d, "function f() { return <div/> }", "import {\n  jsxDEV\n} from \"react/jsx-dev-runtime\";\nfunction f() {\n  return /* @__PURE__ */ jsxDEV(\"div\", {}, void 0, false, {\n    fileName: \"<stdin>\",\n    lineNumber: 1,\n    columnNumber: 23\n  }, this);\n}\n")
+	expectPrintedJSXAutomatic(t, d, "class A extends B { constructor() { super(<div/>) } }", "import {\n  jsxDEV\n} from \"react/jsx-dev-runtime\";\nclass A extends B {\n  constructor() {\n    super(/* @__PURE__ */ jsxDEV(\"div\", {}, void 0, false, {\n      fileName: \"<stdin>\",\n      lineNumber: 1,\n      columnNumber: 43\n    }));\n  }\n}\n")
+	expectParseErrorJSXAutomatic(t, d, "<div __self={this} />",
+		"<stdin>: error: Duplicate \"__self\" prop found when using React's \"automatic\" JSX transform\n")
+	expectParseErrorJSXAutomatic(t, d, "<div __source=\"/path/to/source.jsx\" />",
+		"<stdin>: error: Duplicate \"__source\" prop found when using React's \"automatic\" JSX transform\n")
+
+	// Import source
+	i := JSXAutomaticTestOptions{ImportSource: "my-jsx-lib"}
+	expectPrintedJSXAutomatic(t, i, "<div/>", "import {\n  jsx\n} from \"my-jsx-lib/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {});\n")
+	expectPrintedJSXAutomatic(t, i, "<div {...props} key=\"key\" />", "import {\n  createElement\n} from \"my-jsx-lib\";\n/* @__PURE__ */ createElement(\"div\", {\n  ...props,\n  key: \"key\"\n});\n")
+	di := JSXAutomaticTestOptions{Development: true, ImportSource: "my-jsx-lib"}
+	expectPrintedJSXAutomatic(t, di, "<div/>", "import {\n  jsxDEV\n} from \"my-jsx-lib/jsx-dev-runtime\";\n/* @__PURE__ */ jsxDEV(\"div\", {}, void 0, false, {\n  fileName: \"<stdin>\",\n  lineNumber: 1,\n  columnNumber: 1\n}, this);\n")
+
+	// Pragmas
+	expectPrintedJSX(t, "// @jsxRuntime automatic\n<div/>", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {});\n")
+	expectPrintedJSX(t, "// @jsxRuntime automatic\n// @jsxImportSource preact\n<div/>", "import {\n  jsx\n} from \"preact/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {});\n")
+	expectPrintedJSXAutomatic(t, p, "// @jsxRuntime classic\n<div/>", "/* @__PURE__ */ React.createElement(\"div\", null);\n")
+	expectPrintedJSXAutomatic(t, p, "// @jsxImportSource preact\n<div/>", "import {\n  jsx\n} from \"preact/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {});\n")
+	expectParseErrorJSX(t, "// @jsxRuntime foo\n<div/>",
+		"<stdin>: warning: Invalid JSX runtime: foo\n")
+	expectParseErrorJSXAutomatic(t, p, "// @jsx h\n<div/>", "<stdin>: warning: The JSX factory cannot be set when using React's \"automatic\" JSX transform\n")
+	expectParseErrorJSX(t, "// @jsxImportSource preact\n<div/>", "<stdin>: warning: The JSX import source cannot be set without also enabling React's \"automatic\" JSX transform\n")
+}
+
 func TestPreserveOptionalChainParentheses(t *testing.T) {
 	expectPrinted(t, "a?.b.c", "a?.b.c;\n")
 	expectPrinted(t, "(a?.b).c", "(a?.b).c;\n")
@@ -4541,82 +4658,70 @@ func TestES5(t *testing.T) {
 	expectPrintedTarget(t, 2015, "if (1) function f() {}", "if (1) {\n  let f = function() {\n  };\n  var f = f;\n}\n")
 	expectPrintedTarget(t, 5, "if (1) function f() {}", "if (1) {\n  var f = function() {\n  };\n  var f = f;\n}\n")
 
-	expectParseErrorTarget(t, 5, "function foo(x = 0) {}",
-		"<stdin>: error: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function(x = 0) {})",
-		"<stdin>: error: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(x = 0) => {}",
-		"<stdin>: error: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo(...x) {}",
-		"<stdin>: error: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function(...x) {})",
-		"<stdin>: error: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(...x) => {}",
-		"<stdin>: error: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "foo(...x)",
-		"<stdin>: error: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "[...x]",
-		"<stdin>: error: Transforming array spread to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for (var x of y) ;",
-		"<stdin>: error: Transforming for-of loops to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "function foo(x = 0) {}", "function foo() {\n  var x = arguments[0];\n  if (x === void 0)\n    x = 0;\n}\n")
+	expectPrintedTarget(t, 5, "(function(x = 0) {})", "(function() {\n  var x = arguments[0];\n  if (x === void 0)\n    x = 0;\n});\n")
+	expectPrintedTarget(t, 5, "(x = 0) => {}", "(function() {\n  var x = arguments[0];\n  if (x === void 0)\n    x = 0;\n});\n")
+	expectPrintedTarget(t, 5, "function foo({a} = {}, b = a) {}", "function foo() {\n  var _a = arguments[0];\n  var b = arguments[1];\n  if (_a === void 0)\n    _a = {};\n  var a = _a.a;\n  if (b === void 0)\n    b = a;\n}\n")
+	expectPrintedTarget(t, 5, "function foo(a, b = a + 1, c = () => b) {}", "function foo(a) {\n  var b = arguments[1];\n  var c = arguments[2];\n  if (b === void 0)\n    b = a + 1;\n  if (c === void 0)\n    c = function() {\n      return b;\n    };\n}\n")
+	expectPrintedTarget(t, 5, "function foo(a, {b} = {}, [c]) {}", "function foo(a) {\n  var _a = arguments[1];\n  var _b = arguments[2];\n  if (_a === void 0)\n    _a = {};\n  var b = _a.b;\n  var c = __toArray(_b, 1)[0];\n}\n")
+	expectPrintedTarget(t, 5, "function foo(...x) {}", "function foo() {\n  var x = [].slice.call(arguments, 0);\n}\n")
+	expectPrintedTarget(t, 5, "(function(...x) {})", "(function() {\n  var x = [].slice.call(arguments, 0);\n});\n")
+	expectPrintedTarget(t, 5, "(...x) => {}", "(function() {\n  var x = [].slice.call(arguments, 0);\n});\n")
+	expectPrintedTarget(t, 5, "function foo(a, ...[b, c]) {}", "function foo(a) {\n  var _a = [].slice.call(arguments, 1);\n  var _b = __toArray(_a, 2), b = _b[0], c = _b[1];\n}\n")
+	expectPrintedTarget(t, 5, "function foo(a = 1, ...{b}) { return a + b }", "function foo() {\n  var a = arguments[0];\n  var _a = [].slice.call(arguments, 1);\n  if (a === void 0)\n    a = 1;\n  var b = _a.b;\n  return a + b;\n}\n")
+	expectPrintedTarget(t, 5, "function foo(a = 1, ...[b = a]) {}", "function foo() {\n  var a = arguments[0];\n  var _a = [].slice.call(arguments, 1);\n  if (a === void 0)\n    a = 1;\n  var _b = __toArray(_a, 1)[0], b = _b === void 0 ? a : _b;\n}\n")
+	expectPrintedTarget(t, 5, "foo(...x)", "foo.apply(void 0, __toArray(x));\n")
+	expectPrintedTarget(t, 5, "foo(a, ...b, c)", "foo.apply(void 0, [a].concat(__toArray(b), [c]));\n")
+	expectPrintedTarget(t, 5, "a.foo(...b)", "a.foo.apply(a, __toArray(b));\n")
+	expectPrintedTarget(t, 5, "a()[b()](...c)", "var _a;\n(_a = a())[b()].apply(_a, __toArray(c));\n")
+	expectPrintedTarget(t, 5, "a?.foo(...b)", "a == null ? void 0 : a.foo.apply(a, __toArray(b));\n")
+	expectPrintedTarget(t, 5, "new Foo(...a, b)", "__construct(Foo, __toArray(a).concat([b]));\n")
+	expectPrintedTarget(t, 5, "function foo() { return bar(...arguments) }", "function foo() {\n  return bar.apply(void 0, arguments);\n}\n")
+	expectPrintedTarget(t, 5, "[...x]", "[].concat(__toArray(x));\n")
+	expectPrintedTarget(t, 5, "[a, ...b, c, ...d]", "[a].concat(__toArray(b), [c], __toArray(d));\n")
+	expectPrintedTarget(t, 5, "[...a, b]", "[].concat(__toArray(a), [b]);\n")
+	expectPrintedTarget(t, 5, "for (var x of y) ;", "try {\n  for (var iter = __getIterator(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n    var x = temp.value;\n    ;\n  }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
+	expectPrintedTarget(t, 5, "for (x of y) z(x);", "try {\n  for (var iter = __getIterator(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n    x = temp.value;\n    z(x);\n  }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
+	expectPrintedTarget(t, 5, "for (var x of [1, 2]) z(x);", "for (var _a = 0, _b = [1, 2]; _a < _b.length; _a++) {\n  var x = _b[_a];\n  z(x);\n}\n")
+	expectPrintedTarget(t, 5, "for (var [a, b] of c) z(a, b);", "try {\n  for (var iter = __getIterator(c), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n    var _a = temp.value;\n    var _b = __toArray(_a, 2), a = _b[0], b = _b[1];\n    z(a, b);\n  }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
+	expectPrintedTarget(t, 5, "foo: for (var x of y) continue foo;", "try {\n  foo:\n    for (var iter = __getIterator(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n      var x = temp.value;\n      continue foo;\n    }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
 	expectPrintedTarget(t, 5, "({ x })", "({ x: x });\n")
-	expectParseErrorTarget(t, 5, "({ [x]: y })",
-		"<stdin>: error: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ x() {} });",
-		"<stdin>: error: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ get x() {} });", "")
-	expectParseErrorTarget(t, 5, "({ set x(x) {} });", "")
-	expectParseErrorTarget(t, 5, "({ get [x]() {} });",
-		"<stdin>: error: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ set [x](x) {} });",
-		"<stdin>: error: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([]) {}",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo({}) {}",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function([]) {})",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function({}) {})",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([]) => {}",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({}) => {}",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "var [] = [];",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "var {} = {};",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([] = []);",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({} = {});",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for ([] in []);",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for ({} in []);",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([...x]) {}",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function([...x]) {})",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([...x]) => {}",
-		"<stdin>: error: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([...[x]]) {}",
-		`<stdin>: error: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: error: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: error: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "(function([...[x]]) {})",
-		`<stdin>: error: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: error: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: error: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "([...[x]]) => {}",
-		`<stdin>: error: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: error: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: error: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "([...[x]])",
-		"<stdin>: error: Transforming array spread to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "({ [x]: y })", "var _a;\n_a = {}, __objProp(_a, x, y), _a;\n")
+	expectPrintedTarget(t, 5, "({ x() {} });", "({ x: function() {\n} });\n")
+	expectPrintedTarget(t, 5, "({ get x() {} });", "({ get x() {\n} });\n")
+	expectPrintedTarget(t, 5, "({ set x(x) {} });", "({ set x(x) {\n} });\n")
+	expectPrintedTarget(t, 5, "({ get [x]() {} });", "var _a;\n_a = {}, __objProp(_a, x, function() {\n}, 1), _a;\n")
+	expectPrintedTarget(t, 5, "({ set [x](x) {} });", "var _a;\n_a = {}, __objProp(_a, x, function(x) {\n}, 2), _a;\n")
+	expectPrintedTarget(t, 5, "({ a, [b]: c, d() {}, __proto__: e });", "var _a;\n_a = { a: a }, __objProp(_a, b, c), __objProp(_a, \"d\", function() {\n}), _a.__proto__ = e, _a;\n")
+	expectParseErrorTarget(t, 5, "({ foo() { return super.foo } });",
+		"<stdin>: error: Transforming \"super\" in object literal methods to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "function foo([]) {}", "function foo(_a) {\n  var _b = __toArray(_a, 0);\n}\n")
+	expectPrintedTarget(t, 5, "function foo({}) {}", "function foo(_a) {\n  var _b = _a;\n}\n")
+	expectPrintedTarget(t, 5, "(function([]) {})", "(function(_a) {\n  var _b = __toArray(_a, 0);\n});\n")
+	expectPrintedTarget(t, 5, "(function({}) {})", "(function(_a) {\n  var _b = _a;\n});\n")
+	expectPrintedTarget(t, 5, "([]) => {}", "(function(_a) {\n  var _b = __toArray(_a, 0);\n});\n")
+	expectPrintedTarget(t, 5, "({}) => {}", "(function(_a) {\n  var _b = _a;\n});\n")
+	expectPrintedTarget(t, 5, "var [] = [];", "var _a = __toArray([], 0);\n")
+	expectPrintedTarget(t, 5, "var {} = {};", "var _a = {};\n")
+	expectPrintedTarget(t, 5, "([] = []);", "var _a;\n_a = __toArray([], 0);\n")
+	expectPrintedTarget(t, 5, "({} = {});", "var _a;\n_a = {};\n")
+	expectPrintedTarget(t, 5, "for ([] in []);", "var _a, _b;\nfor (_a in []) {\n  _b = __toArray(_a, 0);\n  ;\n}\n")
+	expectPrintedTarget(t, 5, "for ({} in []);", "var _a, _b;\nfor (_a in []) {\n  _b = _a;\n  ;\n}\n")
+	expectPrintedTarget(t, 5, "function foo([...x]) {}", "function foo(_a) {\n  var x = __toArray(_a).slice(0);\n}\n")
+	expectPrintedTarget(t, 5, "(function([...x]) {})", "(function(_a) {\n  var x = __toArray(_a).slice(0);\n});\n")
+	expectPrintedTarget(t, 5, "([...x]) => {}", "(function(_a) {\n  var x = __toArray(_a).slice(0);\n});\n")
+	expectPrintedTarget(t, 5, "function foo([...[x]]) {}", "function foo(_a) {\n  var x = __toArray(__toArray(_a).slice(0), 1)[0];\n}\n")
+	expectPrintedTarget(t, 5, "(function([...[x]]) {})", "(function(_a) {\n  var x = __toArray(__toArray(_a).slice(0), 1)[0];\n});\n")
+	expectPrintedTarget(t, 5, "([...[x]]) => {}", "(function(_a) {\n  var x = __toArray(__toArray(_a).slice(0), 1)[0];\n});\n")
+	expectPrintedTarget(t, 5, "var [a, , ...b] = c;", "var _a = __toArray(c), a = _a[0], b = _a.slice(2);\n")
+	expectPrintedTarget(t, 5, "var {a, b: [c, d = 1], ...e} = f;", "var _a = f, a = _a.a, _b = __toArray(_a.b, 2), c = _b[0], _c = _b[1], d = _c === void 0 ? 1 : _c, e = __objRest(_a, [\"a\", \"b\"]);\n")
+	expectPrintedTarget(t, 5, "var {[a()]: b, ...c} = d;", "var _b;\nvar _a = d, b = _a[_b = a()], c = __objRest(_a, [__restKey(_b)]);\n")
+	expectPrintedTarget(t, 5, "var {\"a-b\": a, 0: b} = c;", "var _a = c, a = _a[\"a-b\"], b = _a[0];\n")
+	expectPrintedTarget(t, 5, "[a, b] = [b, a];", "var _a;\n_a = __toArray([b, a], 2), a = _a[0], b = _a[1];\n")
+	expectPrintedTarget(t, 5, "x = [a.b, c[0] = 1] = d;", "var _a, _b, _c;\nx = (_b = __toArray(_a = d, 2), a.b = _b[0], _c = _b[1], c[0] = _c === void 0 ? 1 : _c, _a);\n")
+	expectPrintedTarget(t, 5, "for (var [a, b] in c) ;", "for (var _a in c) {\n  var _b = __toArray(_a, 2), a = _b[0], b = _b[1];\n  ;\n}\n")
+	expectPrintedTarget(t, 5, "try {} catch ({ a }) {}", "try {\n} catch (_a) {\n  var a = _a.a;\n}\n")
+	expectPrintedTarget(t, 5, "([...[x]])", "[].concat(__toArray([x]));\n")
 	expectPrintedTarget(t, 5, "`abc`;", "\"abc\";\n")
 	expectPrintedTarget(t, 5, "`a${b}`;", "\"a\".concat(b);\n")
 	expectPrintedTarget(t, 5, "`${a}b`;", "\"\".concat(a, \"b\");\n")
@@ -4635,25 +4740,84 @@ func TestES5(t *testing.T) {
 	expectPrintedTarget(t, 5, "tag`a${b}\\u`;", "var _a;\ntag(_a || (_a = __template([\"a\", void 0], [\"a\", \"\\\\u\"])), b);\n")
 	expectPrintedTarget(t, 5, "tag`\\u${b}c`;", "var _a;\ntag(_a || (_a = __template([void 0, \"c\"], [\"\\\\u\", \"c\"])), b);\n")
 	expectParseErrorTarget(t, 5, "class Foo { constructor() { new.target } }",
-		"<stdin>: error: Transforming class syntax to the configured target environment is not supported yet\n"+
-			"<stdin>: error: Transforming object literal extensions to the configured target environment is not supported yet\n"+
-			"<stdin>: error: Transforming new.target to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "const x = 1;",
-		"<stdin>: error: Transforming const to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "let x = 2;",
-		"<stdin>: error: Transforming let to the configured target environment is not supported yet\n")
+		"<stdin>: error: Transforming new.target to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "const x = 1;", "var x = 1;\n")
+	expectPrintedTarget(t, 5, "let x = 2;", "var x = 2;\n")
+	expectPrintedTarget(t, 5, "let x = 1; { let x = 2; f(x) } f(x);", "var x = 1;\n{\n  var x = 2;\n  f(x);\n}\nf(x);\n")
+	expectPrintedTarget(t, 5, "for (;;) { let x; const y = 1; f(x, y) }", "for (; ; ) {\n  var x = void 0;\n  var y = 1;\n  f(x, y);\n}\n")
+	expectPrintedTarget(t, 5, "for (let x in y) ;", "for (var x in y)\n  ;\n")
+	expectPrintedTarget(t, 5, "for (let i = 0; i < 3; i++) f(() => i);", "var _i, _loop = function(i) {\n  f(function() {\n    return i;\n  });\n  _i = i;\n};\nfor (var i = 0; i < 3; i++) {\n  _loop(i);\n  i = _i;\n}\n")
+	expectPrintedTarget(t, 5, "for (let i = 0; i < 3; i++) { let x = i; f(() => x) }", "var _i, _loop = function(i) {\n  var x = i;\n  f(function() {\n    return x;\n  });\n  _i = i;\n};\nfor (var i = 0; i < 3; i++) {\n  _loop(i);\n  i = _i;\n}\n")
+	expectPrintedTarget(t, 5, "for (let i = 0; i < 3; i++) { f(i); g(() => 0) }", "for (var i = 0; i < 3; i++) {\n  f(i);\n  g(function() {\n    return 0;\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function f() { for (let i = 0; i < 3; i++) { g(() => i); if (a) break; if (b) continue; if (c) return i; if (d) return; i++ } }", "function f() {\n  var _i, _loop = function(i) {\n    g(function() {\n      return i;\n    });\n    if (a)\n      return \"break\";\n    if (b) {\n      _i = i;\n      return;\n    }\n    if (c)\n      return { v: i };\n    if (d)\n      return { v: void 0 };\n    i++;\n    _i = i;\n  };\n  for (var i = 0; i < 3; i++) {\n    var _ret = _loop(i);\n    if (_ret === \"break\")\n      break;\n    if (typeof _ret === \"object\")\n      return _ret.v;\n    i = _i;\n  }\n}\n")
+	expectPrintedTarget(t, 5, "outer: for (let k in o) { for (let j = 0; j < 1; j++) { g(() => j + k); if (a) continue outer; if (b) break outer; if (c) continue; if (d) break } }", "var _loop = function(k) {\n  var _j, _loop = function(j) {\n    g(function() {\n      return j + k;\n    });\n    if (a)\n      return \"continue|outer\";\n    if (b)\n      return \"break|outer\";\n    if (c) {\n      _j = j;\n      return;\n    }\n    if (d)\n      return \"break\";\n    _j = j;\n  };\n  for (var j = 0; j < 1; j++) {\n    var _ret = _loop(j);\n    if (_ret === \"continue|outer\")\n      return;\n    if (_ret === \"break|outer\")\n      return \"break\";\n    if (_ret === \"break\")\n      break;\n    j = _j;\n  }\n};\nouter:\n  for (var k in o) {\n    var _ret = _loop(k);\n    if (_ret === \"break\")\n      break;\n  }\n")
+	expectPrintedTarget(t, 5, "function f() { while (a) { let x = arguments[0]; g(this, function() { return x }) } }", "function f() {\n  var _this = this, _arguments = arguments;\n  var _loop = function() {\n    var x = _arguments[0];\n    g(_this, function() {\n      return x;\n    });\n  };\n  while (a) {\n    _loop();\n  }\n}\n")
+	expectPrintedTarget(t, 5, "for (let i = 0; i < 3; i++) { var y = i; for (var j in o) ; g(() => i) } h(y);", "var y, j, _i, _loop = function(i) {\n  y = i;\n  for (j in o)\n    ;\n  g(function() {\n    return i;\n  });\n  _i = i;\n};\nfor (var i = 0; i < 3; i++) {\n  _loop(i);\n  i = _i;\n}\nh(y);\n")
+	expectPrintedTarget(t, 5, "do { const x = f(); switch (x) { case 1: g(() => x); break; default: continue } } while (a);", "var _loop = function() {\n  var x = f();\n  switch (x) {\n    case 1:\n      g(function() {\n        return x;\n      });\n      break;\n    default:\n      return;\n  }\n};\ndo {\n  _loop();\n} while (a);\n")
+	expectPrintedTarget(t, 5, "function* f() { for (let i = 0; i < 3; i++) yield () => i }", "function f() {\n  var _i, _loop, i;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        _loop = function(i) {\n          return __stateMachine(this, function(_a) {\n            switch (_a.label) {\n              case 0:\n                return [4, function() {\n                  return i;\n                }];\n              case 1:\n                _a.sent();\n                _i = i;\n                return [2];\n            }\n          });\n        };\n        i = 0;\n        _a.label = 1;\n      case 1:\n        if (!(i < 3))\n          return [3, 4];\n        return [5, _loop(i)];\n      case 2:\n        _a.sent();\n        i = _i;\n        _a.label = 3;\n      case 3:\n        i++;\n        return [3, 1];\n      case 4:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "async function f() { for (let i = 0; i < 3; i++) await g(() => i) }", "function f() {\n  return __async(this, null, function() {\n    var _i, _loop, i;\n    return __stateMachine(this, function(_a) {\n      switch (_a.label) {\n        case 0:\n          _loop = function(i) {\n            return __stateMachine(this, function(_a) {\n              switch (_a.label) {\n                case 0:\n                  return [4, g(function() {\n                    return i;\n                  })];\n                case 1:\n                  _a.sent();\n                  _i = i;\n                  return [2];\n              }\n            });\n          };\n          i = 0;\n          _a.label = 1;\n        case 1:\n          if (!(i < 3))\n            return [3, 4];\n          return [5, _loop(i)];\n        case 2:\n          _a.sent();\n          i = _i;\n          _a.label = 3;\n        case 3:\n          i++;\n          return [3, 1];\n        case 4:\n          return [2];\n      }\n    });\n  });\n}\n")
+	expectPrintedTarget(t, 5, "{ class Foo {} } { class Foo {} }", "{\n  var Foo = /* @__PURE__ */ function() {\n    function Foo() {\n      __classCallCheck(this, Foo);\n    }\n    return Foo;\n  }();\n}\n{\n  var Foo = /* @__PURE__ */ function() {\n    function Foo() {\n      __classCallCheck(this, Foo);\n    }\n    return Foo;\n  }();\n}\n")
+	expectParseErrorTarget(t, 5, "x; let x = 1;",
+		"<stdin>: warning: This access will not throw an error because \"x\" is converted to \"var\" for the configured target environment\n<stdin>: note: \"x\" is declared here\n")
+	expectParseErrorTarget(t, 5, "{ f(x); const x = 1 }",
+		"<stdin>: warning: This access will not throw an error because \"x\" is converted to \"var\" for the configured target environment\n<stdin>: note: \"x\" is declared here\n")
+	expectPrintedTarget(t, 5, "if (a) f(x); let x = 1;", "if (a)\n  f(x);\nvar x = 1;\n")
+	expectParseErrorTarget(t, 5, "let x = 1; function f() { return y } let y = x;", "")
+	expectParseErrorTarget(t, 2015, "x; let x = 1;", "")
+	expectParseErrorTarget(t, 5, "const x = 1; x = 2;",
+		"<stdin>: error: Cannot assign to \"x\" because it is a constant\n<stdin>: note: \"x\" was declared a constant here\n")
+	expectParseErrorTarget(t, 5, "const x = 1; function f() { x++ }",
+		"<stdin>: error: Cannot assign to \"x\" because it is a constant\n<stdin>: note: \"x\" was declared a constant here\n")
 	expectPrintedTarget(t, 5, "async => foo;", "(function(async) {\n  return foo;\n});\n")
 	expectPrintedTarget(t, 5, "x => x;", "(function(x) {\n  return x;\n});\n")
-	expectParseErrorTarget(t, 5, "async () => foo;",
-		"<stdin>: error: Transforming async functions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "class Foo {}",
-		"<stdin>: error: Transforming class syntax to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(class {});",
-		"<stdin>: error: Transforming class syntax to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function* gen() {}",
-		"<stdin>: error: Transforming generator functions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function* () {});",
-		"<stdin>: error: Transforming generator functions to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "async () => foo;", "(function() {\n  return __async(this, null, function() {\n    return __stateMachine(this, function(_a) {\n      return [2, foo];\n    });\n  });\n});\n")
+	expectPrintedTarget(t, 5, "class Foo {}", "var Foo = /* @__PURE__ */ function() {\n  function Foo() {\n    __classCallCheck(this, Foo);\n  }\n  return Foo;\n}();\n")
+	expectPrintedTarget(t, 5, "(class {});", "/* @__PURE__ */ (function() {\n  function _class() {\n    __classCallCheck(this, _class);\n  }\n  return _class;\n})();\n")
+	expectPrintedTarget(t, 5, "class Foo { constructor(x) { this.x = x } foo() {} static bar() {} }", "var Foo = /* @__PURE__ */ function() {\n  function Foo(x) {\n    __classCallCheck(this, Foo);\n    this.x = x;\n  }\n  __defMethod(Foo.prototype, \"foo\", function() {\n  });\n  __defMethod(Foo, \"bar\", function() {\n  });\n  return Foo;\n}();\n")
+	expectPrintedTarget(t, 5, "class Foo { get x() {} set x(v) {} static get y() {} [z]() {} }", "var Foo = function() {\n  function Foo() {\n    __classCallCheck(this, Foo);\n  }\n  __defAccessor(Foo.prototype, \"x\", function() {\n  }, function(v) {\n  });\n  __defAccessor(Foo, \"y\", function() {\n  }, void 0);\n  __defMethod(Foo.prototype, z, function() {\n  });\n  return Foo;\n}();\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar {}", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    return _super.apply(this, arguments) || this;\n  }\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Error {} class Bar extends Foo {}", "var Foo = /* @__PURE__ */ function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    return _super.apply(this, arguments) || this;\n  }\n  return Foo;\n}(/* @__PURE__ */ __wrapNativeSuper(Error));\nvar Bar = /* @__PURE__ */ function(_super) {\n  __extendClass(Bar, _super);\n  function Bar() {\n    __classCallCheck(this, Bar);\n    return _super.apply(this, arguments) || this;\n  }\n  return Bar;\n}(Foo);\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { constructor(x) { super(x); this.y = x } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo(x) {\n    __classCallCheck(this, Foo);\n    var _this = _super.call(this, x) || this;\n    _this.y = x;\n    return _this;\n  }\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { constructor() { foo(); super(); return } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    var _this = this;\n    foo();\n    _this = _super.call(this) || this;\n    return _this;\n  }\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { constructor() { var f = () => super(); f() } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    var _this = this;\n    var f = function() {\n      return _this = _super.call(_this) || _this;\n    };\n    f();\n    return _this;\n  }\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { foo() { return super.foo(1) + super[x] } static bar() { return super.bar } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    return _super.apply(this, arguments) || this;\n  }\n  __defMethod(Foo.prototype, \"foo\", function() {\n    return __superGet(_super.prototype, \"foo\", this).call(this, 1) + __superGet(_super.prototype, x, this);\n  });\n  __defMethod(Foo, \"bar\", function() {\n    return __superGet(_super, \"bar\", this);\n  });\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { foo() { return () => super.foo(this) } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    return _super.apply(this, arguments) || this;\n  }\n  __defMethod(Foo.prototype, \"foo\", function() {\n    var _this = this;\n    return function() {\n      return __superGet(_super.prototype, \"foo\", _this).call(_this, _this);\n    };\n  });\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { x = 1 }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    var _this = _super.apply(this, arguments) || this;\n    __publicField(this, \"x\", 1);\n    return _this;\n  }\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { x = 1; static y = super.y + super.z(); constructor() { super(); foo(this) } }", "var _Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    var _this = _super.call(this) || this;\n    __publicField(this, \"x\", 1);\n    foo(_this);\n    return _this;\n  }\n  return Foo;\n}(__wrapNativeSuper(Bar));\nvar Foo = _Foo;\n__publicField(Foo, \"y\", __superGet(__getProtoOf(_Foo), \"y\", _Foo) + __superGet(__getProtoOf(_Foo), \"z\", _Foo).call(_Foo));\n")
+	expectPrintedTarget(t, 5, "x = class extends Bar { static y = 1 };", "var _a;\nx = (_a = function(_super) {\n  __extendClass(_class, _super);\n  function _class() {\n    __classCallCheck(this, _class);\n    return _super.apply(this, arguments) || this;\n  }\n  return _class;\n}(__wrapNativeSuper(Bar)), __publicField(_a, \"y\", 1), _a);\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { get x() { return super.x } set x(v) { super.x = v } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    return _super.apply(this, arguments) || this;\n  }\n  __defAccessor(Foo.prototype, \"x\", function() {\n    return __superGet(_super.prototype, \"x\", this);\n  }, function(v) {\n    __superSet(_super.prototype, \"x\", v, this);\n  });\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { foo() { super.x = 1; super[y] = 2; return super.x } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    return _super.apply(this, arguments) || this;\n  }\n  __defMethod(Foo.prototype, \"foo\", function() {\n    __superSet(_super.prototype, \"x\", 1, this);\n    __superSet(_super.prototype, y, 2, this);\n    return __superGet(_super.prototype, \"x\", this);\n  });\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { foo() { super.x += 1; super[y]++; [super.z] = a } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    return _super.apply(this, arguments) || this;\n  }\n  __defMethod(Foo.prototype, \"foo\", function() {\n    __superWrapper(_super.prototype, \"x\", this)._ += 1;\n    __superWrapper(_super.prototype, y, this)._++;\n    __superWrapper(_super.prototype, \"z\", this)._ = __toArray(a, 1)[0];\n  });\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { static foo() { super.x = super.y } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    return _super.apply(this, arguments) || this;\n  }\n  __defMethod(Foo, \"foo\", function() {\n    __superSet(_super, \"x\", __superGet(_super, \"y\", this), this);\n  });\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { constructor() { super(); super.x = 1 } }", "var Foo = function(_super) {\n  __extendClass(Foo, _super);\n  function Foo() {\n    __classCallCheck(this, Foo);\n    var _this = _super.call(this) || this;\n    __superSet(_super.prototype, \"x\", 1, _this);\n    return _this;\n  }\n  return Foo;\n}(__wrapNativeSuper(Bar));\n")
+	expectPrintedTarget(t, 5, "class Foo { static x = super.x }", "var _Foo = /* @__PURE__ */ function() {\n  function Foo() {\n    __classCallCheck(this, Foo);\n  }\n  return Foo;\n}();\nvar Foo = _Foo;\n__publicField(Foo, \"x\", __superGet(__getProtoOf(_Foo), \"x\", _Foo));\n")
+	expectParseErrorTarget(t, 5, "class Foo { foo() { super.foo() } }",
+		"<stdin>: error: Transforming \"super\" in a class without a base class to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "function* gen() {}", "function gen() {\n  return __stateMachine(this, function(_a) {\n    return [2];\n  });\n}\n")
+	expectPrintedTarget(t, 5, "(function* () {});", "(function() {\n  return __stateMachine(this, function(_a) {\n    return [2];\n  });\n});\n")
+	expectPrintedTarget(t, 5, "function* gen(x) { var y = yield x; return y }", "function gen(x) {\n  var y;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        return [4, x];\n      case 1:\n        y = _a.sent();\n        return [2, y];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { yield* foo(); yield }", "function gen() {\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        return [5, foo()];\n      case 1:\n        _a.sent();\n        return [4];\n      case 2:\n        _a.sent();\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { while (a) { if (b) continue; if (c) break; yield d } }", "function gen() {\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        if (!a)\n          return [3, 2];\n        if (b)\n          return [3, 0];\n        if (c)\n          return [3, 2];\n        return [4, d];\n      case 1:\n        _a.sent();\n        return [3, 0];\n      case 2:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { for (var i = 0; i < 3; i++) yield i }", "function gen() {\n  var i;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        i = 0;\n        _a.label = 1;\n      case 1:\n        if (!(i < 3))\n          return [3, 4];\n        return [4, i];\n      case 2:\n        _a.sent();\n        _a.label = 3;\n      case 3:\n        i++;\n        return [3, 1];\n      case 4:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { for (var k in o) yield k }", "function gen() {\n  var _b, _c, _d, _e, k;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        _b = o, _c = [];\n        for (_d in _b)\n          _c.push(_d);\n        _e = 0;\n        _a.label = 1;\n      case 1:\n        if (_e >= _c.length)\n          return [3, 4];\n        _d = _c[_e];\n        if (!(_d in _b))\n          return [3, 3];\n        k = _d;\n        return [4, k];\n      case 2:\n        _a.sent();\n        _a.label = 3;\n      case 3:\n        _e++;\n        return [3, 1];\n      case 4:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { x: { if (a) break x; yield } }", "function gen() {\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        if (a)\n          return [3, 2];\n        return [4];\n      case 1:\n        _a.sent();\n        _a.label = 2;\n      case 2:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { try { yield a } catch (e) { yield e } finally { b() } }", "function gen() {\n  var e;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        _a.trys.push([0, 2, 4, 5]);\n        return [4, a];\n      case 1:\n        _a.sent();\n        return [3, 5];\n      case 2:\n        e = _a.sent();\n        return [4, e];\n      case 3:\n        _a.sent();\n        return [3, 5];\n      case 4:\n        b();\n        return [7];\n      case 5:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { try { yield a } finally { return } }", "function gen() {\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        _a.trys.push([0, , 2, 3]);\n        return [4, a];\n      case 1:\n        _a.sent();\n        return [3, 3];\n      case 2:\n        return [2];\n      case 3:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { switch (yield a) { case b: yield c; break; default: d() } }", "function gen() {\n  var _b;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        return [4, a];\n      case 1:\n        _b = _a.sent();\n        if (_b === b)\n          return [3, 2];\n        return [3, 4];\n      case 2:\n        return [4, c];\n      case 3:\n        _a.sent();\n        return [3, 5];\n      case 4:\n        d();\n        _a.label = 5;\n      case 5:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { a((yield b) && (yield c)) }", "function gen() {\n  var _b;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        return [4, b];\n      case 1:\n        _b = _a.sent();\n        if (!_b)\n          return [3, 3];\n        return [4, c];\n      case 2:\n        _b = _a.sent();\n        _a.label = 3;\n      case 3:\n        a(_b);\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { a.b(c, yield d) }", "function gen() {\n  var _b, _c;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        _b = a;\n        _c = c;\n        return [4, d];\n      case 1:\n        _b.b(_c, _a.sent());\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { a[b] += yield c }", "function gen() {\n  var _b, _c, _d;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        _b = a;\n        _c = b;\n        _d = _b[_c];\n        return [4, c];\n      case 1:\n        _b[_c] = _d + _a.sent();\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { x = a ? yield b : c }", "function gen() {\n  var _b;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        if (!a)\n          return [3, 2];\n        return [4, b];\n      case 1:\n        _b = _a.sent();\n        return [3, 3];\n      case 2:\n        _b = c;\n        _a.label = 3;\n      case 3:\n        x = _b;\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { yield arguments[0] }", "function gen() {\n  var _arguments = arguments;\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        return [4, _arguments[0]];\n      case 1:\n        _a.sent();\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { function f() {} yield f }", "function gen() {\n  function f() {\n  }\n  return __stateMachine(this, function(_a) {\n    switch (_a.label) {\n      case 0:\n        return [4, f];\n      case 1:\n        _a.sent();\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "async function foo() { await a }", "function foo() {\n  return __async(this, null, function() {\n    return __stateMachine(this, function(_a) {\n      switch (_a.label) {\n        case 0:\n          return [4, a];\n        case 1:\n          _a.sent();\n          return [2];\n      }\n    });\n  });\n}\n")
+	expectPrintedTarget(t, 5, "async function foo() { try { await a } catch { b() } }", "function foo() {\n  return __async(this, null, function() {\n    var e;\n    return __stateMachine(this, function(_a) {\n      switch (_a.label) {\n        case 0:\n          _a.trys.push([0, 2, , 3]);\n          return [4, a];\n        case 1:\n          _a.sent();\n          return [3, 3];\n        case 2:\n          e = _a.sent();\n          b();\n          return [3, 3];\n        case 3:\n          return [2];\n      }\n    });\n  });\n}\n")
+	expectPrintedTarget(t, 5, "async function foo() { await arguments[0] }", "function foo() {\n  return __async(this, arguments, function() {\n    var _arguments = arguments;\n    return __stateMachine(this, function(_a) {\n      switch (_a.label) {\n        case 0:\n          return [4, _arguments[0]];\n        case 1:\n          _a.sent();\n          return [2];\n      }\n    });\n  });\n}\n")
+	expectPrintedTarget(t, 5, "async function* foo() { yield await a; yield* b }", "function foo() {\n  return __asyncGen(this, null, function() {\n    return __stateMachine(this, function(_a) {\n      switch (_a.label) {\n        case 0:\n          return [4, new __awaitValue(a)];\n        case 1:\n          return [4, _a.sent()];\n        case 2:\n          _a.sent();\n          return [4, new __awaitValue(b, 1)];\n        case 3:\n          _a.sent();\n          return [2];\n      }\n    });\n  });\n}\n")
+	expectPrintedTarget(t, 5, "async function foo() { for await (var x of y) z(x) }", "function foo() {\n  return __async(this, null, function() {\n    var iter, more, temp, error, x, _b;\n    return __stateMachine(this, function(_a) {\n      switch (_a.label) {\n        case 0:\n          _a.trys.push([0, 5, 6, 11]);\n          iter = __forAwait(y);\n          _a.label = 1;\n        case 1:\n          return [4, iter.next()];\n        case 2:\n          if (!(more = !(temp = _a.sent()).done))\n            return [3, 4];\n          x = temp.value;\n          z(x);\n          _a.label = 3;\n        case 3:\n          more = false;\n          return [3, 1];\n        case 4:\n          return [3, 11];\n        case 5:\n          temp = _a.sent();\n          error = [temp];\n          return [3, 11];\n        case 6:\n          _a.trys.push([6, , 9, 10]);\n          _b = more && (temp = iter.return);\n          if (!_b)\n            return [3, 8];\n          return [4, temp.call(iter)];\n        case 7:\n          _b = _a.sent();\n          _a.label = 8;\n        case 8:\n          return [3, 10];\n        case 9:\n          if (error)\n            throw error[0];\n          return [7];\n        case 10:\n          return [7];\n        case 11:\n          return [2];\n      }\n    });\n  });\n}\n")
+	expectPrintedTarget(t, 5, "async function foo() { a: for await (var x of y) continue a }", "function foo() {\n  return __async(this, null, function() {\n    var iter, more, temp, error, x, _b;\n    return __stateMachine(this, function(_a) {\n      switch (_a.label) {\n        case 0:\n          _a.trys.push([0, 5, 6, 11]);\n          iter = __forAwait(y);\n          _a.label = 1;\n        case 1:\n          return [4, iter.next()];\n        case 2:\n          if (!(more = !(temp = _a.sent()).done))\n            return [3, 4];\n          x = temp.value;\n          return [3, 3];\n        case 3:\n          more = false;\n          return [3, 1];\n        case 4:\n          return [3, 11];\n        case 5:\n          temp = _a.sent();\n          error = [temp];\n          return [3, 11];\n        case 6:\n          _a.trys.push([6, , 9, 10]);\n          _b = more && (temp = iter.return);\n          if (!_b)\n            return [3, 8];\n          return [4, temp.call(iter)];\n        case 7:\n          _b = _a.sent();\n          _a.label = 8;\n        case 8:\n          return [3, 10];\n        case 9:\n          if (error)\n            throw error[0];\n          return [7];\n        case 10:\n          return [7];\n        case 11:\n          return [2];\n      }\n    });\n  });\n}\n")
+	expectPrintedTarget(t, 2017, "async function foo() { for await (let x of y) z(x) }", "async function foo() {\n  try {\n    for (var iter = __forAwait(y), more, temp, error; more = !(temp = await iter.next()).done; more = false) {\n      let x = temp.value;\n      z(x);\n    }\n  } catch (temp) {\n    error = [temp];\n  } finally {\n    try {\n      more && (temp = iter.return) && await temp.call(iter);\n    } finally {\n      if (error)\n        throw error[0];\n    }\n  }\n}\n")
+	expectPrintedTarget(t, 2017, "async function* foo() { yield await a }", "function foo() {\n  return __asyncGen(this, null, function* () {\n    yield yield new __awaitValue(a);\n  });\n}\n")
 }
 
 func TestASCIIOnly(t *testing.T) {
@@ -1,6 +1,7 @@
 // This file contains code for parsing TypeScript syntax. The parser just skips
 // over type expressions as if they are whitespace and doesn't bother generating
-// an AST because nothing uses type information.
+// an AST because nothing uses type information. The only exception is a small
+// summary of each type that is used for TypeScript's "emitDecoratorMetadata".
 
 package js_parser
 
@@ -125,34 +126,34 @@ func (p *parser) skipTypeScriptFnArgs() {
 // This is a spot where the TypeScript grammar is highly ambiguous. Here are
 // some cases that are valid:
 //
-//     let x = (y: any): (() => {}) => { };
-//     let x = (y: any): () => {} => { };
-//     let x = (y: any): (y) => {} => { };
-//     let x = (y: any): (y[]) => {};
-//     let x = (y: any): (a | b) => {};
+//	let x = (y: any): (() => {}) => { };
+//	let x = (y: any): () => {} => { };
+//	let x = (y: any): (y) => {} => { };
+//	let x = (y: any): (y[]) => {};
+//	let x = (y: any): (a | b) => {};
 //
 // Here are some cases that aren't valid:
 //
-//     let x = (y: any): (y) => {};
-//     let x = (y: any): (y) => {return 0};
-//     let x = (y: any): asserts y is (y) => {};
-//
-func (p *parser) skipTypeScriptParenOrFnType() {
+//	let x = (y: any): (y) => {};
+//	let x = (y: any): (y) => {return 0};
+//	let x = (y: any): asserts y is (y) => {};
+func (p *parser) skipTypeScriptParenOrFnType() js_ast.TSMetadata {
 	if p.trySkipTypeScriptArrowArgsWithBacktracking() {
 		p.skipTypeScriptReturnType()
-	} else {
-		p.lexer.Expect(js_lexer.TOpenParen)
-		p.skipTypeScriptType(js_ast.LLowest)
-		p.lexer.Expect(js_lexer.TCloseParen)
+		return js_ast.TSMetadata{Kind: js_ast.TSMetadataFunction}
 	}
+	p.lexer.Expect(js_lexer.TOpenParen)
+	metadata := p.skipTypeScriptType(js_ast.LLowest)
+	p.lexer.Expect(js_lexer.TCloseParen)
+	return metadata
 }
 
-func (p *parser) skipTypeScriptReturnType() {
-	p.skipTypeScriptTypeWithOpts(js_ast.LLowest, skipTypeOpts{isReturnType: true})
+func (p *parser) skipTypeScriptReturnType() js_ast.TSMetadata {
+	return p.skipTypeScriptTypeWithOpts(js_ast.LLowest, skipTypeOpts{isReturnType: true})
 }
 
-func (p *parser) skipTypeScriptType(level js_ast.L) {
-	p.skipTypeScriptTypeWithOpts(level, skipTypeOpts{})
+func (p *parser) skipTypeScriptType(level js_ast.L) js_ast.TSMetadata {
+	return p.skipTypeScriptTypeWithOpts(level, skipTypeOpts{})
 }
 
 type skipTypeOpts struct {
@@ -192,22 +193,125 @@ var tsTypeIdentifierMap = map[string]tsTypeIdentifierKind{
 	"symbol":    tsTypeIdentifierPrimitive,
 }
 
-func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
+var tsPrimitiveMetadataMap = map[string]js_ast.TSMetadataKind{
+	"any":       js_ast.TSMetadataAny,
+	"never":     js_ast.TSMetadataNever,
+	"unknown":   js_ast.TSMetadataUnknown,
+	"undefined": js_ast.TSMetadataUndefined,
+	"object":    js_ast.TSMetadataObject,
+	"number":    js_ast.TSMetadataNumber,
+	"string":    js_ast.TSMetadataString,
+	"boolean":   js_ast.TSMetadataBoolean,
+	"bigint":    js_ast.TSMetadataBigInt,
+	"symbol":    js_ast.TSMetadataSymbol,
+}
+
+// This follows what the TypeScript compiler does when serializing the
+// constituents of union and intersection types. Constituents that are always
+// elided are skipped, a constituent that absorbs everything else wins, and
+// otherwise all constituents must be the same constructor or the result falls
+// back to "Object". Note that "null" and "undefined" are always elided since
+// we don't know if "strictNullChecks" is enabled.
+func mergeTSMetadata(a js_ast.TSMetadata, b js_ast.TSMetadata, isIntersection bool) js_ast.TSMetadata {
+	object := js_ast.TSMetadata{Kind: js_ast.TSMetadataObject}
+
+	for _, m := range [2]js_ast.TSMetadata{a, b} {
+		switch m.Kind {
+		case js_ast.TSMetadataAny, js_ast.TSMetadataObject:
+			return object
+
+		case js_ast.TSMetadataUnknown:
+			if !isIntersection {
+				return object
+			}
+
+		case js_ast.TSMetadataNever:
+			if isIntersection {
+				return m
+			}
+		}
+	}
+
+	if isTSMetadataElided(a, isIntersection) {
+		if isTSMetadataElided(b, isIntersection) {
+			return js_ast.TSMetadata{Kind: js_ast.TSMetadataUndefined}
+		}
+		return b
+	}
+	if isTSMetadataElided(b, isIntersection) {
+		return a
+	}
+
+	if a.Kind == b.Kind && a.Kind != js_ast.TSMetadataVoid && a.Kind != js_ast.TSMetadataIdentifier {
+		return a
+	}
+	if a.Kind == js_ast.TSMetadataIdentifier && b.Kind == js_ast.TSMetadataIdentifier && len(a.Names) == len(b.Names) {
+		for i, name := range a.Names {
+			if b.Names[i] != name {
+				return object
+			}
+		}
+		return a
+	}
+	return object
+}
+
+func isTSMetadataElided(m js_ast.TSMetadata, isIntersection bool) bool {
+	switch m.Kind {
+	case js_ast.TSMetadataNull, js_ast.TSMetadataUndefined:
+		return true
+
+	case js_ast.TSMetadataNever:
+		return !isIntersection
+
+	case js_ast.TSMetadataUnknown:
+		return isIntersection
+	}
+	return false
+}
+
+func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) js_ast.TSMetadata {
+	var metadata js_ast.TSMetadata
+
 	for {
 		switch p.lexer.Token {
-		case js_lexer.TNumericLiteral, js_lexer.TBigIntegerLiteral, js_lexer.TStringLiteral,
-			js_lexer.TNoSubstitutionTemplateLiteral, js_lexer.TTrue, js_lexer.TFalse,
-			js_lexer.TNull, js_lexer.TVoid, js_lexer.TConst:
+		case js_lexer.TNumericLiteral:
+			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataNumber
+
+		case js_lexer.TBigIntegerLiteral:
+			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataBigInt
+
+		case js_lexer.TStringLiteral, js_lexer.TNoSubstitutionTemplateLiteral:
+			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataString
+
+		case js_lexer.TTrue, js_lexer.TFalse:
+			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataBoolean
+
+		case js_lexer.TNull:
+			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataNull
+
+		case js_lexer.TVoid:
+			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataVoid
+
+		case js_lexer.TConst:
 			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataObject
 
 		case js_lexer.TThis:
 			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataObject
 
 			// "function check(): this is boolean"
 			if p.lexer.IsContextualKeyword("is") && !p.lexer.HasNewlineBefore {
 				p.lexer.Next()
 				p.skipTypeScriptType(js_ast.LLowest)
-				return
+				return js_ast.TSMetadata{Kind: js_ast.TSMetadataBoolean}
 			}
 
 		case js_lexer.TMinus:
@@ -216,8 +320,10 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			p.lexer.Next()
 			if p.lexer.Token == js_lexer.TBigIntegerLiteral {
 				p.lexer.Next()
+				metadata.Kind = js_ast.TSMetadataBigInt
 			} else {
 				p.lexer.Expect(js_lexer.TNumericLiteral)
+				metadata.Kind = js_ast.TSMetadataNumber
 			}
 
 		case js_lexer.TAmpersand:
@@ -232,6 +338,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			p.lexer.Expect(js_lexer.TOpenParen)
 			p.lexer.Expect(js_lexer.TStringLiteral)
 			p.lexer.Expect(js_lexer.TCloseParen)
+			metadata.Kind = js_ast.TSMetadataObject
 
 		case js_lexer.TNew:
 			// "new () => Foo"
@@ -239,26 +346,37 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			p.lexer.Next()
 			p.skipTypeScriptTypeParameters()
 			p.skipTypeScriptParenOrFnType()
+			metadata.Kind = js_ast.TSMetadataFunction
 
 		case js_lexer.TLessThan:
 			// "<T>() => Foo<T>"
 			p.skipTypeScriptTypeParameters()
 			p.skipTypeScriptParenOrFnType()
+			metadata.Kind = js_ast.TSMetadataFunction
 
 		case js_lexer.TOpenParen:
 			// "(number | string)"
-			p.skipTypeScriptParenOrFnType()
+			metadata = p.skipTypeScriptParenOrFnType()
 
 		case js_lexer.TIdentifier:
 			kind := tsTypeIdentifierMap[p.lexer.Identifier]
 
 			if kind == tsTypeIdentifierPrefix {
+				isReadonly := p.lexer.Identifier == "readonly"
 				p.lexer.Next()
-				p.skipTypeScriptType(js_ast.LPrefix)
+				inner := p.skipTypeScriptType(js_ast.LPrefix)
+
+				// "readonly string[]" is still an array
+				if isReadonly {
+					metadata = inner
+				} else {
+					metadata.Kind = js_ast.TSMetadataObject
+				}
 				break
 			}
 
 			checkTypeParameters := true
+			metadata = p.tsMetadataForIdentifier()
 
 			if kind == tsTypeIdentifierUnique {
 				p.lexer.Next()
@@ -266,6 +384,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 				// "let foo: unique symbol"
 				if p.lexer.IsContextualKeyword("symbol") {
 					p.lexer.Next()
+					metadata = js_ast.TSMetadata{Kind: js_ast.TSMetadataObject}
 					break
 				}
 			} else if kind == tsTypeIdentifierAbstract {
@@ -282,8 +401,10 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 				// "function assert(x: boolean): asserts x is boolean"
 				if opts.isReturnType && !p.lexer.HasNewlineBefore && (p.lexer.Token == js_lexer.TIdentifier || p.lexer.Token == js_lexer.TThis) {
 					p.lexer.Next()
+					metadata = js_ast.TSMetadata{Kind: js_ast.TSMetadataBoolean}
 				}
 			} else if kind == tsTypeIdentifierPrimitive {
+				metadata = js_ast.TSMetadata{Kind: tsPrimitiveMetadataMap[p.lexer.Identifier]}
 				p.lexer.Next()
 				checkTypeParameters = false
 			} else {
@@ -294,7 +415,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			if p.lexer.IsContextualKeyword("is") && !p.lexer.HasNewlineBefore {
 				p.lexer.Next()
 				p.skipTypeScriptType(js_ast.LLowest)
-				return
+				return js_ast.TSMetadata{Kind: js_ast.TSMetadataBoolean}
 			}
 
 			// "let foo: any \n <number>foo" must not become a single type
@@ -304,6 +425,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 
 		case js_lexer.TTypeof:
 			p.lexer.Next()
+			metadata.Kind = js_ast.TSMetadataObject
 			if p.lexer.Token == js_lexer.TImport {
 				// "typeof import('fs')"
 				continue
@@ -344,9 +466,11 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 				p.lexer.Next()
 			}
 			p.lexer.Expect(js_lexer.TCloseBracket)
+			metadata.Kind = js_ast.TSMetadataArray
 
 		case js_lexer.TOpenBrace:
 			p.skipTypeScriptObjectType()
+			metadata.Kind = js_ast.TSMetadataObject
 
 		case js_lexer.TTemplateHead:
 			// "`${'a' | 'b'}-${'c' | 'd'}`"
@@ -359,6 +483,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 					break
 				}
 			}
+			metadata.Kind = js_ast.TSMetadataString
 
 		default:
 			p.lexer.Unexpected()
@@ -370,17 +495,17 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 		switch p.lexer.Token {
 		case js_lexer.TBar:
 			if level >= js_ast.LBitwiseOr {
-				return
+				return metadata
 			}
 			p.lexer.Next()
-			p.skipTypeScriptType(js_ast.LBitwiseOr)
+			metadata = mergeTSMetadata(metadata, p.skipTypeScriptType(js_ast.LBitwiseOr), false /* isIntersection */)
 
 		case js_lexer.TAmpersand:
 			if level >= js_ast.LBitwiseAnd {
-				return
+				return metadata
 			}
 			p.lexer.Next()
-			p.skipTypeScriptType(js_ast.LBitwiseAnd)
+			metadata = mergeTSMetadata(metadata, p.skipTypeScriptType(js_ast.LBitwiseAnd), true /* isIntersection */)
 
 		case js_lexer.TExclamation:
 			// A postfix "!" is allowed in JSDoc types in TypeScript, which are only
@@ -389,7 +514,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			// compiler. It turns out parsing this is important for correctness for
 			// "as" casts because the "!" token must still be consumed.
 			if p.lexer.HasNewlineBefore {
-				return
+				return metadata
 			}
 			p.lexer.Next()
 
@@ -398,6 +523,13 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			if !p.lexer.IsIdentifierOrKeyword() {
 				p.lexer.Expect(js_lexer.TIdentifier)
 			}
+
+			// "Foo.Bar" is a qualified type name
+			if metadata.Kind == js_ast.TSMetadataIdentifier && p.options.emitDecoratorMetadata {
+				metadata.Names = append(metadata.Names, p.lexer.Identifier)
+			} else {
+				metadata = js_ast.TSMetadata{Kind: js_ast.TSMetadataObject}
+			}
 			p.lexer.Next()
 
 			// "{ <A extends B>(): c.d \n <E extends F>(): g.h }" must not become a single type
@@ -408,34 +540,57 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 		case js_lexer.TOpenBracket:
 			// "{ ['x']: string \n ['y']: string }" must not become a single type
 			if p.lexer.HasNewlineBefore {
-				return
+				return metadata
 			}
 			p.lexer.Next()
 			if p.lexer.Token != js_lexer.TCloseBracket {
+				// "T[K]"
 				p.skipTypeScriptType(js_ast.LLowest)
+				metadata = js_ast.TSMetadata{Kind: js_ast.TSMetadataObject}
+			} else {
+				// "T[]"
+				var elementOrNil *js_ast.TSMetadata
+				if p.options.emitDecoratorMetadata {
+					element := metadata
+					elementOrNil = &element
+				}
+				metadata = js_ast.TSMetadata{Kind: js_ast.TSMetadataArray, ElementOrNil: elementOrNil}
 			}
 			p.lexer.Expect(js_lexer.TCloseBracket)
 
 		case js_lexer.TExtends:
 			// "{ x: number \n extends: boolean }" must not become a single type
 			if p.lexer.HasNewlineBefore || level >= js_ast.LConditional {
-				return
+				return metadata
 			}
 			p.lexer.Next()
 
 			// The type following "extends" is not permitted to be another conditional type
 			p.skipTypeScriptType(js_ast.LConditional)
 			p.lexer.Expect(js_lexer.TQuestion)
-			p.skipTypeScriptType(js_ast.LLowest)
+			yes := p.skipTypeScriptType(js_ast.LLowest)
 			p.lexer.Expect(js_lexer.TColon)
-			p.skipTypeScriptType(js_ast.LLowest)
+			no := p.skipTypeScriptType(js_ast.LLowest)
+
+			// A conditional type is serialized as the union of both branches
+			metadata = mergeTSMetadata(yes, no, false /* isIntersection */)
 
 		default:
-			return
+			return metadata
 		}
 	}
 }
 
+// This is called with the lexer on the first identifier of a type reference
+// such as "Foo" in "Foo.Bar<T>". The names are only needed for decorators.
+func (p *parser) tsMetadataForIdentifier() js_ast.TSMetadata {
+	metadata := js_ast.TSMetadata{Kind: js_ast.TSMetadataIdentifier, Loc: p.lexer.Loc()}
+	if p.options.emitDecoratorMetadata {
+		metadata.Names = []string{p.lexer.Identifier}
+	}
+	return metadata
+}
+
 func (p *parser) skipTypeScriptObjectType() {
 	p.lexer.Expect(js_lexer.TOpenBrace)
 
@@ -805,26 +960,6 @@ func (p *parser) skipTypeScriptTypeStmt(opts parseStmtOpts) {
 	p.lexer.ExpectOrInsertSemicolon()
 }
 
-func (p *parser) parseTypeScriptDecorators() []js_ast.Expr {
-	var tsDecorators []js_ast.Expr
-	if p.options.ts.Parse {
-		for p.lexer.Token == js_lexer.TAt {
-			p.lexer.Next()
-
-			// Parse a new/call expression with "exprFlagTSDecorator" so we ignore
-			// EIndex expressions, since they may be part of a computed property:
-			//
-			//   class Foo {
-			//     @foo ['computed']() {}
-			//   }
-			//
-			// This matches the behavior of the TypeScript compiler.
-			tsDecorators = append(tsDecorators, p.parseExprWithFlags(js_ast.LNew, exprFlagTSDecorator))
-		}
-	}
-	return tsDecorators
-}
-
 func (p *parser) parseTypeScriptEnumStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt {
 	p.lexer.Expect(js_lexer.TEnum)
 	nameLoc := p.lexer.Loc()
@@ -876,6 +1011,8 @@ func (p *parser) parseTypeScriptEnumStmt(loc logger.Loc, opts parseStmtOpts) js_
 	}
 
 	if !opts.isTypeScriptDeclare {
+		p.recordTSEnumMetadataKind(name.Ref, values)
+
 		// Avoid a collision with the enum closure argument variable if the
 		// enum exports a symbol with the same name as the enum itself:
 		//
@@ -1094,6 +1231,49 @@ func (p *parser) parseTypeScriptNamespaceStmt(loc logger.Loc, opts parseStmtOpts
 	}}
 }
 
+// Decorator metadata for a parameter typed as an enum is "Number" or "String"
+// depending on the enum's values, just like the TypeScript compiler. This is
+// decided before visiting so that it also works for enums declared after the
+// class that uses them. Enums with both kinds of values use "Object".
+func (p *parser) recordTSEnumMetadataKind(ref js_ast.Ref, values []js_ast.EnumValue) {
+	kind, ok := p.tsEnumMetadataKinds[ref]
+	for _, value := range values {
+		valueKind := js_ast.TSMetadataNumber
+		if isTSEnumStringValue(value.ValueOrNil) {
+			valueKind = js_ast.TSMetadataString
+		}
+		if !ok {
+			kind, ok = valueKind, true
+		} else if kind != valueKind {
+			kind = js_ast.TSMetadataObject
+		}
+	}
+	if ok {
+		p.tsEnumMetadataKinds[ref] = kind
+	}
+}
+
+func isTSEnumStringValue(value js_ast.Expr) bool {
+	switch e := value.Data.(type) {
+	case *js_ast.EString:
+		return true
+
+	case *js_ast.ETemplate:
+		return e.TagOrNil.Data == nil
+
+	case *js_ast.EBinary:
+		return e.Op == js_ast.BinOpAdd && (isTSEnumStringValue(e.Left) || isTSEnumStringValue(e.Right))
+	}
+	return false
+}
+
+func (p *parser) tsEnumValueToExpr(loc logger.Loc, value js_ast.TSEnumValue) js_ast.Expr {
+	if value.String != nil {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: value.String}}
+	}
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: value.Number}}
+}
+
 func (p *parser) generateClosureForTypeScriptNamespaceOrEnum(
 	stmts []js_ast.Stmt, stmtLoc logger.Loc, isExport bool, nameLoc logger.Loc,
 	nameRef js_ast.Ref, argRef js_ast.Ref, stmtsInsideClosure []js_ast.Stmt,
@@ -995,6 +995,15 @@ y = [0, Foo?.["A"], Foo?.["A"]()];
   _Foo[_Foo["Foo"] = 1] = "Foo";
   _Foo[_Foo["Bar"] = 1] = "Bar";
 })(Foo || (Foo = {}));
+`)
+
+	// Check inlining of string values
+	expectPrintedTS(t, "enum Foo { A = 'a', B = A } x = [Foo.A, Foo['B']]", `var Foo;
+(function(Foo) {
+  Foo["A"] = "a";
+  Foo["B"] = "a";
+})(Foo || (Foo = {}));
+x = ["a", "a"];
 `)
 }
 
@@ -1337,8 +1346,7 @@ func TestTSArrow(t *testing.T) {
 	expectPrintedTS(t, "function x(): ({y: z}) {}", "function x() {\n}\n")
 
 	expectParseErrorTargetTS(t, 5, "return check ? (hover = 2, bar) : baz()", "")
-	expectParseErrorTargetTS(t, 5, "return check ? (hover = 2, bar) => 0 : baz()",
-		"<stdin>: error: Transforming default arguments to the configured target environment is not supported yet\n")
+	expectParseErrorTargetTS(t, 5, "return check ? (hover = 2, bar) => 0 : baz()", "")
 }
 
 func TestTSCall(t *testing.T) {
@@ -478,6 +478,22 @@ func (p *printer) printIndent() {
 	}
 }
 
+func (p *printer) isPropertyAccessSymbol(ref js_ast.Ref) bool {
+	return p.options.IsPropertyAccessSymbol != nil && p.options.IsPropertyAccessSymbol(ref)
+}
+
+// This prints a call target that is a property access without passing the
+// object as "this" to the call
+func (p *printer) printWithoutThis(name string) {
+	if p.options.RemoveWhitespace {
+		p.print("(0,")
+	} else {
+		p.print("(0, ")
+	}
+	p.printIdentifier(name)
+	p.print(")")
+}
+
 func (p *printer) printSymbol(ref js_ast.Ref) {
 	name := p.renamer.NameForSymbol(ref)
 
@@ -818,7 +834,7 @@ func (p *printer) printClass(class js_ast.Class) {
 	for _, item := range class.Properties {
 		p.printSemicolonIfNeeded()
 		p.printIndent()
-		p.printProperty(item)
+		p.printProperty(item, 0)
 
 		// Need semicolons after class fields
 		if item.ValueOrNil.Data == nil {
@@ -834,10 +850,10 @@ func (p *printer) printClass(class js_ast.Class) {
 	p.print("}")
 }
 
-func (p *printer) printProperty(item js_ast.Property) {
+func (p *printer) printProperty(item js_ast.Property, valueFlags printExprFlags) {
 	if item.Kind == js_ast.PropertySpread {
 		p.print("...")
-		p.printExpr(item.ValueOrNil, js_ast.LComma, 0)
+		p.printExpr(item.ValueOrNil, js_ast.LComma, valueFlags)
 		return
 	}
 
@@ -856,6 +872,11 @@ func (p *printer) printProperty(item js_ast.Property) {
 		p.printSpaceBeforeIdentifier()
 		p.print("set")
 		p.printSpace()
+
+	case js_ast.PropertyAutoAccessor:
+		p.printSpaceBeforeIdentifier()
+		p.print("accessor")
+		p.printSpace()
 	}
 
 	if fn, ok := item.ValueOrNil.Data.(*js_ast.EFunction); item.IsMethod && ok {
@@ -882,7 +903,7 @@ func (p *printer) printProperty(item js_ast.Property) {
 
 			p.print(":")
 			p.printSpace()
-			p.printExpr(item.ValueOrNil, js_ast.LComma, 0)
+			p.printExpr(item.ValueOrNil, js_ast.LComma, valueFlags)
 		}
 
 		if item.InitializerOrNil.Data != nil {
@@ -957,7 +978,7 @@ func (p *printer) printProperty(item js_ast.Property) {
 
 		p.print(":")
 		p.printSpace()
-		p.printExpr(item.ValueOrNil, js_ast.LComma, 0)
+		p.printExpr(item.ValueOrNil, js_ast.LComma, valueFlags)
 	}
 
 	if item.InitializerOrNil.Data != nil {
@@ -1029,6 +1050,70 @@ func (p *printer) printRequireOrImportExpr(
 	}
 
 	if !record.SourceIndex.IsValid() {
+		// Load the stylesheets of a split chunk before the chunk itself
+		if record.Kind == ast.ImportDynamic {
+			if keys := p.options.CSSLoadOrder[record.Path.Text]; len(keys) > 0 {
+				p.printSymbol(p.options.LoadCSSRef)
+				p.print("([")
+				for i, key := range keys {
+					if i > 0 {
+						p.print(",")
+						p.printSpace()
+					}
+					p.printQuotedUTF8(key, true /* allowBacktick */)
+				}
+				p.print("]")
+				if p.options.OutputFormat == config.FormatESModule {
+					p.print(",")
+					p.printSpace()
+					p.print("import.meta.url")
+				} else if p.options.ChunkPublicPath != "" {
+					p.print(",")
+					p.printSpace()
+					p.printQuotedUTF8(p.options.ChunkPublicPath, true /* allowBacktick */)
+				}
+				p.print(")")
+				p.printDotThenPrefix()
+				defer p.printDotThenSuffix()
+			}
+		}
+
+		// Chunk "import()" in the "iife" format
+		if record.IsChunkLoad && p.options.OutputFormat == config.FormatIIFE {
+			p.printSymbol(p.options.LoadChunkRef)
+			p.print("([")
+			for i, key := range p.options.ChunkLoadOrder[record.Path.Text] {
+				if i > 0 {
+					p.print(",")
+					p.printSpace()
+				}
+				p.printQuotedUTF8(key, true /* allowBacktick */)
+			}
+			p.print("]")
+			if p.options.ChunkPublicPath != "" {
+				p.print(",")
+				p.printSpace()
+				p.printQuotedUTF8(p.options.ChunkPublicPath, true /* allowBacktick */)
+			}
+			p.print(")")
+
+			// Wrap the registered exports with a call to "__toModule()"
+			if record.WrapWithToModule {
+				p.print(".then(")
+				p.printSymbol(p.options.ToModuleRef)
+				p.print(")")
+			}
+			return
+		}
+
+		// Modules exposed by other builds are read from their containers
+		if p.options.Federation != nil {
+			if name, ok := p.options.Federation.RemoteForPath(record.Path.Text); ok {
+				p.printFederationRemote(record, p.options.Federation.Remotes[name])
+				return
+			}
+		}
+
 		// External "require()"
 		if record.Kind != ast.ImportDynamic {
 			if record.WrapWithToModule {
@@ -1037,6 +1122,13 @@ func (p *printer) printRequireOrImportExpr(
 				defer p.print(")")
 			}
 
+			// Read packages mapped to a global variable from that variable
+			if globalName, ok := p.options.ExternalGlobals[record.Path.Text]; ok {
+				p.addSourceMapping(record.Range.Loc)
+				p.printGlobalName(globalName)
+				return
+			}
+
 			// Potentially substitute our own "__require" stub for "require"
 			if record.CallRuntimeRequire {
 				p.printSymbol(p.options.RuntimeRequireRef)
@@ -1053,7 +1145,7 @@ func (p *printer) printRequireOrImportExpr(
 		}
 
 		// External "import()"
-		if !p.options.UnsupportedFeatures.Has(compat.DynamicImport) {
+		if p.canPrintImportCall(record) {
 			p.printSpaceBeforeIdentifier()
 			p.print("import(")
 			defer p.print(")")
@@ -1070,6 +1162,13 @@ func (p *printer) printRequireOrImportExpr(
 				defer p.print(")")
 			}
 
+			// Read packages mapped to a global variable from that variable
+			if globalName, ok := p.options.ExternalGlobals[record.Path.Text]; ok {
+				p.addSourceMapping(record.Range.Loc)
+				p.printGlobalName(globalName)
+				return
+			}
+
 			// Potentially substitute our own "__require" stub for "require"
 			if record.CallRuntimeRequire {
 				p.printSymbol(p.options.RuntimeRequireRef)
@@ -1091,7 +1190,7 @@ func (p *printer) printRequireOrImportExpr(
 		}
 		p.addSourceMapping(record.Range.Loc)
 		p.printQuotedUTF8(record.Path.Text, true /* allowBacktick */)
-		if !p.options.UnsupportedFeatures.Has(compat.DynamicImport) {
+		if p.canPrintImportCall(record) {
 			p.printImportCallAssertions(record.Assertions)
 		}
 		if len(leadingInteriorComments) > 0 {
@@ -1154,6 +1253,57 @@ func (p *printer) printRequireOrImportExpr(
 	}
 }
 
+// Prints "__federationRequire('name/path')" or, for "import()", a call to
+// "__federationImport('name/path', 'url')" that first loads the container
+func (p *printer) printFederationRemote(record *ast.ImportRecord, url string) {
+	if record.Kind == ast.ImportDynamic {
+		p.printSymbol(p.options.FederationImportRef)
+	} else {
+		if record.WrapWithToModule {
+			p.printSymbol(p.options.ToModuleRef)
+			p.print("(")
+			defer p.print(")")
+		}
+		p.printSymbol(p.options.FederationRequireRef)
+	}
+	p.print("(")
+	p.addSourceMapping(record.Range.Loc)
+	p.printQuotedUTF8(record.Path.Text, true /* allowBacktick */)
+	if record.Kind == ast.ImportDynamic {
+		p.print(",")
+		p.printSpace()
+		p.printQuotedUTF8(url, true /* allowBacktick */)
+	}
+	p.print(")")
+}
+
+// Prints a global variable such as "window.React" that was split into parts
+func (p *printer) printGlobalName(globalName []string) {
+	p.printSpaceBeforeIdentifier()
+	p.printIdentifier(globalName[0])
+	for _, name := range globalName[1:] {
+		if p.canPrintIdentifier(name) {
+			p.print(".")
+			p.printIdentifier(name)
+		} else {
+			p.print("[")
+			p.printQuotedUTF8(name, true /* allowBacktick */)
+			p.print("]")
+		}
+	}
+}
+
+// Returns false if an external "import()" must be printed using "require()"
+// instead. This is the case when "import()" isn't supported, for chunks that
+// are loaded without "import()", for packages read from a global variable, and
+// for the UMD and SystemJS formats, which pass all external modules to the
+// bundle using a "require" function.
+func (p *printer) canPrintImportCall(record *ast.ImportRecord) bool {
+	_, isGlobal := p.options.ExternalGlobals[record.Path.Text]
+	return !p.options.UnsupportedFeatures.Has(compat.DynamicImport) && !record.IsChunkLoad && !isGlobal &&
+		p.options.OutputFormat != config.FormatUMD && p.options.OutputFormat != config.FormatSystem
+}
+
 func (p *printer) printDotThenPrefix() js_ast.L {
 	if p.options.UnsupportedFeatures.Has(compat.Arrow) {
 		p.print(".then(function()")
@@ -1197,8 +1347,33 @@ const (
 	exprResultIsUnused
 	isFollowedByOf
 	isInsideForAwait
+
+	// Imported enum values aren't inlined into assignment targets, since the
+	// result would be invalid code such as "0 = 1". This only applies to the
+	// expression itself and not to its children.
+	isAssignTarget
 )
 
+func (p *printer) tryToGetImportedEnumValue(target js_ast.Expr, name string) (js_ast.Expr, bool) {
+	if id, ok := target.Data.(*js_ast.EImportIdentifier); ok && p.options.TSEnums != nil {
+		ref := js_ast.FollowSymbols(p.symbols, id.Ref)
+		if value, ok := p.options.TSEnums[ref][name]; ok {
+			if value.String != nil {
+				return js_ast.Expr{Loc: target.Loc, Data: &js_ast.EString{Value: value.String}}, true
+			}
+			return js_ast.Expr{Loc: target.Loc, Data: &js_ast.ENumber{Value: value.Number}}, true
+		}
+	}
+	return js_ast.Expr{}, false
+}
+
+func assignTargetFlag(isTarget bool) printExprFlags {
+	if isTarget {
+		return isAssignTarget
+	}
+	return 0
+}
+
 func (p *printer) printUndefined(level js_ast.L) {
 	if level >= js_ast.LPrefix {
 		p.print("(void 0)")
@@ -1212,6 +1387,8 @@ func (p *printer) printUndefined(level js_ast.L) {
 func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFlags) {
 	wasFollowedByOf := (flags & isFollowedByOf) != 0
 	flags &= ^isFollowedByOf
+	wasAssignTarget := (flags & isAssignTarget) != 0
+	flags &= ^isAssignTarget
 
 	p.addSourceMapping(expr.Loc)
 
@@ -1235,7 +1412,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 
 	case *js_ast.ESpread:
 		p.print("...")
-		p.printExpr(e.Value, js_ast.LComma, 0)
+		p.printExpr(e.Value, js_ast.LComma, assignTargetFlag(wasAssignTarget))
 
 	case *js_ast.ENewTarget:
 		p.printSpaceBeforeIdentifier()
@@ -1449,6 +1626,10 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 			p.print(")")
 		}
 
+	case *js_ast.EURLString:
+		p.addSourceMapping(expr.Loc)
+		p.printQuotedUTF8(p.importRecords[e.ImportRecordIndex].Path.Text, true /* allowBacktick */)
+
 	case *js_ast.EImportString:
 		var leadingInteriorComments []js_ast.Comment
 		if !p.options.RemoveWhitespace {
@@ -1492,6 +1673,11 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		}
 
 	case *js_ast.EDot:
+		if value, ok := p.tryToGetImportedEnumValue(e.Target, e.Name); ok && !wasAssignTarget {
+			p.printExpr(value, level, flags)
+			return
+		}
+
 		wrap := false
 		if e.OptionalChain == js_ast.OptionalChainNone {
 			flags |= hasNonOptionalChainParent
@@ -1529,6 +1715,13 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		}
 
 	case *js_ast.EIndex:
+		if index, ok := e.Index.Data.(*js_ast.EString); ok && !wasAssignTarget {
+			if value, ok := p.tryToGetImportedEnumValue(e.Target, js_lexer.UTF16ToString(index.Value)); ok {
+				p.printExpr(value, level, flags)
+				return
+			}
+		}
+
 		wrap := false
 		if e.OptionalChain == js_ast.OptionalChainNone {
 			flags |= hasNonOptionalChainParent
@@ -1558,6 +1751,24 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		}
 
 	case *js_ast.EIf:
+		if e.IsTSMetadataTypeCheck {
+			if id, ok := e.No.Data.(*js_ast.EImportIdentifier); ok {
+				switch p.options.TSEnumMetadataKinds[js_ast.FollowSymbols(p.symbols, id.Ref)] {
+				case js_ast.TSMetadataNumber:
+					p.printSpaceBeforeIdentifier()
+					p.print("Number")
+					return
+				case js_ast.TSMetadataString:
+					p.printSpaceBeforeIdentifier()
+					p.print("String")
+					return
+				case js_ast.TSMetadataObject:
+					p.printExpr(e.Yes, level, flags)
+					return
+				}
+			}
+		}
+
 		wrap := level >= js_ast.LConditional
 		if wrap {
 			p.print("(")
@@ -1665,7 +1876,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 					p.printNewline()
 					p.printIndent()
 				}
-				p.printExpr(item, js_ast.LComma, 0)
+				p.printExpr(item, js_ast.LComma, assignTargetFlag(wasAssignTarget))
 
 				// Make sure there's a comma after trailing missing items
 				_, ok := item.Data.(*js_ast.EMissing)
@@ -1704,7 +1915,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 					p.printNewline()
 					p.printIndent()
 				}
-				p.printProperty(item)
+				p.printProperty(item, assignTargetFlag(wasAssignTarget))
 			}
 
 			if !e.IsSingleLine {
@@ -1769,6 +1980,8 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 				p.printExpr(e.TagOrNil, js_ast.LLowest, 0)
 				p.print(")")
 			} else {
+				// Template tags are called with "this" just like call targets
+				p.callTarget = e.TagOrNil.Data
 				p.printExpr(e.TagOrNil, js_ast.LPostfix, 0)
 			}
 		}
@@ -1874,7 +2087,11 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		}
 
 		p.printSpaceBeforeIdentifier()
-		p.printIdentifier(name)
+		if p.callTarget == e && p.isPropertyAccessSymbol(e.Ref) {
+			p.printWithoutThis(name)
+		} else {
+			p.printIdentifier(name)
+		}
 
 		if wrap {
 			p.print(")")
@@ -1909,6 +2126,9 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 			if wrap {
 				p.print(")")
 			}
+		} else if p.callTarget == e && p.isPropertyAccessSymbol(e.Ref) {
+			p.printSpaceBeforeIdentifier()
+			p.printWithoutThis(p.renamer.NameForSymbol(e.Ref))
 		} else {
 			p.printSymbol(e.Ref)
 		}
@@ -1959,8 +2179,9 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 			p.print("(")
 		}
 
+		valueFlags := assignTargetFlag(e.Op.UnaryAssignTarget() != js_ast.AssignTargetNone || e.Op == js_ast.UnOpDelete)
 		if !e.Op.IsPrefix() {
-			p.printExpr(e.Value, js_ast.LPostfix-1, 0)
+			p.printExpr(e.Value, js_ast.LPostfix-1, valueFlags)
 		}
 
 		if entry.IsKeyword {
@@ -1975,7 +2196,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		}
 
 		if e.Op.IsPrefix() {
-			p.printExpr(e.Value, js_ast.LPrefix-1, 0)
+			p.printExpr(e.Value, js_ast.LPrefix-1, valueFlags)
 		}
 
 		if wrap {
@@ -2042,7 +2263,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		if private, ok := e.Left.Data.(*js_ast.EPrivateIdentifier); ok && e.Op == js_ast.BinOpIn {
 			p.printSymbol(private.Ref)
 		} else {
-			p.printExpr(e.Left, leftLevel, flags&forbidIn)
+			p.printExpr(e.Left, leftLevel, (flags&forbidIn)|assignTargetFlag(e.Op.BinaryAssignTarget() != js_ast.AssignTargetNone))
 		}
 
 		if e.Op != js_ast.BinOpComma {
@@ -2261,6 +2482,7 @@ func (p *printer) printForLoopInit(init js_ast.Stmt, flags printExprFlags) {
 	case *js_ast.SExpr:
 		p.printExpr(s.Value, js_ast.LLowest, flags|exprResultIsUnused)
 	case *js_ast.SLocal:
+		flags &= ^isAssignTarget
 		switch s.Kind {
 		case js_ast.LocalVar:
 			p.printDecls("var", s.Decls, flags)
@@ -2789,7 +3011,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt) {
 		p.print("for")
 		p.printSpace()
 		p.print("(")
-		p.printForLoopInit(s.Init, forbidIn)
+		p.printForLoopInit(s.Init, forbidIn|isAssignTarget)
 		p.printSpace()
 		p.printSpaceBeforeIdentifier()
 		p.print("in")
@@ -2812,7 +3034,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt) {
 		if s.IsAwait {
 			flags |= isInsideForAwait
 		}
-		p.printForLoopInit(s.Init, flags)
+		p.printForLoopInit(s.Init, flags|isAssignTarget)
 		p.printSpace()
 		p.printSpaceBeforeIdentifier()
 		p.print("of")
@@ -3108,6 +3330,42 @@ type Options struct {
 	UnsupportedFeatures          compat.JSFeature
 	RequireOrImportMetaForSource func(uint32) RequireOrImportMeta
 
+	// For "import()" of split chunks in the "iife" format. This maps the key of
+	// each loaded chunk to the keys of all chunks to load in order, and the
+	// public path is prepended to the file name of each chunk when loading it.
+	LoadChunkRef    js_ast.Ref
+	ChunkLoadOrder  map[string][]string
+	ChunkPublicPath string
+
+	// For "import()" of split chunks with stylesheets. This maps the key of each
+	// loaded chunk to the keys of the stylesheets to load first in order.
+	LoadCSSRef   js_ast.Ref
+	CSSLoadOrder map[string][]string
+
+	// External packages that are read from a global variable instead of being
+	// loaded with "require()", split into parts: "window.React" for "react"
+	ExternalGlobals map[string][]string
+
+	// Symbols imported from other chunks are printed as property accesses off
+	// of the namespace objects for those chunks (e.g. "chunk.foo") in output
+	// formats without "import" statements. Calls to them are printed like
+	// "(0, chunk.foo)()" so that the namespace isn't passed as "this".
+	IsPropertyAccessSymbol func(js_ast.Ref) bool
+
+	// Property accesses on imported TypeScript enums are replaced with the
+	// value of the enum member if it's known at compile time
+	TSEnums map[js_ast.Ref]map[string]js_ast.TSEnumValue
+
+	// Decorator metadata for types that are enums imported from other files is
+	// "Number" or "String" depending on the kind of the enum's values
+	TSEnumMetadataKinds map[js_ast.Ref]js_ast.TSMetadataKind
+
+	// Imports of modules exposed by other builds through module federation are
+	// read from their containers with these runtime functions instead
+	Federation           *config.Federation
+	FederationRequireRef js_ast.Ref
+	FederationImportRef  js_ast.Ref
+
 	// If we're writing out a source map, this table of line start indices lets
 	// us do binary search on to figure out what line a given AST node came from
 	LineOffsetTables []sourcemap.LineOffsetTable
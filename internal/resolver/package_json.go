@@ -20,6 +20,11 @@ type packageJSON struct {
 	mainFields map[string]string
 	moduleType config.ModuleType
 
+	// These are the "name" and "version" fields. They are only used to detect
+	// multiple copies of the same package in different "node_modules" folders.
+	name    string
+	version string
+
 	// Present if the "browser" field is present. This field is intended to be
 	// used by bundlers and lets you redirect the paths of certain 3rd-party
 	// modules that don't work in the browser to other modules that shim that
@@ -73,8 +78,9 @@ const (
 )
 
 func (r resolverQuery) checkBrowserMap(resolveDirInfo *dirInfo, inputPath string, kind browserPathKind) (remapped *string, ok bool) {
-	// This only applies if the current platform is "browser"
-	if r.options.Platform != config.PlatformBrowser {
+	// This only applies if the current platform is "browser" (or a custom
+	// platform that uses the "browser" field)
+	if !r.usesBrowserField() {
 		return nil, false
 	}
 
@@ -203,6 +209,18 @@ func (r resolverQuery) checkBrowserMap(resolveDirInfo *dirInfo, inputPath string
 			r.debugLogs.addNote(fmt.Sprintf("Failed to find %q", inputPath))
 		}
 	}
+	if ok {
+		value := "false"
+		if remapped != nil {
+			value = *remapped
+		}
+		r.traceEnter(TraceRule{
+			Kind:   TraceRuleBrowserMap,
+			Source: packageJSON.source.KeyPath.Text,
+			Key:    inputPath,
+			Value:  value,
+		})
+	}
 	return
 }
 
@@ -255,10 +273,22 @@ func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 		}
 	}
 
+	// Read the "name" and "version" fields
+	if nameJSON, _, ok := getProperty(json, "name"); ok {
+		if name, ok := getString(nameJSON); ok {
+			packageJSON.name = name
+		}
+	}
+	if versionJSON, _, ok := getProperty(json, "version"); ok {
+		if version, ok := getString(versionJSON); ok {
+			packageJSON.version = version
+		}
+	}
+
 	// Read the "main" fields
 	mainFields := r.options.MainFields
 	if mainFields == nil {
-		mainFields = defaultMainFields[r.options.Platform]
+		mainFields = r.defaultMainFields()
 	}
 	for _, field := range mainFields {
 		if mainJSON, _, ok := getProperty(json, field); ok {
@@ -272,7 +302,7 @@ func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 	}
 
 	// Read the "browser" property, but only when targeting the browser
-	if browserJSON, _, ok := getProperty(json, "browser"); ok && r.options.Platform == config.PlatformBrowser {
+	if browserJSON, _, ok := getProperty(json, "browser"); ok && r.usesBrowserField() {
 		// We both want the ability to have the option of CJS vs. ESM and the
 		// option of having node vs. browser. The way to do this is to use the
 		// object literal form of the "browser" field like this:
@@ -97,8 +97,10 @@ type ResolveResult struct {
 	PluginData interface{}
 
 	// If not empty, these should override the default values
-	JSXFactory  []string // Default if empty: "React.createElement"
-	JSXFragment []string // Default if empty: "React.Fragment"
+	JSX             config.TSJSX
+	JSXFactory      []string // Default if empty: "React.createElement"
+	JSXFragment     []string // Default if empty: "React.Fragment"
+	JSXImportSource string   // Default if empty: "react"
 
 	DifferentCase *fs.DifferentCase
 
@@ -110,9 +112,23 @@ type ResolveResult struct {
 
 	IsExternal bool
 
+	// If true, this is a builtin node module that has been configured to be an
+	// error. The path is external so that the bundler can report the error once
+	// all files have been scanned, since only then is the chain of imports that
+	// led here known.
+	IsDisallowedBuiltin bool
+
 	// If true, the class field transform should use Object.defineProperty().
 	UseDefineForClassFieldsTS config.MaybeBool
 
+	// If false, TypeScript decorators follow the JavaScript decorators proposal
+	// instead of TypeScript's "experimentalDecorators" semantics.
+	ExperimentalDecoratorsTS config.MaybeBool
+
+	// If true, TypeScript decorators are passed type information from the type
+	// annotations using "Reflect.metadata" like "emitDecoratorMetadata" does.
+	EmitDecoratorMetadataTS bool
+
 	// If true, unused imports are retained in TypeScript code. This matches the
 	// behavior of the "importsNotUsedAsValues" field in "tsconfig.json" when the
 	// value is not "remove".
@@ -120,6 +136,21 @@ type ResolveResult struct {
 
 	// This is the "type" field from "package.json"
 	ModuleType config.ModuleType
+
+	// This is present if the primary path is inside a "node_modules" directory
+	// and the enclosing "package.json" file has both a "name" and a "version".
+	PackageIdentity *PackageIdentity
+}
+
+// Two files with the same package identity are assumed to have the same
+// contents, even if they come from different "node_modules" directories.
+type PackageIdentity struct {
+	Name    string
+	Version string
+
+	// This is relative to the directory containing "package.json" and always
+	// uses forward slashes
+	RelPath string
 }
 
 type DebugMeta struct {
@@ -148,6 +179,7 @@ func (dm DebugMeta) LogErrorMsg(log logger.Log, source *logger.Source, r logger.
 
 type Resolver interface {
 	Resolve(sourceDir string, importPath string, kind ast.ImportKind) (result *ResolveResult, debug DebugMeta)
+	ResolveWithTrace(sourceDir string, importPath string, kind ast.ImportKind) (result *ResolveResult, debug DebugMeta, trace *Trace)
 	ResolveAbs(absPath string) *ResolveResult
 	PrettyPath(path logger.Path) string
 
@@ -205,25 +237,47 @@ type resolver struct {
 	// This cache maps a directory path to information about that directory and
 	// all parent directories
 	dirCache map[string]*dirInfo
+
+	// If present, directory information is also looked up in and saved to this
+	// cache so that it can be reused by other resolvers
+	sharedCache    *SharedCache
+	sharedCacheKey sharedCacheKey
 }
 
 type resolverQuery struct {
 	*resolver
 	debugLogs *debugLogs
+	trace     *Trace
 	kind      ast.ImportKind
 }
 
-func NewResolver(fs fs.FS, log logger.Log, caches *cache.CacheSet, options config.Options) Resolver {
-	// Bundling for node implies allowing node's builtin modules
-	if options.Platform == config.PlatformNode {
+// The shared cache is optional and may be nil
+func NewResolver(fs fs.FS, log logger.Log, caches *cache.CacheSet, options config.Options, sharedCache *SharedCache) Resolver {
+	// Bundling for node implies allowing node's builtin modules. Custom platforms
+	// have their own list of builtin modules instead.
+	var builtinModules map[string]bool
+	if options.CustomPlatform != nil {
+		builtinModules = options.CustomPlatform.BuiltinModules
+	} else if options.Platform == config.PlatformNode {
+		builtinModules = BuiltInNodeModules
+	}
+	if len(builtinModules) > 0 || len(options.NodeBuiltins) > 0 {
 		externalNodeModules := make(map[string]bool)
 		if options.ExternalModules.NodeModules != nil {
 			for name := range options.ExternalModules.NodeModules {
 				externalNodeModules[name] = true
 			}
 		}
-		for name := range BuiltInNodeModules {
-			externalNodeModules[name] = true
+		for name := range builtinModules {
+			// Builtin modules with an explicit policy are handled during resolution
+			if policy, ok := options.NodeBuiltins[name]; !ok || policy.Mode == config.NodeBuiltinDefault {
+				externalNodeModules[name] = true
+			}
+		}
+		for name, policy := range options.NodeBuiltins {
+			if policy.Mode == config.NodeBuiltinExternal {
+				externalNodeModules[name] = true
+			}
 		}
 		options.ExternalModules.NodeModules = externalNodeModules
 	}
@@ -244,18 +298,24 @@ func NewResolver(fs fs.FS, log logger.Log, caches *cache.CacheSet, options confi
 	for _, condition := range options.Conditions {
 		esmConditionsDefault[condition] = true
 	}
-	switch options.Platform {
-	case config.PlatformBrowser:
-		esmConditionsDefault["browser"] = true
-	case config.PlatformNode:
-		esmConditionsDefault["node"] = true
+	if options.CustomPlatform != nil {
+		for _, condition := range options.CustomPlatform.Conditions {
+			esmConditionsDefault[condition] = true
+		}
+	} else {
+		switch options.Platform {
+		case config.PlatformBrowser:
+			esmConditionsDefault["browser"] = true
+		case config.PlatformNode:
+			esmConditionsDefault["node"] = true
+		}
 	}
 	for key := range esmConditionsDefault {
 		esmConditionsImport[key] = true
 		esmConditionsRequire[key] = true
 	}
 
-	return &resolver{
+	r := &resolver{
 		fs:                     fs,
 		log:                    log,
 		options:                options,
@@ -266,13 +326,72 @@ func NewResolver(fs fs.FS, log logger.Log, caches *cache.CacheSet, options confi
 		esmConditionsImport:    esmConditionsImport,
 		esmConditionsRequire:   esmConditionsRequire,
 	}
+
+	// Sharing directory information with other builds would bypass the file
+	// system reads that watch mode relies on to detect changes
+	if sharedCache != nil && !options.WatchMode {
+		r.sharedCache = sharedCache
+		r.sharedCacheKey = makeSharedCacheKey(r)
+	}
+
+	return r
+}
+
+// Custom platforms replace the default main fields of the built-in platform
+func (r *resolver) defaultMainFields() []string {
+	if r.options.CustomPlatform != nil {
+		return r.options.CustomPlatform.MainFields
+	}
+	return defaultMainFields[r.options.Platform]
+}
+
+// Custom platforms decide for themselves whether to use the "browser" field
+func (r *resolver) usesBrowserField() bool {
+	if r.options.CustomPlatform != nil {
+		return r.options.CustomPlatform.BrowserField
+	}
+	return r.options.Platform == config.PlatformBrowser
+}
+
+// Imports such as "node:fs" are external when the module without the prefix
+// is a builtin module of the current platform
+func (r *resolver) allowsNodePrefix(importPath string) bool {
+	name := strings.TrimPrefix(importPath, "node:")
+	if policy, ok := r.options.NodeBuiltins[name]; ok && policy.Mode != config.NodeBuiltinDefault {
+		return policy.Mode == config.NodeBuiltinExternal
+	}
+	if r.options.CustomPlatform != nil {
+		return r.options.CustomPlatform.BuiltinModules[name]
+	}
+	return r.options.Platform == config.PlatformNode
 }
 
 func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.ImportKind) (*ResolveResult, DebugMeta) {
-	r := resolverQuery{
+	return resolverQuery{
+		resolver: rr,
+		kind:     kind,
+	}.resolve(sourceDir, importPath)
+}
+
+// This is the same as "Resolve" except that it also returns a structured
+// record of every step that was taken. This is meant for tools that want to
+// explain why an import path resolved where it did.
+func (rr *resolver) ResolveWithTrace(sourceDir string, importPath string, kind ast.ImportKind) (*ResolveResult, DebugMeta, *Trace) {
+	trace := &Trace{}
+	result, debug := resolverQuery{
 		resolver: rr,
 		kind:     kind,
+		trace:    trace,
+	}.resolve(sourceDir, importPath)
+	if result == nil {
+		// The rules in a failed resolution didn't produce anything
+		trace.Rules = nil
 	}
+	return result, debug, trace
+}
+
+func (r resolverQuery) resolve(sourceDir string, importPath string) (*ResolveResult, DebugMeta) {
+	kind := r.kind
 	if r.log.Level <= logger.LevelDebug {
 		r.debugLogs = &debugLogs{what: fmt.Sprintf(
 			"Resolving import %q in directory %q of type %q",
@@ -295,11 +414,12 @@ func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.Import
 		strings.HasPrefix(importPath, "//") ||
 
 		// "import fs from 'node:fs'"
-		(r.options.Platform == config.PlatformNode && strings.HasPrefix(importPath, "node:")) {
+		(strings.HasPrefix(importPath, "node:") && r.allowsNodePrefix(importPath)) {
 
 		if r.debugLogs != nil {
 			r.debugLogs.addNote("Marking this path as implicitly external")
 		}
+		r.traceEnter(TraceRule{Kind: TraceRuleExternal, Key: importPath})
 
 		r.flushDebugLogs(flushDueToSuccess)
 		return &ResolveResult{
@@ -315,6 +435,7 @@ func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.Import
 			if r.debugLogs != nil {
 				r.debugLogs.addNote("Putting this path in the \"dataurl\" namespace")
 			}
+			r.traceEnter(TraceRule{Kind: TraceRuleDataURL, Key: importPath})
 			r.flushDebugLogs(flushDueToSuccess)
 			return &ResolveResult{
 				PathPair: PathPair{Primary: logger.Path{Text: importPath, Namespace: "dataurl"}},
@@ -325,6 +446,7 @@ func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.Import
 		if r.debugLogs != nil {
 			r.debugLogs.addNote("Marking this data URL as external")
 		}
+		r.traceEnter(TraceRule{Kind: TraceRuleExternal, Key: importPath})
 		r.flushDebugLogs(flushDueToSuccess)
 		return &ResolveResult{
 			PathPair:   PathPair{Primary: logger.Path{Text: importPath}},
@@ -443,6 +565,135 @@ func (d *debugLogs) decreaseIndent() {
 	d.indent = d.indent[2:]
 }
 
+// This is a structured version of the debug log above. Unlike the debug log,
+// it's collected regardless of the log level but only when explicitly asked
+// for, since recording it is wasted work for normal builds.
+type Trace struct {
+	// Every file and directory that was checked, in the order it was checked
+	Candidates []TraceCandidate
+
+	// The chain of rules that produced the final path, outermost rule first.
+	// For example, a "paths" rule in "tsconfig.json" may have redirected the
+	// import to a package directory where the "module" field then applied.
+	Rules []TraceRule
+
+	// Rules that matched the import path but that didn't lead anywhere
+	Rejected []TraceRejection
+}
+
+type TraceCandidate struct {
+	Path        string
+	IsDirectory bool
+	Exists      bool
+}
+
+type TraceRuleKind uint8
+
+const (
+	// The path matched an "external" setting or is implicitly external (e.g. a URL)
+	TraceRuleExternal TraceRuleKind = iota
+
+	// The path is a data URL with a supported MIME type
+	TraceRuleDataURL
+
+	// An entry in the "paths" map in "tsconfig.json"
+	TraceRuleTSConfigPaths
+
+	// The "baseUrl" setting in "tsconfig.json"
+	TraceRuleTSConfigBaseURL
+
+	// An entry in the "browser" map in "package.json"
+	TraceRuleBrowserMap
+
+	// An entry in the "exports" map in "package.json"
+	TraceRuleExportsMap
+
+	// One of the configured main fields in "package.json" (e.g. "module")
+	TraceRuleMainField
+
+	// A package directory inside a "node_modules" directory
+	TraceRuleNodeModules
+
+	// A directory from the "NODE_PATH" setting
+	TraceRuleNodePath
+
+	// A configured policy for a builtin node module
+	TraceRuleNodeBuiltin
+)
+
+type TraceRule struct {
+	Kind TraceRuleKind
+
+	// The file or directory that contains this rule, if any. This is the
+	// absolute path of the "package.json" or "tsconfig.json" file for rules
+	// that come from those files.
+	Source string
+
+	// What was matched and what it was mapped to. For example, the "paths" key
+	// "@lib/*" and the value "./src/lib/*".
+	Key   string
+	Value string
+}
+
+type TraceRejection struct {
+	Rule   TraceRule
+	Reason string
+}
+
+func (r resolverQuery) traceCandidate(path string, isDirectory bool, exists bool) {
+	if r.trace != nil {
+		r.trace.Candidates = append(r.trace.Candidates, TraceCandidate{
+			Path:        path,
+			IsDirectory: isDirectory,
+			Exists:      exists,
+		})
+	}
+}
+
+// Call this when a rule has matched and resolution is about to continue with
+// the path that the rule maps to. The returned value must be passed to
+// "traceReject" if that path turns out to be a dead end.
+func (r resolverQuery) traceEnter(rule TraceRule) int {
+	if r.trace == nil {
+		return 0
+	}
+	r.trace.Rules = append(r.trace.Rules, rule)
+	return len(r.trace.Rules) - 1
+}
+
+// This returns the index that the next call to "traceEnter" will return. It's
+// for rules that are entered in a helper function (e.g. "checkBrowserMap").
+func (r resolverQuery) traceMark() int {
+	if r.trace == nil {
+		return 0
+	}
+	return len(r.trace.Rules)
+}
+
+// This undoes a call to "traceEnter" along with any rules that were entered
+// since then, and records the reason why the rule wasn't used
+func (r resolverQuery) traceReject(index int, reason string) {
+	if r.trace != nil {
+		r.traceRejectRange(index, len(r.trace.Rules), reason)
+	}
+}
+
+// This is like "traceReject" except that rules entered at or after "end" are
+// kept. It's for when a later sibling rule wins over an earlier one.
+func (r resolverQuery) traceRejectRange(start int, end int, reason string) {
+	if r.trace != nil && start < end && end <= len(r.trace.Rules) {
+		r.trace.Rejected = append(r.trace.Rejected, TraceRejection{Rule: r.trace.Rules[start], Reason: reason})
+		r.trace.Rules = append(r.trace.Rules[:start], r.trace.Rules[end:]...)
+	}
+}
+
+// This records a rule that was considered but that didn't match at all
+func (r resolverQuery) traceRejectWithoutEntering(rule TraceRule, reason string) {
+	if r.trace != nil {
+		r.trace.Rejected = append(r.trace.Rejected, TraceRejection{Rule: rule, Reason: reason})
+	}
+}
+
 type flushMode uint8
 
 const (
@@ -501,6 +752,18 @@ func (r resolverQuery) finalizeResolve(result *ResolveResult) {
 
 					// Also copy over the "type" field
 					result.ModuleType = pkgJSON.moduleType
+
+					// Remember which package this file belongs to so that identical copies
+					// of the same package can be deduplicated by the bundler
+					if pkgJSON.name != "" && pkgJSON.version != "" && helpers.IsInsideNodeModules(path.Text) {
+						if relPath, ok := r.fs.Rel(r.fs.Dir(pkgJSON.source.KeyPath.Text), path.Text); ok {
+							result.PackageIdentity = &PackageIdentity{
+								Name:    pkgJSON.name,
+								Version: pkgJSON.version,
+								RelPath: strings.ReplaceAll(relPath, "\\", "/"),
+							}
+						}
+					}
 				}
 
 				// Copy various fields from the nearest enclosing "tsconfig.json" file if present
@@ -526,9 +789,13 @@ func (r resolverQuery) finalizeResolve(result *ResolveResult) {
 								result.PathPair.Primary.Text))
 						}
 					} else {
+						result.JSX = dirInfo.enclosingTSConfigJSON.JSX
 						result.JSXFactory = dirInfo.enclosingTSConfigJSON.JSXFactory
 						result.JSXFragment = dirInfo.enclosingTSConfigJSON.JSXFragmentFactory
+						result.JSXImportSource = dirInfo.enclosingTSConfigJSON.JSXImportSource
 						result.UseDefineForClassFieldsTS = dirInfo.enclosingTSConfigJSON.UseDefineForClassFields
+						result.ExperimentalDecoratorsTS = dirInfo.enclosingTSConfigJSON.ExperimentalDecorators
+						result.EmitDecoratorMetadataTS = dirInfo.enclosingTSConfigJSON.EmitDecoratorMetadata
 						result.PreserveUnusedImportsTS = dirInfo.enclosingTSConfigJSON.PreserveImportsNotUsedAsValues
 						result.TSTarget = dirInfo.enclosingTSConfigJSON.TSTarget
 
@@ -545,6 +812,11 @@ func (r resolverQuery) finalizeResolve(result *ResolveResult) {
 									strings.Join(result.JSXFragment, "."),
 									dirInfo.enclosingTSConfigJSON.AbsPath))
 							}
+							if result.JSXImportSource != "" {
+								r.debugLogs.addNote(fmt.Sprintf("\"jsxImportSource\" is %q due to %q",
+									result.JSXImportSource,
+									dirInfo.enclosingTSConfigJSON.AbsPath))
+							}
 						}
 					}
 				}
@@ -613,6 +885,7 @@ func (r resolverQuery) resolveWithoutSymlinks(sourceDir string, importPath strin
 			if r.debugLogs != nil {
 				r.debugLogs.addNote(fmt.Sprintf("The path %q was marked as external by the user", importPath))
 			}
+			r.traceEnter(TraceRule{Kind: TraceRuleExternal, Key: importPath})
 			return &ResolveResult{PathPair: PathPair{Primary: logger.Path{Text: importPath}}, IsExternal: true}, DebugMeta{}
 		}
 
@@ -637,11 +910,13 @@ func (r resolverQuery) resolveWithoutSymlinks(sourceDir string, importPath strin
 			if r.debugLogs != nil {
 				r.debugLogs.addNote(fmt.Sprintf("The path %q was marked as external by the user", absPath))
 			}
+			r.traceEnter(TraceRule{Kind: TraceRuleExternal, Key: absPath})
 			return &ResolveResult{PathPair: PathPair{Primary: logger.Path{Text: absPath, Namespace: "file"}}, IsExternal: true}, DebugMeta{}
 		}
 
 		// Check the "browser" map
 		if importDirInfo := r.dirInfoCached(r.fs.Dir(absPath)); importDirInfo != nil {
+			traceIndex := r.traceMark()
 			if remapped, ok := r.checkBrowserMap(importDirInfo, absPath, absolutePathKind); ok {
 				if remapped == nil {
 					return &ResolveResult{PathPair: PathPair{Primary: logger.Path{Text: absPath, Namespace: "file", Flags: logger.PathDisabled}}}, DebugMeta{}
@@ -650,6 +925,8 @@ func (r resolverQuery) resolveWithoutSymlinks(sourceDir string, importPath strin
 					result = ResolveResult{PathPair: remappedResult, DifferentCase: diffCase}
 					checkRelative = false
 					checkPackage = false
+				} else {
+					r.traceReject(traceIndex, fmt.Sprintf("Could not resolve the mapped path %q", *remapped))
 				}
 			}
 		}
@@ -673,6 +950,7 @@ func (r resolverQuery) resolveWithoutSymlinks(sourceDir string, importPath strin
 					if r.debugLogs != nil {
 						r.debugLogs.addNote(fmt.Sprintf("The path %q was marked as external by the user", query))
 					}
+					r.traceEnter(TraceRule{Kind: TraceRuleExternal, Key: query})
 					return &ResolveResult{PathPair: PathPair{Primary: logger.Path{Text: importPath}}, IsExternal: true}, DebugMeta{}
 				}
 
@@ -713,6 +991,11 @@ func (r resolverQuery) resolveWithoutSymlinks(sourceDir string, importPath strin
 			sourceDirInfo = sourceDirInfo.enclosingBrowserScope
 		}
 
+		// Apply the configured policy for builtin node modules
+		if result, ok, debug := r.resolveNodeBuiltin(sourceDirInfo, importPath); ok {
+			return result, debug
+		}
+
 		if absolute, ok, diffCase, debug := r.resolveWithoutRemapping(sourceDirInfo, importPath); ok {
 			result = ResolveResult{PathPair: absolute, DifferentCase: diffCase}
 		} else {
@@ -724,6 +1007,45 @@ func (r resolverQuery) resolveWithoutSymlinks(sourceDir string, importPath strin
 	return &result, DebugMeta{}
 }
 
+func (r resolverQuery) resolveNodeBuiltin(sourceDirInfo *dirInfo, importPath string) (*ResolveResult, bool, DebugMeta) {
+	name := strings.TrimPrefix(importPath, "node:")
+	policy, ok := r.options.NodeBuiltins[name]
+	if !ok || policy.Mode == config.NodeBuiltinDefault {
+		return nil, false, DebugMeta{}
+	}
+	r.traceEnter(TraceRule{Kind: TraceRuleNodeBuiltin, Key: name})
+
+	switch policy.Mode {
+	case config.NodeBuiltinError:
+		if r.debugLogs != nil {
+			r.debugLogs.addNote(fmt.Sprintf("The builtin node module %q is configured to be an error", name))
+		}
+		return &ResolveResult{PathPair: PathPair{Primary: logger.Path{Text: importPath}}, IsExternal: true, IsDisallowedBuiltin: true}, true, DebugMeta{}
+
+	case config.NodeBuiltinEmpty:
+		if r.debugLogs != nil {
+			r.debugLogs.addNote(fmt.Sprintf("The builtin node module %q is configured to be an empty module", name))
+		}
+		return &ResolveResult{PathPair: PathPair{Primary: logger.Path{Text: importPath, Flags: logger.PathDisabled}}}, true, DebugMeta{}
+
+	case config.NodeBuiltinPolyfill:
+		if r.debugLogs != nil {
+			r.debugLogs.addNote(fmt.Sprintf("Substituting the package %q for the builtin node module %q", policy.Polyfill, name))
+		}
+
+		// Resolve the polyfill directly so that it's not subject to this policy
+		// again, which would otherwise loop forever for a package such as "events"
+		if absolute, ok, diffCase, _ := r.resolveWithoutRemapping(sourceDirInfo, policy.Polyfill); ok {
+			return &ResolveResult{PathPair: absolute, DifferentCase: diffCase}, true, DebugMeta{}
+		}
+		return nil, true, DebugMeta{notes: []logger.MsgData{{Text: fmt.Sprintf(
+			"The builtin node module %q is configured to be substituted with the package %q, which could not be found",
+			name, policy.Polyfill)}}}
+	}
+
+	return nil, false, DebugMeta{}
+}
+
 func (r resolverQuery) resolveWithoutRemapping(sourceDirInfo *dirInfo, importPath string) (PathPair, bool, *fs.DifferentCase, DebugMeta) {
 	if IsPackagePath(importPath) {
 		return r.loadNodeModules(importPath, sourceDirInfo)
@@ -776,6 +1098,11 @@ type dirInfo struct {
 	enclosingPackageJSON  *packageJSON  // Is there a "package.json" file in this directory or a parent directory?
 	enclosingTSConfigJSON *TSConfigJSON // Is there a "tsconfig.json" file in this directory or a parent directory?
 	absRealPath           string        // If non-empty, this is the real absolute path resolving any symlinks
+
+	// These are all files that were checked while parsing the "tsconfig.json"
+	// file for this directory, including base config files and missing files.
+	// This is only filled out when using a shared cache.
+	tsConfigFiles []string
 }
 
 func (r resolverQuery) dirInfoCached(path string) *dirInfo {
@@ -785,7 +1112,11 @@ func (r resolverQuery) dirInfoCached(path string) *dirInfo {
 	// Cache hit: stop now
 	if !ok {
 		// Cache miss: read the info
-		cached = r.dirInfoUncached(path)
+		if r.sharedCache != nil {
+			cached = r.dirInfoShared(path)
+		} else {
+			cached = r.dirInfoUncached(path)
+		}
 
 		// Update the cache unconditionally. Even if the read failed, we don't want to
 		// retry again later. The directory is inaccessible so trying again is wasted.
@@ -1011,7 +1342,11 @@ func (r resolverQuery) dirInfoUncached(path string) *dirInfo {
 
 	// Record if this directory has a package.json file
 	if entry, _ := entries.Get("package.json"); entry != nil && entry.Kind(r.fs) == fs.FileEntry {
-		info.packageJSON = r.parsePackageJSON(path)
+		if r.sharedCache != nil {
+			info.packageJSON = r.parsePackageJSONShared(path)
+		} else {
+			info.packageJSON = r.parsePackageJSON(path)
+		}
 
 		// Propagate this "package.json" file into child directories
 		if info.packageJSON != nil {
@@ -1037,7 +1372,11 @@ func (r resolverQuery) dirInfoUncached(path string) *dirInfo {
 		}
 		if tsConfigPath != "" {
 			var err error
-			info.enclosingTSConfigJSON, err = r.parseTSConfig(tsConfigPath, make(map[string]bool))
+			if r.sharedCache != nil {
+				info.enclosingTSConfigJSON, info.tsConfigFiles, err = r.parseTSConfigShared(tsConfigPath)
+			} else {
+				info.enclosingTSConfigJSON, err = r.parseTSConfig(tsConfigPath, make(map[string]bool))
+			}
 			if err != nil {
 				if err == syscall.ENOENT {
 					r.log.AddError(nil, logger.Loc{}, fmt.Sprintf("Cannot find tsconfig file %q",
@@ -1073,6 +1412,7 @@ func (r resolverQuery) loadAsFile(path string, extensionOrder []string) (string,
 				fmt.Sprintf("  Cannot read directory %q: %s",
 					r.PrettyPath(logger.Path{Text: dirPath, Namespace: "file"}), err.Error()))
 		}
+		r.traceCandidate(path, false, false)
 		return "", false, nil
 	}
 
@@ -1086,8 +1426,10 @@ func (r resolverQuery) loadAsFile(path string, extensionOrder []string) (string,
 		if r.debugLogs != nil {
 			r.debugLogs.addNote(fmt.Sprintf("Found file %q", base))
 		}
+		r.traceCandidate(path, false, true)
 		return path, true, diffCase
 	}
+	r.traceCandidate(path, false, false)
 
 	// Try the path with extensions
 	for _, ext := range extensionOrder {
@@ -1098,8 +1440,10 @@ func (r resolverQuery) loadAsFile(path string, extensionOrder []string) (string,
 			if r.debugLogs != nil {
 				r.debugLogs.addNote(fmt.Sprintf("Found file %q", base+ext))
 			}
+			r.traceCandidate(path+ext, false, true)
 			return path + ext, true, diffCase
 		}
+		r.traceCandidate(path+ext, false, false)
 	}
 
 	// TypeScript-specific behavior: if the extension is ".js" or ".jsx", try
@@ -1122,12 +1466,15 @@ func (r resolverQuery) loadAsFile(path string, extensionOrder []string) (string,
 		// Note that the official compiler code always tries ".ts" before
 		// ".tsx" even if the original extension was ".jsx".
 		for _, ext := range []string{".ts", ".tsx"} {
+			rewritten := path[:len(path)-(len(base)-lastDot)] + ext
 			if entry, diffCase := entries.Get(base[:lastDot] + ext); entry != nil && entry.Kind(r.fs) == fs.FileEntry {
 				if r.debugLogs != nil {
 					r.debugLogs.addNote(fmt.Sprintf("Rewrote to %q", base[:lastDot]+ext))
 				}
-				return path[:len(path)-(len(base)-lastDot)] + ext, true, diffCase
+				r.traceCandidate(rewritten, false, true)
+				return rewritten, true, diffCase
 			}
+			r.traceCandidate(rewritten, false, false)
 			if r.debugLogs != nil {
 				r.debugLogs.addNote(fmt.Sprintf("Failed to rewrite to %q", base[:lastDot]+ext))
 			}
@@ -1148,8 +1495,10 @@ func (r resolverQuery) loadAsIndex(dirInfo *dirInfo, path string, extensionOrder
 			if r.debugLogs != nil {
 				r.debugLogs.addNote(fmt.Sprintf("Found file %q", r.fs.Join(path, base)))
 			}
+			r.traceCandidate(r.fs.Join(path, base), false, true)
 			return PathPair{Primary: logger.Path{Text: r.fs.Join(path, base), Namespace: "file"}}, true, diffCase
 		}
+		r.traceCandidate(r.fs.Join(path, base), false, false)
 		if r.debugLogs != nil {
 			r.debugLogs.addNote(fmt.Sprintf("Failed to find file %q", r.fs.Join(path, base)))
 		}
@@ -1161,6 +1510,7 @@ func (r resolverQuery) loadAsIndex(dirInfo *dirInfo, path string, extensionOrder
 func (r resolverQuery) loadAsIndexWithBrowserRemapping(dirInfo *dirInfo, path string, extensionOrder []string) (PathPair, bool, *fs.DifferentCase) {
 	// Potentially remap using the "browser" field
 	absPath := r.fs.Join(path, "index")
+	traceIndex := r.traceMark()
 	if remapped, ok := r.checkBrowserMap(dirInfo, absPath, absolutePathKind); ok {
 		if remapped == nil {
 			return PathPair{Primary: logger.Path{Text: absPath, Namespace: "file", Flags: logger.PathDisabled}}, true, nil
@@ -1180,6 +1530,7 @@ func (r resolverQuery) loadAsIndexWithBrowserRemapping(dirInfo *dirInfo, path st
 			}
 		}
 
+		r.traceReject(traceIndex, fmt.Sprintf("Could not find a file or directory at the mapped path %q", remappedAbs))
 		return PathPair{}, false, nil
 	}
 
@@ -1232,6 +1583,7 @@ func (r resolverQuery) loadAsFileOrDirectory(path string) (PathPair, bool, *fs.D
 		defer r.debugLogs.decreaseIndent()
 	}
 	dirInfo := r.dirInfoCached(path)
+	r.traceCandidate(path, true, dirInfo != nil)
 	if dirInfo == nil {
 		return PathPair{}, false, nil
 	}
@@ -1245,7 +1597,7 @@ func (r resolverQuery) loadAsFileOrDirectory(path string) (PathPair, bool, *fs.D
 		// If the user has not explicitly specified a "main" field order,
 		// use a default one determined by the current platform target
 		if mainFieldKeys == nil {
-			mainFieldKeys = defaultMainFields[r.options.Platform]
+			mainFieldKeys = r.defaultMainFields()
 			autoMain = true
 		}
 
@@ -1285,17 +1637,24 @@ func (r resolverQuery) loadAsFileOrDirectory(path string) (PathPair, bool, *fs.D
 			r.debugLogs.addNote(fmt.Sprintf("Searching for main fields in %q", dirInfo.packageJSON.source.KeyPath.Text))
 		}
 
+		mainFieldRule := func(key string, value string) TraceRule {
+			return TraceRule{Kind: TraceRuleMainField, Source: dirInfo.packageJSON.source.KeyPath.Text, Key: key, Value: value}
+		}
+
 		for _, key := range mainFieldKeys {
 			fieldRelPath, ok := mainFieldValues[key]
 			if !ok {
 				if r.debugLogs != nil {
 					r.debugLogs.addNote(fmt.Sprintf("Did not find main field %q", key))
 				}
+				r.traceRejectWithoutEntering(mainFieldRule(key, ""), "The field is not present")
 				continue
 			}
 
+			traceIndex := r.traceEnter(mainFieldRule(key, fieldRelPath))
 			absolute, ok, diffCase := loadMainField(fieldRelPath, key)
 			if !ok {
+				r.traceReject(traceIndex, "Could not find a file or directory at this path")
 				continue
 			}
 
@@ -1308,7 +1667,9 @@ func (r resolverQuery) loadAsFileOrDirectory(path string) (PathPair, bool, *fs.D
 				var okMain bool
 				var diffCaseMain *fs.DifferentCase
 
+				var mainTraceIndex int
 				if mainRelPath, ok := mainFieldValues["main"]; ok {
+					mainTraceIndex = r.traceEnter(mainFieldRule("main", mainRelPath))
 					if absolute, ok, diffCase := loadMainField(mainRelPath, "main"); ok {
 						absoluteMain = absolute
 						okMain = true
@@ -1318,12 +1679,16 @@ func (r resolverQuery) loadAsFileOrDirectory(path string) (PathPair, bool, *fs.D
 					// Some packages have a "module" field without a "main" field but
 					// still have an implicit "index.js" file. In that case, treat that
 					// as the value for "main".
+					mainTraceIndex = r.traceEnter(mainFieldRule("main", "index"))
 					if absolute, ok, diffCase := r.loadAsIndexWithBrowserRemapping(dirInfo, path, extensionOrder); ok {
 						absoluteMain = absolute
 						okMain = true
 						diffCaseMain = diffCase
 					}
 				}
+				if !okMain {
+					r.traceReject(mainTraceIndex, "Could not find a file or directory at this path")
+				}
 
 				if okMain {
 					// If both the "main" and "module" fields exist, use "main" if the
@@ -1340,6 +1705,7 @@ func (r resolverQuery) loadAsFileOrDirectory(path string) (PathPair, bool, *fs.D
 							r.debugLogs.addNote(fmt.Sprintf("The fallback path in case of \"require\" is %q",
 								absoluteMain.Primary.Text))
 						}
+						r.traceReject(mainTraceIndex, "The \"module\" field is preferred for imports (this is only a fallback for \"require\")")
 						return PathPair{
 							// This is the whole point of the path pair
 							Primary:   absolute.Primary,
@@ -1349,6 +1715,7 @@ func (r resolverQuery) loadAsFileOrDirectory(path string) (PathPair, bool, *fs.D
 						if r.debugLogs != nil {
 							r.debugLogs.addNote(fmt.Sprintf("Resolved to %q because of \"require\"", absoluteMain.Primary.Text))
 						}
+						r.traceRejectRange(traceIndex, mainTraceIndex, "The \"main\" field is preferred for \"require\" calls")
 						return absoluteMain, true, diffCaseMain
 					}
 				}
@@ -1402,9 +1769,11 @@ func (r resolverQuery) matchTSConfigPaths(tsConfigJSON *TSConfigJSON, path strin
 				if !r.fs.IsAbs(originalPath) {
 					absoluteOriginalPath = r.fs.Join(absBaseURL, originalPath)
 				}
+				traceIndex := r.traceEnter(TraceRule{Kind: TraceRuleTSConfigPaths, Source: tsConfigJSON.AbsPath, Key: key, Value: originalPath})
 				if absolute, ok, diffCase := r.loadAsFileOrDirectory(absoluteOriginalPath); ok {
 					return absolute, true, diffCase
 				}
+				r.traceReject(traceIndex, fmt.Sprintf("Could not find a file or directory at %q", absoluteOriginalPath))
 			}
 			return PathPair{}, false, nil
 		}
@@ -1450,6 +1819,13 @@ func (r resolverQuery) matchTSConfigPaths(tsConfigJSON *TSConfigJSON, path strin
 		}
 
 		for _, originalPath := range longestMatch.originalPaths {
+			rule := TraceRule{
+				Kind:   TraceRuleTSConfigPaths,
+				Source: tsConfigJSON.AbsPath,
+				Key:    longestMatch.prefix + "*" + longestMatch.suffix,
+				Value:  originalPath,
+			}
+
 			// Swap out the "*" in the original path for whatever the "*" matched
 			matchedText := path[len(longestMatch.prefix) : len(path)-len(longestMatch.suffix)]
 			originalPath = strings.Replace(originalPath, "*", matchedText, 1)
@@ -1459,9 +1835,11 @@ func (r resolverQuery) matchTSConfigPaths(tsConfigJSON *TSConfigJSON, path strin
 			if !r.fs.IsAbs(originalPath) {
 				absoluteOriginalPath = r.fs.Join(absBaseURL, originalPath)
 			}
+			traceIndex := r.traceEnter(rule)
 			if absolute, ok, diffCase := r.loadAsFileOrDirectory(absoluteOriginalPath); ok {
 				return absolute, true, diffCase
 			}
+			r.traceReject(traceIndex, fmt.Sprintf("Could not find a file or directory at %q", absoluteOriginalPath))
 		}
 	}
 
@@ -1487,9 +1865,16 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 		// Try looking up the path relative to the base URL
 		if dirInfo.enclosingTSConfigJSON.BaseURL != nil {
 			basePath := r.fs.Join(*dirInfo.enclosingTSConfigJSON.BaseURL, importPath)
+			traceIndex := r.traceEnter(TraceRule{
+				Kind:   TraceRuleTSConfigBaseURL,
+				Source: dirInfo.enclosingTSConfigJSON.AbsPath,
+				Key:    importPath,
+				Value:  *dirInfo.enclosingTSConfigJSON.BaseURL,
+			})
 			if absolute, ok, diffCase := r.loadAsFileOrDirectory(basePath); ok {
 				return absolute, true, diffCase, DebugMeta{}
 			}
+			r.traceReject(traceIndex, fmt.Sprintf("Could not find a file or directory at %q", basePath))
 		}
 	}
 
@@ -1507,6 +1892,11 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 			if r.debugLogs != nil {
 				r.debugLogs.addNote(fmt.Sprintf("Checking for a package in the directory %q", absPath))
 			}
+			nodeModulesTraceIndex := r.traceEnter(TraceRule{
+				Kind:   TraceRuleNodeModules,
+				Source: r.fs.Join(dirInfo.absPath, "node_modules"),
+				Key:    importPath,
+			})
 
 			// Check for an "exports" map in the package's package.json folder
 			if esmOK {
@@ -1536,6 +1926,11 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 						// paths. We also want to avoid any "%" characters in the absolute
 						// directory path accidentally being interpreted as URL escapes.
 						resolvedPath, status, debug := r.esmPackageExportsResolveWithPostConditions("/", esmPackageSubpath, packageJSON.exportsMap.root, conditions)
+						exportsRule := TraceRule{Kind: TraceRuleExportsMap, Source: packageJSON.source.KeyPath.Text, Key: esmPackageSubpath}
+						if strings.HasPrefix(resolvedPath, "/") {
+							exportsRule.Value = "." + resolvedPath
+						}
+						exportsTraceIndex := r.traceEnter(exportsRule)
 						if (status == peStatusExact || status == peStatusInexact) && strings.HasPrefix(resolvedPath, "/") {
 							absResolvedPath := r.fs.Join(absPkgPath, resolvedPath[1:])
 
@@ -1553,6 +1948,7 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 									if r.debugLogs != nil {
 										r.debugLogs.addNote(fmt.Sprintf("The path %q is a directory, which is not allowed", absResolvedPath))
 									}
+									r.traceCandidate(absResolvedPath, true, true)
 									status = peStatusUnsupportedDirectoryImport
 								} else if kind != fs.FileEntry {
 									status = peStatusModuleNotFound
@@ -1560,8 +1956,12 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 									if r.debugLogs != nil {
 										r.debugLogs.addNote(fmt.Sprintf("Resolved to %q", absResolvedPath))
 									}
+									r.traceCandidate(absResolvedPath, false, true)
 									return PathPair{Primary: logger.Path{Text: absResolvedPath, Namespace: "file"}}, true, diffCase, DebugMeta{}
 								}
+								if status == peStatusModuleNotFound {
+									r.traceCandidate(absResolvedPath, false, false)
+								}
 
 							case peStatusInexact:
 								// If this was resolved against an expansion key ending in a "/"
@@ -1667,10 +2067,19 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 							}
 						}
 
+						if r.trace != nil {
+							reasons := make([]string, len(debugMeta.notes))
+							for i, note := range debugMeta.notes {
+								reasons[i] = note.Text
+							}
+							r.traceReject(exportsTraceIndex, strings.Join(reasons, "; "))
+							r.traceReject(nodeModulesTraceIndex, "The \"exports\" map in this package did not resolve the import path")
+						}
 						return PathPair{}, false, nil, debugMeta
 					}
 
 					// Check the "browser" map
+					traceIndex := r.traceMark()
 					if remapped, ok := r.checkBrowserMap(pkgDirInfo, absPath, absolutePathKind); ok {
 						if remapped == nil {
 							return PathPair{Primary: logger.Path{Text: absPath, Namespace: "file", Flags: logger.PathDisabled}}, true, nil, DebugMeta{}
@@ -1678,6 +2087,7 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 						if remappedResult, ok, diffCase, notes := r.resolveWithoutRemapping(pkgDirInfo.enclosingBrowserScope, *remapped); ok {
 							return remappedResult, true, diffCase, notes
 						}
+						r.traceReject(traceIndex, fmt.Sprintf("Could not resolve the mapped path %q", *remapped))
 					}
 				}
 			}
@@ -1685,6 +2095,7 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 			if absolute, ok, diffCase := r.loadAsFileOrDirectory(absPath); ok {
 				return absolute, true, diffCase, DebugMeta{}
 			}
+			r.traceReject(nodeModulesTraceIndex, fmt.Sprintf("Could not find a file or directory at %q", absPath))
 		}
 
 		// Go to the parent directory, stopping at the file system root
@@ -1703,9 +2114,11 @@ func (r resolverQuery) loadNodeModules(importPath string, dirInfo *dirInfo) (Pat
 	// published algorithm. See also: https://github.com/nodejs/node/issues/38128.
 	for _, absDir := range r.options.AbsNodePaths {
 		absPath := r.fs.Join(absDir, importPath)
+		traceIndex := r.traceEnter(TraceRule{Kind: TraceRuleNodePath, Source: absDir, Key: importPath})
 		if absolute, ok, diffCase := r.loadAsFileOrDirectory(absPath); ok {
 			return absolute, true, diffCase, DebugMeta{}
 		}
+		r.traceReject(traceIndex, fmt.Sprintf("Could not find a file or directory at %q", absPath))
 	}
 
 	return PathPair{}, false, nil, DebugMeta{}
@@ -1776,3 +2189,34 @@ var BuiltInNodeModules = map[string]bool{
 	"worker_threads":      true,
 	"zlib":                true,
 }
+
+// These are the packages that are substituted for builtin node modules that
+// have been configured to use a polyfill without naming a specific package.
+// They come from the list that Webpack 4 and Browserify used to include
+// automatically. Builtin modules that aren't listed here have no well-known
+// browser equivalent.
+var BuiltInNodeModulePolyfills = map[string]string{
+	"assert":         "assert",
+	"buffer":         "buffer",
+	"console":        "console-browserify",
+	"constants":      "constants-browserify",
+	"crypto":         "crypto-browserify",
+	"domain":         "domain-browser",
+	"events":         "events",
+	"http":           "stream-http",
+	"https":          "https-browserify",
+	"os":             "os-browserify/browser.js",
+	"path":           "path-browserify",
+	"process":        "process/browser.js",
+	"punycode":       "punycode",
+	"querystring":    "querystring-es3",
+	"stream":         "stream-browserify",
+	"string_decoder": "string_decoder",
+	"sys":            "util",
+	"timers":         "timers-browserify",
+	"tty":            "tty-browserify",
+	"url":            "url",
+	"util":           "util",
+	"vm":             "vm-browserify",
+	"zlib":           "browserify-zlib",
+}
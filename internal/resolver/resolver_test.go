@@ -0,0 +1,190 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/trustelem/esbuild/internal/ast"
+	"github.com/trustelem/esbuild/internal/cache"
+	"github.com/trustelem/esbuild/internal/config"
+	"github.com/trustelem/esbuild/internal/fs"
+	"github.com/trustelem/esbuild/internal/logger"
+	"github.com/trustelem/esbuild/internal/test"
+)
+
+var traceRuleKindNames = map[TraceRuleKind]string{
+	TraceRuleExternal:        "external",
+	TraceRuleDataURL:         "data-url",
+	TraceRuleTSConfigPaths:   "tsconfig-paths",
+	TraceRuleTSConfigBaseURL: "tsconfig-base-url",
+	TraceRuleBrowserMap:      "browser-map",
+	TraceRuleExportsMap:      "exports-map",
+	TraceRuleMainField:       "main-field",
+	TraceRuleNodeModules:     "node-modules",
+	TraceRuleNodePath:        "node-path",
+	TraceRuleNodeBuiltin:     "node-builtin",
+}
+
+func formatTraceRule(rule TraceRule) string {
+	return fmt.Sprintf("%s source=%q key=%q value=%q", traceRuleKindNames[rule.Kind], rule.Source, rule.Key, rule.Value)
+}
+
+// This formats the result of a traced resolve so that tests can compare it
+// against the expected text in one go
+func formatTrace(result *ResolveResult, trace *Trace) string {
+	sb := strings.Builder{}
+	if result != nil {
+		sb.WriteString(fmt.Sprintf("path: %s\n", result.PathPair.Primary.Text))
+	} else {
+		sb.WriteString("path: (unresolved)\n")
+	}
+	for _, candidate := range trace.Candidates {
+		kind := "file"
+		if candidate.IsDirectory {
+			kind = "dir"
+		}
+		sb.WriteString(fmt.Sprintf("candidate: %s %s exists=%v\n", kind, candidate.Path, candidate.Exists))
+	}
+	for _, rule := range trace.Rules {
+		sb.WriteString(fmt.Sprintf("rule: %s\n", formatTraceRule(rule)))
+	}
+	for _, rejection := range trace.Rejected {
+		sb.WriteString(fmt.Sprintf("rejected: %s\n  reason: %s\n", formatTraceRule(rejection.Rule), rejection.Reason))
+	}
+	return sb.String()
+}
+
+func expectTrace(t *testing.T, files map[string]string, options config.Options, sourceDir string, importPath string, kind ast.ImportKind, expected string) {
+	t.Helper()
+	t.Run(importPath, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug)
+		r := NewResolver(fs.MockFS(files), log, cache.MakeCacheSet(), options, nil)
+		result, _, trace := r.ResolveWithTrace(sourceDir, importPath, kind)
+		test.AssertEqualWithDiff(t, formatTrace(result, trace), expected)
+	})
+}
+
+func TestResolveTraceRelative(t *testing.T) {
+	files := map[string]string{
+		"/src/entry.js":      ``,
+		"/src/util/index.js": ``,
+	}
+	options := config.Options{ExtensionOrder: []string{".ts", ".js"}}
+
+	expectTrace(t, files, options, "/src", "./util", ast.ImportStmt, `path: /src/util/index.js
+candidate: file /src/util exists=false
+candidate: file /src/util.ts exists=false
+candidate: file /src/util.js exists=false
+candidate: dir /src/util exists=true
+candidate: file /src/util/index.ts exists=false
+candidate: file /src/util/index.js exists=true
+`)
+	expectTrace(t, files, options, "/src", "./missing", ast.ImportStmt, `path: (unresolved)
+candidate: file /src/missing exists=false
+candidate: file /src/missing.ts exists=false
+candidate: file /src/missing.js exists=false
+candidate: dir /src/missing exists=false
+`)
+}
+
+func TestResolveTraceMainFields(t *testing.T) {
+	files := map[string]string{
+		"/src/entry.js": ``,
+		"/node_modules/pkg/package.json": `{
+			"main": "./main.js",
+			"module": "./module.js",
+			"browser": "./missing.js"
+		}`,
+		"/node_modules/pkg/main.js":   ``,
+		"/node_modules/pkg/module.js": ``,
+	}
+	options := config.Options{
+		ExtensionOrder: []string{".js"},
+		MainFields:     []string{"browser", "module", "main"},
+	}
+
+	expectTrace(t, files, options, "/src", "pkg", ast.ImportStmt, `path: /node_modules/pkg/module.js
+candidate: file /node_modules/pkg exists=false
+candidate: file /node_modules/pkg.js exists=false
+candidate: dir /node_modules/pkg exists=true
+candidate: file /node_modules/pkg/missing.js exists=false
+candidate: file /node_modules/pkg/missing.js.js exists=false
+candidate: file /node_modules/pkg/missing.ts exists=false
+candidate: file /node_modules/pkg/missing.tsx exists=false
+candidate: file /node_modules/pkg/module.js exists=true
+rule: node-modules source="/node_modules" key="pkg" value=""
+rule: main-field source="/node_modules/pkg/package.json" key="module" value="./module.js"
+rejected: main-field source="/node_modules/pkg/package.json" key="browser" value="./missing.js"
+  reason: Could not find a file or directory at this path
+`)
+}
+
+func TestResolveTraceExportsMap(t *testing.T) {
+	files := map[string]string{
+		"/src/entry.js": ``,
+		"/node_modules/pkg/package.json": `{
+			"main": "./main.js",
+			"exports": {
+				".": { "import": "./esm.js", "require": "./cjs.js" },
+				"./feature": "./feature.js"
+			}
+		}`,
+		"/node_modules/pkg/main.js":    ``,
+		"/node_modules/pkg/esm.js":     ``,
+		"/node_modules/pkg/cjs.js":     ``,
+		"/node_modules/pkg/feature.js": ``,
+		"/node_modules/pkg/private.js": ``,
+	}
+	options := config.Options{ExtensionOrder: []string{".js"}}
+
+	expectTrace(t, files, options, "/src", "pkg", ast.ImportStmt, `path: /node_modules/pkg/esm.js
+candidate: file /node_modules/pkg/esm.js exists=true
+rule: node-modules source="/node_modules" key="pkg" value=""
+rule: exports-map source="/node_modules/pkg/package.json" key="." value="./esm.js"
+`)
+	expectTrace(t, files, options, "/src", "pkg", ast.ImportRequire, `path: /node_modules/pkg/cjs.js
+candidate: file /node_modules/pkg/cjs.js exists=true
+rule: node-modules source="/node_modules" key="pkg" value=""
+rule: exports-map source="/node_modules/pkg/package.json" key="." value="./cjs.js"
+`)
+	expectTrace(t, files, options, "/src", "pkg/feature", ast.ImportStmt, `path: /node_modules/pkg/feature.js
+candidate: file /node_modules/pkg/feature.js exists=true
+rule: node-modules source="/node_modules" key="pkg/feature" value=""
+rule: exports-map source="/node_modules/pkg/package.json" key="./feature" value="./feature.js"
+`)
+	expectTrace(t, files, options, "/src", "pkg/private.js", ast.ImportStmt, `path: (unresolved)
+candidate: file /node_modules/pkg/private.js exists=true
+rejected: exports-map source="/node_modules/pkg/package.json" key="./private.js" value=""
+  reason: The path "./private.js" is not exported by package "pkg"
+rejected: node-modules source="/node_modules" key="pkg/private.js" value=""
+  reason: The "exports" map in this package did not resolve the import path
+`)
+}
+
+func TestResolveTraceTSConfigPaths(t *testing.T) {
+	files := map[string]string{
+		"/src/entry.ts": ``,
+		"/tsconfig.json": `{
+			"compilerOptions": {
+				"baseUrl": ".",
+				"paths": {
+					"@lib/*": ["./missing/*", "./lib/*"]
+				}
+			}
+		}`,
+		"/lib/util.ts": ``,
+	}
+	options := config.Options{ExtensionOrder: []string{".ts", ".js"}}
+
+	expectTrace(t, files, options, "/src", "@lib/util", ast.ImportStmt, `path: /lib/util.ts
+candidate: file /missing/util exists=false
+candidate: dir /missing/util exists=false
+candidate: file /lib/util exists=false
+candidate: file /lib/util.ts exists=true
+rule: tsconfig-paths source="/tsconfig.json" key="@lib/*" value="./lib/*"
+rejected: tsconfig-paths source="/tsconfig.json" key="@lib/*" value="./missing/*"
+  reason: Could not find a file or directory at "/missing/util"
+`)
+}
@@ -0,0 +1,321 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/trustelem/esbuild/internal/fs"
+	"github.com/trustelem/esbuild/internal/logger"
+)
+
+// Normally every resolver builds its directory information from scratch. This
+// cache lets many resolvers (e.g. one per build in a long-running process)
+// reuse directory listings and parsed "package.json" and "tsconfig.json"
+// files from previous builds.
+//
+// Each cache entry remembers the modification keys of every file and
+// directory it was derived from and is only reused if none of them have
+// changed. File systems that can't provide modification keys never get any
+// cache hits. Directory entries also bake in information inherited from
+// their parent directory, so they are only reused if that information is
+// still the same. Parsed files are cached separately so that a change to a
+// parent directory's listing doesn't invalidate everything below it.
+//
+// Messages logged while computing an entry are stored with the entry and are
+// logged again each time the entry is reused so that every build reports the
+// same problems.
+type SharedCache struct {
+	mutex      sync.Mutex
+	partitions map[sharedCacheKey]*sharedCachePartition
+}
+
+func NewSharedCache() *SharedCache {
+	return &SharedCache{
+		partitions: make(map[sharedCacheKey]*sharedCachePartition),
+	}
+}
+
+// Information is only shared between resolvers that agree on all options that
+// affect how it's computed
+type sharedCacheKey struct {
+	cwd              string
+	tsConfigOverride string
+	mainFields       string
+	preserveSymlinks bool
+	usesBrowserField bool
+}
+
+func makeSharedCacheKey(r *resolver) sharedCacheKey {
+	mainFields := r.options.MainFields
+	if mainFields == nil {
+		mainFields = r.defaultMainFields()
+	}
+	return sharedCacheKey{
+		cwd:              r.fs.Cwd(),
+		tsConfigOverride: r.options.TsConfigOverride,
+		mainFields:       strings.Join(mainFields, "\x00"),
+		preserveSymlinks: r.options.PreserveSymlinks,
+		usesBrowserField: r.usesBrowserField(),
+	}
+}
+
+type sharedCachePartition struct {
+	dirs  map[string]*sharedDirEntry
+	files map[string]*sharedFileEntry
+}
+
+type sharedDirEntry struct {
+	info *dirInfo
+	deps []sharedCacheDep
+	msgs []logger.Msg
+}
+
+type sharedFileEntry struct {
+	packageJSON  *packageJSON
+	tsConfigJSON *TSConfigJSON
+	err          error
+	deps         []sharedCacheDep
+	msgs         []logger.Msg
+}
+
+type sharedCacheDep struct {
+	path    string
+	modKey  fs.ModKey
+	missing bool
+}
+
+func (c *SharedCache) partition(key sharedCacheKey) *sharedCachePartition {
+	partition := c.partitions[key]
+	if partition == nil {
+		partition = &sharedCachePartition{
+			dirs:  make(map[string]*sharedDirEntry),
+			files: make(map[string]*sharedFileEntry),
+		}
+		c.partitions[key] = partition
+	}
+	return partition
+}
+
+func (c *SharedCache) getDir(key sharedCacheKey, path string) *sharedDirEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.partition(key).dirs[path]
+}
+
+func (c *SharedCache) setDir(key sharedCacheKey, path string, entry *sharedDirEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.partition(key).dirs[path] = entry
+}
+
+func (c *SharedCache) getFile(key sharedCacheKey, path string) *sharedFileEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.partition(key).files[path]
+}
+
+func (c *SharedCache) setFile(key sharedCacheKey, path string, entry *sharedFileEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.partition(key).files[path] = entry
+}
+
+func (r resolverQuery) dirInfoShared(path string) *dirInfo {
+	// Resolve the parent directory first so we can check what was inherited
+	var parentInfo *dirInfo
+	if parentDir := r.fs.Dir(path); parentDir != path {
+		parentInfo = r.dirInfoCached(parentDir)
+		if parentInfo == nil {
+			return nil
+		}
+	}
+
+	// Cache hit: replay any messages from when this entry was created
+	if entry := r.sharedCache.getDir(r.sharedCacheKey, path); entry != nil &&
+		inheritsSameInfo(entry.info, parentInfo) && r.areSharedCacheDepsUnchanged(entry.deps) {
+		info := entry.info
+
+		// The parent directory was recomputed but nothing we inherited from it
+		// changed. Point a copy of this entry at the new parent.
+		if info.parent != parentInfo {
+			clone := *info
+			clone.parent = parentInfo
+			if clone.enclosingBrowserScope == info {
+				clone.enclosingBrowserScope = &clone
+			}
+			info = &clone
+			r.sharedCache.setDir(r.sharedCacheKey, path, &sharedDirEntry{info: info, deps: entry.deps, msgs: entry.msgs})
+		}
+
+		for _, msg := range entry.msgs {
+			r.log.AddMsg(msg)
+		}
+		if r.debugLogs != nil {
+			r.debugLogs.addNote(fmt.Sprintf("Reusing shared information for directory %q", path))
+		}
+		return info
+	}
+
+	// Cache miss
+	var info *dirInfo
+	msgs := r.captureMsgs(func() {
+		info = r.dirInfoUncached(path)
+	})
+
+	// Don't cache failures or anything we wouldn't be able to invalidate
+	if info == nil {
+		return nil
+	}
+	deps := make([]sharedCacheDep, 0, 2+len(info.tsConfigFiles))
+	ok := r.appendSharedCacheDep(&deps, path, false)
+	if ok {
+		if entry, _ := info.entries.Get("package.json"); entry != nil {
+			ok = r.appendSharedCacheDep(&deps, r.fs.Join(path, "package.json"), false)
+		}
+	}
+	for _, file := range info.tsConfigFiles {
+		if !ok {
+			break
+		}
+		ok = r.appendSharedCacheDep(&deps, file, true)
+	}
+	if ok {
+		r.sharedCache.setDir(r.sharedCacheKey, path, &sharedDirEntry{info: info, deps: deps, msgs: msgs})
+	}
+	return info
+}
+
+// This checks that a cached directory would inherit the same information from
+// the given parent directory as it did from the parent it was created with
+func inheritsSameInfo(info *dirInfo, parentInfo *dirInfo) bool {
+	if info.parent == parentInfo {
+		return true
+	}
+	if info.parent == nil || parentInfo == nil || info.parent.absRealPath != parentInfo.absRealPath {
+		return false
+	}
+	if info.packageJSON == nil {
+		if info.enclosingPackageJSON != parentInfo.enclosingPackageJSON ||
+			info.enclosingBrowserScope != parentInfo.enclosingBrowserScope {
+			return false
+		}
+	} else if info.enclosingBrowserScope != info && info.enclosingBrowserScope != parentInfo.enclosingBrowserScope {
+		return false
+	}
+	if info.tsConfigFiles == nil && info.enclosingTSConfigJSON != parentInfo.enclosingTSConfigJSON {
+		return false
+	}
+	return true
+}
+
+func (r resolverQuery) parsePackageJSONShared(inputPath string) *packageJSON {
+	packageJSONPath := r.fs.Join(inputPath, "package.json")
+	if entry := r.sharedCache.getFile(r.sharedCacheKey, packageJSONPath); entry != nil && r.areSharedCacheDepsUnchanged(entry.deps) {
+		for _, msg := range entry.msgs {
+			r.log.AddMsg(msg)
+		}
+		return entry.packageJSON
+	}
+
+	var result *packageJSON
+	msgs := r.captureMsgs(func() {
+		result = r.parsePackageJSON(inputPath)
+	})
+	var deps []sharedCacheDep
+	if r.appendSharedCacheDep(&deps, packageJSONPath, false) {
+		r.sharedCache.setFile(r.sharedCacheKey, packageJSONPath, &sharedFileEntry{packageJSON: result, deps: deps, msgs: msgs})
+	}
+	return result
+}
+
+// This also returns every file that was checked, including base config files
+// from "extends" and files that turned out to be missing
+func (r resolverQuery) parseTSConfigShared(file string) (*TSConfigJSON, []string, error) {
+	if entry := r.sharedCache.getFile(r.sharedCacheKey, file); entry != nil && r.areSharedCacheDepsUnchanged(entry.deps) {
+		for _, msg := range entry.msgs {
+			r.log.AddMsg(msg)
+		}
+		files := make([]string, len(entry.deps))
+		for i, dep := range entry.deps {
+			files[i] = dep.path
+		}
+		return entry.tsConfigJSON, files, entry.err
+	}
+
+	var result *TSConfigJSON
+	var err error
+	visited := make(map[string]bool)
+	msgs := r.captureMsgs(func() {
+		result, err = r.parseTSConfig(file, visited)
+	})
+	files := make([]string, 0, len(visited))
+	deps := make([]sharedCacheDep, 0, len(visited))
+	ok := true
+	for visitedFile := range visited {
+		files = append(files, visitedFile)
+		if ok {
+			ok = r.appendSharedCacheDep(&deps, visitedFile, true)
+		}
+	}
+	if ok {
+		r.sharedCache.setFile(r.sharedCacheKey, file, &sharedFileEntry{tsConfigJSON: result, err: err, deps: deps, msgs: msgs})
+	}
+	return result, files, err
+}
+
+// This runs the callback while collecting any messages it logs so that they
+// can be stored in the shared cache. The messages are still logged as usual.
+// Swapping out the log is safe because the resolver mutex is held for the
+// duration of the resolve operation.
+func (r resolverQuery) captureMsgs(callback func()) []logger.Msg {
+	log := r.log
+	tempLog := logger.NewDeferLog(logger.DeferLogAll)
+	tempLog.Level = log.Level
+	r.resolver.log = tempLog
+	callback()
+	r.resolver.log = log
+	msgs := tempLog.Done()
+	for _, msg := range msgs {
+		log.AddMsg(msg)
+	}
+	return msgs
+}
+
+func (r resolverQuery) appendSharedCacheDep(deps *[]sharedCacheDep, path string, allowMissing bool) bool {
+	if key, err := r.fs.ModKey(path); err == nil {
+		*deps = append(*deps, sharedCacheDep{path: path, modKey: key})
+		return true
+	}
+
+	// Some "tsconfig.json" files are only probed for and don't exist. Creating
+	// one of them later on will change the modification key of the enclosing
+	// directory, so depend on that as well.
+	if allowMissing {
+		dir := r.fs.Dir(path)
+		if entries, err, _ := r.fs.ReadDirectory(dir); err != nil {
+			*deps = append(*deps, sharedCacheDep{path: path, missing: true})
+			return true
+		} else if entry, _ := entries.Get(r.fs.Base(path)); entry == nil {
+			*deps = append(*deps, sharedCacheDep{path: path, missing: true})
+			return r.appendSharedCacheDep(deps, dir, false)
+		}
+	}
+
+	// The file exists but its modification key is unusable (e.g. it's too new)
+	return false
+}
+
+func (r resolverQuery) areSharedCacheDepsUnchanged(deps []sharedCacheDep) bool {
+	for _, dep := range deps {
+		key, err := r.fs.ModKey(dep.path)
+		if dep.missing {
+			if err == nil {
+				return false
+			}
+		} else if err != nil || key != dep.modKey {
+			return false
+		}
+	}
+	return true
+}
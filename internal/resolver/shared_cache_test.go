@@ -0,0 +1,248 @@
+package resolver
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/trustelem/esbuild/internal/ast"
+	"github.com/trustelem/esbuild/internal/cache"
+	"github.com/trustelem/esbuild/internal/config"
+	"github.com/trustelem/esbuild/internal/fs"
+	"github.com/trustelem/esbuild/internal/logger"
+	"github.com/trustelem/esbuild/internal/test"
+)
+
+// This is a file system for testing the shared cache. It's a snapshot of a
+// set of files that are versioned so that it can provide modification keys.
+// Each build uses a new snapshot, just like each build would see the current
+// state of the real file system. It also counts how many times each file is
+// read so tests can tell whether information came from the cache or not.
+type versionedFS struct {
+	fs.FS
+	versions map[string]uint64
+	mutex    sync.Mutex
+	reads    map[string]int
+}
+
+type versionedFiles struct {
+	files    map[string]string
+	versions map[string]uint64
+}
+
+func newVersionedFiles(files map[string]string) *versionedFiles {
+	v := &versionedFiles{files: make(map[string]string), versions: make(map[string]uint64)}
+	for file, contents := range files {
+		v.write(file, contents)
+	}
+	return v
+}
+
+// Writing to a file changes its modification key. Creating a new file also
+// changes the modification key of every directory that gains a new entry.
+func (v *versionedFiles) write(file string, contents string) {
+	if _, ok := v.files[file]; !ok {
+		for dir := path.Dir(file); ; dir = path.Dir(dir) {
+			v.versions[dir]++
+			if dir == "/" {
+				break
+			}
+		}
+	}
+	v.files[file] = contents
+	v.versions[file]++
+}
+
+func (v *versionedFiles) snapshot() *versionedFS {
+	versions := make(map[string]uint64, len(v.versions))
+	for k, version := range v.versions {
+		versions[k] = version
+	}
+	return &versionedFS{FS: fs.MockFS(v.files), versions: versions, reads: make(map[string]int)}
+}
+
+func (f *versionedFS) ReadFile(path string) (string, error, error) {
+	f.mutex.Lock()
+	f.reads[path]++
+	f.mutex.Unlock()
+	return f.FS.ReadFile(path)
+}
+
+func (f *versionedFS) ModKey(path string) (fs.ModKey, error) {
+	if version, ok := f.versions[path]; ok {
+		return fs.MockModKey(version), nil
+	}
+	return fs.ModKey{}, syscall.ENOENT
+}
+
+func runSharedCacheBuild(files *versionedFiles, shared *SharedCache, sourceDir string, importPath string) (string, *versionedFS, string) {
+	fsys := files.snapshot()
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug)
+	r := NewResolver(fsys, log, cache.MakeCacheSet(), config.Options{ExtensionOrder: []string{".ts", ".js"}}, shared)
+	result, _ := r.Resolve(sourceDir, importPath, ast.ImportStmt)
+	text := ""
+	for _, msg := range log.Done() {
+		text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+	}
+	if result == nil {
+		return "(unresolved)", fsys, text
+	}
+	return result.PathPair.Primary.Text, fsys, text
+}
+
+func TestSharedCacheHits(t *testing.T) {
+	files := newVersionedFiles(map[string]string{
+		"/project/src/entry.ts": ``,
+		"/project/tsconfig.json": `{
+			"compilerOptions": {
+				"baseUrl": ".",
+				"paths": { "@lib/*": ["./lib/*"] }
+			}
+		}`,
+		"/project/lib/util.ts":                   ``,
+		"/project/node_modules/pkg/package.json": `{ "main": "./main.js" }`,
+		"/project/node_modules/pkg/main.js":      ``,
+	})
+	shared := NewSharedCache()
+
+	// The first build reads everything
+	path, fsys, _ := runSharedCacheBuild(files, shared, "/project/src", "pkg")
+	test.AssertEqual(t, path, "/project/node_modules/pkg/main.js")
+	test.AssertEqual(t, fsys.reads["/project/node_modules/pkg/package.json"], 1)
+	test.AssertEqual(t, fsys.reads["/project/tsconfig.json"], 1)
+
+	// The second build reuses everything
+	path, fsys, _ = runSharedCacheBuild(files, shared, "/project/src", "pkg")
+	test.AssertEqual(t, path, "/project/node_modules/pkg/main.js")
+	test.AssertEqual(t, fsys.reads["/project/node_modules/pkg/package.json"], 0)
+	test.AssertEqual(t, fsys.reads["/project/tsconfig.json"], 0)
+
+	path, fsys, _ = runSharedCacheBuild(files, shared, "/project/src", "@lib/util")
+	test.AssertEqual(t, path, "/project/lib/util.ts")
+	test.AssertEqual(t, fsys.reads["/project/tsconfig.json"], 0)
+
+	// Builds without the shared cache don't get any hits
+	path, fsys, _ = runSharedCacheBuild(files, nil, "/project/src", "pkg")
+	test.AssertEqual(t, path, "/project/node_modules/pkg/main.js")
+	test.AssertEqual(t, fsys.reads["/project/node_modules/pkg/package.json"], 1)
+	test.AssertEqual(t, fsys.reads["/project/tsconfig.json"], 1)
+}
+
+func TestSharedCacheInvalidation(t *testing.T) {
+	files := newVersionedFiles(map[string]string{
+		"/project/src/entry.ts": ``,
+		"/project/tsconfig.json": `{
+			"compilerOptions": {
+				"baseUrl": ".",
+				"paths": { "@lib/*": ["./lib/*"] }
+			}
+		}`,
+		"/project/lib/util.ts":                   ``,
+		"/project/lib2/util.ts":                  ``,
+		"/project/node_modules/pkg/package.json": `{ "main": "./main.js" }`,
+		"/project/node_modules/pkg/main.js":      ``,
+		"/project/node_modules/pkg/other.js":     ``,
+	})
+	shared := NewSharedCache()
+
+	path, _, _ := runSharedCacheBuild(files, shared, "/project/src", "pkg")
+	test.AssertEqual(t, path, "/project/node_modules/pkg/main.js")
+	path, _, _ = runSharedCacheBuild(files, shared, "/project/src", "@lib/util")
+	test.AssertEqual(t, path, "/project/lib/util.ts")
+
+	// Editing "package.json" invalidates it
+	files.write("/project/node_modules/pkg/package.json", `{ "main": "./other.js" }`)
+	path, fsys, _ := runSharedCacheBuild(files, shared, "/project/src", "pkg")
+	test.AssertEqual(t, path, "/project/node_modules/pkg/other.js")
+	test.AssertEqual(t, fsys.reads["/project/node_modules/pkg/package.json"], 1)
+	test.AssertEqual(t, fsys.reads["/project/tsconfig.json"], 0)
+
+	// Editing "tsconfig.json" invalidates it
+	files.write("/project/tsconfig.json", `{
+		"compilerOptions": {
+			"baseUrl": ".",
+			"paths": { "@lib/*": ["./lib2/*"] }
+		}
+	}`)
+	path, fsys, _ = runSharedCacheBuild(files, shared, "/project/src", "@lib/util")
+	test.AssertEqual(t, path, "/project/lib2/util.ts")
+	test.AssertEqual(t, fsys.reads["/project/tsconfig.json"], 1)
+
+	// Adding a "tsconfig.json" file to a directory invalidates that directory
+	files.write("/project/src/tsconfig.json", `{
+		"compilerOptions": {
+			"baseUrl": ".",
+			"paths": { "@lib/*": ["../lib/*"] }
+		}
+	}`)
+	path, fsys, _ = runSharedCacheBuild(files, shared, "/project/src", "@lib/util")
+	test.AssertEqual(t, path, "/project/lib/util.ts")
+	test.AssertEqual(t, fsys.reads["/project/src/tsconfig.json"], 1)
+	test.AssertEqual(t, fsys.reads["/project/tsconfig.json"], 0)
+}
+
+func TestSharedCacheReplaysMessages(t *testing.T) {
+	files := newVersionedFiles(map[string]string{
+		"/project/src/entry.js":                  ``,
+		"/project/node_modules/pkg/package.json": `{ "main": "./main.js", }`,
+		"/project/node_modules/pkg/main.js":      ``,
+	})
+	shared := NewSharedCache()
+
+	// Problems with a cached file must be reported by every build, not just
+	// the build that first parsed the file
+	_, fsys, first := runSharedCacheBuild(files, shared, "/project/src", "pkg")
+	test.AssertEqual(t, fsys.reads["/project/node_modules/pkg/package.json"], 1)
+	_, fsys, second := runSharedCacheBuild(files, shared, "/project/src", "pkg")
+	test.AssertEqual(t, fsys.reads["/project/node_modules/pkg/package.json"], 0)
+	if first == "" {
+		t.Fatal("Expected a message about the invalid \"package.json\" file")
+	}
+	test.AssertEqualWithDiff(t, second, first)
+}
+
+func TestSharedCacheConcurrentBuilds(t *testing.T) {
+	files := newVersionedFiles(map[string]string{
+		"/project/tsconfig.json": `{
+			"compilerOptions": {
+				"baseUrl": ".",
+				"paths": { "@lib/*": ["./lib/*"] }
+			}
+		}`,
+		"/project/lib/util.ts": ``,
+	})
+	for i := 0; i < 10; i++ {
+		files.write(fmt.Sprintf("/project/src%d/entry.ts", i), ``)
+		files.write(fmt.Sprintf("/project/node_modules/pkg%d/package.json", i), fmt.Sprintf(`{ "main": "./main%d.js" }`, i))
+		files.write(fmt.Sprintf("/project/node_modules/pkg%d/main%d.js", i, i), ``)
+	}
+	shared := NewSharedCache()
+
+	// Many builds may share one cache at the same time. Each build must still
+	// see the correct results regardless of which build populated the cache.
+	var wait sync.WaitGroup
+	errors := make(chan string, 200)
+	for build := 0; build < 10; build++ {
+		wait.Add(1)
+		go func(build int) {
+			defer wait.Done()
+			for i := 0; i < 10; i++ {
+				n := (build + i) % 10
+				sourceDir := fmt.Sprintf("/project/src%d", n)
+				if path, _, _ := runSharedCacheBuild(files, shared, sourceDir, fmt.Sprintf("pkg%d", n)); path != fmt.Sprintf("/project/node_modules/pkg%d/main%d.js", n, n) {
+					errors <- fmt.Sprintf("Build %d resolved \"pkg%d\" to %q", build, n, path)
+				}
+				if path, _, _ := runSharedCacheBuild(files, shared, sourceDir, "@lib/util"); path != "/project/lib/util.ts" {
+					errors <- fmt.Sprintf("Build %d resolved \"@lib/util\" to %q", build, path)
+				}
+			}
+		}(build)
+	}
+	wait.Wait()
+	close(errors)
+	for err := range errors {
+		t.Error(err)
+	}
+}
@@ -35,10 +35,14 @@ type TSConfigJSON struct {
 	// "baseUrl" value in the "tsconfig.json" file.
 	Paths map[string][]string
 
+	JSX                            config.TSJSX
 	JSXFactory                     []string
 	JSXFragmentFactory             []string
+	JSXImportSource                string
 	TSTarget                       *config.TSTarget
 	UseDefineForClassFields        config.MaybeBool
+	ExperimentalDecorators         config.MaybeBool
+	EmitDecoratorMetadata          bool
 	PreserveImportsNotUsedAsValues bool
 }
 
@@ -88,6 +92,20 @@ func ParseTSConfigJSON(
 			}
 		}
 
+		// Parse "jsx"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "jsx"); ok {
+			if value, ok := getString(valueJSON); ok {
+				switch strings.ToLower(value) {
+				case "react":
+					result.JSX = config.TSJSXReact
+				case "react-jsx":
+					result.JSX = config.TSJSXReactJSX
+				case "react-jsxdev":
+					result.JSX = config.TSJSXReactJSXDev
+				}
+			}
+		}
+
 		// Parse "jsxFactory"
 		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "jsxFactory"); ok {
 			if value, ok := getString(valueJSON); ok {
@@ -102,6 +120,13 @@ func ParseTSConfigJSON(
 			}
 		}
 
+		// Parse "jsxImportSource"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "jsxImportSource"); ok {
+			if value, ok := getString(valueJSON); ok {
+				result.JSXImportSource = value
+			}
+		}
+
 		// Parse "useDefineForClassFields"
 		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "useDefineForClassFields"); ok {
 			if value, ok := getBool(valueJSON); ok {
@@ -113,6 +138,24 @@ func ParseTSConfigJSON(
 			}
 		}
 
+		// Parse "experimentalDecorators"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "experimentalDecorators"); ok {
+			if value, ok := getBool(valueJSON); ok {
+				if value {
+					result.ExperimentalDecorators = config.True
+				} else {
+					result.ExperimentalDecorators = config.False
+				}
+			}
+		}
+
+		// Parse "emitDecoratorMetadata"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "emitDecoratorMetadata"); ok {
+			if value, ok := getBool(valueJSON); ok {
+				result.EmitDecoratorMetadata = value
+			}
+		}
+
 		// Parse "target"
 		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "target"); ok {
 			if value, ok := getString(valueJSON); ok {
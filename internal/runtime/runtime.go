@@ -61,9 +61,6 @@ func code(isES6 bool) string {
 	//   __spreadArray
 	//   __spreadArrays
 	//   __values
-	//
-	// Note: The "__objRest" function has a for-of loop which requires ES6, but
-	// transforming destructuring to ES5 isn't even supported so it's ok.
 	text := `
 		var __create = Object.create
 		var __freeze = Object.freeze
@@ -73,7 +70,8 @@ func code(isES6 bool) string {
 		var __getOwnPropDescs = Object.getOwnPropertyDescriptors
 		var __getOwnPropNames = Object.getOwnPropertyNames
 		var __getOwnPropSymbols = Object.getOwnPropertySymbols
-		var __getProtoOf = Object.getPrototypeOf
+		export var __getProtoOf = Object.getPrototypeOf
+		var __setProtoOf = Object.setPrototypeOf
 		var __hasOwnProp = Object.prototype.hasOwnProperty
 		var __propIsEnum = Object.prototype.propertyIsEnumerable
 
@@ -162,6 +160,22 @@ func code(isES6 bool) string {
 			return target
 		}
 
+		// For array destructuring patterns when destructuring isn't supported.
+		// Only "count" items are taken from the iterator unless it's undefined.
+		export var __toArray = (value, count) => {
+			if (Array.isArray(value))
+				return value
+			var iterator = typeof Symbol !== 'undefined' && value[Symbol.iterator]
+			if (!iterator)
+				return Array.prototype.slice.call(value)
+			for (var result = [], it = iterator.call(value), step; count === void 0 || result.length < count; result.push(step.value))
+				if ((step = it.next()).done)
+					return result
+			if (it.return)
+				it.return()
+			return result
+		}
+
 		// This is for lazily-initialized ESM code. This has two implementations, a
 		// compact one for minified code and a verbose one that generates friendly
 		// names in V8's profiler and in stack traces.
@@ -178,6 +192,71 @@ func code(isES6 bool) string {
 		}
 		export var __commonJSMin = (cb, mod) => () => (mod || cb((mod = {exports: {}}).exports, mod), mod.exports)
 
+		// Hot module replacement keeps every module in a global registry by path.
+		// An update replaces the closures of the modules that changed and then
+		// evaluates them again along with their importers, up to the nearest
+		// modules that accept updates with "import.meta.hot.accept()". An update
+		// that isn't accepted by any module reloads the page instead.
+		var __hmrRegistry = () => {
+			var modules = {}, updated = [], current
+			var require = id => {
+				var mod = modules[id], parent = current
+				if (parent && mod.importers.indexOf(parent) < 0) mod.importers.push(parent)
+				if (!mod.module) {
+					mod.accept = null
+					mod.dispose = []
+					mod.module = { exports: {}, hot: {
+						data: mod.data || {},
+						accept: callback => { mod.accept = callback || (() => {}) },
+						dispose: callback => { mod.dispose.push(callback) },
+					} }
+					current = id
+					try {
+						(0, mod.factory)(mod.module.exports, mod.module)
+					} finally {
+						current = parent
+					}
+				}
+				return mod.module.exports
+			}
+			var apply = () => {
+				var queue = updated, stale = {}, accepted = {}, id, mod
+				updated = []
+				while (queue.length) {
+					mod = modules[id = queue.pop()]
+					if (stale[id] || !mod.module) continue
+					stale[id] = true
+					if (mod.accept) accepted[id] = mod.accept
+					else if (mod.importers.length) queue = queue.concat(mod.importers)
+					else if (typeof location !== 'undefined') return location.reload()
+					else return console.warn('Hot update of "' + id + '" was not accepted')
+				}
+				for (id in stale) {
+					mod = modules[id]
+					mod.data = {}
+					mod.dispose.forEach(callback => callback(mod.data))
+					mod.module = null
+				}
+				for (id in accepted) accepted[id](require(id))
+			}
+			return {
+				register: (id, factory) => {
+					if (modules[id]) {
+						modules[id].factory = factory
+						if (updated.push(id) < 2) Promise.resolve().then(apply)
+					} else {
+						modules[id] = { factory: factory, importers: [] }
+					}
+				},
+				require,
+			}
+		}
+		export var __hmrModule = cb => {
+			var hmr = globalThis.esbuildHMR ||= __hmrRegistry(), id = Object.keys(cb)[0]
+			hmr.register(id, cb[id])
+			return () => hmr.require(id)
+		}
+
 		// Used to implement ES6 exports to CommonJS
 		export var __export = (target, all) => {
 			__markAsModule(target)
@@ -227,6 +306,103 @@ func code(isES6 bool) string {
 			), module)
 		}
 
+		// Loads chunks generated by code splitting for the "iife" format, which
+		// register their exports in a global registry. Each chunk is loaded after
+		// the chunks it depends on. Assigning a function that returns a promise to
+		// the "load" property of the registry overrides the default script loader.
+		export var __loadChunk = (ids, publicPath) => {
+			var chunks = globalThis.esbuildChunks ||= {}
+			return ids.reduce((promise, id) => promise.then(() => id in chunks || (chunks.load ? chunks.load(id) :
+				new Promise((resolve, reject) => {
+					var script = document.createElement('script')
+					script.src = (publicPath || '') + id
+					script.onload = resolve
+					script.onerror = () => reject(new Error('Could not load chunk "' + id + '"'))
+					document.head.appendChild(script)
+				}))), Promise.resolve()).then(() => chunks[ids[ids.length - 1]])
+		}
+
+		// Loads the stylesheets of chunks generated by code splitting before the
+		// chunks themselves. The stylesheets are inserted in order so later ones
+		// take precedence, and each stylesheet is only ever inserted once.
+		export var __loadCSS = (paths, base) => {
+			var sheets = globalThis.esbuildStyles ||= {}
+			return typeof document > 'u' ? Promise.resolve() : Promise.all(paths.map(path => {
+				var href = new URL(path, new URL(base || '', document.baseURI)).href
+				return sheets[href] ||= new Promise((resolve, reject) => {
+					var link = document.createElement('link')
+					link.rel = 'stylesheet'
+					link.href = href
+					link.onload = resolve
+					link.onerror = () => reject(new Error('Could not load stylesheet "' + href + '"'))
+					document.head.appendChild(link)
+				})
+			}))
+		}
+
+		// Module federation keeps a page-wide registry of the container of each
+		// build and of the copies of shared packages that each build bundles
+		var __federation = () => globalThis.esbuildFederation ||= { containers: {}, shared: {} }
+		var __parseVersion = version => (version.split(/[-+]/)[0] + '.0.0').split('.').map(x => +x || 0)
+		var __compareVersions = (a, b) => a[0] - b[0] || a[1] - b[1] || a[2] - b[2]
+
+		// Ranges follow npm: "^" allows changes that don't modify the first
+		// non-zero part, "~" allows patch changes (or minor changes if only the
+		// major version is given), and partial versions such as "1.2" or "1.x"
+		// allow any version with that prefix
+		var __satisfiesVersion = (version, range) => {
+			var match = /^(\^|~|>=)?\s*(.*)$/.exec(range.trim()), op = match[1], v = __parseVersion(version)
+			var parts = match[2].split(/[-+]/)[0].split('.'), r = [], n, b
+			while (r.length < 3 && /^\d+$/.test(parts[r.length])) r.push(+parts[r.length])
+			if (!(n = r.length)) return true
+			r = __parseVersion(r.join('.'))
+			if (op === '>=') return __compareVersions(v, r) >= 0
+			if (op === '^') for (b = 0; b < n - 1 && !r[b]; b++);
+			else b = op === '~' ? Math.min(1, n - 1) : n - 1
+			return __compareVersions(v, r) >= 0 && __compareVersions(v, r.map((x, i) => i < b ? x : i > b ? 0 : x + 1)) < 0
+		}
+
+		// Returns a "require()" function for a shared package that evaluates the
+		// best copy from any build. Copies that are already in use are preferred
+		// over newer ones so that every build ends up using the same copy.
+		export var __federationShare = (name, version, range, require) => {
+			var versions = __federation().shared[name] ||= {}, shared
+			versions[version] ||= { get: require }
+			return () => {
+				if (!shared) {
+					var best = version
+					for (var v in versions)
+						if (__satisfiesVersion(v, range) && (versions[v].loaded !== versions[best].loaded ? versions[v].loaded :
+								__compareVersions(__parseVersion(v), __parseVersion(best)) > 0))
+							best = v
+					shared = versions[best]
+					shared.loaded = true
+				}
+				return shared.get()
+			}
+		}
+
+		// Reads a module exposed by another build from that build's container.
+		// Importing "name/path" reads the module exposed as "./path".
+		export var __federationRequire = request => {
+			var slash = request.indexOf('/'), name = slash < 0 ? request : request.slice(0, slash)
+			var container = __federation().containers[name]
+			if (!container) throw new Error('Container "' + name + '" is not loaded')
+			return container.get(slash < 0 ? '.' : '.' + request.slice(slash))
+		}
+
+		// Like "__federationRequire" but loads the container entry first if needed
+		export var __federationImport = (request, url) => {
+			var federation = __federation(), loading = federation.loading ||= {}
+			return (request.split('/')[0] in federation.containers ? Promise.resolve() : loading[url] ||= new Promise((resolve, reject) => {
+				var script = document.createElement('script')
+				script.src = url
+				script.onload = resolve
+				script.onerror = () => reject(new Error('Could not load container "' + url + '"'))
+				document.head.appendChild(script)
+			})).then(() => __toModule(__federationRequire(request)))
+		}
+
 		// For TypeScript decorators
 		// - kind === undefined: class
 		// - kind === 1: method, parameter
@@ -241,6 +417,73 @@ func code(isES6 bool) string {
 			return result
 		}
 		export var __decorateParam = (index, decorator) => (target, key) => decorator(target, key, index)
+		export var __decorateMetadata = (key, value) => typeof Reflect === 'object' && typeof Reflect.metadata === 'function' ? Reflect.metadata(key, value) : void 0
+
+		// For JavaScript decorators. The array holds the extra initializers of the
+		// class, of static elements, and of instance elements, followed by the
+		// initializers of each decorated field and auto-accessor.
+		// - flags & 7: 0 class, 1 method, 2 getter, 3 setter, 4 accessor, 5 field
+		// - flags & 8: static
+		// - flags & 16: private (the target holds the function(s) and "extra" holds
+		//   the private brand or storage)
+		export var __decoratorStart = () => [[], [], []]
+		var __expectFn = fn => {
+			if (fn !== void 0 && typeof fn !== 'function') throw TypeError('Function expected')
+			return fn
+		}
+		var __decoratorContext = (kind, name, done, fns) => ({
+			kind: ['class', 'method', 'getter', 'setter', 'accessor', 'field'][kind],
+			name,
+			addInitializer: fn => {
+				if (done._) throw TypeError('Cannot add initializers after decoration has completed')
+				fns.push(__expectFn(fn || null))
+			},
+		})
+		export var __runInitializers = (array, index, self, value) => {
+			for (var i = 0, fns = array[index]; i < fns.length; i++)
+				index < 3 ? fns[i].call(self) : value = fns[i].call(self, value)
+			return value
+		}
+		export var __decorateElement = (array, flags, name, decorators, target, extra) => {
+			var kind = flags & 7, isStatic = !!(flags & 8), isPrivate = !!(flags & 16)
+			var key = kind > 2 ? 'set' : kind > 1 ? 'get' : 'value'
+			var initializers = kind > 3 && array[array.push([]) - 1]
+			var extraInitializers = array[kind && 1 + !isStatic]
+			var desc = {}, ctx, access, done, fn, result
+			if (kind && kind < 5) {
+				if (!isPrivate) desc = __getOwnPropDesc(target, name)
+				else if (kind > 3) desc = target
+				else desc[key] = target
+			}
+			for (var i = decorators.length - 1; i >= 0; i--) {
+				ctx = __decoratorContext(kind, name, done = {}, extraInitializers)
+				if (kind) {
+					ctx.static = isStatic, ctx.private = isPrivate, access = ctx.access = {}
+					access.has = isPrivate ? x => extra.has(x) : x => name in x
+					if (kind !== 3)
+						access.get = isPrivate ? x => kind === 1 ? __privateMethod(x, extra, desc.value) : __privateGet(x, extra, desc.get) : x => x[name]
+					if (kind > 2)
+						access.set = isPrivate ? (x, y) => __privateSet(x, extra, y, desc.set) : (x, y) => x[name] = y
+				}
+				fn = decorators[i]
+				result = fn(kind < 4 ? kind ? desc[key] : target : kind < 5 ? { get: desc.get, set: desc.set } : void 0, ctx)
+				done._ = 1
+				if (kind === 4) {
+					if (result === null || typeof result !== 'object' && result !== void 0) throw TypeError('Object expected')
+					if (result) {
+						if (__expectFn(result.get)) desc.get = result.get
+						if (__expectFn(result.set)) desc.set = result.set
+						if (__expectFn(result.init)) initializers.unshift(result.init)
+					}
+				} else if (__expectFn(result)) {
+					if (kind > 4) initializers.unshift(result)
+					else if (kind) desc[key] = result
+					else target = result
+				}
+			}
+			if (kind && kind < 5 && !isPrivate) __defProp(target, name, desc)
+			return kind ? kind < 4 ? desc[key] : desc : target
+		}
 
 		// For class members
 		export var __publicField = (obj, key, value) => {
@@ -278,6 +521,95 @@ func code(isES6 bool) string {
 			return method
 		}
 
+		// For lowering classes to ES5 constructor functions
+		export var __extendClass = (child, parent) => {
+			if (typeof parent !== 'function' && parent !== null)
+				throw TypeError('Class extends value ' + String(parent) + ' is not a constructor or null')
+			child.prototype = __create(parent && parent.prototype, {
+				constructor: { value: child, writable: true, configurable: true },
+			})
+			if (parent)
+				__setProtoOf ? __setProtoOf(child, parent) : child.__proto__ = parent
+		}
+		export var __defMethod = (obj, key, value) => __defProp(obj, key, { value, writable: true, configurable: true })
+		export var __defAccessor = (obj, key, get, set) => {
+			var desc = __getOwnPropDesc(obj, key)
+			__defProp(obj, key, {
+				get: get || desc && desc.get,
+				set: set || desc && desc.set,
+				configurable: true,
+			})
+		}
+		export var __wrapNativeSuper = (parent) => {
+			// Native constructors such as "Error" and "Array" ignore "this" when
+			// called as a function, so construct the instance with the subclass as
+			// "new.target" instead to keep the subclass prototype
+			if (typeof parent !== 'function' || Function.prototype.toString.call(parent).indexOf('[native code]') < 0)
+				return parent
+			var wrapper = function () {
+				var newTarget = __getProtoOf(this).constructor
+				if (typeof Reflect !== 'undefined' && Reflect.construct)
+					return Reflect.construct(parent, arguments, newTarget)
+				var obj = new (Function.prototype.bind.apply(parent, [null].concat([].slice.call(arguments))))()
+				__setProtoOf ? __setProtoOf(obj, newTarget.prototype) : obj.__proto__ = newTarget.prototype
+				return obj
+			}
+			__extendClass(wrapper, parent)
+			return wrapper
+		}
+		export var __classCallCheck = (obj, ctor) => {
+			if (!(obj instanceof ctor)) throw TypeError('Class constructor cannot be invoked without "new"')
+		}
+
+		// For lowering "super" property accesses in ES5 classes. The lookup starts
+		// at "proto" but getters and setters are called with "receiver" as "this".
+		var __superDesc = (proto, key) => {
+			for (var desc; proto && !(desc = __getOwnPropDesc(proto, key)); proto = __getProtoOf(proto)) ;
+			return desc
+		}
+		export var __superGet = (proto, key, receiver) => {
+			var desc = __superDesc(proto, key)
+			return desc && (desc.get ? desc.get.call(receiver) : desc.value)
+		}
+		export var __superSet = (proto, key, value, receiver) => {
+			var desc = __superDesc(proto, key)
+			if (desc && !('value' in desc)) {
+				if (!desc.set) throw TypeError('Cannot set property ' + String(key) + ' which has only a getter')
+				desc.set.call(receiver, value)
+			} else if (desc && !desc.writable) {
+				throw TypeError('Cannot assign to read only property ' + String(key))
+			} else {
+				// Like a normal assignment, this creates an own property on the receiver
+				desc = __getOwnPropDesc(receiver, key)
+				if (desc && !desc.writable) throw TypeError('Cannot assign to read only property ' + String(key))
+				desc ? receiver[key] = value : __defProp(receiver, key, { value, writable: true, enumerable: true, configurable: true })
+			}
+			return value
+		}
+		export var __superWrapper = (proto, key, receiver) => {
+			return {
+				set _(value) { __superSet(proto, key, value, receiver) },
+				get _() { return __superGet(proto, key, receiver) },
+			}
+		}
+
+		// For lowering computed keys and accessors in object literals. A "kind" of
+		// 1 is a getter and 2 is a setter, which are merged with an existing pair.
+		export var __objProp = (obj, key, value, kind) => {
+			if (!kind)
+				return __defNormalProp(obj, key, value)
+			var desc = __getOwnPropDesc(obj, key)
+			__defProp(obj, key, {
+				get: kind === 1 ? value : desc && desc.get,
+				set: kind === 2 ? value : desc && desc.set,
+				enumerable: true,
+				configurable: true,
+			})
+		}
+
+		// For lowering "new" expressions with spread arguments
+		export var __construct = (target, args) => new (Function.prototype.bind.apply(target, [null].concat(args)))()
+
 		// For lowering tagged template literals
 		export var __template = (cooked, raw) => __freeze(__defProp(cooked, 'raw', { value: __freeze(raw || cooked.slice()) }))
 
@@ -303,6 +635,172 @@ func code(isES6 bool) string {
 			})
 		}
 
+		// This is for lowering generators to a state machine. The body function is
+		// called with the state object and returns an instruction: [0, value] for
+		// next, [1, error] for throw, [2, value] for return, [3, label] for a jump,
+		// [4, value] for yield, [5, iterable] for yield*, [6, error] for a caught
+		// exception, and [7] for the end of a finally block. Each entry in "trys"
+		// holds the labels of a try block: [try, catch, finally, end].
+		export var __stateMachine = (__this, body) => {
+			var sent, delegate, started, running, state = {
+				label: 0,
+				sent: () => {
+					if (sent[0] & 1) throw sent[1]
+					return sent[1]
+				},
+				trys: [],
+				ops: [],
+			}
+			var step = op => {
+				if (running) throw new TypeError('Generator is already running')
+				if (!started && op[0]) state = 0
+				started = 1
+				while (state) {
+					try {
+						running = 1
+
+						// Forward to the iterator from "yield*" until it's done
+						if (delegate) {
+							var method = delegate[op[0] & 2 ? 'return' : op[0] ? 'throw' : 'next']
+							if (!method && op[0] === 1 && delegate.return) delegate.return()
+							var result = method && method.call(delegate, op[1])
+							if (result && !result.done) return result
+							delegate = 0
+							if (result) op = [op[0] & 2, result.value]
+						}
+
+						switch (op[0]) {
+							case 0: case 1:
+								sent = op
+								break
+							case 4:
+								state.label++
+								return { value: op[1], done: false }
+							case 5:
+								state.label++
+								delegate = __getIterator(op[1])
+								op = [0]
+								continue
+							case 7:
+								op = state.ops.pop()
+								state.trys.pop()
+								continue
+							default:
+								var region = state.trys[state.trys.length - 1]
+								if (!region && (op[0] === 6 || op[0] === 2)) {
+									state = 0
+									continue
+								}
+								if (op[0] === 3 && (!region || op[1] > region[0] && op[1] < region[3])) {
+									state.label = op[1]
+									break
+								}
+								if (op[0] === 6 && state.label < region[1]) {
+									state.label = region[1]
+									sent = op
+									break
+								}
+								if (state.label < region[2]) {
+									state.label = region[2]
+									state.ops.push(op)
+									break
+								}
+								if (region[2]) state.ops.pop()
+								state.trys.pop()
+								continue
+						}
+						op = body.call(__this, state)
+					} catch (e) {
+						op = [6, e]
+						delegate = 0
+					} finally {
+						running = 0
+					}
+				}
+				if (op[0] & 5) throw op[1]
+				return { value: op[0] ? op[1] : void 0, done: true }
+			}
+			var it = {
+				next: value => step([0, value]),
+				throw: error => step([1, error]),
+				return: value => step([2, value]),
+			}
+			if (typeof Symbol !== 'undefined' && Symbol.iterator) it[Symbol.iterator] = () => it
+			return it
+		}
+
+		// For iterating over a value when "for-of" loops or generators are lowered
+		export var __getIterator = obj => {
+			var method = typeof Symbol !== 'undefined' && Symbol.iterator && obj[Symbol.iterator], i = 0
+			if (method) return method.call(obj)
+			if (obj == null || typeof obj.length !== 'number') throw new TypeError(obj + ' is not iterable')
+			return { next: () => ({ value: obj[i], done: i++ >= obj.length }) }
+		}
+
+		// For lowering async generators. Inside the lowered generator "await x"
+		// becomes "yield new __awaitValue(x)" and "yield* x" becomes
+		// "yield new __awaitValue(x, 1)".
+		export var __awaitValue = function (value, isYieldStar) {
+			this[0] = value
+			this[1] = isYieldStar
+		}
+		export var __asyncGen = (__this, __arguments, generator) => {
+			var queue = [], delegate, it = {}
+			var settle = (isReject, value) => {
+				queue.shift()[isReject ? 3 : 2](value)
+				if (queue.length) send(queue[0][0], queue[0][1])
+			}
+			var send = (key, value) => {
+				// Forward to the async iterator from "yield*" until it's done
+				if (delegate) {
+					var method = delegate[key]
+					if (!method) {
+						delegate = 0
+						return send(key, value)
+					}
+					return new Promise(resolve => resolve(method.call(delegate, value))).then(
+						result => result.done ? (delegate = 0, send(key === 'return' ? key : 'next', result.value)) : settle(0, result),
+						error => (delegate = 0, send('throw', error)))
+				}
+				try {
+					var result = generator[key](value), isAwait = result.value instanceof __awaitValue
+				} catch (e) {
+					return settle(1, e)
+				}
+				if (isAwait && result.value[1]) {
+					delegate = __forAwait(result.value[0])
+					return send('next')
+				}
+				Promise.resolve(isAwait ? result.value[0] : result.value).then(
+					value => isAwait ? send('next', value) : settle(0, { value, done: result.done }),
+					error => isAwait || !result.done ? send('throw', error) : settle(1, error))
+			}
+			var method = key => it[key] = value => new Promise((resolve, reject) => {
+				if (queue.push([key, value, resolve, reject]) === 1) send(key, value)
+			})
+			method('next')
+			method('throw')
+			method('return')
+			generator = generator.apply(__this, __arguments)
+			if (typeof Symbol !== 'undefined' && Symbol.asyncIterator) it[Symbol.asyncIterator] = () => it
+			return it
+		}
+
+		// For lowering "for await" loops
+		export var __forAwait = (obj, it, method) => {
+			var asyncIterator = typeof Symbol !== 'undefined' && Symbol.asyncIterator && obj[Symbol.asyncIterator]
+			if (asyncIterator) return asyncIterator.call(obj)
+			obj = __getIterator(obj)
+			it = {}
+			method = (key, fn) => (fn = obj[key]) && (it[key] = value => new Promise((resolve, reject) => {
+				var result = fn.call(obj, value)
+				Promise.resolve(result.value).then(value => resolve({ value, done: result.done }), reject)
+			}))
+			method('next')
+			method('return')
+			return it
+		}
+
 		// This is for the "binary" loader (custom code is ~2x faster than "atob")
 		export var __toBinaryNode = base64 => new Uint8Array(Buffer.from(base64, 'base64'))
 		export var __toBinary = /* @__PURE__ */ (() => {
@@ -396,3 +894,16 @@ var ES5Source = logger.Source{
 //                                      __decorateClass([
 //                                        dec
 //                                      ], C.prototype, 'foo', 2);
+//
+// ============================ Decorator metadata ============================
+//
+//   // TypeScript                      // JavaScript
+//   class C {                          class C {
+//     @dec                               foo(bar) {}
+//     foo(bar: string): number {}      }
+//   }                                  __decorateClass([
+//                                        dec,
+//                                        __decorateMetadata('design:type', Function),
+//                                        __decorateMetadata('design:paramtypes', [String]),
+//                                        __decorateMetadata('design:returntype', Number)
+//                                      ], C.prototype, 'foo', 1);
@@ -113,6 +113,8 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   let jsx = getFlag(options, keys, 'jsx', mustBeString);
   let jsxFactory = getFlag(options, keys, 'jsxFactory', mustBeString);
   let jsxFragment = getFlag(options, keys, 'jsxFragment', mustBeString);
+  let jsxImportSource = getFlag(options, keys, 'jsxImportSource', mustBeString);
+  let jsxDev = getFlag(options, keys, 'jsxDev', mustBeBoolean);
   let define = getFlag(options, keys, 'define', mustBeObject);
   let pure = getFlag(options, keys, 'pure', mustBeArray);
   let keepNames = getFlag(options, keys, 'keepNames', mustBeBoolean);
@@ -138,6 +140,8 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   if (jsx) flags.push(`--jsx=${jsx}`);
   if (jsxFactory) flags.push(`--jsx-factory=${jsxFactory}`);
   if (jsxFragment) flags.push(`--jsx-fragment=${jsxFragment}`);
+  if (jsxImportSource) flags.push(`--jsx-import-source=${jsxImportSource}`);
+  if (jsxDev) flags.push(`--jsx-dev`);
 
   if (define) {
     for (let key in define) {
@@ -179,7 +183,13 @@ function flagsForBuildOptions(
   let bundle = getFlag(options, keys, 'bundle', mustBeBoolean);
   let watch = getFlag(options, keys, 'watch', mustBeBooleanOrObject);
   let splitting = getFlag(options, keys, 'splitting', mustBeBoolean);
+  let manualChunks = getFlag(options, keys, 'manualChunks', mustBeObject);
+  let minChunkSize = getFlag(options, keys, 'minChunkSize', mustBeInteger);
+  let maxChunkSize = getFlag(options, keys, 'maxChunkSize', mustBeInteger);
+  let hmr = getFlag(options, keys, 'hmr', mustBeBoolean);
+  let federation = getFlag(options, keys, 'federation', mustBeObject);
   let preserveSymlinks = getFlag(options, keys, 'preserveSymlinks', mustBeBoolean);
+  let dedupePackages = getFlag(options, keys, 'dedupePackages', mustBeBoolean);
   let metafile = getFlag(options, keys, 'metafile', mustBeBoolean);
   let outfile = getFlag(options, keys, 'outfile', mustBeString);
   let outdir = getFlag(options, keys, 'outdir', mustBeString);
@@ -191,6 +201,7 @@ function flagsForBuildOptions(
   let mainFields = getFlag(options, keys, 'mainFields', mustBeArray);
   let conditions = getFlag(options, keys, 'conditions', mustBeArray);
   let external = getFlag(options, keys, 'external', mustBeArray);
+  let nodeBuiltins = getFlag(options, keys, 'nodeBuiltins', mustBeObject);
   let loader = getFlag(options, keys, 'loader', mustBeObject);
   let outExtension = getFlag(options, keys, 'outExtension', mustBeObject);
   let publicPath = getFlag(options, keys, 'publicPath', mustBeString);
@@ -224,7 +235,30 @@ function flagsForBuildOptions(
     }
   }
   if (splitting) flags.push('--splitting');
+  if (manualChunks) {
+    for (let pattern in manualChunks) {
+      let name = manualChunks[pattern] + '';
+      if (name.indexOf('=') >= 0) throw new Error(`Invalid manual chunk name: ${name}`);
+      flags.push(`--manual-chunk:${pattern}=${name}`);
+    }
+  }
+  if (minChunkSize) flags.push(`--min-chunk-size=${minChunkSize}`);
+  if (maxChunkSize) flags.push(`--max-chunk-size=${maxChunkSize}`);
+  if (hmr) flags.push('--hmr');
+  if (federation) {
+    let federationKeys: OptionKeys = Object.create(null);
+    let name = getFlag(federation, federationKeys, 'name', mustBeString);
+    let exposes = getFlag(federation, federationKeys, 'exposes', mustBeObject);
+    let remotes = getFlag(federation, federationKeys, 'remotes', mustBeObject);
+    let shared = getFlag(federation, federationKeys, 'shared', mustBeObject);
+    checkForInvalidFlags(federation, federationKeys, `on "federation" in ${callName}() call`);
+    if (name !== void 0) flags.push(`--federation-name=${name}`);
+    if (exposes) for (let key in exposes) flags.push(`--federation-expose:${key}=${exposes[key]}`);
+    if (remotes) for (let key in remotes) flags.push(`--federation-remote:${key}=${remotes[key]}`);
+    if (shared) for (let key in shared) flags.push(`--federation-shared:${key}=${shared[key]}`);
+  }
   if (preserveSymlinks) flags.push('--preserve-symlinks');
+  if (dedupePackages) flags.push('--dedupe-packages');
   if (metafile) flags.push(`--metafile`);
   if (outfile) flags.push(`--outfile=${outfile}`);
   if (outdir) flags.push(`--outdir=${outdir}`);
@@ -263,6 +297,20 @@ function flagsForBuildOptions(
     flags.push(`--conditions=${values.join(',')}`);
   }
   if (external) for (let name of external) flags.push(`--external:${name}`);
+  if (nodeBuiltins) {
+    for (let name in nodeBuiltins) {
+      if (name.indexOf('=') >= 0) throw new Error(`Invalid node builtin: ${name}`);
+      let policy = nodeBuiltins[name];
+      if (typeof policy === 'object' && policy !== null) {
+        let policyKeys: OptionKeys = Object.create(null);
+        let polyfill = getFlag(policy, policyKeys, 'polyfill', mustBeString);
+        checkForInvalidFlags(policy, policyKeys, `on node builtin ${JSON.stringify(name)}`);
+        flags.push(`--node-builtin:${name}=polyfill${polyfill ? `:${polyfill}` : ''}`);
+      } else {
+        flags.push(`--node-builtin:${name}=${policy}`);
+      }
+    }
+  }
   if (banner) {
     for (let type in banner) {
       if (type.indexOf('=') >= 0) throw new Error(`Invalid banner file type: ${type}`);
@@ -1,8 +1,9 @@
 export type Platform = 'browser' | 'node' | 'neutral';
-export type Format = 'iife' | 'cjs' | 'esm';
+export type Format = 'iife' | 'cjs' | 'esm' | 'umd' | 'system';
 export type Loader = 'js' | 'jsx' | 'ts' | 'tsx' | 'css' | 'json' | 'text' | 'base64' | 'file' | 'dataurl' | 'binary' | 'default';
 export type LogLevel = 'verbose' | 'debug' | 'info' | 'warning' | 'error' | 'silent';
 export type Charset = 'ascii' | 'utf8';
+export type NodeBuiltinPolicy = 'default' | 'external' | 'error' | 'empty' | 'polyfill' | { polyfill: string };
 
 interface CommonOptions {
   sourcemap?: boolean | 'inline' | 'external' | 'both';
@@ -22,9 +23,11 @@ interface CommonOptions {
   treeShaking?: boolean;
   ignoreAnnotations?: boolean;
 
-  jsx?: 'transform' | 'preserve';
+  jsx?: 'transform' | 'preserve' | 'automatic';
   jsxFactory?: string;
   jsxFragment?: string;
+  jsxImportSource?: string;
+  jsxDev?: boolean;
 
   define?: { [key: string]: string };
   pure?: string[];
@@ -38,13 +41,20 @@ interface CommonOptions {
 export interface BuildOptions extends CommonOptions {
   bundle?: boolean;
   splitting?: boolean;
+  manualChunks?: { [pattern: string]: string };
+  minChunkSize?: number;
+  maxChunkSize?: number;
+  hmr?: boolean;
+  federation?: Federation;
   preserveSymlinks?: boolean;
+  dedupePackages?: boolean;
   outfile?: string;
   metafile?: boolean;
   outdir?: string;
   outbase?: string;
   platform?: Platform;
   external?: string[];
+  nodeBuiltins?: { [name: string]: NodeBuiltinPolicy };
   loader?: { [ext: string]: Loader };
   resolveExtensions?: string[];
   mainFields?: string[];
@@ -73,6 +83,13 @@ export interface WatchMode {
   onRebuild?: (error: BuildFailure | null, result: BuildResult | null) => void;
 }
 
+export interface Federation {
+  name?: string; // The name of this build's container
+  exposes?: { [name: string]: string }; // Maps a public module name such as "./Button" to a path
+  remotes?: { [name: string]: string }; // Maps the container name of another build to its URL
+  shared?: { [name: string]: string }; // Maps a package name to the versions to accept ("" for compatible versions)
+}
+
 export interface StdinOptions {
   contents: string;
   resolveDir?: string;
@@ -160,8 +177,10 @@ export interface ServeResult {
 export interface TransformOptions extends CommonOptions {
   tsconfigRaw?: string | {
     compilerOptions?: {
+      jsx?: 'react' | 'react-jsx' | 'react-jsxdev' | 'preserve' | 'react-native',
       jsxFactory?: string,
       jsxFragmentFactory?: string,
+      jsxImportSource?: string,
       useDefineForClassFields?: boolean,
       importsNotUsedAsValues?: 'remove' | 'preserve' | 'error',
     },
@@ -228,6 +247,8 @@ export type ImportKind =
   | 'require-call'
   | 'dynamic-import'
   | 'require-resolve'
+  | 'new-url'
+  | 'worker'
 
   // CSS
   | 'import-rule'
@@ -312,8 +333,17 @@ export interface Metafile {
       }[]
       exports: string[]
       entryPoint?: string
+      manualChunk?: string
     }
   }
+  deduplicated?: {
+    path: string
+    canonical: string
+    package: string
+    version: string
+    inputBytes: number
+  }[]
+  deduplicatedInputBytes?: number
 }
 
 export interface FormatMessagesOptions {
@@ -77,7 +77,10 @@
 //
 package api
 
-import "github.com/trustelem/esbuild/internal/fs"
+import (
+	"github.com/trustelem/esbuild/internal/fs"
+	"github.com/trustelem/esbuild/internal/resolver"
+)
 
 type SourceMap uint8
 
@@ -112,6 +115,7 @@ type JSXMode uint8
 const (
 	JSXModeTransform JSXMode = iota
 	JSXModePreserve
+	JSXModeAutomatic
 )
 
 type Target uint8
@@ -155,6 +159,49 @@ const (
 	PlatformNeutral
 )
 
+// This is for environments that don't match any of the built-in platforms
+// such as React Native or edge runtimes. The fields below replace the
+// defaults of the base platform. Everything else (e.g. the default output
+// format) behaves like the base platform.
+type CustomPlatform struct {
+	Name string
+	Base Platform
+
+	Conditions     []string          // Default conditions for the "exports" field in "package.json"
+	MainFields     []string          // Default main fields if "MainFields" isn't specified
+	BrowserField   bool              // Whether to respect the "browser" field in "package.json"
+	BuiltinModules []string          // These are always external, with or without a "node:" prefix
+	Define         map[string]string // Entries in "Define" take precedence over these
+}
+
+// Module federation lets independently built bundles on the same page share
+// modules at run-time. Importing "name/path" where "name" is a remote reads
+// the module exposed as "./path" from that build's container.
+type Federation struct {
+	Name    string            // The name of this build's container
+	Exposes map[string]string // Maps a public module name such as "./Button" to a path
+	Remotes map[string]string // Maps the container name of another build to the URL of its container entry
+	Shared  map[string]string // Maps a package name to the versions to accept from other builds (empty for compatible versions)
+}
+
+type NodeBuiltinMode uint8
+
+const (
+	NodeBuiltinDefault  NodeBuiltinMode = iota // External for node, resolved normally otherwise
+	NodeBuiltinExternal                        // Always external
+	NodeBuiltinError                           // Importing it is an error
+	NodeBuiltinEmpty                           // Replaced with an empty module
+	NodeBuiltinPolyfill                        // Replaced with a polyfill package
+)
+
+type NodeBuiltinPolicy struct {
+	Mode NodeBuiltinMode
+
+	// The package to use with "NodeBuiltinPolyfill". This defaults to the
+	// well-known browser polyfill for that module if there is one.
+	Polyfill string
+}
+
 type Format uint8
 
 const (
@@ -162,6 +209,8 @@ const (
 	FormatIIFE
 	FormatCommonJS
 	FormatESModule
+	FormatUMD
+	FormatSystem
 )
 
 type EngineName uint8
@@ -266,9 +315,11 @@ type BuildOptions struct {
 	IgnoreAnnotations bool
 	LegalComments     LegalComments
 
-	JSXMode     JSXMode
-	JSXFactory  string
-	JSXFragment string
+	JSXMode         JSXMode
+	JSXFactory      string
+	JSXFragment     string
+	JSXImportSource string
+	JSXDev          bool
 
 	Define    map[string]string
 	Pure      []string
@@ -277,15 +328,23 @@ type BuildOptions struct {
 	GlobalName        string
 	Bundle            bool
 	PreserveSymlinks  bool
+	DedupePackages    bool
 	Splitting         bool
+	ManualChunks      map[string]string // Maps a package name or path pattern to a chunk name
+	MinChunkSize      int               // Merge shared chunks smaller than this many bytes
+	MaxChunkSize      int               // Split shared chunks larger than this many bytes
+	HMR               bool              // Allow modules to be replaced at run-time using "import.meta.hot"
+	Federation        *Federation       // Share modules with other builds on the same page
 	Outfile           string
 	Metafile          bool
 	Outdir            string
 	Outbase           string
 	AbsWorkingDir     string
 	Platform          Platform
+	CustomPlatform    *CustomPlatform // Overrides "Platform" if present
 	Format            Format
 	External          []string
+	NodeBuiltins      map[string]NodeBuiltinPolicy // The key "*" applies to all other builtin modules
 	MainFields        []string
 	Conditions        []string // For the "exports" field in "package.json"
 	Loader            map[string]Loader
@@ -310,6 +369,7 @@ type BuildOptions struct {
 	Write          bool
 	AllowOverwrite bool
 	Incremental    bool
+	ResolverCache  *ResolverCache
 	Plugins        []Plugin
 
 	Watch *WatchMode
@@ -324,6 +384,18 @@ type WatchMode struct {
 	OnRebuild func(BuildResult)
 }
 
+// This can be shared between builds to avoid re-reading directories and re-
+// parsing "package.json" and "tsconfig.json" files that haven't changed since
+// the previous build. Incremental builds automatically get their own cache.
+// A cache can be used by multiple builds at the same time.
+type ResolverCache struct {
+	shared *resolver.SharedCache
+}
+
+func NewResolverCache() *ResolverCache {
+	return &ResolverCache{shared: resolver.NewSharedCache()}
+}
+
 type StdinOptions struct {
 	Contents   string
 	ResolveDir string
@@ -340,6 +412,9 @@ type BuildResult struct {
 
 	Rebuild func() BuildResult // Only when "Incremental: true"
 	Stop    func()             // Only when "Watch: true"
+
+	// The paths of the output files that are hot module replacement updates
+	hmrUpdatePaths map[string]bool
 }
 
 type OutputFile struct {
@@ -376,9 +451,11 @@ type TransformOptions struct {
 	IgnoreAnnotations bool
 	LegalComments     LegalComments
 
-	JSXMode     JSXMode
-	JSXFactory  string
-	JSXFragment string
+	JSXMode         JSXMode
+	JSXFactory      string
+	JSXFragment     string
+	JSXImportSource string
+	JSXDev          bool
 
 	TsconfigRaw string
 	Footer      string
@@ -404,6 +481,104 @@ func Transform(input string, options TransformOptions) TransformResult {
 	return transformImpl(input, options)
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Resolve API
+
+type ResolveOptions struct {
+	Color    StderrColor
+	LogLimit int
+	LogLevel LogLevel
+
+	ResolveDir string      // Defaults to the current working directory
+	Kind       ResolveKind // Determines which "exports" conditions apply
+
+	PreserveSymlinks  bool
+	AbsWorkingDir     string
+	Platform          Platform
+	CustomPlatform    *CustomPlatform // Overrides "Platform" if present
+	External          []string
+	NodeBuiltins      map[string]NodeBuiltinPolicy // The key "*" applies to all other builtin modules
+	MainFields        []string
+	Conditions        []string // For the "exports" field in "package.json"
+	Loader            map[string]Loader
+	ResolveExtensions []string
+	Tsconfig          string
+	NodePaths         []string // The "NODE_PATH" variable from Node.js
+
+	FS FsLike
+}
+
+type ResolveResult struct {
+	Errors   []Message
+	Warnings []Message
+
+	// The final path after symlinks have been resolved. This is empty if the
+	// import path could not be resolved.
+	Path      string
+	Namespace string
+	External  bool
+	Disabled  bool // Disabled via the "browser" field in "package.json"
+
+	// If the "module" field in "package.json" was used, this is the path that
+	// a "require()" call to the same import path would have resolved to
+	SecondaryPath string
+
+	// Every file and directory that was checked, in the order it was checked
+	Candidates []ResolveCandidate
+
+	// The chain of rules that produced the final path, outermost rule first
+	Rules []ResolveRule
+
+	// Rules that matched the import path but that were not used, and why
+	Rejected []ResolveRejection
+}
+
+type ResolveCandidate struct {
+	Path        string
+	IsDirectory bool
+	Exists      bool
+}
+
+type ResolveRuleKind uint8
+
+const (
+	ResolveRuleExternal        ResolveRuleKind = iota
+	ResolveRuleDataURL                         // A "data:" URL
+	ResolveRuleTSConfigPaths                   // "paths" in "tsconfig.json"
+	ResolveRuleTSConfigBaseURL                 // "baseUrl" in "tsconfig.json"
+	ResolveRuleBrowserMap                      // "browser" in "package.json"
+	ResolveRuleExportsMap                      // "exports" in "package.json"
+	ResolveRuleMainField                       // "main", "module", etc. in "package.json"
+	ResolveRuleNodeModules                     // A "node_modules" directory
+	ResolveRuleNodePath                        // A directory in "NODE_PATH"
+	ResolveRuleNodeBuiltin                     // A policy in "NodeBuiltins"
+)
+
+type ResolveRule struct {
+	Kind ResolveRuleKind
+
+	// The "package.json" file, "tsconfig.json" file, or directory that this
+	// rule comes from. This is empty for external paths and data URLs.
+	Source string
+
+	// What the rule matched and what it maps to. For example, the main field
+	// "module" and its value "./dist/index.mjs".
+	Key   string
+	Value string
+}
+
+type ResolveRejection struct {
+	Rule   ResolveRule
+	Reason string
+}
+
+// This runs esbuild's path resolution algorithm on a single import path and
+// explains the result. It's intended for tools that want to answer "why did
+// this import resolve to this file?". Plugins are not run.
+func Resolve(importPath string, options ResolveOptions) ResolveResult {
+	return resolveImpl(importPath, options)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Serve API
 
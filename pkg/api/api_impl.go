@@ -106,6 +106,109 @@ func validatePlatform(value Platform) config.Platform {
 	}
 }
 
+// This returns the base platform and the defines with the custom platform's
+// defines filled in as defaults
+func validateCustomPlatform(
+	log logger.Log, platform Platform, defines map[string]string, custom *CustomPlatform,
+) (Platform, map[string]string, *config.CustomPlatform) {
+	if custom == nil {
+		return platform, defines, nil
+	}
+	if custom.Name == "" {
+		log.AddError(nil, logger.Loc{}, "Custom platforms must have a name")
+	}
+
+	// Entries in "Define" take precedence over the platform's defines
+	if len(custom.Define) > 0 {
+		merged := make(map[string]string, len(custom.Define)+len(defines))
+		for key, value := range custom.Define {
+			merged[key] = value
+		}
+		for key, value := range defines {
+			merged[key] = value
+		}
+		defines = merged
+	}
+
+	builtinModules := make(map[string]bool, len(custom.BuiltinModules))
+	for _, name := range custom.BuiltinModules {
+		builtinModules[strings.TrimPrefix(name, "node:")] = true
+	}
+
+	// Make sure "MainFields" isn't nil so it doesn't inherit the defaults of the
+	// base platform
+	mainFields := append([]string{}, custom.MainFields...)
+
+	return custom.Base, defines, &config.CustomPlatform{
+		Name:           custom.Name,
+		Conditions:     append([]string{}, custom.Conditions...),
+		MainFields:     mainFields,
+		BuiltinModules: builtinModules,
+		BrowserField:   custom.BrowserField,
+	}
+}
+
+func validateNodeBuiltinMode(value NodeBuiltinMode) config.NodeBuiltinMode {
+	switch value {
+	case NodeBuiltinDefault:
+		return config.NodeBuiltinDefault
+	case NodeBuiltinExternal:
+		return config.NodeBuiltinExternal
+	case NodeBuiltinError:
+		return config.NodeBuiltinError
+	case NodeBuiltinEmpty:
+		return config.NodeBuiltinEmpty
+	case NodeBuiltinPolyfill:
+		return config.NodeBuiltinPolyfill
+	default:
+		panic("Invalid node builtin mode")
+	}
+}
+
+func validateNodeBuiltins(log logger.Log, builtins map[string]NodeBuiltinPolicy) map[string]config.NodeBuiltinPolicy {
+	if len(builtins) == 0 {
+		return nil
+	}
+	result := make(map[string]config.NodeBuiltinPolicy)
+
+	// The "*" entry applies to every builtin module without its own entry. Any
+	// builtin module without a well-known polyfill keeps the default behavior.
+	if policy, ok := builtins["*"]; ok {
+		mode := validateNodeBuiltinMode(policy.Mode)
+		for name := range resolver.BuiltInNodeModules {
+			polyfill := policy.Polyfill
+			if mode == config.NodeBuiltinPolyfill && polyfill == "" {
+				if polyfill = resolver.BuiltInNodeModulePolyfills[name]; polyfill == "" {
+					continue
+				}
+			}
+			result[name] = config.NodeBuiltinPolicy{Mode: mode, Polyfill: polyfill}
+		}
+	}
+
+	for key, policy := range builtins {
+		if key == "*" {
+			continue
+		}
+		name := strings.TrimPrefix(key, "node:")
+		if !resolver.BuiltInNodeModules[name] {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("%q is not a builtin node module", key))
+			continue
+		}
+		mode := validateNodeBuiltinMode(policy.Mode)
+		polyfill := policy.Polyfill
+		if mode == config.NodeBuiltinPolyfill && polyfill == "" {
+			if polyfill = resolver.BuiltInNodeModulePolyfills[name]; polyfill == "" {
+				log.AddError(nil, logger.Loc{}, fmt.Sprintf(
+					"There is no known polyfill for the builtin node module %q, so a polyfill package must be specified", name))
+				continue
+			}
+		}
+		result[name] = config.NodeBuiltinPolicy{Mode: mode, Polyfill: polyfill}
+	}
+	return result
+}
+
 func validateFormat(value Format) config.Format {
 	switch value {
 	case FormatDefault:
@@ -116,6 +219,10 @@ func validateFormat(value Format) config.Format {
 		return config.FormatCommonJS
 	case FormatESModule:
 		return config.FormatESModule
+	case FormatUMD:
+		return config.FormatUMD
+	case FormatSystem:
+		return config.FormatSystem
 	default:
 		panic("Invalid format")
 	}
@@ -213,7 +320,7 @@ func validateTreeShaking(value TreeShaking, bundle bool, format Format) bool {
 		// code to the bundle, you should be doing that by including it in the
 		// bundle instead of concatenating it afterward, so we also assume tree
 		// shaking is safe then. Otherwise we assume tree shaking is not safe.
-		return bundle || format == FormatIIFE
+		return bundle || format == FormatIIFE || format == FormatUMD || format == FormatSystem
 	case TreeShakingFalse:
 		return false
 	case TreeShakingTrue:
@@ -381,6 +488,29 @@ func validateExternals(log logger.Log, fs fs.FS, paths []string) config.External
 		AbsPaths:    make(map[string]bool),
 	}
 	for _, path := range paths {
+		// "react=window.React" means "react" is loaded from a global variable
+		if equals := strings.IndexByte(path, '='); equals != -1 {
+			name, global := path[:equals], path[equals+1:]
+			if !resolver.IsPackagePath(name) || strings.ContainsRune(name, '*') {
+				log.AddError(nil, logger.Loc{}, fmt.Sprintf("Only package names can be mapped to a global: %q", path))
+				continue
+			}
+			if global == "" {
+				log.AddError(nil, logger.Loc{}, fmt.Sprintf("Missing global for external package %q", name))
+				continue
+			}
+			globalName := validateGlobalName(log, global)
+			if globalName == nil {
+				continue // The error was already logged
+			}
+			if result.Globals == nil {
+				result.Globals = make(map[string][]string)
+			}
+			result.Globals[name] = globalName
+			result.NodeModules[name] = true
+			continue
+		}
+
 		if index := strings.IndexByte(path, '*'); index != -1 {
 			if strings.ContainsRune(path[index+1:], '*') {
 				log.AddError(nil, logger.Loc{}, fmt.Sprintf("External path %q cannot have more than one \"*\" wildcard", path))
@@ -399,6 +529,93 @@ func validateExternals(log logger.Log, fs fs.FS, paths []string) config.External
 	return result
 }
 
+// Exposed module names must look like relative paths because importing
+// "name/path" from another build reads the module exposed as "./path"
+func validateFederation(log logger.Log, fs fs.FS, federation *Federation) *config.Federation {
+	if federation == nil {
+		return nil
+	}
+	if federation.Name == "" && len(federation.Exposes) > 0 {
+		log.AddError(nil, logger.Loc{}, "Must specify a container name when exposing modules")
+	}
+	exposes := make(map[string]string, len(federation.Exposes))
+	for name, path := range federation.Exposes {
+		if name != "." && !strings.HasPrefix(name, "./") {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid exposed module name %q (must be \".\" or start with \"./\")", name))
+			continue
+		}
+		exposes[name] = validatePath(log, fs, path, "exposed module path")
+	}
+	for name := range federation.Remotes {
+		if name == "" || strings.ContainsRune(name, '/') {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid remote container name: %q", name))
+		}
+	}
+	return &config.Federation{
+		Name:    federation.Name,
+		Exposes: exposes,
+		Remotes: federation.Remotes,
+		Shared:  federation.Shared,
+	}
+}
+
+// Patterns that start with "./", "../", or "/" match file paths and all other
+// patterns match package names. Both kinds may contain a single "*" wildcard.
+// Path patterns are checked before package patterns, and longer patterns are
+// checked before shorter ones so that more specific patterns take precedence.
+func validateManualChunks(log logger.Log, fs fs.FS, manualChunks map[string]string) []config.ManualChunkPattern {
+	if len(manualChunks) == 0 {
+		return nil
+	}
+	result := make([]config.ManualChunkPattern, 0, len(manualChunks))
+	for pattern, name := range manualChunks {
+		if name == "" || strings.ContainsAny(name, "/\\") {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid manual chunk name: %q", name))
+			continue
+		}
+		prefix, suffix := pattern, ""
+		if index := strings.IndexByte(pattern, '*'); index != -1 {
+			if strings.ContainsRune(pattern[index+1:], '*') {
+				log.AddError(nil, logger.Loc{}, fmt.Sprintf("Manual chunk pattern %q cannot have more than one \"*\" wildcard", pattern))
+				continue
+			}
+			prefix, suffix = pattern[:index], pattern[index+1:]
+		}
+		isPackage := resolver.IsPackagePath(pattern)
+		if !isPackage {
+			// Make the part before the wildcard absolute, but keep a trailing slash
+			// so that "./src/*" doesn't also match "./src-other/file.js"
+			absPrefix := validatePath(log, fs, prefix, "manual chunk pattern")
+			if absPrefix == "" {
+				continue
+			}
+			if strings.HasSuffix(prefix, "/") && !strings.HasSuffix(absPrefix, "/") {
+				absPrefix += "/"
+			}
+			prefix = absPrefix
+		}
+		result = append(result, config.ManualChunkPattern{
+			WildcardPattern: config.WildcardPattern{Prefix: prefix, Suffix: suffix},
+			ChunkName:       name,
+			IsPackage:       isPackage,
+		})
+	}
+	sort.SliceStable(result, func(i int, j int) bool {
+		a, b := result[i], result[j]
+		if a.IsPackage != b.IsPackage {
+			return !a.IsPackage
+		}
+		if aLen, bLen := len(a.Prefix)+len(a.Suffix), len(b.Prefix)+len(b.Suffix); aLen != bLen {
+			return aLen > bLen
+		}
+		if a.Prefix != b.Prefix {
+			return a.Prefix < b.Prefix
+		}
+		return a.Suffix < b.Suffix
+	})
+	return result
+}
+
 func isValidExtension(ext string) bool {
 	return len(ext) >= 2 && ext[0] == '.' && ext[len(ext)-1] != '.'
 }
@@ -733,7 +950,16 @@ func buildImpl(buildOpts BuildOptions) internalBuildResult {
 		panic("Mutating \"AbsWorkingDir\" is not allowed")
 	}
 
-	internalResult := rebuildImpl(buildOpts, cache.MakeCacheSet(), plugins, onEndCallbacks, logOptions, log, false /* isRebuild */)
+	// Rebuilds always share directory information with previous builds
+	if buildOpts.Incremental && buildOpts.ResolverCache == nil {
+		buildOpts.ResolverCache = NewResolverCache()
+	}
+
+	// Rebuilds triggered by watch mode and rebuilds triggered by calling
+	// "rebuild()" share the same caches, so they must not run at the same time
+	rebuildMutex := &sync.Mutex{}
+
+	internalResult := rebuildImpl(buildOpts, cache.MakeCacheSet(), rebuildMutex, plugins, onEndCallbacks, logOptions, log, false /* isRebuild */)
 
 	// Print a summary of the generated files to stderr. Except don't do
 	// this if the terminal is already being used for something else.
@@ -799,6 +1025,7 @@ func printSummary(logOptions logger.OutputOptions, outputFiles []OutputFile, sta
 func rebuildImpl(
 	buildOpts BuildOptions,
 	caches *cache.CacheSet,
+	rebuildMutex *sync.Mutex,
 	plugins []config.Plugin,
 	onEndCallbacks []func(*BuildResult),
 	logOptions logger.OutputOptions,
@@ -827,20 +1054,25 @@ func rebuildImpl(
 	bannerJS, bannerCSS := validateBannerOrFooter(log, "banner", buildOpts.Banner)
 	footerJS, footerCSS := validateBannerOrFooter(log, "footer", buildOpts.Footer)
 	minify := buildOpts.MinifyWhitespace && buildOpts.MinifyIdentifiers && buildOpts.MinifySyntax
-	defines, injectedDefines := validateDefines(log, buildOpts.Define, buildOpts.Pure, buildOpts.Platform, minify)
+	platform, define, customPlatform := validateCustomPlatform(log, buildOpts.Platform, buildOpts.Define, buildOpts.CustomPlatform)
+	defines, injectedDefines := validateDefines(log, define, buildOpts.Pure, platform, minify)
 	options := config.Options{
 		IsTargetUnconfigured:   isTargetUnconfigured,
 		UnsupportedJSFeatures:  jsFeatures,
 		UnsupportedCSSFeatures: cssFeatures,
 		OriginalTargetEnv:      targetEnv,
 		JSX: config.JSXOptions{
-			Preserve: buildOpts.JSXMode == JSXModePreserve,
-			Factory:  validateJSXExpr(log, buildOpts.JSXFactory, "factory", js_parser.JSXFactory),
-			Fragment: validateJSXExpr(log, buildOpts.JSXFragment, "fragment", js_parser.JSXFragment),
+			Preserve:         buildOpts.JSXMode == JSXModePreserve,
+			AutomaticRuntime: buildOpts.JSXMode == JSXModeAutomatic,
+			Factory:          validateJSXExpr(log, buildOpts.JSXFactory, "factory", js_parser.JSXFactory),
+			Fragment:         validateJSXExpr(log, buildOpts.JSXFragment, "fragment", js_parser.JSXFragment),
+			ImportSource:     buildOpts.JSXImportSource,
+			Development:      buildOpts.JSXDev,
 		},
 		Defines:               defines,
 		InjectedDefines:       injectedDefines,
-		Platform:              validatePlatform(buildOpts.Platform),
+		Platform:              validatePlatform(platform),
+		CustomPlatform:        customPlatform,
 		SourceMap:             validateSourceMap(buildOpts.Sourcemap),
 		LegalComments:         validateLegalComments(buildOpts.LegalComments, buildOpts.Bundle),
 		SourceRoot:            buildOpts.SourceRoot,
@@ -849,6 +1081,7 @@ func rebuildImpl(
 		RemoveWhitespace:      buildOpts.MinifyWhitespace,
 		MinifyIdentifiers:     buildOpts.MinifyIdentifiers,
 		AllowOverwrite:        buildOpts.AllowOverwrite,
+		DedupePackages:        buildOpts.DedupePackages,
 		ASCIIOnly:             validateASCIIOnly(buildOpts.Charset),
 		IgnoreDCEAnnotations:  buildOpts.IgnoreAnnotations,
 		TreeShaking:           validateTreeShaking(buildOpts.TreeShaking, buildOpts.Bundle, buildOpts.Format),
@@ -867,6 +1100,12 @@ func rebuildImpl(
 		ExtensionToLoader:     validateLoaders(log, buildOpts.Loader),
 		ExtensionOrder:        validateResolveExtensions(log, buildOpts.ResolveExtensions),
 		ExternalModules:       validateExternals(log, realFS, buildOpts.External),
+		NodeBuiltins:          validateNodeBuiltins(log, buildOpts.NodeBuiltins),
+		ManualChunks:          validateManualChunks(log, realFS, buildOpts.ManualChunks),
+		MinChunkSize:          buildOpts.MinChunkSize,
+		MaxChunkSize:          buildOpts.MaxChunkSize,
+		HotModuleReplacement:  buildOpts.HMR,
+		Federation:            validateFederation(log, realFS, buildOpts.Federation),
 		TsConfigOverride:      validatePath(log, realFS, buildOpts.Tsconfig, "tsconfig path"),
 		MainFields:            buildOpts.MainFields,
 		Conditions:            append([]string{}, buildOpts.Conditions...),
@@ -930,6 +1169,9 @@ func rebuildImpl(
 		if options.LegalComments.HasExternalFile() {
 			log.AddError(nil, logger.Loc{}, "Cannot use linked or external legal comments without an output path")
 		}
+		if options.HotModuleReplacement {
+			log.AddError(nil, logger.Loc{}, "Cannot use \"hmr\" without an output path")
+		}
 		for _, loader := range options.ExtensionToLoader {
 			if loader == config.LoaderFile {
 				log.AddError(nil, logger.Loc{}, "Cannot use the \"file\" loader without an output path")
@@ -966,17 +1208,64 @@ func rebuildImpl(
 		options.Mode = config.ModeConvertFormat
 	}
 
-	// Code splitting is experimental and currently only enabled for ES6 modules
-	if options.CodeSplitting && options.OutputFormat != config.FormatESModule {
-		log.AddError(nil, logger.Loc{}, "Splitting currently only works with the \"esm\" format")
+	// Code splitting is experimental and currently only enabled for formats
+	// that can load other chunks (the "iife" format uses a chunk registry)
+	if options.CodeSplitting && options.OutputFormat != config.FormatESModule &&
+		options.OutputFormat != config.FormatCommonJS && options.OutputFormat != config.FormatIIFE {
+		log.AddError(nil, logger.Loc{}, "Splitting currently only works with the \"esm\", \"cjs\", and \"iife\" formats")
+	}
+	if len(options.ManualChunks) > 0 && !options.CodeSplitting {
+		log.AddError(nil, logger.Loc{}, "Cannot use \"manualChunks\" without code splitting")
+	}
+	if options.MinChunkSize < 0 {
+		log.AddError(nil, logger.Loc{}, "Invalid minimum chunk size: must not be negative")
+	} else if options.MinChunkSize > 0 && !options.CodeSplitting {
+		log.AddError(nil, logger.Loc{}, "Cannot use \"minChunkSize\" without code splitting")
+	}
+	if options.MaxChunkSize < 0 {
+		log.AddError(nil, logger.Loc{}, "Invalid maximum chunk size: must not be negative")
+	} else if options.MaxChunkSize > 0 && !options.CodeSplitting {
+		log.AddError(nil, logger.Loc{}, "Cannot use \"maxChunkSize\" without code splitting")
+	} else if options.MaxChunkSize > 0 && options.MaxChunkSize < options.MinChunkSize {
+		log.AddError(nil, logger.Loc{}, "Cannot use a \"maxChunkSize\" that is smaller than \"minChunkSize\"")
+	}
+
+	// Hot module replacement relies on a global registry of modules that is
+	// shared between the bundle and the updates, which are loaded as scripts
+	if options.HotModuleReplacement {
+		if !buildOpts.Bundle {
+			log.AddError(nil, logger.Loc{}, "Cannot use \"hmr\" without \"bundle\"")
+		} else if options.OutputFormat != config.FormatESModule && options.OutputFormat != config.FormatIIFE {
+			log.AddError(nil, logger.Loc{}, "Hot module replacement currently only works with the \"esm\" and \"iife\" formats")
+		} else if options.CodeSplitting {
+			log.AddError(nil, logger.Loc{}, "Cannot use \"hmr\" with code splitting")
+		}
+	}
+
+	// Module federation reads containers from a global registry, and the
+	// container entry is written to the output directory next to the other files
+	if options.Federation != nil {
+		if !buildOpts.Bundle {
+			log.AddError(nil, logger.Loc{}, "Cannot use \"federation\" without \"bundle\"")
+		} else if options.OutputFormat != config.FormatIIFE {
+			log.AddError(nil, logger.Loc{}, "Module federation currently only works with the \"iife\" format")
+		} else if len(options.Federation.Exposes) > 0 && (options.AbsOutputFile != "" || options.WriteToStdout) {
+			log.AddError(nil, logger.Loc{}, "Must use \"outdir\" when exposing modules")
+		}
 	}
 
 	var outputFiles []OutputFile
+	var hmrUpdatePaths map[string]bool
 	var metafileJSON string
 	var watchData fs.WatchData
 
+	var sharedCache *resolver.SharedCache
+	if buildOpts.ResolverCache != nil {
+		sharedCache = buildOpts.ResolverCache.shared
+	}
+
 	// Stop now if there were errors
-	resolver := resolver.NewResolver(realFS, log, caches, options)
+	resolver := resolver.NewResolver(realFS, log, caches, options, sharedCache)
 	if !log.HasErrors() {
 		var timer *helpers.Timer
 		if api_helpers.UseTimer {
@@ -1049,6 +1338,12 @@ func rebuildImpl(
 						Path:     result.AbsPath,
 						Contents: result.Contents,
 					}
+					if result.IsHMRUpdate {
+						if hmrUpdatePaths == nil {
+							hmrUpdatePaths = make(map[string]bool)
+						}
+						hmrUpdatePaths[result.AbsPath] = true
+					}
 				}
 			}
 		}
@@ -1068,7 +1363,9 @@ func rebuildImpl(
 			data:     watchData,
 			resolver: resolver,
 			rebuild: func() fs.WatchData {
-				value := rebuildImpl(buildOpts, caches, plugins, onEndCallbacks, logOptions, logger.NewStderrLog(logOptions), true /* isRebuild */)
+				rebuildMutex.Lock()
+				value := rebuildImpl(buildOpts, caches, rebuildMutex, plugins, onEndCallbacks, logOptions, logger.NewStderrLog(logOptions), true /* isRebuild */)
+				rebuildMutex.Unlock()
 				if onRebuild != nil {
 					go onRebuild(value.result)
 				}
@@ -1085,7 +1382,9 @@ func rebuildImpl(
 	var rebuild func() BuildResult
 	if buildOpts.Incremental {
 		rebuild = func() BuildResult {
-			value := rebuildImpl(buildOpts, caches, plugins, onEndCallbacks, logOptions, logger.NewStderrLog(logOptions), true /* isRebuild */)
+			rebuildMutex.Lock()
+			value := rebuildImpl(buildOpts, caches, rebuildMutex, plugins, onEndCallbacks, logOptions, logger.NewStderrLog(logOptions), true /* isRebuild */)
+			rebuildMutex.Unlock()
 			if watch != nil {
 				watch.setWatchData(value.watchData)
 			}
@@ -1100,6 +1399,8 @@ func rebuildImpl(
 		Metafile:    metafileJSON,
 		Rebuild:     rebuild,
 		Stop:        stop,
+
+		hmrUpdatePaths: hmrUpdatePaths,
 	}
 
 	for _, onEnd := range onEndCallbacks {
@@ -1272,10 +1573,15 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 	// Settings from the user come first
 	preserveUnusedImportsTS := false
 	useDefineForClassFieldsTS := config.Unspecified
+	experimentalDecoratorsTS := config.Unspecified
+	emitDecoratorMetadataTS := false
 	jsx := config.JSXOptions{
-		Preserve: transformOpts.JSXMode == JSXModePreserve,
-		Factory:  validateJSXExpr(log, transformOpts.JSXFactory, "factory", js_parser.JSXFactory),
-		Fragment: validateJSXExpr(log, transformOpts.JSXFragment, "fragment", js_parser.JSXFragment),
+		Preserve:         transformOpts.JSXMode == JSXModePreserve,
+		AutomaticRuntime: transformOpts.JSXMode == JSXModeAutomatic,
+		Factory:          validateJSXExpr(log, transformOpts.JSXFactory, "factory", js_parser.JSXFactory),
+		Fragment:         validateJSXExpr(log, transformOpts.JSXFragment, "fragment", js_parser.JSXFragment),
+		ImportSource:     transformOpts.JSXImportSource,
+		Development:      transformOpts.JSXDev,
 	}
 
 	// Settings from "tsconfig.json" override those
@@ -1288,15 +1594,27 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 			Contents:   transformOpts.TsconfigRaw,
 		}
 		if result := resolver.ParseTSConfigJSON(log, source, &caches.JSONCache, nil); result != nil {
+			if result.JSX != config.TSJSXNone {
+				jsx.SetOptionsFromTSJSX(result.JSX)
+			}
 			if len(result.JSXFactory) > 0 {
 				jsx.Factory = config.JSXExpr{Parts: result.JSXFactory}
 			}
 			if len(result.JSXFragmentFactory) > 0 {
 				jsx.Fragment = config.JSXExpr{Parts: result.JSXFragmentFactory}
 			}
+			if result.JSXImportSource != "" {
+				jsx.ImportSource = result.JSXImportSource
+			}
 			if result.UseDefineForClassFields != config.Unspecified {
 				useDefineForClassFieldsTS = result.UseDefineForClassFields
 			}
+			if result.ExperimentalDecorators != config.Unspecified {
+				experimentalDecoratorsTS = result.ExperimentalDecorators
+			}
+			if result.EmitDecoratorMetadata {
+				emitDecoratorMetadataTS = true
+			}
 			if result.PreserveImportsNotUsedAsValues {
 				preserveUnusedImportsTS = true
 			}
@@ -1339,6 +1657,8 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		AbsOutputFile:           transformOpts.Sourcefile + "-out",
 		KeepNames:               transformOpts.KeepNames,
 		UseDefineForClassFields: useDefineForClassFieldsTS,
+		ExperimentalDecorators:  experimentalDecoratorsTS,
+		EmitDecoratorMetadata:   emitDecoratorMetadataTS,
 		PreserveUnusedImportsTS: preserveUnusedImportsTS,
 		Stdin: &config.StdinInfo{
 			Loader:     validateLoader(transformOpts.Loader),
@@ -1381,7 +1701,7 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 
 		// Scan over the bundle
 		mockFS := fs.MockFS(make(map[string]string))
-		resolver := resolver.NewResolver(mockFS, log, caches, options)
+		resolver := resolver.NewResolver(mockFS, log, caches, options, nil)
 		bundle := bundler.ScanBundle(log, mockFS, resolver, caches, nil, options, timer)
 
 		// Stop now if there were errors
@@ -1418,6 +1738,159 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Resolve API
+
+func validateResolveKind(value ResolveKind) ast.ImportKind {
+	switch value {
+	case ResolveEntryPoint:
+		return ast.ImportEntryPoint
+	case ResolveJSImportStatement:
+		return ast.ImportStmt
+	case ResolveJSRequireCall:
+		return ast.ImportRequire
+	case ResolveJSDynamicImport:
+		return ast.ImportDynamic
+	case ResolveJSRequireResolve:
+		return ast.ImportRequireResolve
+	case ResolveCSSImportRule:
+		return ast.ImportAt
+	case ResolveCSSURLToken:
+		return ast.ImportURL
+	default:
+		panic("Invalid resolve kind")
+	}
+}
+
+func convertTraceRuleToPublic(rule resolver.TraceRule) ResolveRule {
+	var kind ResolveRuleKind
+	switch rule.Kind {
+	case resolver.TraceRuleExternal:
+		kind = ResolveRuleExternal
+	case resolver.TraceRuleDataURL:
+		kind = ResolveRuleDataURL
+	case resolver.TraceRuleTSConfigPaths:
+		kind = ResolveRuleTSConfigPaths
+	case resolver.TraceRuleTSConfigBaseURL:
+		kind = ResolveRuleTSConfigBaseURL
+	case resolver.TraceRuleBrowserMap:
+		kind = ResolveRuleBrowserMap
+	case resolver.TraceRuleExportsMap:
+		kind = ResolveRuleExportsMap
+	case resolver.TraceRuleMainField:
+		kind = ResolveRuleMainField
+	case resolver.TraceRuleNodeModules:
+		kind = ResolveRuleNodeModules
+	case resolver.TraceRuleNodePath:
+		kind = ResolveRuleNodePath
+	case resolver.TraceRuleNodeBuiltin:
+		kind = ResolveRuleNodeBuiltin
+	default:
+		panic("Internal error")
+	}
+	return ResolveRule{
+		Kind:   kind,
+		Source: rule.Source,
+		Key:    rule.Key,
+		Value:  rule.Value,
+	}
+}
+
+func resolveImpl(importPath string, resolveOpts ResolveOptions) ResolveResult {
+	log := logger.NewStderrLog(logger.OutputOptions{
+		IncludeSource: true,
+		MessageLimit:  resolveOpts.LogLimit,
+		Color:         validateColor(resolveOpts.Color),
+		LogLevel:      validateLogLevel(resolveOpts.LogLevel),
+	})
+
+	var realFS fs.FS
+	if resolveOpts.FS != nil {
+		realFS = fs.NewIntfFS(resolveOpts.FS)
+	} else {
+		var err error
+		realFS, err = fs.RealFS(fs.RealFSOptions{
+			AbsWorkingDir: resolveOpts.AbsWorkingDir,
+		})
+		if err != nil {
+			log.AddError(nil, logger.Loc{}, err.Error())
+			return ResolveResult{Errors: convertMessagesToPublic(logger.Error, log.Done())}
+		}
+	}
+
+	// Convert and validate the resolveOpts
+	platform, _, customPlatform := validateCustomPlatform(log, resolveOpts.Platform, nil, resolveOpts.CustomPlatform)
+	options := config.Options{
+		Platform:          validatePlatform(platform),
+		CustomPlatform:    customPlatform,
+		ExtensionToLoader: validateLoaders(log, resolveOpts.Loader),
+		ExtensionOrder:    validateResolveExtensions(log, resolveOpts.ResolveExtensions),
+		ExternalModules:   validateExternals(log, realFS, resolveOpts.External),
+		NodeBuiltins:      validateNodeBuiltins(log, resolveOpts.NodeBuiltins),
+		TsConfigOverride:  validatePath(log, realFS, resolveOpts.Tsconfig, "tsconfig path"),
+		MainFields:        resolveOpts.MainFields,
+		Conditions:        append([]string{}, resolveOpts.Conditions...),
+		PreserveSymlinks:  resolveOpts.PreserveSymlinks,
+		AbsNodePaths:      make([]string, len(resolveOpts.NodePaths)),
+	}
+	if options.MainFields != nil {
+		options.MainFields = append([]string{}, options.MainFields...)
+	}
+	for i, path := range resolveOpts.NodePaths {
+		options.AbsNodePaths[i] = validatePath(log, realFS, path, "node path")
+	}
+	resolveDir := realFS.Cwd()
+	if resolveOpts.ResolveDir != "" {
+		resolveDir = validatePath(log, realFS, resolveOpts.ResolveDir, "resolve directory path")
+	}
+	kind := validateResolveKind(resolveOpts.Kind)
+
+	var result ResolveResult
+
+	// Stop now if there were errors
+	if !log.HasErrors() {
+		r := resolver.NewResolver(realFS, log, cache.MakeCacheSet(), options, nil)
+		resolved, debug, trace := r.ResolveWithTrace(resolveDir, importPath, kind)
+
+		if resolved != nil && resolved.IsDisallowedBuiltin {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Importing the builtin node module %q is not allowed",
+				strings.TrimPrefix(importPath, "node:")))
+		} else if resolved != nil {
+			result.Path = resolved.PathPair.Primary.Text
+			result.Namespace = resolved.PathPair.Primary.Namespace
+			result.External = resolved.IsExternal
+			result.Disabled = resolved.PathPair.Primary.IsDisabled()
+			if resolved.PathPair.HasSecondary() {
+				result.SecondaryPath = resolved.PathPair.Secondary.Text
+			}
+		} else {
+			debug.LogErrorMsg(log, nil, logger.Range{}, fmt.Sprintf("Could not resolve %q", importPath))
+		}
+
+		for _, candidate := range trace.Candidates {
+			result.Candidates = append(result.Candidates, ResolveCandidate{
+				Path:        candidate.Path,
+				IsDirectory: candidate.IsDirectory,
+				Exists:      candidate.Exists,
+			})
+		}
+		for _, rule := range trace.Rules {
+			result.Rules = append(result.Rules, convertTraceRuleToPublic(rule))
+		}
+		for _, rejection := range trace.Rejected {
+			result.Rejected = append(result.Rejected, ResolveRejection{
+				Rule:   convertTraceRuleToPublic(rejection.Rule),
+				Reason: rejection.Reason,
+			})
+		}
+	}
+
+	msgs := log.Done()
+	result.Errors = convertMessagesToPublic(logger.Error, msgs)
+	result.Warnings = convertMessagesToPublic(logger.Warning, msgs)
+	return result
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Plugin API
 
@@ -35,6 +35,11 @@ type apiHandler struct {
 	fs               fs.FS
 	serveWaitGroup   sync.WaitGroup
 	serveError       error
+
+	// Hot module replacement updates are sent to each connected client. This is
+	// nil if hot module replacement is disabled.
+	hmrClients map[chan []byte]bool
+	stopWatch  func()
 }
 
 type runningBuild struct {
@@ -57,6 +62,7 @@ func (h *apiHandler) build() BuildResult {
 				h.rebuild = result.Rebuild
 				build.result = result
 				build.waitGroup.Done()
+				h.sendHMRUpdates(result)
 
 				// Build results stay valid for a little bit afterward since a page
 				// load may involve multiple requests and don't want to rebuild
@@ -114,9 +120,89 @@ func errorsToString(errors []Message) string {
 	return sb.String()
 }
 
+func (h *apiHandler) sendHMRUpdates(result BuildResult) {
+	if h.hmrClients == nil {
+		return
+	}
+	// Hot module replacement updates are generated as additional output files
+	// named after the output file they update (e.g. "out.hmr.js" for "out.js").
+	// Only the files that the build marked as updates are sent, since another
+	// output file may also happen to have a name like that.
+	for _, file := range result.OutputFiles {
+		if result.hmrUpdatePaths[file.Path] {
+			h.mutex.Lock()
+			for client := range h.hmrClients {
+				// Don't block the build on a client that isn't reading its updates
+				select {
+				case client <- file.Contents:
+				default:
+				}
+			}
+			h.mutex.Unlock()
+		}
+	}
+}
+
+// Send each update to the client as a server-sent event. The client is
+// expected to evaluate each update as a script:
+//
+//	new EventSource('/esbuild-hmr').onmessage = e => (0, eval)(e.data)
+func (h *apiHandler) serveHMREvents(res http.ResponseWriter, req *http.Request) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write([]byte("500 - Internal server error: Streaming is not supported"))
+		return
+	}
+
+	client := make(chan []byte, 16)
+	h.mutex.Lock()
+	h.hmrClients[client] = true
+	h.mutex.Unlock()
+	defer func() {
+		h.mutex.Lock()
+		delete(h.hmrClients, client)
+		h.mutex.Unlock()
+	}()
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case update := <-client:
+			// Each line of the update is sent as a separate "data" field
+			sb := strings.Builder{}
+			for _, line := range strings.Split(strings.TrimSuffix(string(update), "\n"), "\n") {
+				sb.WriteString("data: ")
+				sb.WriteString(strings.TrimSuffix(line, "\r"))
+				sb.WriteString("\n")
+			}
+			sb.WriteString("\n")
+			if _, err := res.Write([]byte(sb.String())); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
 func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	start := time.Now()
 
+	// Stream hot module replacement updates if requested
+	if req.Method == "GET" && req.URL.Path == "/esbuild-hmr" && h.hmrClients != nil {
+		res.Header().Set("Access-Control-Allow-Origin", "*")
+		go h.notifyRequest(time.Since(start), req, http.StatusOK)
+		h.serveHMREvents(res, req)
+		return
+	}
+
 	// Handle get requests
 	if req.Method == "GET" && strings.HasPrefix(req.URL.Path, "/") {
 		res.Header().Set("Access-Control-Allow-Origin", "*")
@@ -562,6 +648,21 @@ func serveImpl(serveOptions ServeOptions, buildOptions BuildOptions) (ServeResul
 
 	// The first build will just build normally
 	var handler *apiHandler
+
+	// Hot module replacement needs to know about changes as soon as they happen
+	// instead of on the next request, so also watch the file system. Updates
+	// are sent from whichever rebuild notices the change first. This is unlike
+	// combining "watch" with "serve" above because the watcher is internal. Its
+	// rebuilds and the rebuilds for requests never run at the same time since
+	// they share the same caches (see "rebuildImpl").
+	if buildOptions.HMR {
+		buildOptions.Watch = &WatchMode{
+			OnRebuild: func(result BuildResult) {
+				handler.sendHMRUpdates(result)
+			},
+		}
+	}
+
 	handler = &apiHandler{
 		onRequest:        serveOptions.OnRequest,
 		outdirPathPrefix: outdirPathPrefix,
@@ -578,11 +679,15 @@ func serveImpl(serveOptions ServeOptions, buildOptions BuildOptions) (ServeResul
 			build := buildImpl(buildOptions)
 			if handler.options == nil {
 				handler.options = &build.options
+				handler.stopWatch = build.result.Stop
 			}
 			return build.result
 		},
 		fs: realFS,
 	}
+	if buildOptions.HMR {
+		handler.hmrClients = make(map[chan []byte]bool)
+	}
 
 	// When wait is called, block until the server's call to "Serve()" returns
 	result.Wait = func() error {
@@ -604,6 +709,11 @@ func serveImpl(serveOptions ServeOptions, buildOptions BuildOptions) (ServeResul
 		}
 		isStopping = true
 
+		// Stop watching for hot module replacement
+		if handler.stopWatch != nil {
+			handler.stopWatch()
+		}
+
 		// Close the server and wait for it to close
 		server.Close()
 		handler.serveWaitGroup.Wait()
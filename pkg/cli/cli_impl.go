@@ -30,6 +30,14 @@ func newTransformOptions() api.TransformOptions {
 	}
 }
 
+// The "--federation-*" flags all configure the same set of options
+func ensureFederation(buildOpts *api.BuildOptions) *api.Federation {
+	if buildOpts.Federation == nil {
+		buildOpts.Federation = &api.Federation{}
+	}
+	return buildOpts.Federation
+}
+
 type parseOptionsKind uint8
 
 const (
@@ -54,9 +62,15 @@ func parseOptionsImpl(
 		case arg == "--preserve-symlinks" && buildOpts != nil:
 			buildOpts.PreserveSymlinks = true
 
+		case arg == "--dedupe-packages" && buildOpts != nil:
+			buildOpts.DedupePackages = true
+
 		case arg == "--splitting" && buildOpts != nil:
 			buildOpts.Splitting = true
 
+		case arg == "--hmr" && buildOpts != nil:
+			buildOpts.HMR = true
+
 		case arg == "--allow-overwrite" && buildOpts != nil:
 			buildOpts.AllowOverwrite = true
 
@@ -389,13 +403,122 @@ func parseOptionsImpl(
 				} else {
 					transformOpts.Format = api.FormatESModule
 				}
+			case "umd":
+				if buildOpts != nil {
+					buildOpts.Format = api.FormatUMD
+				} else {
+					transformOpts.Format = api.FormatUMD
+				}
+			case "system":
+				if buildOpts != nil {
+					buildOpts.Format = api.FormatSystem
+				} else {
+					transformOpts.Format = api.FormatSystem
+				}
 			default:
-				return fmt.Errorf("Invalid format: %q (valid: iife, cjs, esm)", value), nil
+				return fmt.Errorf("Invalid format: %q (valid: iife, cjs, esm, umd, system)", value), nil
 			}
 
 		case strings.HasPrefix(arg, "--external:") && buildOpts != nil:
 			buildOpts.External = append(buildOpts.External, arg[len("--external:"):])
 
+		case strings.HasPrefix(arg, "--federation-name=") && buildOpts != nil:
+			federation := ensureFederation(buildOpts)
+			federation.Name = arg[len("--federation-name="):]
+
+		case strings.HasPrefix(arg, "--federation-expose:") && buildOpts != nil:
+			value := arg[len("--federation-expose:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return fmt.Errorf("Missing \"=\": %q", value), nil
+			}
+			federation := ensureFederation(buildOpts)
+			if federation.Exposes == nil {
+				federation.Exposes = make(map[string]string)
+			}
+			federation.Exposes[value[:equals]] = value[equals+1:]
+
+		case strings.HasPrefix(arg, "--federation-remote:") && buildOpts != nil:
+			value := arg[len("--federation-remote:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return fmt.Errorf("Missing \"=\": %q", value), nil
+			}
+			federation := ensureFederation(buildOpts)
+			if federation.Remotes == nil {
+				federation.Remotes = make(map[string]string)
+			}
+			federation.Remotes[value[:equals]] = value[equals+1:]
+
+		case strings.HasPrefix(arg, "--federation-shared:") && buildOpts != nil:
+			value := arg[len("--federation-shared:"):]
+			versionRange := ""
+			if equals := strings.IndexByte(value, '='); equals != -1 {
+				value, versionRange = value[:equals], value[equals+1:]
+			}
+			federation := ensureFederation(buildOpts)
+			if federation.Shared == nil {
+				federation.Shared = make(map[string]string)
+			}
+			federation.Shared[value] = versionRange
+
+		case strings.HasPrefix(arg, "--manual-chunk:") && buildOpts != nil:
+			value := arg[len("--manual-chunk:"):]
+			equals := strings.LastIndexByte(value, '=')
+			if equals == -1 {
+				return fmt.Errorf("Missing \"=\": %q", value), nil
+			}
+			if buildOpts.ManualChunks == nil {
+				buildOpts.ManualChunks = make(map[string]string)
+			}
+			buildOpts.ManualChunks[value[:equals]] = value[equals+1:]
+
+		case strings.HasPrefix(arg, "--min-chunk-size=") && buildOpts != nil:
+			value := arg[len("--min-chunk-size="):]
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 0 {
+				return fmt.Errorf("Invalid minimum chunk size: %q", value), nil
+			}
+			buildOpts.MinChunkSize = size
+
+		case strings.HasPrefix(arg, "--max-chunk-size=") && buildOpts != nil:
+			value := arg[len("--max-chunk-size="):]
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 0 {
+				return fmt.Errorf("Invalid maximum chunk size: %q", value), nil
+			}
+			buildOpts.MaxChunkSize = size
+
+		case strings.HasPrefix(arg, "--node-builtin:") && buildOpts != nil:
+			value := arg[len("--node-builtin:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return fmt.Errorf("Missing \"=\": %q", value), nil
+			}
+			name, text := value[:equals], value[equals+1:]
+			var policy api.NodeBuiltinPolicy
+			switch {
+			case text == "default":
+				policy.Mode = api.NodeBuiltinDefault
+			case text == "external":
+				policy.Mode = api.NodeBuiltinExternal
+			case text == "error":
+				policy.Mode = api.NodeBuiltinError
+			case text == "empty":
+				policy.Mode = api.NodeBuiltinEmpty
+			case text == "polyfill":
+				policy.Mode = api.NodeBuiltinPolyfill
+			case strings.HasPrefix(text, "polyfill:"):
+				policy.Mode = api.NodeBuiltinPolyfill
+				policy.Polyfill = text[len("polyfill:"):]
+			default:
+				return fmt.Errorf("Invalid node builtin policy: %q (valid: default, external, error, empty, polyfill, polyfill:P)", text), nil
+			}
+			if buildOpts.NodeBuiltins == nil {
+				buildOpts.NodeBuiltins = make(map[string]api.NodeBuiltinPolicy)
+			}
+			buildOpts.NodeBuiltins[name] = policy
+
 		case strings.HasPrefix(arg, "--inject:") && buildOpts != nil:
 			buildOpts.Inject = append(buildOpts.Inject, arg[len("--inject:"):])
 
@@ -407,8 +530,10 @@ func parseOptionsImpl(
 				mode = api.JSXModeTransform
 			case "preserve":
 				mode = api.JSXModePreserve
+			case "automatic":
+				mode = api.JSXModeAutomatic
 			default:
-				return fmt.Errorf("Invalid jsx: %q (valid: transform, preserve)", value), nil
+				return fmt.Errorf("Invalid jsx: %q (valid: transform, preserve, automatic)", value), nil
 			}
 			if buildOpts != nil {
 				buildOpts.JSXMode = mode
@@ -432,6 +557,21 @@ func parseOptionsImpl(
 				transformOpts.JSXFragment = value
 			}
 
+		case strings.HasPrefix(arg, "--jsx-import-source="):
+			value := arg[len("--jsx-import-source="):]
+			if buildOpts != nil {
+				buildOpts.JSXImportSource = value
+			} else {
+				transformOpts.JSXImportSource = value
+			}
+
+		case arg == "--jsx-dev":
+			if buildOpts != nil {
+				buildOpts.JSXDev = true
+			} else {
+				transformOpts.JSXDev = true
+			}
+
 		case strings.HasPrefix(arg, "--banner=") && transformOpts != nil:
 			transformOpts.Banner = arg[len("--banner="):]
 
@@ -687,8 +827,10 @@ func runImpl(osArgs []string) int {
 			}
 		}
 
-		// Read from stdin when there are no entry points
-		if len(buildOptions.EntryPoints)+len(buildOptions.EntryPointsAdvanced) == 0 {
+		// Read from stdin when there are no entry points. A build that only
+		// exposes modules through module federation has its container entry.
+		exposesModules := buildOptions.Federation != nil && len(buildOptions.Federation.Exposes) > 0
+		if len(buildOptions.EntryPoints)+len(buildOptions.EntryPointsAdvanced) == 0 && !exposesModules {
 			if buildOptions.Stdin == nil {
 				buildOptions.Stdin = &api.StdinOptions{}
 			}
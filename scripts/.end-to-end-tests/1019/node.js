@@ -0,0 +1,3 @@
+import abc from "pkg/dir/foo.js";
+if (abc !== 123)
+  throw "fail";
@@ -0,0 +1 @@
+import abc from '@scope/pkg/foo.js'; if (abc !== 123) throw 'fail'
\ No newline at end of file
@@ -0,0 +1,3 @@
+import abc from "@scope/pkg/dir/foo.js";
+if (abc !== 123)
+  throw "fail";
@@ -0,0 +1 @@
+const abc = require('pkg/dir/test'); if (abc !== 123) throw 'fail'
\ No newline at end of file
@@ -0,0 +1,3 @@
+const abc = require("pkg/dir/test");
+if (abc !== 123)
+  throw "fail";
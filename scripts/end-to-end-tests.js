@@ -4843,8 +4843,76 @@
         import './out/in.js'
       `,
     }),
+
+    // The "cjs" and "iife" formats export a promise for the exports
+    test(['in.js', '--outfile=out.js', '--format=cjs', '--bundle'], {
+      'in.js': `
+        import { a } from './a.js'
+        export let e = a + 1
+      `,
+      'a.js': `
+        export let a = await new Promise(resolve => setTimeout(() => resolve(1), 10))
+      `,
+      'node.js': `
+        exports.async = async () => {
+          const out = await require('./out.js')
+          if (out.e !== 2) throw 'fail'
+        }
+      `,
+    }, { async: true }),
+    test(['in.js', '--outfile=out.js', '--format=cjs', '--bundle'], {
+      'in.js': `
+        throw await Promise.resolve('stop')
+      `,
+      'node.js': `
+        exports.async = async () => {
+          try { await require('./out.js') } catch (e) { if (e === 'stop') return }
+          throw 'fail'
+        }
+      `,
+    }, { async: true }),
+    test(['in.js', '--outfile=out.js', '--format=iife', '--global-name=lib', '--bundle'], {
+      'in.js': `
+        export let e = await Promise.resolve(2)
+      `,
+      'node.js': `
+        exports.async = async () => {
+          const code = require('fs').readFileSync(__dirname + '/out.js', 'utf8')
+          const out = await new Function(code + '; return lib')()
+          if (out.e !== 2) throw 'fail'
+        }
+      `,
+    }, { async: true }),
   )
 
+  // Test version ranges for shared packages with module federation
+  for (const [range, version, registered, expected] of [
+    ['^0.0.3', '0.0.3', ['0.0.4', '0.1.0'], '0.0.3'],
+    ['^0.2.3', '0.2.3', ['0.2.9', '0.3.0'], '0.2.9'],
+    ['^0.x', '0.2.0', ['0.9.0', '1.0.0'], '0.9.0'],
+    ['~1', '1.0.0', ['1.5.0', '2.0.0'], '1.5.0'],
+    ['~1.2.3', '1.2.3', ['1.2.9', '1.3.0'], '1.2.9'],
+    ['1.2', '1.2.0', ['1.2.5', '1.3.0'], '1.2.5'],
+  ]) {
+    tests.push(test(['in.js', '--outfile=out.js', '--format=iife', '--bundle', `--federation-shared:lib=${range}`], {
+      'in.js': `
+        import { version } from 'lib'
+        globalThis.sharedVersion = version
+      `,
+      'node_modules/lib/package.json': `{ "name": "lib", "version": "${version}" }`,
+      'node_modules/lib/index.js': `
+        export let version = ${JSON.stringify(version)}
+      `,
+      'node.js': `
+        const shared = {}
+        for (const v of ${JSON.stringify(registered)}) shared[v] = { get: () => ({ version: v }) }
+        globalThis.esbuildFederation = { containers: {}, shared: { lib: shared } }
+        require('./out.js')
+        if (globalThis.sharedVersion !== ${JSON.stringify(expected)}) throw 'fail: ' + globalThis.sharedVersion
+      `,
+    }))
+  }
+
   // Test writing to stdout
   tests.push(
     // These should succeed
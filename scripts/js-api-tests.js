@@ -2919,6 +2919,69 @@ let serveTests = {
     result.stop();
     await result.wait;
   },
+  async serveHotModuleReplacement({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const dep = path.join(testDir, 'dep.js')
+    await writeFileAsync(input, `import {x} from './dep'; console.log(x); import.meta.hot.accept()`)
+    await writeFileAsync(dep, `export let x = 1`)
+
+    const result = await esbuild.serve({
+      host: '127.0.0.1',
+    }, {
+      entryPoints: [input],
+      bundle: true,
+      format: 'esm',
+      outfile: 'out.js',
+      hmr: true,
+    })
+
+    // Listen for updates as server-sent events. Each event is one update.
+    let onEvent
+    let events = []
+    const nextEvent = () => new Promise((resolve, reject) => {
+      const timeout = setTimeout(() => reject(new Error('Timeout after 30 seconds')), 30 * 1000)
+      onEvent = () => {
+        if (events.length) clearTimeout(timeout), resolve(events.shift())
+      }
+      onEvent()
+    })
+    const request = await new Promise((resolve, reject) => {
+      const req = http.get({ host: result.host, port: result.port, path: '/esbuild-hmr' }, res => {
+        assert.strictEqual(res.statusCode, 200)
+        assert.strictEqual(res.headers['content-type'], 'text/event-stream')
+        let buffer = ''
+        res.on('data', chunk => {
+          buffer += chunk
+          let end
+          while ((end = buffer.indexOf('\n\n')) >= 0) {
+            const lines = buffer.slice(0, end).split('\n')
+            buffer = buffer.slice(end + 2)
+            events.push(lines.map(line => line.replace(/^data: /, '')).join('\n'))
+            onEvent && onEvent()
+          }
+        })
+        resolve(req)
+      }).on('error', reject)
+    })
+
+    try {
+      // The first build has nothing to update
+      const buffer = await fetch(result.host, result.port, '/out.js')
+      assert(buffer.toString().includes(`var x = 1;`))
+
+      // Changing a file sends an update with only the module that changed
+      const event = nextEvent()
+      await writeFileAtomic(dep, `export let x = 2`)
+      const update = await event
+      assert(update.includes(`"dep.js"(exports) {`), update)
+      assert(update.includes(`var x = 2;`), update)
+      assert(!update.includes(`console.log`), update)
+    } finally {
+      request.destroy()
+      result.stop()
+      await result.wait
+    }
+  },
 }
 
 async function futureSyntax(esbuild, js, targetBelow, targetAbove) {